@@ -38,6 +38,7 @@ import (
 	tr "github.com/tricksterproxy/trickster/pkg/observability/tracing/registration"
 	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
 	th "github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/handlers/purge"
 	"github.com/tricksterproxy/trickster/pkg/routing"
 	"github.com/tricksterproxy/trickster/pkg/runtime"
 
@@ -152,7 +153,11 @@ func applyConfig(conf, oldConf *config.Config, wg *sync.WaitGroup, logger *tl.Lo
 	alb.StartALBPools(o, hc.Statuses())
 	routing.RegisterDefaultBackendRoutes(router, o, logger, tracers)
 	routing.RegisterHealthHandler(mr, conf.Main.HealthHandlerPath, hc)
-	applyListenerConfigs(conf, oldConf, router, http.HandlerFunc(rh), mr, logger, tracers)
+	var ph http.Handler
+	if conf.ReloadConfig.PurgeAuthToken != "" {
+		ph = purge.Handler(conf.ReloadConfig.PurgeAuthToken, o, caches, logger)
+	}
+	applyListenerConfigs(conf, oldConf, router, http.HandlerFunc(rh), ph, mr, logger, tracers)
 
 	metrics.LastReloadSuccessfulTimestamp.Set(float64(time.Now().Unix()))
 	metrics.LastReloadSuccessful.Set(1)
@@ -214,6 +219,7 @@ func applyCachingConfig(c, oc *config.Config, logger *tl.Logger,
 		for k, v := range c.Caches {
 			caches[k] = registration.NewCache(k, v, logger)
 		}
+		registration.ConnectTiers(caches, logger)
 		return caches
 	}
 
@@ -224,8 +230,9 @@ func applyCachingConfig(c, oc *config.Config, logger *tl.Logger,
 			ocfg := w.Configuration()
 
 			// if a cache is in both the old and new config, and unchanged, pass the
-			// pre-existing object instead of making a new one
-			if v.Equal(ocfg) {
+			// pre-existing object instead of making a new one. tiered caches are always
+			// remade, since their tiers may have been remade
+			if v.Equal(ocfg) && v.ProviderID != providers.Tiered {
 				caches[k] = w
 				continue
 			}
@@ -254,6 +261,7 @@ func applyCachingConfig(c, oc *config.Config, logger *tl.Logger,
 		// the newly-named cache is not in the old config or couldn't be reused, so make it anew
 		caches[k] = registration.NewCache(k, v, logger)
 	}
+	registration.ConnectTiers(caches, logger)
 	return caches
 }
 
@@ -36,6 +36,8 @@ import (
 	tracing "github.com/tricksterproxy/trickster/pkg/observability/tracing/options"
 	rewriter "github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
 	rwopts "github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter/options"
+	resprewriter "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter"
+	resprwopts "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter/options"
 	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
 
 	"gopkg.in/yaml.v2"
@@ -63,16 +65,19 @@ type Config struct {
 	Rules map[string]*rule.Options `yaml:"rules,omitempty"`
 	// RequestRewriters is a map of the Rewriters
 	RequestRewriters map[string]*rwopts.Options `yaml:"request_rewriters,omitempty"`
+	// ResponseRewriters is a map of the Response Rewriters
+	ResponseRewriters map[string]*resprwopts.Options `yaml:"response_rewriters,omitempty"`
 	// ReloadConfig provides configurations for in-process config reloading
 	ReloadConfig *reload.Options `yaml:"reloading,omitempty"`
 
 	// Resources holds runtime resources uses by the Config
 	Resources *Resources `yaml:"-"`
 
-	CompiledRewriters map[string]rewriter.RewriteInstructions `yaml:"-"`
-	activeCaches      map[string]interface{}
-	providedOriginURL string
-	providedProvider  string
+	CompiledRewriters         map[string]rewriter.RewriteInstructions `yaml:"-"`
+	CompiledResponseRewriters map[string]*resprewriter.Rewriter       `yaml:"-"`
+	activeCaches              map[string]interface{}
+	providedOriginURL         string
+	providedProvider          string
 
 	LoaderWarnings []string `yaml:"-"`
 }
@@ -204,9 +209,17 @@ func (c *Config) setDefaults(metadata yamlx.KeyLookup) error {
 		}
 	}
 
+	if c.ResponseRewriters != nil {
+		if c.CompiledResponseRewriters, err =
+			resprewriter.ProcessConfigs(c.ResponseRewriters); err != nil {
+			return err
+		}
+	}
+
 	c.activeCaches = make(map[string]interface{})
 	for k, v := range c.Backends {
-		w, err := bo.SetDefaults(k, v, metadata, c.CompiledRewriters, c.Backends, c.activeCaches)
+		w, err := bo.SetDefaults(k, v, metadata, c.CompiledRewriters,
+			c.CompiledResponseRewriters, c.Backends, c.activeCaches)
 		if err != nil {
 			return err
 		}
@@ -319,6 +332,13 @@ func (c *Config) Clone() *Config {
 		}
 	}
 
+	if c.ResponseRewriters != nil && len(c.ResponseRewriters) > 0 {
+		nc.ResponseRewriters = make(map[string]*resprwopts.Options)
+		for k, v := range c.ResponseRewriters {
+			nc.ResponseRewriters[k] = v.Clone()
+		}
+	}
+
 	return nc
 }
 
@@ -339,3 +339,45 @@ func TestConfigFilePath(t *testing.T) {
 	}
 
 }
+
+const testResponseRewriter = `
+response_rewriters:
+  example:
+    match_status_codes: [ 200 ]
+    instructions:
+      - [ header, delete, Set-Cookie ]
+      - [ json, rename, data.result.*.metric.job, service ]
+`
+
+func TestProcessResponseRewriters(t *testing.T) {
+
+	c, _ := emptyTestConfig()
+	c.Backends["test"].RespRewriterName = "example"
+	yml := c.String() + testResponseRewriter
+	err := c.loadYAMLConfig(yml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.CompiledResponseRewriters["example"]; !ok {
+		t.Error("expected compiled response rewriter")
+	}
+	if c.Backends["test"].RespRewriter == nil {
+		t.Error("expected backend response rewriter")
+	}
+
+	c2 := c.Clone()
+	if len(c2.ResponseRewriters["example"].Instructions) != 2 {
+		t.Error("expected cloned response rewriter")
+	}
+
+	err = c.loadYAMLConfig(strings.Replace(yml, "[ json, rename", "[ json, invalid", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for response rewriter compilation")
+	}
+
+	err = c.loadYAMLConfig(strings.Replace(yml, "resp_rewriter_name: example",
+		"resp_rewriter_name: invalid", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid response rewriter name")
+	}
+}
@@ -28,4 +28,6 @@ const (
 	DefaultRateLimitMS = 3000
 	// DefaultReloadHandlerPath defines the default path for the Reload Handler
 	DefaultReloadHandlerPath = "/trickster/config/reload"
+	// DefaultPurgeHandlerPath defines the default path for the Cache Purge Handler
+	DefaultPurgeHandlerPath = "/trickster/cache/purge"
 )
@@ -33,15 +33,21 @@ type Options struct {
 	// This prevents a bad actor from stating the config file with millions of concurrent requets
 	// The rate limit does not apply to SIGHUP-based reload requests
 	RateLimitMS int `yaml:"rate_limit_ms,omitempty"`
+	// PurgeHandlerPath provides the path to register the Cache Purge Handler
+	PurgeHandlerPath string `yaml:"purge_handler_path,omitempty"`
+	// PurgeAuthToken is the bearer token that clients must provide in the Authorization header
+	// of Cache Purge requests. The Cache Purge Handler is not registered when this is empty.
+	PurgeAuthToken string `yaml:"purge_auth_token,omitempty"`
 }
 
 // New returns a new Options references with Default Values set
 func New() *Options {
 	return &Options{
-		ListenAddress:  DefaultReloadAddress,
-		ListenPort:     DefaultReloadPort,
-		HandlerPath:    DefaultReloadHandlerPath,
-		DrainTimeoutMS: DefaultDrainTimeoutMS,
-		RateLimitMS:    DefaultRateLimitMS,
+		ListenAddress:    DefaultReloadAddress,
+		ListenPort:       DefaultReloadPort,
+		HandlerPath:      DefaultReloadHandlerPath,
+		DrainTimeoutMS:   DefaultDrainTimeoutMS,
+		RateLimitMS:      DefaultRateLimitMS,
+		PurgeHandlerPath: DefaultPurgeHandlerPath,
 	}
 }
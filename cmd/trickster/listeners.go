@@ -34,8 +34,8 @@ import (
 var lg = listener.NewListenerGroup()
 
 func applyListenerConfigs(conf, oldConf *config.Config,
-	router, reloadHandler http.Handler, metricsRouter *http.ServeMux, log *tl.Logger,
-	tracers tracing.Tracers) {
+	router, reloadHandler, purgeHandler http.Handler, metricsRouter *http.ServeMux,
+	log *tl.Logger, tracers tracing.Tracers) {
 
 	var err error
 	var tlsConfig *tls.Config
@@ -46,6 +46,9 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 
 	adminRouter := http.NewServeMux()
 	adminRouter.Handle(conf.ReloadConfig.HandlerPath, reloadHandler)
+	if purgeHandler != nil {
+		adminRouter.Handle(conf.ReloadConfig.PurgeHandlerPath, purgeHandler)
+	}
 
 	// No changes in frontend config
 	if oldConf != nil && oldConf.Frontend != nil &&
@@ -158,6 +161,9 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 		lg.DrainAndClose("reloadListener", time.Millisecond*500)
 		rr.HandleFunc(conf.Main.ConfigHandlerPath, ph.ConfigHandleFunc(conf))
 		rr.Handle(conf.ReloadConfig.HandlerPath, reloadHandler)
+		if purgeHandler != nil {
+			rr.Handle(conf.ReloadConfig.PurgeHandlerPath, purgeHandler)
+		}
 		if conf.Main.PprofServer == "both" || conf.Main.PprofServer == "reload" {
 			routing.RegisterPprofRoutes("reload", rr, log)
 		}
@@ -167,6 +173,9 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 	} else {
 		rr.HandleFunc(conf.Main.ConfigHandlerPath, ph.ConfigHandleFunc(conf))
 		rr.Handle(conf.ReloadConfig.HandlerPath, reloadHandler)
+		if purgeHandler != nil {
+			rr.Handle(conf.ReloadConfig.PurgeHandlerPath, purgeHandler)
+		}
 		lg.UpdateRouter("reloadListener", rr)
 	}
 }
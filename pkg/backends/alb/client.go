@@ -29,6 +29,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
 )
 
 // Client Implements the Proxy Client Interface
@@ -38,8 +39,13 @@ type Client struct {
 	pool               pool.Pool
 	handler            http.Handler // this is the actual handler for all request to this backend
 	fgr                bool
+	hashKeyElement     string       // when mechanism is ch, the request element used as the hash key
+	hashKeyName        string       // when mechanism is ch, the param or header name used as the hash key
 	mergePaths         []string     // paths handled by the alb client that are enabled for tsmerge
 	nonmergeHandler    http.Handler // when methodology is tsmerge, this handler is for non-mergable paths
+	outputFormat       string       // when mechanism is tsmerge, the provider format of the merged response
+	outputMarshaler    timeseries.MarshalWriterFunc
+	tiers              []*tier // when mechanism is tt, the pool members ordered from newest to oldest
 	hasTransformations bool
 }
 
@@ -48,9 +54,11 @@ func (c *Client) Handlers() map[string]http.Handler {
 	return map[string]http.Handler{"alb": c.handler}
 }
 
-// NewClient returns a new ALB client reference
+// NewClient returns a new ALB client reference. When the mechanism is tsmerge and an
+// output_format is configured, outputModeler is the Modeler for that provider, which
+// writes merged time series regardless of the pool members' providers
 func NewClient(name string, o *bo.Options, router http.Handler,
-) (*Client, error) {
+	outputModeler *timeseries.Modeler) (*Client, error) {
 	c := &Client{}
 
 	b, err := backends.New(name, o, nil, router, nil)
@@ -72,9 +80,20 @@ func NewClient(name string, o *bo.Options, router http.Handler,
 			c.handler = http.HandlerFunc(c.handleResponseMerge)
 			c.nonmergeHandler = http.HandlerFunc(c.handleRoundRobin)
 			c.mergePaths = o.ALBOptions.MergeablePaths
-		default:
+		case pool.TimeTiered.String():
+			c.handler = http.HandlerFunc(c.handleTimeTiered)
+			c.mergePaths = o.ALBOptions.MergeablePaths
+		case pool.ConsistentHash.String():
+			c.handler = http.HandlerFunc(c.handleConsistentHash)
+			c.hashKeyElement = o.ALBOptions.HashKeyElement
+			c.hashKeyName = o.ALBOptions.HashKeyName
+		default: // rr, wrr and lor select a single pool member without the request
 			c.handler = http.HandlerFunc(c.handleRoundRobin)
 		}
+		if o.ALBOptions.OutputFormat != "" && outputModeler != nil {
+			c.outputFormat = o.ALBOptions.OutputFormat
+			c.outputMarshaler = outputModeler.WireMarshalWriter
+		}
 		c.hasTransformations = o.HasTransformations()
 	}
 	return c, nil
@@ -123,9 +142,72 @@ func (c *Client) ValidatePool(clients backends.Backends) error {
 			return fmt.Errorf("invalid pool member name [%s] in backend [%s]", n, c.Name())
 		}
 	}
+	switch c.Configuration().ALBOptions.MechanismName {
+	case pool.TimeTiered.String():
+		if _, err := c.timeTiers(clients, nil); err != nil {
+			return err
+		}
+		fallthrough
+	case pool.TimeSeriesMerge.String():
+		if _, err := c.mergeablePaths(clients); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// mergeablePaths returns the paths that the pool members' providers support merging.
+// Time series merges require that all pool members share the same provider, unless an
+// output_format is configured, in which case the members' results are translated into
+// that format and the mergeable paths are the union of each member provider's paths
+func (c *Client) mergeablePaths(clients backends.Backends) ([]string, error) {
+	outputFormat := c.Configuration().ALBOptions.OutputFormat
+	var provider string
+	var paths []string
+	seen := make(map[string]bool)
+	for _, n := range c.Configuration().ALBOptions.Pool {
+		tc, ok := clients[n]
+		if !ok || tc.Configuration() == nil {
+			continue
+		}
+		p := tc.Configuration().Provider
+		if provider == "" {
+			provider = p
+		} else if p != provider {
+			if outputFormat == "" {
+				return nil, fmt.Errorf("tsmerge pool members in backend [%s] must share the same "+
+					"provider: found %s and %s", c.Name(), provider, p)
+			}
+			for _, v := range []string{provider, p, outputFormat} {
+				if _, ok := untranslatableProviders[v]; ok {
+					return nil, fmt.Errorf("tsmerge pool members in backend [%s] cannot be "+
+						"translated to or from provider %s", c.Name(), v)
+				}
+			}
+		}
+		if mc, ok := tc.(mergeable); ok {
+			for _, v := range mc.MergeablePaths() {
+				if !seen[v] {
+					seen[v] = true
+					paths = append(paths, v)
+				}
+			}
+		}
+	}
+	return paths, nil
+}
+
+// untranslatableProviders lists the providers whose time series are not represented as
+// DataSets, and so can't be merged with, or written as, another provider's time series
+var untranslatableProviders = map[string]interface{}{
+	"irondb": nil,
+}
+
+// mergeable is implemented by Backends that support merging responses across pool members
+type mergeable interface {
+	MergeablePaths() []string
+}
+
 // ValidateAndStartPool starts this Client's pool up using the provided list of backends to
 // validate and map out the pool configuration
 func (c *Client) ValidateAndStartPool(clients backends.Backends, hcs healthcheck.StatusLookup) error {
@@ -146,7 +228,22 @@ func (c *Client) ValidateAndStartPool(clients backends.Backends, hcs healthcheck
 			return fmt.Errorf("invalid pool member name [%s] in backend [%s]", n, c.Name())
 		}
 		hc, _ := hcs[n]
-		targets = append(targets, pool.NewTarget(tc.Router(), hc))
+		targets = append(targets, pool.NewWeightedTarget(n, o.Weights[n], tc.Router(), hc))
+	}
+	if m == pool.TimeSeriesMerge || m == pool.TimeTiered {
+		paths, err := c.mergeablePaths(clients)
+		if err != nil {
+			return err
+		}
+		o.MergeablePaths = paths
+		c.mergePaths = paths
+	}
+	if m == pool.TimeTiered {
+		tiers, err := c.timeTiers(clients, targets)
+		if err != nil {
+			return err
+		}
+		c.tiers = tiers
 	}
 	c.pool = pool.New(m, targets, o.HealthyFloor)
 	return nil
@@ -23,6 +23,7 @@ import (
 	ao "github.com/tricksterproxy/trickster/pkg/backends/alb/options"
 	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
 	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
 )
 
 func TestHandlers(t *testing.T) {
@@ -33,7 +34,7 @@ func TestHandlers(t *testing.T) {
 	o := bo.New()
 	o.ALBOptions = a
 
-	cl, err := NewClient("test", o, nil)
+	cl, err := NewClient("test", o, nil, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -43,29 +44,48 @@ func TestHandlers(t *testing.T) {
 	}
 
 	a.MechanismName = "fgr"
-	cl, err = NewClient("test", o, nil)
+	cl, err = NewClient("test", o, nil, nil)
 	if err != nil {
 		t.Error(err)
 	}
 
 	a.MechanismName = "nlm"
-	cl, err = NewClient("test", o, nil)
+	cl, err = NewClient("test", o, nil, nil)
 	if err != nil {
 		t.Error(err)
 	}
 
 	a.MechanismName = "tsm"
-	cl, err = NewClient("test", o, nil)
+	cl, err = NewClient("test", o, nil, nil)
 	if err != nil {
 		t.Error(err)
 	}
 
 	a.MechanismName = "rr"
-	cl, err = NewClient("test", o, nil)
+	cl, err = NewClient("test", o, nil, nil)
 	if err != nil {
 		t.Error(err)
 	}
 
+	for _, m := range []string{"wrr", "lor"} {
+		a.MechanismName = m
+		cl, err = NewClient("test", o, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	a.MechanismName = "ch"
+	a.HashKeyElement = ao.HashKeyHeader
+	a.HashKeyName = "X-Tenant"
+	cl, err = NewClient("test", o, nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if cl.hashKeyElement != ao.HashKeyHeader || cl.hashKeyName != "X-Tenant" {
+		t.Error("expected hash key options")
+	}
+
 }
 
 func TestDefaultPathConfigs(t *testing.T) {
@@ -81,7 +101,7 @@ func TestStartALBPools(t *testing.T) {
 		t.Error(err)
 	}
 	o := bo.New()
-	cl, _ := NewClient("test", o, nil)
+	cl, _ := NewClient("test", o, nil, nil)
 	b := backends.Backends{"test": cl}
 	err = StartALBPools(b, nil)
 	if err == nil || err.Error() != "invalid options" {
@@ -101,7 +121,7 @@ func TestValidatePools(t *testing.T) {
 
 	o.ALBOptions = a
 	o.Provider = "alb"
-	cl, _ := NewClient("test", o, nil)
+	cl, _ := NewClient("test", o, nil, nil)
 	b := backends.Backends{"test": cl}
 	err = ValidatePools(b)
 	expected := `invalid mechanism name [rx] in backend [test]`
@@ -134,7 +154,7 @@ func TestValidateAndStartPool(t *testing.T) {
 
 	o := bo.New()
 	o.ALBOptions = nil
-	cl, _ := NewClient("test", o, nil)
+	cl, _ := NewClient("test", o, nil, nil)
 	err := cl.ValidateAndStartPool(nil, nil)
 	if err == nil || err.Error() != "invalid options" {
 		t.Error("expected error for invalid options, got ", err)
@@ -171,3 +191,119 @@ func TestValidateAndStartPool(t *testing.T) {
 	}
 
 }
+
+type testMergeableBackend struct {
+	backends.Backend
+}
+
+func (b *testMergeableBackend) MergeablePaths() []string {
+	return []string{"/query"}
+}
+
+func newTestMergeableBackend(t *testing.T, name, provider string) backends.Backend {
+	o := bo.New()
+	o.Name = name
+	o.Provider = provider
+	b, err := backends.New(name, o, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testMergeableBackend{Backend: b}
+}
+
+func TestValidateAndStartPoolMergeablePaths(t *testing.T) {
+
+	o := bo.New()
+	o.Provider = "alb"
+	o.ALBOptions = &ao.Options{MechanismName: "tsm", Pool: []string{"a", "b"}}
+	cl, _ := NewClient("test", o, nil, nil)
+
+	b := backends.Backends{
+		"test": cl,
+		"a":    newTestMergeableBackend(t, "a", "influxdb"),
+		"b":    newTestMergeableBackend(t, "b", "influxdb"),
+	}
+
+	hcs := healthcheck.StatusLookup{"a": &healthcheck.Status{}, "b": &healthcheck.Status{},
+		"c": &healthcheck.Status{}}
+
+	err := cl.ValidateAndStartPool(b, hcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cl.mergePaths) != 1 || cl.mergePaths[0] != "/query" ||
+		len(o.ALBOptions.MergeablePaths) != 1 {
+		t.Errorf("expected mergeable paths got %v", cl.mergePaths)
+	}
+
+	// pool members without mergeable paths are not merged
+	rp, _ := backends.New("c", bo.New(), nil, nil, nil)
+	b["c"] = rp
+	o.ALBOptions.Pool = []string{"c"}
+	err = cl.ValidateAndStartPool(b, hcs)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(cl.mergePaths) != 0 {
+		t.Errorf("expected no mergeable paths got %v", cl.mergePaths)
+	}
+
+	b["b"] = newTestMergeableBackend(t, "b", "clickhouse")
+	o.ALBOptions.Pool = []string{"a", "b"}
+	expected := "tsmerge pool members in backend [test] must share the same provider: " +
+		"found influxdb and clickhouse"
+	err = cl.ValidateAndStartPool(b, hcs)
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %s got %v", expected, err)
+	}
+	err = cl.ValidatePool(b)
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %s got %v", expected, err)
+	}
+}
+
+func TestValidateAndStartPoolOutputFormat(t *testing.T) {
+
+	o := bo.New()
+	o.Provider = "alb"
+	o.ALBOptions = &ao.Options{MechanismName: "tsm", Pool: []string{"a", "b"},
+		OutputFormat: "prometheus"}
+	cl, _ := NewClient("test", o, nil, &timeseries.Modeler{})
+	if cl.outputFormat != "prometheus" {
+		t.Errorf("expected %s got %s", "prometheus", cl.outputFormat)
+	}
+
+	p := newTestMergeableBackend(t, "b", "prometheus")
+	b := backends.Backends{
+		"test": cl,
+		"a":    newTestMergeableBackend(t, "a", "influxdb"),
+		"b":    &testPromBackend{testMergeableBackend: p.(*testMergeableBackend)},
+	}
+	hcs := healthcheck.StatusLookup{"a": &healthcheck.Status{}, "b": &healthcheck.Status{}}
+
+	// members of different providers are merged into the output format
+	err := cl.ValidateAndStartPool(b, hcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cl.mergePaths) != 2 || cl.mergePaths[0] != "/query" ||
+		cl.mergePaths[1] != "/api/v1/query_range" {
+		t.Errorf("expected mergeable paths got %v", cl.mergePaths)
+	}
+
+	b["b"] = newTestMergeableBackend(t, "b", "irondb")
+	expected := "tsmerge pool members in backend [test] cannot be translated to or from " +
+		"provider irondb"
+	err = cl.ValidatePool(b)
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %s got %v", expected, err)
+	}
+}
+
+type testPromBackend struct {
+	*testMergeableBackend
+}
+
+func (b *testPromBackend) MergeablePaths() []string {
+	return []string{"/query", "/api/v1/query_range"}
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alb
+
+import (
+	"net/http"
+
+	ao "github.com/tricksterproxy/trickster/pkg/backends/alb/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+)
+
+func (c *Client) handleConsistentHash(w http.ResponseWriter, r *http.Request) {
+	if c.pool == nil {
+		handlers.HandleBadGateway(w, r)
+		return
+	}
+	hl := c.pool.NextForKey(c.hashKey(r))
+	if len(hl) > 0 {
+		hl[0].ServeHTTP(w, r)
+		return
+	}
+	handlers.HandleBadGateway(w, r)
+}
+
+// hashKey returns the value of the request element configured as the hash key
+func (c *Client) hashKey(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	switch c.hashKeyElement {
+	case ao.HashKeyParam:
+		v, _, _ := params.GetRequestValues(r)
+		return v.Get(c.hashKeyName)
+	case ao.HashKeyHeader:
+		return r.Header.Get(c.hashKeyName)
+	}
+	if r.URL == nil {
+		return ""
+	}
+	return r.URL.Path
+}
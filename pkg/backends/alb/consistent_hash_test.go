@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	ao "github.com/tricksterproxy/trickster/pkg/backends/alb/options"
+	"github.com/tricksterproxy/trickster/pkg/backends/alb/pool"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+func TestHandleConsistentHash(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	c := &Client{}
+	c.handleConsistentHash(w, nil)
+	if w.Code != http.StatusBadGateway {
+		t.Error("expected 502 got", w.Code)
+	}
+
+	var hits [2]int
+	hs := make([]http.Handler, 2)
+	for i := range hs {
+		j := i
+		hs[i] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[j]++
+		})
+	}
+	c.pool, _, _ = testPool(pool.ConsistentHash, -1, hs)
+	time.Sleep(250 * time.Millisecond)
+
+	c.hashKeyElement = ao.HashKeyParam
+	c.hashKeyName = "query"
+	for i := 0; i < 10; i++ {
+		r, _ := http.NewRequest(http.MethodGet, "http://0/api/v1/query_range?query=up&step="+
+			string(rune('0'+i)), nil)
+		c.handleConsistentHash(httptest.NewRecorder(), r)
+	}
+	if hits[0]+hits[1] != 10 || (hits[0] != 0 && hits[1] != 0) {
+		t.Errorf("expected all requests to route to the same member, got %v", hits)
+	}
+
+}
+
+func TestHashKey(t *testing.T) {
+
+	c := &Client{}
+	if k := c.hashKey(nil); k != "" {
+		t.Errorf("expected empty key got %s", k)
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, "http://0/api/v1/query?query=up", nil)
+	r.Header.Set("X-Tenant", "tenant1")
+	if k := c.hashKey(r); k != "/api/v1/query" {
+		t.Errorf("expected %s got %s", "/api/v1/query", k)
+	}
+
+	c.hashKeyElement, c.hashKeyName = ao.HashKeyHeader, "X-Tenant"
+	if k := c.hashKey(r); k != "tenant1" {
+		t.Errorf("expected %s got %s", "tenant1", k)
+	}
+
+	c.hashKeyElement, c.hashKeyName = ao.HashKeyParam, "query"
+	if k := c.hashKey(r); k != "up" {
+		t.Errorf("expected %s got %s", "up", k)
+	}
+
+	v := url.Values{"query": []string{"sum(up)"}}
+	r, _ = http.NewRequest(http.MethodPost, "http://0/api/v1/query", strings.NewReader(v.Encode()))
+	r.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+	if k := c.hashKey(r); k != "sum(up)" {
+		t.Errorf("expected %s got %s", "sum(up)", k)
+	}
+
+}
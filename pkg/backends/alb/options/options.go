@@ -18,7 +18,9 @@ package options
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/backends/providers"
 	"github.com/tricksterproxy/trickster/pkg/util/copiers"
@@ -40,27 +42,102 @@ type Options struct {
 	// OutputFormat accompanies the tsmerge Mechanism to indicate the provider output format
 	// options include any valid time seres backend like prometheus, influxdb or clickhouse
 	OutputFormat string `yaml:"output_format,omitempty"`
+	// MergeTimeoutMS accompanies the tsmerge Mechanism to indicate how long to wait for all pool
+	// members to respond before merging the responses received so far. 0 (default) waits indefinitely
+	MergeTimeoutMS int `yaml:"merge_timeout_ms,omitempty"`
+	// MinResponders accompanies the tsmerge Mechanism to indicate the minimum number of pool members
+	// that must respond within the merge timeout for a merged response to be served. Default is 1
+	MinResponders int `yaml:"min_responders,omitempty"`
+	// Weights maps pool member names to their weights, for use with the Weighted Round Robin
+	// and Consistent Hash mechanisms. Pool members not in the map have a weight of 1
+	Weights map[string]int `yaml:"weights,omitempty"`
+	// HashKey accompanies the Consistent Hash Mechanism to indicate the request element used
+	// as the hash key. Options are 'path' (default), 'param:<name>' and 'header:<name>'
+	HashKey string `yaml:"hash_key,omitempty"`
+	// TierBoundariesMS accompanies the Time Tiered Mechanism to indicate the ages at which the
+	// requested time range is split across the pool members. The pool is ordered from the newest
+	// tier to the oldest, so pool member N serves data newer than boundary N, and the last pool
+	// member serves all data older than the last boundary
+	TierBoundariesMS []int `yaml:"tier_boundaries_ms,omitempty"`
+	// ReplicaLabels accompanies the tsmerge Mechanism to name the labels that distinguish the
+	// replicas of an HA pair. Series that are identical apart from these labels are deduplicated
+	ReplicaLabels []string `yaml:"replica_labels,omitempty"`
+	// ReplicaPenaltyMS accompanies ReplicaLabels to indicate how much later than another replica's
+	// next point the current replica's next point may be before deduplication switches replicas
+	ReplicaPenaltyMS int `yaml:"replica_penalty_ms,omitempty"`
 	// MergeablePaths are ones that Trickster can merge multiple documents into a single response
-	MergeablePaths []string `yaml:"-"` // this is populated by backends that support tsmerge
+	MergeablePaths []string `yaml:"-"` // this is populated from the pool members' provider
 
+	// MergeTimeout is the time.Duration representation of MergeTimeoutMS
+	MergeTimeout time.Duration `yaml:"-"`
+	// HashKeyElement is the request element portion of HashKey (path, param or header)
+	HashKeyElement string `yaml:"-"`
+	// HashKeyName is the param or header name portion of HashKey
+	HashKeyName string `yaml:"-"`
+	// TierBoundaries is the time.Duration representation of TierBoundariesMS
+	TierBoundaries []time.Duration `yaml:"-"`
+	// ReplicaPenalty is the time.Duration representation of ReplicaPenaltyMS
+	ReplicaPenalty time.Duration `yaml:"-"`
 }
 
 // New returns a New Options object with the default values
 func New() *Options {
-	return &Options{}
+	return &Options{
+		MinResponders:    DefaultMinResponders,
+		ReplicaPenaltyMS: DefaultReplicaPenaltyMS,
+		ReplicaPenalty:   time.Duration(DefaultReplicaPenaltyMS) * time.Millisecond,
+	}
 }
 
+// DefaultMinResponders is the default minimum number of pool members that must
+// respond within the merge timeout for a tsmerge response to be served
+const DefaultMinResponders = 1
+
+// DefaultReplicaPenaltyMS is the default replica penalty used when deduplicating
+// the series of HA replicas in a tsmerge response
+const DefaultReplicaPenaltyMS = 5000
+
+// Hash Key Elements
+const (
+	HashKeyPath   = "path"
+	HashKeyParam  = "param"
+	HashKeyHeader = "header"
+)
+
 // Clone returns a perfect copy of the Options
 func (o *Options) Clone() *Options {
 
 	c := &Options{
-		MechanismName: o.MechanismName,
-		HealthyFloor:  o.HealthyFloor,
-		OutputFormat:  o.OutputFormat,
+		MechanismName:    o.MechanismName,
+		HealthyFloor:     o.HealthyFloor,
+		OutputFormat:     o.OutputFormat,
+		MergeTimeoutMS:   o.MergeTimeoutMS,
+		MinResponders:    o.MinResponders,
+		MergeTimeout:     o.MergeTimeout,
+		HashKey:          o.HashKey,
+		HashKeyElement:   o.HashKeyElement,
+		HashKeyName:      o.HashKeyName,
+		ReplicaPenaltyMS: o.ReplicaPenaltyMS,
+		ReplicaPenalty:   o.ReplicaPenalty,
 	}
 
 	c.Pool = copiers.CopyStrings(o.Pool)
+	if o.Weights != nil {
+		c.Weights = make(map[string]int, len(o.Weights))
+		for k, v := range o.Weights {
+			c.Weights[k] = v
+		}
+	}
 	c.MergeablePaths = copiers.CopyStrings(o.MergeablePaths)
+	c.ReplicaLabels = copiers.CopyStrings(o.ReplicaLabels)
+	if o.TierBoundariesMS != nil {
+		c.TierBoundariesMS = make([]int, len(o.TierBoundariesMS))
+		copy(c.TierBoundariesMS, o.TierBoundariesMS)
+	}
+	if o.TierBoundaries != nil {
+		c.TierBoundaries = make([]time.Duration, len(o.TierBoundaries))
+		copy(c.TierBoundaries, o.TierBoundaries)
+	}
 
 	return c
 }
@@ -91,8 +168,8 @@ func SetDefaults(name string, options *Options, metadata yamlx.KeyLookup) (*Opti
 	}
 
 	if metadata.IsDefined("backends", name, "alb", "output_format") && options.OutputFormat != "" {
-		if !strings.HasPrefix(o.MechanismName, "tsm") {
-			return nil, errors.New("'output_format' option is only valid for provider 'alb' and mechanism 'tsmerge'")
+		if !strings.HasPrefix(o.MechanismName, "tsm") && o.MechanismName != "tt" {
+			return nil, errors.New("'output_format' option is only valid for provider 'alb' and mechanisms 'tsmerge' and 'tt'")
 		}
 		o.OutputFormat = options.OutputFormat
 		if !providers.IsSupportedTimeSeriesProvider(o.OutputFormat) {
@@ -100,6 +177,113 @@ func SetDefaults(name string, options *Options, metadata yamlx.KeyLookup) (*Opti
 		}
 	}
 
+	if metadata.IsDefined("backends", name, "alb", "merge_timeout_ms") {
+		if !strings.HasPrefix(o.MechanismName, "tsm") {
+			return nil, errors.New("'merge_timeout_ms' option is only valid for provider 'alb' and mechanism 'tsmerge'")
+		}
+		if options.MergeTimeoutMS < 0 {
+			return nil, errors.New("value for 'merge_timeout_ms' is invalid")
+		}
+		o.MergeTimeoutMS = options.MergeTimeoutMS
+		o.MergeTimeout = time.Duration(o.MergeTimeoutMS) * time.Millisecond
+	}
+
+	if metadata.IsDefined("backends", name, "alb", "min_responders") {
+		if !strings.HasPrefix(o.MechanismName, "tsm") {
+			return nil, errors.New("'min_responders' option is only valid for provider 'alb' and mechanism 'tsmerge'")
+		}
+		if options.MinResponders < 1 {
+			return nil, errors.New("value for 'min_responders' is invalid")
+		}
+		o.MinResponders = options.MinResponders
+	}
+
+	if metadata.IsDefined("backends", name, "alb", "replica_labels") {
+		if !strings.HasPrefix(o.MechanismName, "tsm") {
+			return nil, errors.New("'replica_labels' option is only valid for provider 'alb' and mechanism 'tsmerge'")
+		}
+		o.ReplicaLabels = options.ReplicaLabels
+	}
+
+	if metadata.IsDefined("backends", name, "alb", "replica_penalty_ms") {
+		if len(o.ReplicaLabels) == 0 {
+			return nil, errors.New("'replica_penalty_ms' option is only valid with 'replica_labels'")
+		}
+		if options.ReplicaPenaltyMS < 0 {
+			return nil, errors.New("value for 'replica_penalty_ms' is invalid")
+		}
+		o.ReplicaPenaltyMS = options.ReplicaPenaltyMS
+		o.ReplicaPenalty = time.Duration(o.ReplicaPenaltyMS) * time.Millisecond
+	}
+
+	if metadata.IsDefined("backends", name, "alb", "weights") {
+		if o.MechanismName != "wrr" && o.MechanismName != "ch" {
+			return nil, errors.New("'weights' option is only valid for provider 'alb' and mechanisms 'wrr' and 'ch'")
+		}
+		for k, v := range options.Weights {
+			if !inPool(k, o.Pool) {
+				return nil, fmt.Errorf("'weights' entry [%s] is not a pool member", k)
+			}
+			if v < 1 {
+				return nil, fmt.Errorf("value for 'weights' entry [%s] is invalid", k)
+			}
+		}
+		o.Weights = options.Weights
+	}
+
+	if o.MechanismName == "ch" {
+		o.HashKey = HashKeyPath
+		if metadata.IsDefined("backends", name, "alb", "hash_key") {
+			o.HashKey = options.HashKey
+		}
+		el, n, err := ParseHashKey(o.HashKey)
+		if err != nil {
+			return nil, err
+		}
+		o.HashKeyElement, o.HashKeyName = el, n
+	} else if metadata.IsDefined("backends", name, "alb", "hash_key") {
+		return nil, errors.New("'hash_key' option is only valid for provider 'alb' and mechanism 'ch'")
+	}
+
+	if o.MechanismName == "tt" {
+		if len(options.TierBoundariesMS) != len(o.Pool)-1 {
+			return nil, errors.New("'tier_boundaries_ms' option must have one fewer entry than the pool")
+		}
+		o.TierBoundariesMS = options.TierBoundariesMS
+		o.TierBoundaries = make([]time.Duration, len(o.TierBoundariesMS))
+		for i, v := range o.TierBoundariesMS {
+			if v < 1 || (i > 0 && v <= o.TierBoundariesMS[i-1]) {
+				return nil, fmt.Errorf("value for 'tier_boundaries_ms' entry [%d] is invalid", i)
+			}
+			o.TierBoundaries[i] = time.Duration(v) * time.Millisecond
+		}
+	} else if metadata.IsDefined("backends", name, "alb", "tier_boundaries_ms") {
+		return nil, errors.New("'tier_boundaries_ms' option is only valid for provider 'alb' and mechanism 'tt'")
+	}
+
 	return o, nil
 
 }
+
+// ParseHashKey returns the request element and the param or header name for the provided
+// hash_key value, which must be 'path', 'param:<name>' or 'header:<name>'
+func ParseHashKey(s string) (string, string, error) {
+	if s == HashKeyPath {
+		return s, "", nil
+	}
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) == 2 && parts[1] != "" &&
+		(parts[0] == HashKeyParam || parts[0] == HashKeyHeader) {
+		return parts[0], parts[1], nil
+	}
+	return "", "", fmt.Errorf("value for 'hash_key' is invalid: %s", s)
+}
+
+func inPool(name string, pool []string) bool {
+	for _, n := range pool {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -46,3 +46,158 @@ backends:
       healthy_floor: 1
       pool: [ 'test' ]
 `
+
+const testTOMLMergeTimeout = `
+backends:
+  test:
+    alb:
+      mechanism: tsm
+      merge_timeout_ms: 2500
+      min_responders: 2
+      pool: [ 'test1', 'test2', 'test3' ]
+`
+
+const testTOMLBadMergeTimeout = `
+backends:
+  test:
+    alb:
+      mechanism: rr
+      merge_timeout_ms: 2500
+`
+
+const testTOMLBadMinResponders = `
+backends:
+  test:
+    alb:
+      mechanism: tsm
+      min_responders: 0
+`
+
+const testTOMLWeights = `
+backends:
+  test:
+    alb:
+      mechanism: ch
+      hash_key: 'param:query'
+      weights:
+        test1: 3
+      pool: [ 'test1', 'test2' ]
+`
+
+const testTOMLBadWeightsMechanism = `
+backends:
+  test:
+    alb:
+      mechanism: lor
+      weights:
+        test1: 3
+      pool: [ 'test1', 'test2' ]
+`
+
+const testTOMLBadWeightsMember = `
+backends:
+  test:
+    alb:
+      mechanism: wrr
+      weights:
+        test3: 3
+      pool: [ 'test1', 'test2' ]
+`
+
+const testTOMLBadWeightsValue = `
+backends:
+  test:
+    alb:
+      mechanism: wrr
+      weights:
+        test1: 0
+      pool: [ 'test1', 'test2' ]
+`
+
+const testTOMLBadHashKeyMechanism = `
+backends:
+  test:
+    alb:
+      mechanism: rr
+      hash_key: path
+`
+
+const testTOMLBadHashKey = `
+backends:
+  test:
+    alb:
+      mechanism: ch
+      hash_key: 'cookie:session'
+`
+
+const testTOMLTierBoundaries = `
+backends:
+  test:
+    alb:
+      mechanism: tt
+      output_format: prometheus
+      tier_boundaries_ms: [ 3600000, 86400000 ]
+      pool: [ 'test1', 'test2', 'test3' ]
+`
+
+const testTOMLBadTierBoundariesMechanism = `
+backends:
+  test:
+    alb:
+      mechanism: tsm
+      tier_boundaries_ms: [ 3600000 ]
+      pool: [ 'test1', 'test2' ]
+`
+
+const testTOMLBadTierBoundariesCount = `
+backends:
+  test:
+    alb:
+      mechanism: tt
+      tier_boundaries_ms: [ 3600000 ]
+      pool: [ 'test1', 'test2', 'test3' ]
+`
+
+const testTOMLBadTierBoundariesOrder = `
+backends:
+  test:
+    alb:
+      mechanism: tt
+      tier_boundaries_ms: [ 86400000, 3600000 ]
+      pool: [ 'test1', 'test2', 'test3' ]
+`
+
+const testTOMLReplicaLabels = `
+backends:
+  test:
+    alb:
+      mechanism: tsm
+      replica_labels: [ replica, prometheus_replica ]
+      replica_penalty_ms: 10000
+      pool: [ 'test1', 'test2' ]
+`
+
+const testTOMLBadReplicaLabelsMechanism = `
+backends:
+  test:
+    alb:
+      mechanism: rr
+      replica_labels: [ replica ]
+`
+
+const testTOMLBadReplicaPenalty = `
+backends:
+  test:
+    alb:
+      mechanism: tsm
+      replica_labels: [ replica ]
+      replica_penalty_ms: -1
+`
+
+const testTOMLBadReplicaPenaltyNoLabels = `
+backends:
+  test:
+    alb:
+      mechanism: tsm
+      replica_penalty_ms: 1000
+`
@@ -18,6 +18,7 @@ package options
 
 import (
 	"testing"
+	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
 
@@ -134,4 +135,172 @@ func TestSetDefaults(t *testing.T) {
 		t.Error("expected output_format error")
 	}
 
+	o, md, err = fromYAML(testTOMLMergeTimeout)
+	if err != nil {
+		t.Error(err)
+	}
+	o2, err = SetDefaults("test", o, md)
+	if err != nil {
+		t.Error(err)
+	}
+	if o2.MergeTimeout != 2500*time.Millisecond {
+		t.Errorf("expected %s got %s", 2500*time.Millisecond, o2.MergeTimeout)
+	}
+	if o2.MinResponders != 2 {
+		t.Errorf("expected %d got %d", 2, o2.MinResponders)
+	}
+	if co := o2.Clone(); co.MergeTimeout != o2.MergeTimeout || co.MinResponders != 2 {
+		t.Error("clone mismatch")
+	}
+
+	for _, conf := range []string{testTOMLBadMergeTimeout, testTOMLBadMinResponders} {
+		o, md, err = fromYAML(conf)
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = SetDefaults("test", o, md)
+		if err == nil {
+			t.Error("expected merge option error")
+		}
+	}
+
+}
+
+func TestSetDefaultsWeightsAndHashKey(t *testing.T) {
+
+	o, md, err := fromYAML(testTOMLWeights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o2, err := SetDefaults("test", o, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o2.Weights["test1"] != 3 {
+		t.Errorf("expected %d got %d", 3, o2.Weights["test1"])
+	}
+	if o2.HashKeyElement != HashKeyParam || o2.HashKeyName != "query" {
+		t.Errorf("unexpected hash key %s %s", o2.HashKeyElement, o2.HashKeyName)
+	}
+	co := o2.Clone()
+	if co.Weights["test1"] != 3 || co.HashKey != o2.HashKey ||
+		co.HashKeyElement != HashKeyParam || co.HashKeyName != "query" {
+		t.Error("clone mismatch")
+	}
+
+	for _, conf := range []string{testTOMLBadWeightsMechanism, testTOMLBadWeightsMember,
+		testTOMLBadWeightsValue, testTOMLBadHashKeyMechanism, testTOMLBadHashKey} {
+		o, md, err = fromYAML(conf)
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = SetDefaults("test", o, md)
+		if err == nil {
+			t.Error("expected weights or hash_key error")
+		}
+	}
+
+}
+
+func TestSetDefaultsTierBoundaries(t *testing.T) {
+
+	o, md, err := fromYAML(testTOMLTierBoundaries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o2, err := SetDefaults("test", o, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o2.TierBoundaries) != 2 || o2.TierBoundaries[0] != time.Hour ||
+		o2.TierBoundaries[1] != 24*time.Hour {
+		t.Errorf("unexpected tier boundaries %v", o2.TierBoundaries)
+	}
+	if o2.OutputFormat != "prometheus" {
+		t.Errorf("expected %s got %s", "prometheus", o2.OutputFormat)
+	}
+	co := o2.Clone()
+	if len(co.TierBoundariesMS) != 2 || len(co.TierBoundaries) != 2 ||
+		co.TierBoundaries[1] != 24*time.Hour {
+		t.Error("clone mismatch")
+	}
+
+	for _, conf := range []string{testTOMLBadTierBoundariesMechanism,
+		testTOMLBadTierBoundariesCount, testTOMLBadTierBoundariesOrder} {
+		o, md, err = fromYAML(conf)
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = SetDefaults("test", o, md)
+		if err == nil {
+			t.Error("expected tier_boundaries_ms error")
+		}
+	}
+
+}
+
+func TestSetDefaultsReplicaLabels(t *testing.T) {
+
+	if o := New(); o.ReplicaPenalty != 5*time.Second {
+		t.Errorf("expected %s got %s", 5*time.Second, o.ReplicaPenalty)
+	}
+
+	o, md, err := fromYAML(testTOMLReplicaLabels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o2, err := SetDefaults("test", o, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o2.ReplicaLabels) != 2 || o2.ReplicaLabels[1] != "prometheus_replica" {
+		t.Errorf("unexpected replica labels %v", o2.ReplicaLabels)
+	}
+	if o2.ReplicaPenalty != 10*time.Second {
+		t.Errorf("expected %s got %s", 10*time.Second, o2.ReplicaPenalty)
+	}
+	co := o2.Clone()
+	if len(co.ReplicaLabels) != 2 || co.ReplicaPenalty != o2.ReplicaPenalty ||
+		co.ReplicaPenaltyMS != 10000 {
+		t.Error("clone mismatch")
+	}
+
+	for _, conf := range []string{testTOMLBadReplicaLabelsMechanism, testTOMLBadReplicaPenalty,
+		testTOMLBadReplicaPenaltyNoLabels} {
+		o, md, err = fromYAML(conf)
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = SetDefaults("test", o, md)
+		if err == nil {
+			t.Error("expected replica option error")
+		}
+	}
+
+}
+
+func TestParseHashKey(t *testing.T) {
+
+	tests := []struct {
+		input, element, name string
+		err                  bool
+	}{
+		{"path", HashKeyPath, "", false},
+		{"param:query", HashKeyParam, "query", false},
+		{"header:X-Tenant", HashKeyHeader, "X-Tenant", false},
+		{"param:", "", "", true},
+		{"header", "", "", true},
+		{"", "", "", true},
+	}
+
+	for _, test := range tests {
+		el, n, err := ParseHashKey(test.input)
+		if (err != nil) != test.err {
+			t.Errorf("unexpected error result for %s: %v", test.input, err)
+		}
+		if el != test.element || n != test.name {
+			t.Errorf("expected %s %s got %s %s", test.element, test.name, el, n)
+		}
+	}
+
 }
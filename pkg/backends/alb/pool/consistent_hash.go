@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pool
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// ringReplicas is the number of points each unit of target weight occupies on the hash ring
+const ringReplicas = 128
+
+type ringPoint struct {
+	hash    uint64
+	handler http.Handler
+}
+
+// hashRing is a list of ringPoints sorted by hash
+type hashRing []ringPoint
+
+// newHashRing returns a hash ring of the provided targets. Points are derived from
+// each target's name, so the removal of an unhealthy target only remaps the keys
+// that were assigned to it
+func newHashRing(targets []*Target) hashRing {
+	var total int
+	for _, t := range targets {
+		total += t.weight * ringReplicas
+	}
+	r := make(hashRing, 0, total)
+	for i, t := range targets {
+		name := t.name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		for j := 0; j < t.weight*ringReplicas; j++ {
+			r = append(r, ringPoint{hash: hashKey(name + "#" + strconv.Itoa(j)), handler: t.handler})
+		}
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].hash < r[j].hash })
+	return r
+}
+
+// get returns the handler for the first point on the ring at or after the key's hash
+func (r hashRing) get(key string) http.Handler {
+	if len(r) == 0 {
+		return nil
+	}
+	h := hashKey(key)
+	i := sort.Search(len(r), func(i int) bool { return r[i].hash >= h })
+	if i == len(r) {
+		i = 0
+	}
+	return r[i].handler
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// nextConsistentHash returns the target assigned to the key on the hash ring.
+// Requests without a key are distributed round robin
+func nextConsistentHash(p *pool, key string) []http.Handler {
+	if key == "" {
+		return nextRoundRobin(p, key)
+	}
+	p.mtx.RLock()
+	r := p.ring
+	p.mtx.RUnlock()
+	if h := r.get(key); h != nil {
+		return []http.Handler{h}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pool
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestHashRing(t *testing.T) {
+
+	var r hashRing
+	if r.get("test") != nil {
+		t.Error("expected nil handler")
+	}
+
+	targets := []*Target{
+		NewWeightedTarget("a", 1, testHandler(0), nil),
+		NewWeightedTarget("b", 1, testHandler(1), nil),
+		NewWeightedTarget("c", 2, testHandler(2), nil),
+	}
+	r = newHashRing(targets)
+	if len(r) != 4*ringReplicas {
+		t.Fatalf("expected %d got %d", 4*ringReplicas, len(r))
+	}
+
+	const n = 10000
+	assigned := make([]testHandler, n)
+	counts := make(map[testHandler]int)
+	for i := 0; i < n; i++ {
+		h := r.get("key" + strconv.Itoa(i)).(testHandler)
+		assigned[i] = h
+		counts[h]++
+	}
+	// weight 2 should receive roughly half of the keys
+	if counts[2] < n*4/10 || counts[2] > n*6/10 {
+		t.Errorf("unexpected distribution %v", counts)
+	}
+
+	// a ring built independently from the same targets assigns keys identically
+	r2 := newHashRing([]*Target{
+		NewWeightedTarget("a", 1, testHandler(0), nil),
+		NewWeightedTarget("b", 1, testHandler(1), nil),
+		NewWeightedTarget("c", 2, testHandler(2), nil),
+	})
+	for i := 0; i < n; i++ {
+		if r2.get("key"+strconv.Itoa(i)).(testHandler) != assigned[i] {
+			t.Fatal("expected identical assignment")
+		}
+	}
+
+	// removing a target only remaps the keys that were assigned to it
+	r = newHashRing(targets[1:])
+	for i := 0; i < n; i++ {
+		h := r.get("key" + strconv.Itoa(i)).(testHandler)
+		if assigned[i] != 0 && h != assigned[i] {
+			t.Fatalf("key%d moved from %d to %d", i, assigned[i], h)
+		}
+	}
+
+}
+
+func TestNextConsistentHash(t *testing.T) {
+
+	p := &pool{}
+	if h := nextConsistentHash(p, "test"); len(h) != 0 {
+		t.Errorf("expected %d got %d", 0, len(h))
+	}
+	if h := nextConsistentHash(p, ""); len(h) != 0 {
+		t.Errorf("expected %d got %d", 0, len(h))
+	}
+
+	targets := []*Target{
+		NewWeightedTarget("a", 1, testHandler(0), nil),
+		NewWeightedTarget("b", 1, testHandler(1), nil),
+	}
+	p.healthy = []http.Handler{targets[0].handler, targets[1].handler}
+	p.ring = newHashRing(targets)
+
+	h := nextConsistentHash(p, "test")
+	for i := 0; i < 10; i++ {
+		if h2 := nextConsistentHash(p, "test"); len(h2) != 1 || h2[0] != h[0] {
+			t.Error("expected the same handler for the same key")
+		}
+	}
+
+	// requests without a key are distributed round robin
+	seen := make(map[http.Handler]bool)
+	for i := 0; i < 2; i++ {
+		seen[nextConsistentHash(p, "")[0]] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("expected %d got %d", 2, len(seen))
+	}
+
+}
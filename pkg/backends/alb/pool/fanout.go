@@ -20,7 +20,7 @@ import (
 	"net/http"
 )
 
-func nextFanout(p *pool) []http.Handler {
+func nextFanout(p *pool, _ string) []http.Handler {
 	p.mtx.RLock()
 	t := p.healthy
 	p.mtx.RUnlock()
@@ -25,7 +25,7 @@ func TestNextFanout(t *testing.T) {
 
 	p := &pool{healthy: []http.Handler{http.NotFoundHandler()}}
 
-	p2 := nextFanout(p)
+	p2 := nextFanout(p, "")
 	if len(p2) != 1 {
 		t.Errorf("expected %d got %d", 1, len(p2))
 	}
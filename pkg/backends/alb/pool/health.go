@@ -28,12 +28,21 @@ func (p *pool) checkHealth() {
 		case <-p.ch: // msg arrives whenever the healthy list must be rebuilt
 			p.mtx.Lock()
 			h := make([]http.Handler, 0, len(p.targets))
+			ht := make([]*Target, 0, len(p.targets))
 			for _, t := range p.targets {
 				if t.hcStatus.Get() >= p.healthyFloor {
 					h = append(h, t.handler)
+					ht = append(ht, t)
 				}
 			}
 			p.healthy = h
+			p.healthyTargets = ht
+			switch p.mechanism {
+			case WeightedRoundRobin:
+				p.weighted = weightedSchedule(ht)
+			case ConsistentHash:
+				p.ring = newHashRing(ht)
+			}
 			p.mtx.Unlock()
 		}
 	}
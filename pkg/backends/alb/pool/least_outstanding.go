@@ -0,0 +1,44 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pool
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// nextLeastOutstanding returns the healthy target with the fewest in-flight requests.
+// The scan starts from a rotating position so that ties are distributed round robin
+func nextLeastOutstanding(p *pool, _ string) []http.Handler {
+	p.mtx.RLock()
+	t := p.healthyTargets
+	p.mtx.RUnlock()
+	if len(t) == 0 {
+		return nil
+	}
+	n := uint64(len(t))
+	start := atomic.AddUint64(&p.pos, 1) % n
+	best := t[start]
+	min := best.Outstanding()
+	for i := uint64(1); i < n && min > 0; i++ {
+		c := t[(start+i)%n]
+		if o := c.Outstanding(); o < min {
+			best, min = c, o
+		}
+	}
+	return []http.Handler{best}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pool
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNextLeastOutstanding(t *testing.T) {
+
+	p := &pool{}
+	if h := nextLeastOutstanding(p, ""); len(h) != 0 {
+		t.Errorf("expected %d got %d", 0, len(h))
+	}
+
+	targets := []*Target{
+		NewWeightedTarget("a", 1, testHandler(0), nil),
+		NewWeightedTarget("b", 1, testHandler(1), nil),
+		NewWeightedTarget("c", 1, testHandler(2), nil),
+	}
+	targets[0].outstanding = 2
+	targets[1].outstanding = 1
+	targets[2].outstanding = 3
+	p.healthyTargets = targets
+
+	for i := 0; i < 3; i++ {
+		h := nextLeastOutstanding(p, "")
+		if len(h) != 1 || h[0] != targets[1] {
+			t.Error("expected target with the fewest outstanding requests")
+		}
+	}
+
+	// ties are rotated among the targets with the fewest outstanding requests
+	targets[0].outstanding = 1
+	seen := make(map[http.Handler]bool)
+	for i := 0; i < 3; i++ {
+		seen[nextLeastOutstanding(p, "")[0]] = true
+	}
+	if len(seen) != 2 || seen[targets[2]] {
+		t.Errorf("expected %d got %d", 2, len(seen))
+	}
+
+}
+
+func TestTargetOutstanding(t *testing.T) {
+
+	var tgt *Target
+	tgt = NewWeightedTarget("a", 0, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if tgt.Outstanding() != 1 {
+				t.Errorf("expected %d got %d", 1, tgt.Outstanding())
+			}
+		}), nil)
+	if tgt.weight != 1 {
+		t.Errorf("expected %d got %d", 1, tgt.weight)
+	}
+
+	tgt.ServeHTTP(httptest.NewRecorder(), nil)
+	if tgt.Outstanding() != 0 {
+		t.Errorf("expected %d got %d", 0, tgt.Outstanding())
+	}
+
+}
@@ -32,6 +32,14 @@ const (
 	NewestLastModified
 	// TimeSeriesMerge defines the Time Series Merge load balancing mechanism
 	TimeSeriesMerge
+	// WeightedRoundRobin defines the Weighted Round Robin load balancing mechanism
+	WeightedRoundRobin
+	// LeastOutstanding defines the Least Outstanding Requests load balancing mechanism
+	LeastOutstanding
+	// ConsistentHash defines the Consistent Hash load balancing mechanism
+	ConsistentHash
+	// TimeTiered defines the Time Tiered load balancing mechanism
+	TimeTiered
 )
 
 // MechanismLookup provides for looking up Mechanisms by name
@@ -41,6 +49,10 @@ var MechanismLookup = map[string]Mechanism{
 	"fgr": FirstGoodResponse,
 	"nlm": NewestLastModified,
 	"tsm": TimeSeriesMerge,
+	"wrr": WeightedRoundRobin,
+	"lor": LeastOutstanding,
+	"ch":  ConsistentHash,
+	"tt":  TimeTiered,
 }
 
 // MechanismValues provides for looking up Mechanism by names
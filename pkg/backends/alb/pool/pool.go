@@ -21,21 +21,29 @@ import (
 	"context"
 	"net/http"
 	"sync"
+	"sync/atomic"
 
 	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
 )
 
 // Pool defines the interface for a load balancer pool
 type Pool interface {
+	// Next returns the next handler(s) selected by the pool's mechanism
 	Next() []http.Handler
+	// NextForKey returns the next handler(s) for the provided key. Only the consistent hash
+	// mechanism considers the key; all others return the same as Next()
+	NextForKey(string) []http.Handler
 }
 
-type selectionFunc func(*pool) []http.Handler
+type selectionFunc func(*pool, string) []http.Handler
 
 // Target defines an alb pool target
 type Target struct {
-	hcStatus *healthcheck.Status
-	handler  http.Handler
+	name        string
+	weight      int
+	outstanding int64
+	hcStatus    *healthcheck.Status
+	handler     http.Handler
 }
 
 // New returns a new pool
@@ -67,23 +75,60 @@ func NewTarget(handler http.Handler, hcStatus *healthcheck.Status) *Target {
 	return &Target{
 		hcStatus: hcStatus,
 		handler:  handler,
+		weight:   1,
 	}
 }
 
+// NewWeightedTarget returns a new Target with the provided pool member name and weight.
+// The name places the Target on the consistent hash ring, so that separate ALBs with the
+// same pool select the same Target for a given key
+func NewWeightedTarget(name string, weight int, handler http.Handler,
+	hcStatus *healthcheck.Status) *Target {
+	if weight < 1 {
+		weight = 1
+	}
+	return &Target{
+		name:     name,
+		weight:   weight,
+		hcStatus: hcStatus,
+		handler:  handler,
+	}
+}
+
+// ServeHTTP serves the request using the Target's handler, while tracking
+// the number of outstanding requests to the Target
+func (t *Target) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&t.outstanding, 1)
+	defer atomic.AddInt64(&t.outstanding, -1)
+	t.handler.ServeHTTP(w, r)
+}
+
+// Outstanding returns the number of requests to the Target that are in flight
+func (t *Target) Outstanding() int64 {
+	return atomic.LoadInt64(&t.outstanding)
+}
+
 type pool struct {
-	mechanism    Mechanism
-	f            selectionFunc
-	targets      []*Target
-	healthy      []http.Handler
-	healthyFloor int
-	pos          uint64
-	mtx          sync.RWMutex
-	ctx          context.Context
-	ch           chan bool
+	mechanism      Mechanism
+	f              selectionFunc
+	targets        []*Target
+	healthy        []http.Handler
+	healthyTargets []*Target
+	weighted       []http.Handler // the weighted round robin schedule of healthy targets
+	ring           hashRing       // the consistent hash ring of healthy targets
+	healthyFloor   int
+	pos            uint64
+	mtx            sync.RWMutex
+	ctx            context.Context
+	ch             chan bool
 }
 
 func (p *pool) Next() []http.Handler {
-	return p.f(p)
+	return p.f(p, "")
+}
+
+func (p *pool) NextForKey(key string) []http.Handler {
+	return p.f(p, key)
 }
 
 func mechsToFuncs() map[Mechanism]selectionFunc {
@@ -93,5 +138,9 @@ func mechsToFuncs() map[Mechanism]selectionFunc {
 		FirstGoodResponse:  nextFanout,
 		NewestLastModified: nextFanout,
 		TimeSeriesMerge:    nextFanout,
+		WeightedRoundRobin: nextWeightedRoundRobin,
+		LeastOutstanding:   nextLeastOutstanding,
+		ConsistentHash:     nextConsistentHash,
+		TimeTiered:         nextFanout,
 	}
 }
@@ -26,8 +26,8 @@ import (
 func TestMechsToFuncs(t *testing.T) {
 
 	m := mechsToFuncs()
-	if len(m) != 5 {
-		t.Errorf("expected %d got %d", 5, len(m))
+	if len(m) != 9 {
+		t.Errorf("expected %d got %d", 8, len(m))
 	}
 
 	if _, ok := m[RoundRobin]; !ok {
@@ -46,7 +46,21 @@ func TestNext(t *testing.T) {
 
 }
 
-func testNextFunc(p *pool) []http.Handler {
+func TestNextForKey(t *testing.T) {
+
+	var key string
+	p := &pool{f: func(p *pool, k string) []http.Handler {
+		key = k
+		return nil
+	}}
+	p.NextForKey("test")
+	if key != "test" {
+		t.Errorf("expected %s got %s", "test", key)
+	}
+
+}
+
+func testNextFunc(p *pool, _ string) []http.Handler {
 	return []http.Handler{http.NotFoundHandler()}
 }
 
@@ -21,7 +21,7 @@ import (
 	"sync/atomic"
 )
 
-func nextRoundRobin(p *pool) []http.Handler {
+func nextRoundRobin(p *pool, _ string) []http.Handler {
 	p.mtx.RLock()
 	t := p.healthy
 	p.mtx.RUnlock()
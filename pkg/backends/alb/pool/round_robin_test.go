@@ -25,13 +25,13 @@ func TestNextRoundRobin(t *testing.T) {
 
 	p := &pool{healthy: []http.Handler{http.NotFoundHandler()}}
 
-	p2 := nextRoundRobin(p)
+	p2 := nextRoundRobin(p, "")
 	if len(p2) != 1 {
 		t.Errorf("expected %d got %d", 1, len(p2))
 	}
 
 	p = &pool{}
-	p2 = nextRoundRobin(p)
+	p2 = nextRoundRobin(p, "")
 	if len(p2) != 0 {
 		t.Errorf("expected %d got %d", 0, len(p2))
 	}
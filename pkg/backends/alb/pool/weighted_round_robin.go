@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pool
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+func nextWeightedRoundRobin(p *pool, _ string) []http.Handler {
+	p.mtx.RLock()
+	t := p.weighted
+	p.mtx.RUnlock()
+	if len(t) == 0 {
+		return nil
+	}
+	i := atomic.AddUint64(&p.pos, 1) % uint64(len(t))
+	return []http.Handler{t[i]}
+}
+
+// weightedSchedule returns a list of the targets' handlers in which each target
+// appears as many times as its weight. Appearances are interleaved using the smooth
+// weighted round robin method, so that consecutive requests are spread across targets
+func weightedSchedule(targets []*Target) []http.Handler {
+	var total int
+	for _, t := range targets {
+		total += t.weight
+	}
+	out := make([]http.Handler, 0, total)
+	current := make([]int, len(targets))
+	for len(out) < total {
+		best := -1
+		for i, t := range targets {
+			current[i] += t.weight
+			if best == -1 || current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		out = append(out, targets[best].handler)
+	}
+	return out
+}
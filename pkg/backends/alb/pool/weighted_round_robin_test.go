@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pool
+
+import (
+	"net/http"
+	"testing"
+)
+
+type testHandler int
+
+func (h testHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+
+func TestWeightedSchedule(t *testing.T) {
+
+	targets := []*Target{
+		NewWeightedTarget("a", 5, testHandler(0), nil),
+		NewWeightedTarget("b", 1, testHandler(1), nil),
+		NewWeightedTarget("c", 1, testHandler(2), nil),
+	}
+
+	s := weightedSchedule(targets)
+	if len(s) != 7 {
+		t.Fatalf("expected %d got %d", 7, len(s))
+	}
+
+	// smooth weighting interleaves the lighter targets rather than bunching them
+	expected := []testHandler{0, 0, 1, 0, 2, 0, 0}
+	for i, h := range s {
+		if h.(testHandler) != expected[i] {
+			t.Errorf("expected %d got %d at %d", expected[i], h.(testHandler), i)
+		}
+	}
+
+	if s = weightedSchedule(nil); len(s) != 0 {
+		t.Errorf("expected %d got %d", 0, len(s))
+	}
+
+}
+
+func TestNextWeightedRoundRobin(t *testing.T) {
+
+	p := &pool{}
+	if h := nextWeightedRoundRobin(p, ""); len(h) != 0 {
+		t.Errorf("expected %d got %d", 0, len(h))
+	}
+
+	p.weighted = weightedSchedule([]*Target{
+		NewWeightedTarget("a", 3, testHandler(0), nil),
+		NewWeightedTarget("b", 1, testHandler(1), nil),
+	})
+	counts := make(map[testHandler]int)
+	for i := 0; i < 400; i++ {
+		h := nextWeightedRoundRobin(p, "")
+		if len(h) != 1 {
+			t.Fatalf("expected %d got %d", 1, len(h))
+		}
+		counts[h[0].(testHandler)]++
+	}
+	if counts[0] != 300 || counts[1] != 100 {
+		t.Errorf("unexpected distribution %v", counts)
+	}
+
+}
@@ -18,9 +18,11 @@ package alb
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
@@ -56,10 +58,55 @@ func (c *Client) handleResponseMerge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mgs := GetResponseGates(w, r, hl)
-	SetStatusHeader(w, mgs)
+	var timeout time.Duration
+	minResponders := 1
+	if c.Backend != nil {
+		if o := c.Configuration(); o != nil && o.ALBOptions != nil {
+			timeout = o.ALBOptions.MergeTimeout
+			if o.ALBOptions.MinResponders > 1 {
+				minResponders = o.ALBOptions.MinResponders
+			}
+		}
+	}
+
+	mgs, dropped := GetResponseGatesWithTimeout(w, r, hl, timeout)
+	if l-dropped < minResponders {
+		handlers.HandleGatewayTimeout(w, r)
+		return
+	}
+	c.mergeResponseGates(w, r, mgs, dropped, timeout)
+}
+
+// mergeResponseGates merges the collected responses into a single response using the merge
+// function provided by the pool members, and writes it to the ResponseWriter
+func (c *Client) mergeResponseGates(w http.ResponseWriter, r *http.Request,
+	mgs merge.ResponseGates, dropped int, timeout time.Duration) {
 
-	rsc := request.GetResources(mgs[0].Request)
+	var first *merge.ResponseGate
+	for _, mg := range mgs {
+		if mg != nil {
+			first = mg
+			break
+		}
+	}
+	if first == nil {
+		handlers.HandleBadGateway(w, r)
+		return
+	}
+
+	if dropped > 0 {
+		// merge functions include the warnings in the response when the output format supports them
+		first.Resources.Warnings = append(first.Resources.Warnings,
+			fmt.Sprintf("%d of %d pool members did not respond within the %s merge timeout",
+				dropped, len(mgs), timeout))
+	}
+	SetStatusHeader(w, mgs, dropped)
+
+	if c.outputMarshaler != nil {
+		c.setOutputFormat(mgs)
+	}
+
+	rsc := request.GetResources(first.Request)
 	if rsc != nil && rsc.ResponseMergeFunc != nil {
 		if f, ok := rsc.ResponseMergeFunc.(func(http.ResponseWriter,
 			*http.Request, merge.ResponseGates)); ok {
@@ -68,43 +115,124 @@ func (c *Client) handleResponseMerge(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// setOutputFormat configures the response gates so the merged time series is written in the
+// ALB's configured output format. Request options are provider-specific, so they are retained
+// only for members whose provider matches the output format
+func (c *Client) setOutputFormat(mgs merge.ResponseGates) {
+	for _, mg := range mgs {
+		if mg == nil || mg.Resources == nil {
+			continue
+		}
+		mg.Resources.TSMarshaler = c.outputMarshaler
+		if mg.Resources.BackendOptions == nil ||
+			mg.Resources.BackendOptions.Provider != c.outputFormat {
+			mg.Resources.TSReqestOptions = nil
+		}
+	}
+}
+
 // GetResponseGates make the client request to each fanout backend and returns a collection of responses
 func GetResponseGates(w http.ResponseWriter, r *http.Request, hl []http.Handler) merge.ResponseGates {
+	mgs, _ := GetResponseGatesWithTimeout(w, r, hl, 0)
+	return mgs
+}
+
+// GetResponseGatesWithTimeout makes the client request to each fanout backend and returns a
+// collection of responses. If the timeout is > 0, responses not received within the timeout are
+// dropped: their requests are canceled, their members of the collection are nil, and the number
+// of dropped responses is returned
+func GetResponseGatesWithTimeout(w http.ResponseWriter, r *http.Request, hl []http.Handler,
+	timeout time.Duration) (merge.ResponseGates, int) {
 	var wg sync.WaitGroup
 	var mtx sync.Mutex
 	l := len(hl)
 	mgs := make(merge.ResponseGates, l)
+	completed := make([]bool, l)
+	cancels := make([]context.CancelFunc, l)
 	wg.Add(l)
 	for i := 0; i < l; i++ {
 		go func(j int) {
+			defer wg.Done()
 			if hl[j] == nil {
 				return
 			}
 			rsc := &request.Resources{IsMergeMember: true}
-			ctx := tctx.WithResources(context.Background(), rsc)
+			ctx, cancel := context.WithCancel(tctx.WithResources(context.Background(), rsc))
+			defer cancel()
 			mtx.Lock()
 			r2 := r.Clone(ctx)
 			mgs[j] = merge.NewResponseGate(w, r2, rsc)
+			cancels[j] = cancel
 			mtx.Unlock()
 			hl[j].ServeHTTP(mgs[j], r2)
-			wg.Done()
+			mtx.Lock()
+			completed[j] = true
+			mtx.Unlock()
 		}(i)
 	}
-	wg.Wait()
-	return mgs
+
+	if timeout <= 0 {
+		wg.Wait()
+		return mgs, 0
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-done:
+		return mgs, 0
+	case <-t.C:
+	}
+
+	// the timeout has elapsed, so return only the completed responses. the incomplete
+	// gates are still in use by their handlers, so they are omitted from the collection
+	mtx.Lock()
+	defer mtx.Unlock()
+	out := make(merge.ResponseGates, l)
+	var dropped int
+	for i := range mgs {
+		if hl[i] == nil {
+			continue
+		}
+		if completed[i] {
+			out[i] = mgs[i]
+			continue
+		}
+		dropped++
+		if cancels[i] != nil {
+			cancels[i]()
+		}
+	}
+	return out, dropped
 }
 
 // SetStatusHeader inspects the X-Trickster-Result header value crafted for each mergeable response
-// and aggregates into a single header value for the primary merged response
-func SetStatusHeader(w http.ResponseWriter, mgs merge.ResponseGates) {
+// and aggregates into a single header value for the primary merged response, including the count
+// of any pool members that were dropped from the merged response
+func SetStatusHeader(w http.ResponseWriter, mgs merge.ResponseGates, dropped int) {
 	statusHeader := ""
 	for _, mg := range mgs {
+		if mg == nil {
+			continue
+		}
 		if h := mg.Header(); h != nil {
 			headers.StripMergeHeaders(h)
 			statusHeader = headers.MergeResultHeaderVals(statusHeader,
 				h.Get(headers.NameTricksterResult))
 		}
 	}
+	if dropped > 0 {
+		if statusHeader == "" {
+			statusHeader = headers.MakeResultsHeader("ALB", "", "", nil)
+		}
+		statusHeader = headers.SetDroppedCount(statusHeader, dropped)
+	}
 	if statusHeader != "" {
 		h := w.Header()
 		h.Set(headers.NameTricksterResult, statusHeader)
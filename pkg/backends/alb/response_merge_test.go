@@ -17,15 +17,21 @@
 package alb
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	ao "github.com/tricksterproxy/trickster/pkg/backends/alb/options"
 	"github.com/tricksterproxy/trickster/pkg/backends/alb/pool"
 	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/proxy/response/merge"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
 	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
 )
 
@@ -84,3 +90,116 @@ func TestHandleResponseMerge(t *testing.T) {
 	}
 
 }
+
+func TestHandleResponseMergeTimeout(t *testing.T) {
+
+	var warnings []string
+	var responders int
+	mergeFunc := func(w http.ResponseWriter, r *http.Request, rgs merge.ResponseGates) {
+		warnings = rgs.Warnings()
+		for _, rg := range rgs {
+			if rg != nil {
+				responders++
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rsc := request.GetResources(r)
+		rsc.ResponseMergeFunc = mergeFunc
+		rsc.Response = &http.Response{StatusCode: http.StatusOK}
+		w.Header().Set(headers.NameTricksterResult, "engine=DeltaProxyCache; status=hit")
+	})
+
+	canceled := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	o := bo.New()
+	o.ALBOptions = &ao.Options{MechanismName: "tsm", MergeTimeout: 50 * time.Millisecond,
+		MinResponders: 1}
+	c, err := NewClient("test", o, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.mergePaths = []string{"/"}
+
+	var st []*healthcheck.Status
+	c.pool, _, st = testPool(pool.TimeSeriesMerge, -1, []http.Handler{fast, slow, fast})
+	for _, s := range st {
+		s.Set(0)
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	r, _ := http.NewRequest("GET", "http://tricksterproxy.io/", nil)
+	w := httptest.NewRecorder()
+	c.handleResponseMerge(w, r)
+	if w.Code != http.StatusOK {
+		t.Error("expected 200 got", w.Code)
+	}
+	if responders != 2 {
+		t.Errorf("expected %d got %d", 2, responders)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "1 of 3 pool members") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if h := w.Header().Get(headers.NameTricksterResult); !strings.HasSuffix(h, "; dropped=1") {
+		t.Errorf("unexpected result header %s", h)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("expected dropped request to be canceled")
+	}
+
+	// the quorum is not met when only 2 of 3 members respond
+	o.ALBOptions.MinResponders = 3
+	canceled = make(chan struct{})
+	w = httptest.NewRecorder()
+	c.handleResponseMerge(w, r)
+	if w.Code != http.StatusGatewayTimeout {
+		t.Error("expected 504 got", w.Code)
+	}
+}
+
+func TestSetOutputFormat(t *testing.T) {
+
+	var marshaled bool
+	f := func(timeseries.Timeseries, *timeseries.RequestOptions, int, io.Writer) error {
+		marshaled = true
+		return nil
+	}
+	c := &Client{outputFormat: "prometheus", outputMarshaler: f}
+
+	newGate := func(provider string) *merge.ResponseGate {
+		o := bo.New()
+		o.Provider = provider
+		rsc := request.NewResources(o, nil, nil, nil, nil, nil, nil)
+		rsc.TSReqestOptions = &timeseries.RequestOptions{}
+		return merge.NewResponseGate(nil, nil, rsc)
+	}
+
+	mgs := merge.ResponseGates{newGate("influxdb"), nil, newGate("prometheus")}
+	c.setOutputFormat(mgs)
+
+	if mgs[0].Resources.TSReqestOptions != nil {
+		t.Error("expected nil request options for non-output provider")
+	}
+	if mgs[2].Resources.TSReqestOptions == nil {
+		t.Error("expected request options for output provider")
+	}
+	for _, i := range []int{0, 2} {
+		mgs[i].Resources.TSMarshaler(nil, nil, 200, nil)
+		if !marshaled {
+			t.Errorf("expected output marshaler for gate %d", i)
+		}
+		marshaled = false
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/backends"
+	"github.com/tricksterproxy/trickster/pkg/backends/alb/pool"
+	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// tier is a Time Tiered pool member, which serves the portion of a requested time range
+// that is newer than its boundary and older than the boundary of the preceding tier
+type tier struct {
+	name     string
+	backend  backends.TimeseriesBackend
+	handler  http.Handler
+	boundary time.Duration // 0 for the oldest tier, which has no lower boundary
+}
+
+// tierExtent is the portion of a requested time range that is served by a tier
+type tierExtent struct {
+	tier   *tier
+	extent timeseries.Extent
+}
+
+// timeTiers returns the pool members as tiers, ordered from newest to oldest. When targets is
+// nil, the tiers' handlers are not populated, which is sufficient for validation
+func (c *Client) timeTiers(clients backends.Backends, targets []*pool.Target) ([]*tier, error) {
+	o := c.Configuration().ALBOptions
+	tiers := make([]*tier, 0, len(o.Pool))
+	for i, n := range o.Pool {
+		tb, ok := clients[n].(backends.TimeseriesBackend)
+		if !ok {
+			return nil, fmt.Errorf("tier pool member [%s] in backend [%s] is not a "+
+				"time series backend", n, c.Name())
+		}
+		t := &tier{name: n, backend: tb}
+		if i < len(o.TierBoundaries) {
+			t.boundary = o.TierBoundaries[i]
+		}
+		if i < len(targets) {
+			t.handler = targets[i]
+		}
+		tiers = append(tiers, t)
+	}
+	return tiers, nil
+}
+
+// tierExtents splits the requested time range at the tier boundaries, relative to the provided
+// time, and returns the portions with the tiers that serve them, ordered from newest to oldest.
+// Boundaries are aligned to the query step, and tiers that serve no portion are omitted
+func (c *Client) tierExtents(trq *timeseries.TimeRangeQuery, now time.Time) []tierExtent {
+	out := make([]tierExtent, 0, len(c.tiers))
+	upper := trq.Extent.End
+	for i, t := range c.tiers {
+		lower := trq.Extent.Start
+		if i < len(c.tiers)-1 {
+			if b := now.Add(-t.boundary).Truncate(trq.Step); b.After(lower) {
+				lower = b
+			}
+		}
+		if !upper.Before(lower) {
+			out = append(out, tierExtent{tier: t,
+				extent: timeseries.Extent{Start: lower, End: upper}})
+		}
+		if !lower.After(trq.Extent.Start) {
+			break
+		}
+		if n := lower.Add(-trq.Step); n.Before(upper) {
+			upper = n
+		}
+	}
+	return out
+}
+
+// handleTimeTiered splits the requested time range across the tiers, requests each tier's
+// portion of the time range from its pool member, and merges the responses. Requests for
+// paths that are not mergeable, or that are not time range queries, are served by the
+// newest tier
+func (c *Client) handleTimeTiered(w http.ResponseWriter, r *http.Request) {
+	if len(c.tiers) == 0 || c.tiers[0].handler == nil {
+		handlers.HandleBadGateway(w, r)
+		return
+	}
+	newest := c.tiers[0]
+
+	var isMergeablePath bool
+	for _, v := range c.mergePaths {
+		if strings.HasPrefix(r.URL.Path, v) {
+			isMergeablePath = true
+			break
+		}
+	}
+	if !isMergeablePath {
+		newest.handler.ServeHTTP(w, r)
+		return
+	}
+
+	var body []byte
+	if methods.HasBody(r.Method) {
+		body = request.GetBody(r)
+	}
+
+	// the time range query is parsed from a clone, since parsing may modify the request
+	trq, _, _, err := newest.backend.ParseTimeRangeQuery(cloneWithBody(r, body))
+	if err != nil || trq == nil || trq.Step <= 0 {
+		newest.handler.ServeHTTP(w, r)
+		return
+	}
+
+	tes := c.tierExtents(trq, time.Now())
+	switch len(tes) {
+	case 0:
+		newest.handler.ServeHTTP(w, r)
+		return
+	case 1:
+		// a single tier serves the entire time range, so there is nothing to merge
+		tes[0].tier.handler.ServeHTTP(w, r)
+		return
+	}
+
+	hl := make([]http.Handler, len(tes))
+	for i, te := range tes {
+		te := te
+		hl[i] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// each tier parses its own time range query, since its provider may differ from
+			// the newest tier's, and since setting the extent may modify the parsed query
+			ttrq, _, _, err := te.tier.backend.ParseTimeRangeQuery(cloneWithBody(r, body))
+			if err != nil || ttrq == nil {
+				handlers.HandleBadGateway(w, r)
+				return
+			}
+			if body != nil {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			te.tier.backend.SetExtent(r, ttrq, &te.extent)
+			te.tier.handler.ServeHTTP(w, r)
+		})
+	}
+
+	mgs := GetResponseGates(w, r, hl)
+	c.mergeResponseGates(w, r, mgs, 0, 0)
+}
+
+// cloneWithBody returns a clone of the request, with a copy of the provided body
+func cloneWithBody(r *http.Request, body []byte) *http.Request {
+	r2 := r.Clone(r.Context())
+	if body != nil {
+		r2.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return r2
+}
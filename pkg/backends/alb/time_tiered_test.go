@@ -0,0 +1,263 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/backends"
+	ao "github.com/tricksterproxy/trickster/pkg/backends/alb/options"
+	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/response/merge"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// testTieredBackend is a time series backend whose time range queries are expressed
+// as start, end and step params in epoch seconds
+type testTieredBackend struct {
+	backends.TimeseriesBackend
+	name string
+}
+
+func (b *testTieredBackend) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuery,
+	*timeseries.RequestOptions, bool, error) {
+	qp := r.URL.Query()
+	start, err := strconv.ParseInt(qp.Get("start"), 10, 64)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	end, _ := strconv.ParseInt(qp.Get("end"), 10, 64)
+	step, _ := strconv.ParseInt(qp.Get("step"), 10, 64)
+	return &timeseries.TimeRangeQuery{Statement: b.name, Step: time.Duration(step) * time.Second,
+			Extent: timeseries.Extent{Start: time.Unix(start, 0), End: time.Unix(end, 0)}},
+		nil, false, nil
+}
+
+func (b *testTieredBackend) SetExtent(r *http.Request, trq *timeseries.TimeRangeQuery,
+	e *timeseries.Extent) {
+	qp := r.URL.Query()
+	qp.Set("start", strconv.FormatInt(e.Start.Unix(), 10))
+	qp.Set("end", strconv.FormatInt(e.End.Unix(), 10))
+	qp.Set("parsed_by", trq.Statement)
+	r.URL.RawQuery = qp.Encode()
+	// modify the time range query, as some providers do, so that sharing it is detected
+	trq.Statement = ""
+}
+
+func (b *testTieredBackend) MergeablePaths() []string {
+	return []string{"/query_range"}
+}
+
+func newTestTieredBackend(t *testing.T, name string) backends.Backend {
+	o := bo.New()
+	o.Name = name
+	o.Provider = "prometheus"
+	b, err := backends.NewTimeseriesBackend(name, o, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testTieredBackend{TimeseriesBackend: b, name: name}
+}
+
+func TestTierExtents(t *testing.T) {
+
+	now := time.Unix(100000, 0)
+	c := &Client{tiers: []*tier{{name: "recent", boundary: time.Hour},
+		{name: "daily", boundary: 24 * time.Hour}, {name: "archive"}}}
+
+	tests := []struct {
+		start, end int64
+		expected   []string
+	}{
+		// entirely within the recent tier
+		{now.Unix() - 1800, now.Unix(), []string{"recent:98200-100000"}},
+		// spans the recent and daily tiers
+		{now.Unix() - 7200, now.Unix(), []string{"recent:96360-100000", "daily:92800-96300"}},
+		// spans all tiers
+		{0, now.Unix(), []string{"recent:96360-100000", "daily:13560-96300",
+			"archive:0-13500"}},
+		// entirely within the archive tier
+		{0, 3600, []string{"archive:0-3600"}},
+		// spans the daily and archive tiers
+		{3600, 36000, []string{"daily:13560-36000", "archive:3600-13500"}},
+	}
+
+	for i, test := range tests {
+		trq := &timeseries.TimeRangeQuery{Step: time.Minute,
+			Extent: timeseries.Extent{Start: time.Unix(test.start, 0), End: time.Unix(test.end, 0)}}
+		tes := c.tierExtents(trq, now)
+		if len(tes) != len(test.expected) {
+			t.Errorf("test %d: expected %d tiers got %d", i, len(test.expected), len(tes))
+			continue
+		}
+		for j, te := range tes {
+			s := te.tier.name + ":" + strconv.FormatInt(te.extent.Start.Unix(), 10) +
+				"-" + strconv.FormatInt(te.extent.End.Unix(), 10)
+			if s != test.expected[j] {
+				t.Errorf("test %d: expected %s got %s", i, test.expected[j], s)
+			}
+		}
+	}
+}
+
+func TestValidateAndStartPoolTimeTiered(t *testing.T) {
+
+	o := bo.New()
+	o.Provider = "alb"
+	o.ALBOptions = &ao.Options{MechanismName: "tt", Pool: []string{"a", "b"},
+		TierBoundaries: []time.Duration{time.Hour}}
+	cl, _ := NewClient("test", o, nil, nil)
+
+	b := backends.Backends{
+		"test": cl,
+		"a":    newTestTieredBackend(t, "a"),
+		"b":    newTestTieredBackend(t, "b"),
+	}
+	hcs := healthcheck.StatusLookup{"a": &healthcheck.Status{}, "b": &healthcheck.Status{}}
+
+	err := cl.ValidateAndStartPool(b, hcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cl.tiers) != 2 || cl.tiers[0].boundary != time.Hour || cl.tiers[1].boundary != 0 ||
+		cl.tiers[1].handler == nil {
+		t.Errorf("unexpected tiers %v", cl.tiers)
+	}
+	if len(cl.mergePaths) != 1 || cl.mergePaths[0] != "/query_range" {
+		t.Errorf("expected mergeable paths got %v", cl.mergePaths)
+	}
+
+	rp, _ := backends.New("b", bo.New(), nil, nil, nil)
+	b["b"] = rp
+	expected := "tier pool member [b] in backend [test] is not a time series backend"
+	err = cl.ValidatePool(b)
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %s got %v", expected, err)
+	}
+}
+
+func TestHandleTimeTiered(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "http://tricksterproxy.io/query_range", nil)
+	c := &Client{}
+	c.handleTimeTiered(w, r)
+	if w.Code != http.StatusBadGateway {
+		t.Error("expected 502 got", w.Code)
+	}
+
+	var mtx sync.Mutex
+	received := make(map[string]string)
+	var merged int
+	mergeFunc := func(w http.ResponseWriter, r *http.Request, rgs merge.ResponseGates) {
+		for _, rg := range rgs {
+			if rg != nil {
+				merged++
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	newHandler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rsc := request.GetResources(r); rsc != nil {
+				rsc.ResponseMergeFunc = mergeFunc
+				rsc.Response = &http.Response{StatusCode: http.StatusOK}
+			}
+			mtx.Lock()
+			received[name] = r.URL.Query().Get("start") + "-" + r.URL.Query().Get("end")
+			mtx.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+
+	tb := newTestTieredBackend(t, "a").(backends.TimeseriesBackend)
+	c.tiers = []*tier{
+		{name: "recent", backend: tb, handler: newHandler("recent"), boundary: time.Hour},
+		{name: "archive", backend: tb, handler: newHandler("archive")},
+	}
+	c.mergePaths = []string{"/query_range"}
+
+	// paths that are not mergeable are served by the newest tier
+	r, _ = http.NewRequest("GET", "http://tricksterproxy.io/labels", nil)
+	w = httptest.NewRecorder()
+	c.handleTimeTiered(w, r)
+	if w.Code != http.StatusNoContent || received["recent"] != "-" {
+		t.Errorf("expected newest tier to serve request got %d %v", w.Code, received)
+	}
+
+	// a time range within the newest tier is served without merging
+	now := time.Now().Unix()
+	received = make(map[string]string)
+	r, _ = http.NewRequest("GET", "http://tricksterproxy.io/query_range?step=60&start="+
+		strconv.FormatInt(now-600, 10)+"&end="+strconv.FormatInt(now, 10), nil)
+	w = httptest.NewRecorder()
+	c.handleTimeTiered(w, r)
+	if w.Code != http.StatusNoContent || len(received) != 1 || received["recent"] == "" {
+		t.Errorf("expected newest tier to serve request got %d %v", w.Code, received)
+	}
+
+	// a time range spanning both tiers is split and merged
+	received = make(map[string]string)
+	r, _ = http.NewRequest("GET", "http://tricksterproxy.io/query_range?step=60&start="+
+		strconv.FormatInt(now-7200, 10)+"&end="+strconv.FormatInt(now, 10), nil)
+	w = httptest.NewRecorder()
+	c.handleTimeTiered(w, r)
+	if w.Code != http.StatusOK || merged != 2 {
+		t.Errorf("expected merged response got %d %d", w.Code, merged)
+	}
+	boundary := time.Unix(now, 0).Add(-time.Hour).Truncate(time.Minute).Unix()
+	if received["recent"] != strconv.FormatInt(boundary, 10)+"-"+strconv.FormatInt(now, 10) {
+		t.Errorf("unexpected recent tier extent %s", received["recent"])
+	}
+	if received["archive"] != strconv.FormatInt(now-7200, 10)+"-"+
+		strconv.FormatInt(boundary-60, 10) {
+		t.Errorf("unexpected archive tier extent %s", received["archive"])
+	}
+
+	// each tier's portion of the time range query is parsed by its own backend
+	parsedBy := make(map[string]string)
+	newParsedByHandler := func(name string) http.Handler {
+		h := newHandler(name)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mtx.Lock()
+			parsedBy[name] = r.URL.Query().Get("parsed_by")
+			mtx.Unlock()
+			h.ServeHTTP(w, r)
+		})
+	}
+	c.tiers = []*tier{
+		{name: "recent", backend: tb, handler: newParsedByHandler("recent"), boundary: time.Hour},
+		{name: "archive", backend: newTestTieredBackend(t, "b").(backends.TimeseriesBackend),
+			handler: newParsedByHandler("archive")},
+	}
+	merged = 0
+	w = httptest.NewRecorder()
+	c.handleTimeTiered(w, r)
+	if w.Code != http.StatusOK || merged != 2 {
+		t.Errorf("expected merged response got %d %d", w.Code, merged)
+	}
+	if parsedBy["recent"] != "a" || parsedBy["archive"] != "b" {
+		t.Errorf("expected tiers to parse their own queries got %v", parsedBy)
+	}
+}
@@ -20,12 +20,14 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/tricksterproxy/trickster/pkg/backends/breaker"
 	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
 	ho "github.com/tricksterproxy/trickster/pkg/backends/healthcheck/options"
 	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/proxy"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/ratelimit"
 	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
 )
 
@@ -59,6 +61,12 @@ type Backend interface {
 	DefaultHealthCheckConfig() *ho.Options
 	// HealthCheckHTTPClient returns the HTTP Client used for Health Checking
 	HealthCheckHTTPClient() *http.Client
+	// SetBreaker sets the Circuit Breaker for the Backend
+	SetBreaker(*breaker.Breaker)
+	// Breaker returns the Circuit Breaker for the Backend, if any
+	Breaker() *breaker.Breaker
+	// Limiter returns the Rate Limiter for the Backend, if any
+	Limiter() *ratelimit.Limiter
 }
 
 type backend struct {
@@ -70,6 +78,8 @@ type backend struct {
 	handlers           map[string]http.Handler
 	handlersRegistered bool
 	healthProbe        healthcheck.DemandProbe
+	breaker            *breaker.Breaker
+	limiter            *ratelimit.Limiter
 	router             http.Handler
 	baseUpstreamURL    *url.URL
 	registrar          func(map[string]http.Handler)
@@ -104,11 +114,16 @@ func New(name string, o *bo.Options, registrar Registrar,
 	}
 
 	var bur *url.URL
+	var rl *ratelimit.Limiter
 	if o != nil {
 		bur = urls.FromParts(o.Scheme, o.Host, o.PathPrefix, "", "")
+		if o.RateLimit != nil {
+			rl = ratelimit.New(o.RateLimit)
+		}
 	}
 	return &backend{name: name, config: o, router: router, cache: cache,
-		webClient: c, healthCheckClient: hcc, baseUpstreamURL: bur, registrar: registrar}, err
+		webClient: c, healthCheckClient: hcc, baseUpstreamURL: bur, registrar: registrar,
+		limiter: rl}, err
 
 }
 
@@ -169,6 +184,21 @@ func (b *backend) SetHealthCheckProbe(p healthcheck.DemandProbe) {
 	b.healthProbe = p
 }
 
+// SetBreaker sets the Circuit Breaker for the Backend
+func (b *backend) SetBreaker(cb *breaker.Breaker) {
+	b.breaker = cb
+}
+
+// Breaker returns the Circuit Breaker for the Backend, if any
+func (b *backend) Breaker() *breaker.Breaker {
+	return b.breaker
+}
+
+// Limiter returns the Rate Limiter for the Backend, if any
+func (b *backend) Limiter() *ratelimit.Limiter {
+	return b.limiter
+}
+
 // HealthHandler is the Health Check Handler for the backend
 func (b *backend) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	if b.healthProbe != nil {
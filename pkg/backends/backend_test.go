@@ -22,6 +22,8 @@ import (
 	"testing"
 
 	"github.com/tricksterproxy/trickster/cmd/trickster/config"
+	"github.com/tricksterproxy/trickster/pkg/backends/breaker"
+	cbo "github.com/tricksterproxy/trickster/pkg/backends/breaker/options"
 	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
 	cr "github.com/tricksterproxy/trickster/pkg/cache/registration"
 	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
@@ -130,6 +132,18 @@ func TestSetHealthCheckProbe(t *testing.T) {
 	}
 }
 
+func TestSetBreaker(t *testing.T) {
+	b := &backend{name: "test"}
+	if b.Breaker() != nil {
+		t.Error("expected nil")
+	}
+	cb := breaker.New("test", cbo.New(), nil, nil)
+	b.SetBreaker(cb)
+	if b.Breaker() != cb {
+		t.Error("breaker mismatch")
+	}
+}
+
 func TestHealthHandler(t *testing.T) {
 
 	b := &backend{name: "test"}
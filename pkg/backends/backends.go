@@ -20,6 +20,7 @@ package backends
 import (
 	"net/http"
 
+	"github.com/tricksterproxy/trickster/pkg/backends/breaker"
 	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
 	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
 )
@@ -28,7 +29,8 @@ import (
 type Backends map[string]Backend
 
 // StartHealthChecks iterates the backends to fully configure health checkers
-// and start up any intervaled health checks
+// and start up any intervaled health checks, and attaches any configured circuit
+// breakers to their backend's health status
 func (b Backends) StartHealthChecks(logger interface{}) (healthcheck.HealthChecker, error) {
 	hc := healthcheck.New()
 	for k, c := range b {
@@ -51,6 +53,9 @@ func (b Backends) StartHealthChecks(logger interface{}) (healthcheck.HealthCheck
 			return nil, err
 		}
 		c.SetHealthCheckProbe(st.Prober())
+		if bo.CircuitBreaker != nil {
+			c.SetBreaker(breaker.New(k, bo.CircuitBreaker, st, logger))
+		}
 	}
 	return hc, nil
 }
@@ -0,0 +1,279 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package breaker provides a passive circuit breaker for backends, driven
+// by the outcomes of live requests
+package breaker
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	cbo "github.com/tricksterproxy/trickster/pkg/backends/breaker/options"
+	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// State is the state of a Circuit Breaker
+type State int32
+
+const (
+	// StateClosed indicates requests are permitted to the backend
+	StateClosed = State(iota)
+	// StateOpen indicates requests to the backend fail fast
+	StateOpen
+	// StateHalfOpen indicates a limited number of trial requests are permitted to the backend
+	StateHalfOpen
+)
+
+var stateNames = map[State]string{
+	StateClosed:   "closed",
+	StateOpen:     "open",
+	StateHalfOpen: "half-open",
+}
+
+func (s State) String() string {
+	if v, ok := stateNames[s]; ok {
+		return v
+	}
+	return strconv.Itoa(int(s))
+}
+
+// windowBuckets is the number of buckets in the sliding window of request outcomes
+const windowBuckets = 10
+
+type bucket struct {
+	idx    int64
+	total  int
+	errors int
+	slow   int
+}
+
+// Breaker is a Circuit Breaker for a backend. It opens when the rate of failed or slow
+// requests in its sliding window exceeds the configured thresholds, and half-opens after
+// the open duration to test recovery with a limited number of trial requests. Each request
+// permitted by Allow must be followed by a call to Record or Release
+type Breaker struct {
+	name    string
+	options *cbo.Options
+	status  *healthcheck.Status
+	logger  interface{}
+
+	mtx         sync.Mutex
+	nmtx        sync.Mutex
+	changed     bool
+	state       State
+	openedAt    time.Time
+	halfOpenAt  time.Time
+	buckets     [windowBuckets]bucket
+	bucketWidth int64
+	trials      int
+	successes   int
+	timer       *time.Timer
+}
+
+// New returns a new Breaker for the named backend. State changes are propagated to the
+// provided Status, if any, so that its subscribers are notified
+func New(name string, o *cbo.Options, status *healthcheck.Status, logger interface{}) *Breaker {
+	bw := int64(o.Window) / windowBuckets
+	if bw < 1 {
+		bw = 1
+	}
+	return &Breaker{
+		name:        name,
+		options:     o,
+		status:      status,
+		logger:      logger,
+		bucketWidth: bw,
+	}
+}
+
+// State returns the current state of the Breaker
+func (b *Breaker) State() State {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.state
+}
+
+// Allow returns true if a request may be sent to the backend
+func (b *Breaker) Allow() bool {
+	b.mtx.Lock()
+	defer b.unlock()
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.options.OpenDuration {
+			return false
+		}
+		b.setState(StateHalfOpen, "open duration elapsed")
+		fallthrough
+	case StateHalfOpen:
+		if b.trials >= b.options.HalfOpenRequests {
+			return false
+		}
+		b.trials++
+	}
+	return true
+}
+
+// Release returns the trial slot of a request permitted by Allow whose outcome will not be
+// recorded, such as one abandoned by the client
+func (b *Breaker) Release() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.state == StateHalfOpen && b.trials > b.successes {
+		b.trials--
+	}
+}
+
+// Record records the outcome of a request to the backend
+func (b *Breaker) Record(statusCode int, elapsed time.Duration) {
+
+	failed := statusCode >= http.StatusInternalServerError
+	slow := b.options.LatencyThreshold > 0 && elapsed > b.options.LatencyThreshold
+
+	b.mtx.Lock()
+	defer b.unlock()
+
+	switch b.state {
+	case StateOpen:
+		return
+	case StateHalfOpen:
+		if failed || slow {
+			b.setState(StateOpen, "trial request failed")
+			return
+		}
+		b.successes++
+		if b.successes >= b.options.HalfOpenRequests {
+			b.setState(StateClosed, "trial requests succeeded")
+		}
+		return
+	}
+
+	idx := time.Now().UnixNano() / b.bucketWidth
+	bk := &b.buckets[idx%windowBuckets]
+	if bk.idx != idx {
+		*bk = bucket{idx: idx}
+	}
+	bk.total++
+	if failed {
+		bk.errors++
+	}
+	if slow {
+		bk.slow++
+	}
+
+	var total, errors, slows int
+	for _, v := range b.buckets {
+		if v.idx > idx-windowBuckets {
+			total += v.total
+			errors += v.errors
+			slows += v.slow
+		}
+	}
+	if total < b.options.MinRequests {
+		return
+	}
+	if b.options.ErrorRateThreshold > 0 &&
+		float64(errors)*100/float64(total) >= b.options.ErrorRateThreshold {
+		b.setState(StateOpen, "error rate threshold exceeded")
+	} else if b.options.LatencyThreshold > 0 && b.options.SlowRateThreshold > 0 &&
+		float64(slows)*100/float64(total) >= b.options.SlowRateThreshold {
+		b.setState(StateOpen, "slow rate threshold exceeded")
+	}
+}
+
+// unlock releases the lock and then propagates any state change to the Status, since
+// notifying its subscribers can block
+func (b *Breaker) unlock() {
+	changed := b.changed
+	b.changed = false
+	b.mtx.Unlock()
+	if !changed || b.status == nil {
+		return
+	}
+	// notifications are serialized and reflect the state at the time they are sent, so the
+	// Status ends up matching the latest state when concurrent changes are propagated
+	b.nmtx.Lock()
+	b.status.SetCircuitOpen(b.State() == StateOpen)
+	b.nmtx.Unlock()
+}
+
+// setState transitions the Breaker to the provided state. The caller must hold the lock
+// and release it with unlock
+func (b *Breaker) setState(s State, reason string) {
+	b.state = s
+	b.trials = 0
+	b.successes = 0
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	switch s {
+	case StateOpen:
+		b.openedAt = time.Now()
+		// half-open even when no requests arrive, such as when an ALB pool has
+		// dropped the backend, so that it is returned to service for the trials
+		b.timer = time.AfterFunc(b.options.OpenDuration, b.halfOpen)
+	case StateHalfOpen:
+		b.halfOpenAt = time.Now()
+		// reopen if the trials are not completed in time, so that a trial that is never
+		// recorded can not leave the circuit half-open indefinitely
+		b.timer = time.AfterFunc(b.options.HalfOpenTimeout, b.halfOpenExpired)
+	case StateClosed:
+		b.buckets = [windowBuckets]bucket{}
+	}
+	b.changed = true
+	tl.Info(b.logger, "circuit breaker state changed",
+		tl.Pairs{"backendName": b.name, "state": s.String(), "reason": reason})
+}
+
+// halfOpen transitions an open Breaker to half-open once the open duration has elapsed
+func (b *Breaker) halfOpen() {
+	b.mtx.Lock()
+	defer b.unlock()
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.options.OpenDuration {
+		b.setState(StateHalfOpen, "open duration elapsed")
+	}
+}
+
+// halfOpenExpired reopens a half-open Breaker whose trials did not complete in time
+func (b *Breaker) halfOpenExpired() {
+	b.mtx.Lock()
+	defer b.unlock()
+	if b.state == StateHalfOpen && time.Since(b.halfOpenAt) >= b.options.HalfOpenTimeout {
+		b.setState(StateOpen, "trial requests timed out")
+	}
+}
+
+// Response returns the response served in place of an upstream response while
+// requests to the backend are not allowed
+func (b *Breaker) Response(r *http.Request) *http.Response {
+	h := headers.Lookup(b.options.ResponseHeaders).ToHeader()
+	h.Set(headers.NameTrkCBStatus, b.State().String())
+	return &http.Response{
+		StatusCode:    b.options.ResponseCode,
+		Status:        http.StatusText(b.options.ResponseCode),
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader([]byte(b.options.ResponseBody))),
+		ContentLength: int64(len(b.options.ResponseBody)),
+		Request:       r,
+	}
+}
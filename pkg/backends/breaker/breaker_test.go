@@ -0,0 +1,289 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package breaker
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	cbo "github.com/tricksterproxy/trickster/pkg/backends/breaker/options"
+	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+func testOptions() *cbo.Options {
+	o := cbo.New()
+	o.MinRequests = 4
+	o.OpenDuration = 20 * time.Millisecond
+	o.HalfOpenRequests = 2
+	return o
+}
+
+func TestStateString(t *testing.T) {
+	if s := StateHalfOpen.String(); s != "half-open" {
+		t.Errorf("expected %s got %s", "half-open", s)
+	}
+	if s := State(9).String(); s != "9" {
+		t.Errorf("expected %s got %s", "9", s)
+	}
+}
+
+func TestBreakerErrorRate(t *testing.T) {
+
+	st := &healthcheck.Status{}
+	st.Set(1)
+	ch := make(chan bool, 4)
+	st.RegisterSubscriber(ch)
+
+	b := New("test", testOptions(), st, nil)
+
+	b.Record(200, time.Millisecond)
+	b.Record(500, time.Millisecond)
+	b.Record(200, time.Millisecond)
+	if b.State() != StateClosed {
+		t.Errorf("expected %s got %s", StateClosed, b.State())
+	}
+	b.Record(502, time.Millisecond)
+	if b.State() != StateOpen {
+		t.Fatalf("expected %s got %s", StateOpen, b.State())
+	}
+	if st.Get() != -1 {
+		t.Errorf("expected %d got %d", -1, st.Get())
+	}
+	if open := <-ch; !open {
+		t.Error("expected subscriber to be notified of open circuit")
+	}
+	if b.Allow() {
+		t.Error("expected requests to be disallowed")
+	}
+
+	// after the open duration, a limited number of trial requests are permitted
+	time.Sleep(25 * time.Millisecond)
+	if !b.Allow() || !b.Allow() {
+		t.Error("expected trial requests to be allowed")
+	}
+	if b.Allow() {
+		t.Error("expected requests beyond the trials to be disallowed")
+	}
+	if b.State() != StateHalfOpen {
+		t.Errorf("expected %s got %s", StateHalfOpen, b.State())
+	}
+	if st.Get() != 1 {
+		t.Errorf("expected %d got %d", 1, st.Get())
+	}
+
+	// a failed trial reopens the circuit
+	b.Record(503, time.Millisecond)
+	if b.State() != StateOpen {
+		t.Errorf("expected %s got %s", StateOpen, b.State())
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	b.Allow()
+	b.Allow()
+	b.Record(200, time.Millisecond)
+	b.Record(200, time.Millisecond)
+	if b.State() != StateClosed {
+		t.Errorf("expected %s got %s", StateClosed, b.State())
+	}
+	if !b.Allow() {
+		t.Error("expected requests to be allowed")
+	}
+}
+
+func TestBreakerSlowSubscriber(t *testing.T) {
+
+	st := &healthcheck.Status{}
+	st.Set(1)
+	ch := make(chan bool) // unbuffered and not yet read, so a notification blocks
+	st.RegisterSubscriber(ch)
+
+	b := New("test", testOptions(), st, nil)
+	go func() {
+		for i := 0; i < 4; i++ {
+			b.Record(500, time.Millisecond)
+		}
+	}()
+
+	// the breaker remains usable while its subscribers are being notified
+	done := make(chan State)
+	go func() {
+		for b.State() != StateOpen {
+			time.Sleep(time.Millisecond)
+		}
+		b.Allow()
+		done <- b.State()
+	}()
+	select {
+	case s := <-done:
+		if s != StateOpen {
+			t.Errorf("expected %s got %s", StateOpen, s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected breaker not to block on subscriber notification")
+	}
+	if open := <-ch; !open {
+		t.Error("expected subscriber to be notified of open circuit")
+	}
+}
+
+func TestBreakerSlowRate(t *testing.T) {
+
+	o := testOptions()
+	o.ErrorRateThreshold = 0
+	o.LatencyThreshold = 10 * time.Millisecond
+
+	b := New("test", o, nil, nil)
+	// failures do not open the circuit when the error rate threshold is disabled
+	b.Record(500, time.Millisecond)
+	b.Record(500, time.Millisecond)
+	b.Record(200, 20*time.Millisecond)
+	if b.State() != StateClosed {
+		t.Errorf("expected %s got %s", StateClosed, b.State())
+	}
+	b.Record(200, 20*time.Millisecond)
+	if b.State() != StateOpen {
+		t.Errorf("expected %s got %s", StateOpen, b.State())
+	}
+}
+
+func TestBreakerWindow(t *testing.T) {
+
+	o := testOptions()
+	o.Window = 20 * time.Millisecond
+
+	b := New("test", o, nil, nil)
+	b.Record(500, time.Millisecond)
+	b.Record(500, time.Millisecond)
+	b.Record(500, time.Millisecond)
+
+	// outcomes older than the window are no longer considered
+	time.Sleep(30 * time.Millisecond)
+	b.Record(500, time.Millisecond)
+	if b.State() != StateClosed {
+		t.Errorf("expected %s got %s", StateClosed, b.State())
+	}
+}
+
+func TestBreakerHalfOpenTimer(t *testing.T) {
+
+	st := &healthcheck.Status{}
+	st.Set(1)
+	ch := make(chan bool, 4)
+	st.RegisterSubscriber(ch)
+
+	b := New("test", testOptions(), st, nil)
+	for i := 0; i < 4; i++ {
+		b.Record(500, time.Millisecond)
+	}
+	<-ch
+	if st.Get() != -1 {
+		t.Errorf("expected %d got %d", -1, st.Get())
+	}
+
+	// the circuit half-opens without any requests arriving
+	<-ch
+	if b.State() != StateHalfOpen {
+		t.Errorf("expected %s got %s", StateHalfOpen, b.State())
+	}
+	if st.Get() != 1 {
+		t.Errorf("expected %d got %d", 1, st.Get())
+	}
+}
+
+func TestBreakerRelease(t *testing.T) {
+
+	b := New("test", testOptions(), nil, nil)
+	for i := 0; i < 4; i++ {
+		b.Record(500, time.Millisecond)
+	}
+	time.Sleep(25 * time.Millisecond)
+	if !b.Allow() || !b.Allow() {
+		t.Error("expected trial requests to be allowed")
+	}
+
+	// a released trial frees its slot for another request
+	b.Release()
+	if !b.Allow() {
+		t.Error("expected released trial slot to be allowed")
+	}
+	b.Record(200, time.Millisecond)
+	b.Record(200, time.Millisecond)
+	if b.State() != StateClosed {
+		t.Errorf("expected %s got %s", StateClosed, b.State())
+	}
+
+	// releasing while closed has no effect
+	b.Release()
+	if b.State() != StateClosed {
+		t.Errorf("expected %s got %s", StateClosed, b.State())
+	}
+}
+
+func TestBreakerHalfOpenTimeout(t *testing.T) {
+
+	o := testOptions()
+	o.HalfOpenTimeout = 20 * time.Millisecond
+
+	st := &healthcheck.Status{}
+	st.Set(1)
+	ch := make(chan bool, 4)
+	st.RegisterSubscriber(ch)
+
+	b := New("test", o, st, nil)
+	for i := 0; i < 4; i++ {
+		b.Record(500, time.Millisecond)
+	}
+	<-ch
+	<-ch
+	if !b.Allow() || !b.Allow() {
+		t.Error("expected trial requests to be allowed")
+	}
+
+	// the trials are never recorded, so the circuit reopens
+	if open := <-ch; !open {
+		t.Error("expected subscriber to be notified of open circuit")
+	}
+	if b.State() != StateOpen {
+		t.Errorf("expected %s got %s", StateOpen, b.State())
+	}
+}
+
+func TestBreakerResponse(t *testing.T) {
+
+	o := testOptions()
+	o.ResponseCode = http.StatusTooManyRequests
+	o.ResponseBody = "circuit open"
+	o.ResponseHeaders = map[string]string{"Retry-After": "30"}
+
+	b := New("test", o, nil, nil)
+	resp := b.Response(nil)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected %d got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+	if v := resp.Header.Get("Retry-After"); v != "30" {
+		t.Errorf("expected %s got %s", "30", v)
+	}
+	if v := resp.Header.Get(headers.NameTrkCBStatus); v != "closed" {
+		t.Errorf("expected %s got %s", "closed", v)
+	}
+	if b, _ := io.ReadAll(resp.Body); string(b) != "circuit open" {
+		t.Errorf("expected %s got %s", "circuit open", string(b))
+	}
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for backend circuit breakers
+package options
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
+)
+
+const (
+	// DefaultWindowMS is the default length of the sliding window of recorded request outcomes
+	DefaultWindowMS = 10000
+	// DefaultMinRequests is the default minimum number of requests in the window before
+	// the error and slow rates are evaluated
+	DefaultMinRequests = 20
+	// DefaultErrorRateThreshold is the default percentage of failed requests that opens the circuit
+	DefaultErrorRateThreshold = 50
+	// DefaultSlowRateThreshold is the default percentage of slow requests that opens the circuit
+	DefaultSlowRateThreshold = 50
+	// DefaultOpenDurationMS is the default time the circuit remains open before half-opening
+	DefaultOpenDurationMS = 30000
+	// DefaultHalfOpenRequests is the default number of trial requests permitted while half-open
+	DefaultHalfOpenRequests = 3
+	// DefaultHalfOpenTimeoutMS is the default time the circuit waits for the trial requests
+	// to complete before reopening
+	DefaultHalfOpenTimeoutMS = 10000
+	// DefaultResponseCode is the default status code of the response served while the circuit is open
+	DefaultResponseCode = http.StatusServiceUnavailable
+)
+
+// Options defines the Circuit Breaker Options for a Backend
+type Options struct {
+	// WindowMS is the length of the sliding window of recorded request outcomes
+	WindowMS int `yaml:"window_ms,omitempty"`
+	// MinRequests is the minimum number of requests in the window before the circuit can open
+	MinRequests int `yaml:"min_requests,omitempty"`
+	// ErrorRateThreshold is the percentage of failed (5xx) requests in the window that
+	// opens the circuit. 0 disables error rate checking
+	ErrorRateThreshold float64 `yaml:"error_rate_threshold,omitempty"`
+	// LatencyThresholdMS is the duration beyond which a request is considered slow.
+	// 0 (default) disables latency checking
+	LatencyThresholdMS int `yaml:"latency_threshold_ms,omitempty"`
+	// SlowRateThreshold is the percentage of slow requests in the window that opens the circuit
+	SlowRateThreshold float64 `yaml:"slow_rate_threshold,omitempty"`
+	// OpenDurationMS is how long the circuit remains open before half-opening to test recovery
+	OpenDurationMS int `yaml:"open_duration_ms,omitempty"`
+	// HalfOpenRequests is the number of trial requests permitted while half-open. The circuit
+	// closes when they all succeed, and reopens on any failure
+	HalfOpenRequests int `yaml:"half_open_requests,omitempty"`
+	// HalfOpenTimeoutMS is how long the circuit waits for the trial requests to complete
+	// before reopening
+	HalfOpenTimeoutMS int `yaml:"half_open_timeout_ms,omitempty"`
+	// ResponseCode is the status code of the response served while the circuit is open
+	ResponseCode int `yaml:"response_code,omitempty"`
+	// ResponseBody is the body of the response served while the circuit is open
+	ResponseBody string `yaml:"response_body,omitempty"`
+	// ResponseHeaders are the headers of the response served while the circuit is open
+	ResponseHeaders map[string]string `yaml:"response_headers,omitempty"`
+
+	// Window is the time.Duration representation of WindowMS
+	Window time.Duration `yaml:"-"`
+	// LatencyThreshold is the time.Duration representation of LatencyThresholdMS
+	LatencyThreshold time.Duration `yaml:"-"`
+	// OpenDuration is the time.Duration representation of OpenDurationMS
+	OpenDuration time.Duration `yaml:"-"`
+	// HalfOpenTimeout is the time.Duration representation of HalfOpenTimeoutMS
+	HalfOpenTimeout time.Duration `yaml:"-"`
+}
+
+// New returns a New Options object with the default values
+func New() *Options {
+	o := &Options{
+		WindowMS:           DefaultWindowMS,
+		MinRequests:        DefaultMinRequests,
+		ErrorRateThreshold: DefaultErrorRateThreshold,
+		SlowRateThreshold:  DefaultSlowRateThreshold,
+		OpenDurationMS:     DefaultOpenDurationMS,
+		HalfOpenRequests:   DefaultHalfOpenRequests,
+		HalfOpenTimeoutMS:  DefaultHalfOpenTimeoutMS,
+		ResponseCode:       DefaultResponseCode,
+	}
+	o.compile()
+	return o
+}
+
+// Clone returns a perfect copy of the Options
+func (o *Options) Clone() *Options {
+	c := &Options{
+		WindowMS:           o.WindowMS,
+		MinRequests:        o.MinRequests,
+		ErrorRateThreshold: o.ErrorRateThreshold,
+		LatencyThresholdMS: o.LatencyThresholdMS,
+		SlowRateThreshold:  o.SlowRateThreshold,
+		OpenDurationMS:     o.OpenDurationMS,
+		HalfOpenRequests:   o.HalfOpenRequests,
+		HalfOpenTimeoutMS:  o.HalfOpenTimeoutMS,
+		ResponseCode:       o.ResponseCode,
+		ResponseBody:       o.ResponseBody,
+	}
+	if o.ResponseHeaders != nil {
+		c.ResponseHeaders = make(map[string]string, len(o.ResponseHeaders))
+		for k, v := range o.ResponseHeaders {
+			c.ResponseHeaders[k] = v
+		}
+	}
+	c.compile()
+	return c
+}
+
+func (o *Options) compile() {
+	o.Window = time.Duration(o.WindowMS) * time.Millisecond
+	o.LatencyThreshold = time.Duration(o.LatencyThresholdMS) * time.Millisecond
+	o.OpenDuration = time.Duration(o.OpenDurationMS) * time.Millisecond
+	o.HalfOpenTimeout = time.Duration(o.HalfOpenTimeoutMS) * time.Millisecond
+}
+
+// SetDefaults iterates the provided Options, and overlays user-set values onto the default Options
+func SetDefaults(name string, options *Options, metadata yamlx.KeyLookup) (*Options, error) {
+
+	if metadata == nil {
+		return nil, errors.New("invalid metadata")
+	}
+
+	if !metadata.IsDefined("backends", name, "circuit_breaker") || options == nil {
+		return nil, nil
+	}
+
+	o := New()
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "window_ms") {
+		if options.WindowMS < 1 {
+			return nil, errors.New("value for 'window_ms' is invalid")
+		}
+		o.WindowMS = options.WindowMS
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "min_requests") {
+		if options.MinRequests < 1 {
+			return nil, errors.New("value for 'min_requests' is invalid")
+		}
+		o.MinRequests = options.MinRequests
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "error_rate_threshold") {
+		if options.ErrorRateThreshold < 0 || options.ErrorRateThreshold > 100 {
+			return nil, errors.New("value for 'error_rate_threshold' is invalid")
+		}
+		o.ErrorRateThreshold = options.ErrorRateThreshold
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "latency_threshold_ms") {
+		if options.LatencyThresholdMS < 0 {
+			return nil, errors.New("value for 'latency_threshold_ms' is invalid")
+		}
+		o.LatencyThresholdMS = options.LatencyThresholdMS
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "slow_rate_threshold") {
+		if options.SlowRateThreshold < 0 || options.SlowRateThreshold > 100 {
+			return nil, errors.New("value for 'slow_rate_threshold' is invalid")
+		}
+		o.SlowRateThreshold = options.SlowRateThreshold
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "open_duration_ms") {
+		if options.OpenDurationMS < 1 {
+			return nil, errors.New("value for 'open_duration_ms' is invalid")
+		}
+		o.OpenDurationMS = options.OpenDurationMS
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "half_open_requests") {
+		if options.HalfOpenRequests < 1 {
+			return nil, errors.New("value for 'half_open_requests' is invalid")
+		}
+		o.HalfOpenRequests = options.HalfOpenRequests
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "half_open_timeout_ms") {
+		if options.HalfOpenTimeoutMS < 1 {
+			return nil, errors.New("value for 'half_open_timeout_ms' is invalid")
+		}
+		o.HalfOpenTimeoutMS = options.HalfOpenTimeoutMS
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "response_code") {
+		if options.ResponseCode < 100 || options.ResponseCode > 599 {
+			return nil, errors.New("value for 'response_code' is invalid")
+		}
+		o.ResponseCode = options.ResponseCode
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "response_body") {
+		o.ResponseBody = options.ResponseBody
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker", "response_headers") {
+		o.ResponseHeaders = options.ResponseHeaders
+	}
+
+	o.compile()
+
+	return o, nil
+}
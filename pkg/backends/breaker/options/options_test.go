@@ -0,0 +1,139 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
+
+	"gopkg.in/yaml.v2"
+)
+
+type testOptions1 struct {
+	Backends map[string]*testOptions2 `yaml:"backends,omitempty"`
+}
+
+type testOptions2 struct {
+	CircuitBreaker *Options `yaml:"circuit_breaker,omitempty"`
+}
+
+func fromYAML(conf string) (*Options, yamlx.KeyLookup, error) {
+	to := &testOptions1{}
+	err := yaml.Unmarshal([]byte(conf), to)
+	if err != nil {
+		return nil, nil, err
+	}
+	md, err := yamlx.GetKeyList(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, v := range to.Backends {
+		if v != nil && v.CircuitBreaker != nil {
+			return v.CircuitBreaker, md, nil
+		}
+	}
+	return nil, md, nil
+}
+
+const testYAML = `
+backends:
+  test:
+    circuit_breaker:
+      window_ms: 5000
+      min_requests: 10
+      error_rate_threshold: 25
+      latency_threshold_ms: 2000
+      slow_rate_threshold: 75
+      open_duration_ms: 10000
+      half_open_requests: 1
+      half_open_timeout_ms: 5000
+      response_code: 502
+      response_body: unavailable
+      response_headers:
+        Retry-After: '10'
+`
+
+func TestClone(t *testing.T) {
+	o := New()
+	o.ResponseHeaders = map[string]string{"a": "b"}
+	c := o.Clone()
+	c.ResponseHeaders["a"] = "c"
+	if o.ResponseHeaders["a"] != "b" {
+		t.Error("clone mismatch")
+	}
+	if c.OpenDuration != DefaultOpenDurationMS*time.Millisecond {
+		t.Errorf("expected %d got %s", DefaultOpenDurationMS, c.OpenDuration)
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+
+	if _, err := SetDefaults("test", nil, nil); err == nil {
+		t.Error("expected error for invalid metadata")
+	}
+
+	o, md, err := fromYAML(testYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err = SetDefaults("test", o, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Window != 5*time.Second || o.MinRequests != 10 || o.ErrorRateThreshold != 25 ||
+		o.LatencyThreshold != 2*time.Second || o.SlowRateThreshold != 75 ||
+		o.OpenDuration != 10*time.Second || o.HalfOpenRequests != 1 ||
+		o.HalfOpenTimeout != 5*time.Second ||
+		o.ResponseCode != 502 || o.ResponseBody != "unavailable" ||
+		o.ResponseHeaders["Retry-After"] != "10" {
+		t.Errorf("unexpected options %+v", o)
+	}
+
+	// backends without a circuit_breaker section have no breaker
+	o, md, _ = fromYAML("backends:\n  test:\n    provider: rpc\n")
+	o, err = SetDefaults("test", o, md)
+	if err != nil || o != nil {
+		t.Error("expected nil options and error")
+	}
+}
+
+func TestSetDefaultsInvalid(t *testing.T) {
+
+	tests := []string{
+		"window_ms: 0",
+		"min_requests: 0",
+		"error_rate_threshold: 101",
+		"latency_threshold_ms: -1",
+		"slow_rate_threshold: -1",
+		"open_duration_ms: 0",
+		"half_open_requests: 0",
+		"half_open_timeout_ms: 0",
+		"response_code: 99",
+	}
+
+	for _, test := range tests {
+		o, md, err := fromYAML("backends:\n  test:\n    circuit_breaker:\n      " + test + "\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = SetDefaults("test", o, md); err == nil {
+			t.Errorf("expected error for %s", test)
+		}
+	}
+}
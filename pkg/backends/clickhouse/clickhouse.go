@@ -95,6 +95,11 @@ func (c *Client) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuer
 		// Swap in the Tokenized Query in the Url Params
 		qi.Set(upQuery, trq.Statement)
 		trq.TemplateURL.RawQuery = qi.Encode()
+		if ss, ok := trq.ParsedQuery.(string); ok {
+			trq.StepTemplateURL = urls.Clone(trq.TemplateURL)
+			qi.Set(upQuery, ss)
+			trq.StepTemplateURL.RawQuery = qi.Encode()
+		}
 	}
 
 	return trq, ro, canOPC, nil
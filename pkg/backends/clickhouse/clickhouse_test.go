@@ -19,6 +19,7 @@ package clickhouse
 import (
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/tricksterproxy/trickster/cmd/trickster/config"
@@ -106,6 +107,10 @@ func TestParseTimeRangeQuery(t *testing.T) {
 		if res.Extent.End.Sub(res.Extent.Start).Hours() != 6 {
 			t.Errorf("expected 6 got %f", res.Extent.End.Sub(res.Extent.Start).Hours())
 		}
+		if res.StepTemplateURL == nil ||
+			!strings.Contains(res.StepTemplateURL.Query().Get(upQuery), tkStep) {
+			t.Error("expected step template url with a tokenized step")
+		}
 	}
 
 	req.URL.RawQuery = ""
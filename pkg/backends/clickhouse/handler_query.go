@@ -25,12 +25,18 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/response/merge"
 	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
 )
 
 // QueryHandler handles timeseries requests for ClickHouse and processes them through the delta proxy cache
 func (c *Client) QueryHandler(w http.ResponseWriter, r *http.Request) {
 
+	// if this request is part of a scatter/gather, provide a reconstitution function
+	if rsc := request.GetResources(r); rsc != nil && rsc.IsMergeMember {
+		rsc.ResponseMergeFunc = merge.Timeseries
+	}
+
 	q := r.URL.Query()
 	sqlQuery := q.Get(upQuery)
 	if methods.HasBody(r.Method) {
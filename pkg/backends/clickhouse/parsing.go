@@ -86,6 +86,10 @@ func parse(statement string) (*timeseries.TimeRangeQuery, *timeseries.RequestOpt
 	if t, err = parseWhereTokens(results, trq, ro); err != nil {
 		return nil, nil, canObjectCache, parsing.ParserError(err, t)
 	}
+	// the parsed query holds the statement with its step tokenized, when it can be isolated
+	if st, ok := results["stepTemplate"].([2]string); ok && strings.Contains(trq.Statement, st[0]) {
+		trq.ParsedQuery = strings.Replace(trq.Statement, st[0], st[1], -1)
+	}
 	return trq, ro, canObjectCache, nil
 }
 
@@ -95,6 +99,7 @@ const (
 	tkTS1    = "<$TS1$>"
 	tkTS2    = "<$TS2$>"
 	tkFormat = "<$FORMAT$>"
+	tkStep   = "<$STEP$>"
 )
 
 const day = time.Hour * 24
@@ -295,6 +300,7 @@ func parseSelectTokens(results map[string]interface{},
 		var d time.Duration
 		var x int
 		var err error
+		var stepTokens token.Tokens
 		if len(fieldParts) == 0 {
 			continue
 		}
@@ -366,6 +372,7 @@ func parseSelectTokens(results map[string]interface{},
 							return t, err
 						}
 						d = time.Duration(n) * time.Second
+						stepTokens = append(stepTokens, t)
 					}
 					if prev.Typ == token.Multiply {
 						n, err := getInt(t)
@@ -379,6 +386,8 @@ func parseSelectTokens(results map[string]interface{},
 						trq.Step = d
 						needStep = false
 						checkMultiplier = true
+						stepTokens = append(stepTokens, t)
+						setStepTemplate(results, trq.Statement, fieldParts[0].Pos, stepTokens)
 					}
 				}
 				if expectBaseTimeField {
@@ -422,6 +431,27 @@ func parseSelectTokens(results map[string]interface{},
 	return nil, nil
 }
 
+// setStepTemplate records the intDiv timestamp expression that starts at pos, along with
+// a copy of it in which the step is tokenized, so that a statement can be related to those
+// that differ only by step. Nothing is recorded if a step came from a WITH variable.
+func setStepTemplate(results map[string]interface{}, statement string, pos int,
+	stepTokens token.Tokens) {
+	if len(stepTokens) != 2 {
+		return
+	}
+	last := stepTokens[1]
+	end := last.Pos + len(last.Val)
+	if stepTokens[0].Pos < pos || end > len(statement) ||
+		statement[stepTokens[0].Pos:stepTokens[0].Pos+len(stepTokens[0].Val)] != stepTokens[0].Val ||
+		statement[last.Pos:end] != last.Val {
+		return
+	}
+	expr := statement[pos:end]
+	tmpl := statement[pos:stepTokens[0].Pos] + tkStep +
+		statement[stepTokens[0].Pos+len(stepTokens[0].Val):last.Pos] + tkStep
+	results["stepTemplate"] = [2]string{expr, tmpl}
+}
+
 func getInt(t *token.Token) (int, error) {
 	var n int
 	var err error
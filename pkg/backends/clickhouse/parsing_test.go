@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -153,6 +154,42 @@ func TestGoodQueries(t *testing.T) {
 	}
 }
 
+func TestParseStepTemplate(t *testing.T) {
+	trq, _, _, err := parse(tq03)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trq.Step != 60*time.Second {
+		t.Errorf("Step of %d did not match 60 seconds", trq.Step)
+	}
+	ss, ok := trq.ParsedQuery.(string)
+	if !ok {
+		t.Fatal("expected step-tokenized statement")
+	}
+	if !strings.Contains(ss, `(intDiv(toUInt32(time_column), <$STEP$>) * <$STEP$>) * 1000 AS t`) {
+		t.Errorf("unexpected step-tokenized statement: %s", ss)
+	}
+	// the same query at another step has the same step-tokenized statement
+	trq2, _, _, err := parse(strings.Replace(tq03, "60) * 60", "300) * 300", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trq2.Step != 300*time.Second {
+		t.Errorf("Step of %d did not match 300 seconds", trq2.Step)
+	}
+	if trq2.Statement == trq.Statement || trq2.ParsedQuery != ss {
+		t.Errorf("expected %s got %v", ss, trq2.ParsedQuery)
+	}
+	// steps that are not isolated by intDiv are not tokenized
+	trq, _, _, err = parse(tq01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trq.ParsedQuery != nil {
+		t.Errorf("unexpected step-tokenized statement: %v", trq.ParsedQuery)
+	}
+}
+
 func TestParseErrors(t *testing.T) {
 	_, _, _, err := parse("")
 	if err != sqlparser.ErrNotTimeRangeQuery {
@@ -37,6 +37,12 @@ func (c *Client) RegisterHandlers(map[string]http.Handler) {
 	)
 }
 
+// MergeablePaths returns the list of ClickHouse Paths for which Trickster supports
+// merging multiple documents into a single response
+func (c *Client) MergeablePaths() []string {
+	return []string{"/"}
+}
+
 // DefaultPathConfigs returns the default PathConfigs for the given Provider
 func (c *Client) DefaultPathConfigs(o *bo.Options) map[string]*po.Options {
 	paths := map[string]*po.Options{
@@ -66,3 +66,20 @@ func TestDefaultPathConfigs(t *testing.T) {
 	}
 
 }
+
+func TestMergeablePaths(t *testing.T) {
+	c, err := NewClient("test", nil, nil, nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	mp := c.MergeablePaths()
+	if len(mp) != 1 {
+		t.Errorf("expected %d got %d", 1, len(mp))
+	}
+	dp := c.DefaultPathConfigs(nil)
+	for _, p := range mp {
+		if _, ok := dp[p]; !ok {
+			t.Errorf("expected default path config for mergeable path %s", p)
+		}
+	}
+}
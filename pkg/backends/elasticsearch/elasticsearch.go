@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package elasticsearch provides the Elasticsearch (and OpenSearch) backend provider
+package elasticsearch
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/backends"
+	"github.com/tricksterproxy/trickster/pkg/backends/elasticsearch/model"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+var _ backends.TimeseriesBackend = (*Client)(nil)
+
+// Client Implements the Proxy Client Interface
+type Client struct {
+	backends.TimeseriesBackend
+}
+
+// NewClient returns a new Client Instance
+func NewClient(name string, o *bo.Options, router http.Handler,
+	cache cache.Cache, modeler *timeseries.Modeler) (backends.TimeseriesBackend, error) {
+	if o != nil {
+		o.FastForwardDisable = true
+	}
+	c := &Client{}
+	b, err := backends.NewTimeseriesBackend(name, o, c.RegisterHandlers, router, cache, modeler)
+	c.TimeseriesBackend = b
+	return c, err
+}
+
+// ParseTimeRangeQuery parses the key parts of a TimeRangeQuery from the inbound HTTP Request
+func (c *Client) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuery,
+	*timeseries.RequestOptions, bool, error) {
+
+	body := request.GetBody(r)
+	if len(body) == 0 {
+		return nil, nil, false, errors.ErrNotTimeRangeQuery
+	}
+
+	rlo := &timeseries.RequestOptions{OutputFormat: model.OutputFormatSearch}
+	multi := strings.HasSuffix(r.URL.Path, "/"+mnMultiSearch)
+	if multi {
+		rlo.OutputFormat = model.OutputFormatMultiSearch
+	}
+
+	trq, err := parseSearches(body, multi, time.Now())
+	if err != nil {
+		// searches that are not time series can still be object cached, so the body
+		// is included in the cache key from the template url
+		trq = &timeseries.TimeRangeQuery{Statement: string(body)}
+	}
+
+	trq.TemplateURL = urls.Clone(r.URL)
+	qt := trq.TemplateURL.Query()
+	qt.Set(upBody, trq.Statement)
+	trq.TemplateURL.RawQuery = qt.Encode()
+
+	if err != nil {
+		return trq, rlo, true, err
+	}
+
+	return trq, rlo, false, nil
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticsearch
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/cmd/trickster/config"
+	"github.com/tricksterproxy/trickster/pkg/backends"
+	"github.com/tricksterproxy/trickster/pkg/backends/elasticsearch/model"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
+	cr "github.com/tricksterproxy/trickster/pkg/cache/registration"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+)
+
+var testModeler = model.NewModeler()
+
+const testSearch = `{"size":0,"query":{"bool":{"filter":[{"range":{"@timestamp":` +
+	`{"gte":1600000000000,"lte":1600003600000,"format":"epoch_millis"}}},` +
+	`{"query_string":{"analyze_wildcard":true,"query":"*"}}]}},` +
+	`"aggs":{"2":{"date_histogram":{"field":"@timestamp","fixed_interval":"30s",` +
+	`"min_doc_count":0,"extended_bounds":{"min":1600000000000,"max":1600003600000},` +
+	`"format":"epoch_millis"},"aggs":{"1":{"avg":{"field":"value"}}}}}}`
+
+const testMultiSearch = `{"search_type":"query_then_fetch","ignore_unavailable":true,"index":"metrics-*"}` +
+	"\n" + testSearch + "\n"
+
+func TestElasticsearchClientInterfacing(t *testing.T) {
+
+	// this test ensures the client will properly conform to the
+	// Client and TimeseriesBackend interfaces
+
+	c, err := NewClient("test", nil, nil, nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var oc backends.Backend = c
+	var tc backends.TimeseriesBackend = c
+
+	if oc.Name() != "test" {
+		t.Errorf("expected %s got %s", "test", oc.Name())
+	}
+
+	if tc.Name() != "test" {
+		t.Errorf("expected %s got %s", "test", tc.Name())
+	}
+}
+
+func TestNewClient(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-provider", "elasticsearch", "-origin-url", "http://1"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := cr.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer cr.CloseCaches(caches)
+	cache, ok := caches["default"]
+	if !ok {
+		t.Errorf("Could not find default configuration")
+	}
+
+	o := &bo.Options{Provider: "TEST_CLIENT"}
+	c, err := NewClient("default", o, nil, cache, testModeler)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if c.Name() != "default" {
+		t.Errorf("expected %s got %s", "default", c.Name())
+	}
+
+	if c.Cache().Configuration().Provider != "memory" {
+		t.Errorf("expected %s got %s", "memory", c.Cache().Configuration().Provider)
+	}
+
+	if c.Configuration().Provider != "TEST_CLIENT" {
+		t.Errorf("expected %s got %s", "TEST_CLIENT", c.Configuration().Provider)
+	}
+}
+
+func TestParseTimeRangeQuery(t *testing.T) {
+
+	client := &Client{}
+
+	req, _ := http.NewRequest(http.MethodPost, "http://blah.com/_msearch",
+		bytes.NewBufferString(testMultiSearch))
+	trq, rlo, canOPC, err := client.ParseTimeRangeQuery(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canOPC {
+		t.Error("expected false")
+	}
+	if rlo.OutputFormat != model.OutputFormatMultiSearch {
+		t.Errorf("expected %d got %d", model.OutputFormatMultiSearch, rlo.OutputFormat)
+	}
+	if trq.Step.Seconds() != 30 {
+		t.Errorf("expected 30 got %f", trq.Step.Seconds())
+	}
+	if trq.Extent.End.Sub(trq.Extent.Start).Hours() != 1 {
+		t.Errorf("expected 1 got %f", trq.Extent.End.Sub(trq.Extent.Start).Hours())
+	}
+	if !strings.Contains(trq.TemplateURL.Query().Get(upBody), tkStart) {
+		t.Errorf("expected templatized body in template url got %s", trq.TemplateURL.RawQuery)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "http://blah.com/metrics-*/_search",
+		bytes.NewBufferString(testSearch))
+	_, rlo, _, err = client.ParseTimeRangeQuery(req)
+	if err != nil {
+		t.Error(err)
+	}
+	if rlo.OutputFormat != model.OutputFormatSearch {
+		t.Errorf("expected %d got %d", model.OutputFormatSearch, rlo.OutputFormat)
+	}
+
+	// document searches are not time series, but can be object cached
+	const docSearch = `{"size":500,"query":{"match_all":{}}}`
+	req, _ = http.NewRequest(http.MethodPost, "http://blah.com/_search",
+		bytes.NewBufferString(docSearch))
+	trq, _, canOPC, err = client.ParseTimeRangeQuery(req)
+	if err == nil {
+		t.Error("expected error for document search")
+	}
+	if !canOPC {
+		t.Error("expected true")
+	}
+	if trq == nil || trq.TemplateURL.Query().Get(upBody) != docSearch {
+		t.Error("expected raw body in template url")
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "http://blah.com/_search", nil)
+	_, _, _, err = client.ParseTimeRangeQuery(req)
+	if err == nil {
+		t.Error("expected error for empty body")
+	}
+}
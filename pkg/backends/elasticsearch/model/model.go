@@ -0,0 +1,562 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package model provides the Elasticsearch aggregation response modeling
+// for the Elasticsearch backend provider
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/dataset"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/epoch"
+)
+
+// Output Formats for the RequestOptions, which indicate the response document type
+const (
+	// OutputFormatMultiSearch indicates a Multi Search (_msearch) response document
+	OutputFormatMultiSearch byte = iota
+	// OutputFormatSearch indicates a Search (_search) response document
+	OutputFormatSearch
+)
+
+// AggPathSeparator separates the aggregation names in a Series Name, which describes the
+// path of bucket aggregations from the top level to the date_histogram that provides the
+// Series' Points. This is the same separator used by Elasticsearch in a buckets_path
+const AggPathSeparator = ">"
+
+// Field names used for date_histogram bucket values
+const (
+	FieldDocCount    = "doc_count"
+	FieldKeyAsString = "key_as_string"
+)
+
+// ErrSearchFailed indicates a search response included an error or partial results,
+// and should not be cached
+var ErrSearchFailed = errors.New("search response was not successful")
+
+var marshalers = map[byte]dataset.Marshaler{
+	OutputFormatMultiSearch: marshalMultiSearch,
+	OutputFormatSearch:      marshalSearch,
+}
+
+// NewModeler returns a collection of modeling functions for elasticsearch interoperability
+func NewModeler() *timeseries.Modeler {
+	return &timeseries.Modeler{
+		WireUnmarshalerReader: UnmarshalTimeseriesReader,
+		WireMarshaler:         MarshalTimeseries,
+		WireMarshalWriter:     MarshalTimeseriesWriter,
+		WireUnmarshaler:       UnmarshalTimeseries,
+		CacheMarshaler:        dataset.MarshalDataSet,
+		CacheUnmarshaler:      dataset.UnmarshalDataSet,
+	}
+}
+
+// searchResponse is the portion of an Elasticsearch search response used by Trickster
+type searchResponse struct {
+	TimedOut     bool                   `json:"timed_out"`
+	Shards       *shards                `json:"_shards"`
+	Aggregations map[string]interface{} `json:"aggregations"`
+	Error        interface{}            `json:"error"`
+	Status       int                    `json:"status"`
+}
+
+type shards struct {
+	Failed int `json:"failed"`
+}
+
+// UnmarshalTimeseries converts a JSON blob into a Timeseries
+func UnmarshalTimeseries(data []byte, trq *timeseries.TimeRangeQuery) (timeseries.Timeseries, error) {
+	return UnmarshalTimeseriesReader(bytes.NewReader(data), trq)
+}
+
+// UnmarshalTimeseriesReader converts a Search or Multi Search response into a Timeseries.
+// Each search response becomes a Result, and each date_histogram within the search's
+// aggregations becomes a Series, whose Tags are the keys of any parent terms buckets
+func UnmarshalTimeseriesReader(reader io.Reader, trq *timeseries.TimeRangeQuery) (timeseries.Timeseries, error) {
+	if trq == nil {
+		return nil, timeseries.ErrNoTimerangeQuery
+	}
+
+	var doc struct {
+		searchResponse
+		Responses []*searchResponse `json:"responses"`
+	}
+	d := json.NewDecoder(reader)
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	responses := doc.Responses
+	if responses == nil {
+		if doc.Aggregations == nil && doc.Error == nil {
+			return nil, timeseries.ErrInvalidBody
+		}
+		responses = []*searchResponse{&doc.searchResponse}
+	}
+
+	ds := &dataset.DataSet{
+		TimeRangeQuery: trq,
+		ExtentList:     timeseries.ExtentList{trq.Extent},
+		Results:        make([]*dataset.Result, len(responses)),
+	}
+
+	for i, sr := range responses {
+		if sr == nil || sr.Error != nil || sr.TimedOut || (sr.Shards != nil && sr.Shards.Failed > 0) ||
+			(sr.Status != 0 && sr.Status != http.StatusOK) {
+			return nil, ErrSearchFailed
+		}
+		r := &dataset.Result{StatementID: i}
+		if err := unmarshalAggs(sr.Aggregations, nil, dataset.Tags{}, trq, r); err != nil {
+			return nil, err
+		}
+		ds.Results[i] = r
+	}
+
+	return ds, nil
+}
+
+// unmarshalAggs walks the bucket aggregations, adding a Series to the Result for each
+// date_histogram. The date_histogram is the innermost bucket aggregation, so a bucket
+// aggregation whose buckets contain no further bucket aggregations provides the Points
+func unmarshalAggs(aggs map[string]interface{}, path []string, tags dataset.Tags,
+	trq *timeseries.TimeRangeQuery, r *dataset.Result) error {
+
+	for _, name := range sortedKeys(aggs) {
+		agg, ok := aggs[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		bv, ok := agg["buckets"]
+		if !ok {
+			// metric aggregations outside of a date_histogram are not cached
+			continue
+		}
+		buckets, ok := bv.([]interface{})
+		if !ok {
+			return timeseries.ErrInvalidBody
+		}
+		p := append(append(make([]string, 0, len(path)+1), path...), name)
+
+		if isHistogram(buckets) {
+			s, err := unmarshalHistogram(buckets, p, tags, trq)
+			if err != nil {
+				return err
+			}
+			if s != nil {
+				r.SeriesList = append(r.SeriesList, s)
+			}
+			continue
+		}
+
+		for _, b := range buckets {
+			bucket, ok := b.(map[string]interface{})
+			if !ok {
+				return timeseries.ErrInvalidBody
+			}
+			key, err := json.Marshal(bucket["key"])
+			if err != nil {
+				return timeseries.ErrInvalidBody
+			}
+			bt := tags.Clone()
+			bt[name] = string(key)
+			if kas, ok := bucket[FieldKeyAsString].(string); ok {
+				bt[name+"."+FieldKeyAsString] = kas
+			}
+			if err := unmarshalAggs(bucket, p, bt, trq, r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isHistogram returns true if the buckets do not contain any bucket aggregations
+func isHistogram(buckets []interface{}) bool {
+	for _, b := range buckets {
+		bucket, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, v := range bucket {
+			if m, ok := v.(map[string]interface{}); ok {
+				if _, ok := m["buckets"]; ok {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func unmarshalHistogram(buckets []interface{}, path []string, tags dataset.Tags,
+	trq *timeseries.TimeRangeQuery) (*dataset.Series, error) {
+
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+
+	// the first pass collects the bucket values and the complete list of fields,
+	// so that each Point's Values are consistently ordered
+	type bucketValues struct {
+		epoch  epoch.Epoch
+		values map[string]interface{}
+	}
+	bvs := make([]bucketValues, len(buckets))
+	fields := make(map[string]timeseries.FieldDataType)
+	for i, b := range buckets {
+		bucket, ok := b.(map[string]interface{})
+		if !ok {
+			return nil, timeseries.ErrInvalidBody
+		}
+		kn, ok := bucket["key"].(json.Number)
+		if !ok {
+			return nil, timeseries.ErrInvalidTimeFormat
+		}
+		ms, err := kn.Int64()
+		if err != nil {
+			return nil, timeseries.ErrInvalidTimeFormat
+		}
+		bv := bucketValues{epoch: epoch.Epoch(ms * 1000000), values: make(map[string]interface{})}
+		for k, v := range bucket {
+			switch k {
+			case "key":
+			case FieldKeyAsString:
+				bv.values[k] = v
+				fields[k] = timeseries.String
+			case FieldDocCount:
+				n, ok := v.(json.Number)
+				if !ok {
+					return nil, timeseries.ErrInvalidBody
+				}
+				if bv.values[k], err = n.Int64(); err != nil {
+					return nil, timeseries.ErrInvalidBody
+				}
+				fields[k] = timeseries.Int64
+			default:
+				m, ok := v.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if err := unmarshalMetric(k, m, bv.values, fields); err != nil {
+					return nil, err
+				}
+			}
+		}
+		bvs[i] = bv
+	}
+
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	fl := make([]timeseries.FieldDefinition, len(names))
+	for i, n := range names {
+		fl[i] = timeseries.FieldDefinition{Name: n, DataType: fields[n], OutputPosition: i}
+	}
+
+	sh := dataset.SeriesHeader{
+		Name:           strings.Join(path, AggPathSeparator),
+		Tags:           tags,
+		FieldsList:     fl,
+		QueryStatement: trq.Statement,
+	}
+	sh.CalculateSize()
+	s := &dataset.Series{
+		Header: sh,
+		Points: make(dataset.Points, len(bvs)),
+	}
+	for i, bv := range bvs {
+		p := dataset.Point{
+			Epoch:  bv.epoch,
+			Size:   12,
+			Values: make([]interface{}, len(names)),
+		}
+		for j, n := range names {
+			v := bv.values[n]
+			p.Values[j] = v
+			if str, ok := v.(string); ok {
+				p.Size += len(str)
+			} else {
+				p.Size += 8
+			}
+		}
+		s.Points[i] = p
+		s.PointSize += int64(p.Size)
+	}
+	sort.Sort(s.Points)
+	return s, nil
+}
+
+// unmarshalMetric flattens the numeric values of a metric aggregation into fields named
+// <aggName>.<key> (e.g., 1.value) or <aggName>.<key>.<subkey> (e.g., 3.values.95.0)
+func unmarshalMetric(name string, m map[string]interface{}, values map[string]interface{},
+	fields map[string]timeseries.FieldDataType) error {
+	for k, v := range m {
+		switch t := v.(type) {
+		case nil, json.Number:
+			f, err := metricValue(t)
+			if err != nil {
+				return err
+			}
+			values[name+"."+k] = f
+			fields[name+"."+k] = timeseries.Float64
+		case map[string]interface{}:
+			for sk, sv := range t {
+				switch sv.(type) {
+				case nil, json.Number:
+				default:
+					continue
+				}
+				f, err := metricValue(sv)
+				if err != nil {
+					return err
+				}
+				fn := name + "." + k + "." + sk
+				values[fn] = f
+				fields[fn] = timeseries.Float64
+			}
+		}
+	}
+	return nil
+}
+
+func metricValue(v interface{}) (interface{}, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, timeseries.ErrInvalidBody
+	}
+	return f, nil
+}
+
+// MarshalTimeseries converts a Timeseries into a JSON blob
+func MarshalTimeseries(ts timeseries.Timeseries, rlo *timeseries.RequestOptions, status int) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	err := MarshalTimeseriesWriter(ts, rlo, status, buf)
+	return buf.Bytes(), err
+}
+
+// MarshalTimeseriesWriter converts a Timeseries into a JSON blob via an io.Writer
+func MarshalTimeseriesWriter(ts timeseries.Timeseries, rlo *timeseries.RequestOptions,
+	status int, w io.Writer) error {
+	if ts == nil {
+		return timeseries.ErrUnknownFormat
+	}
+	ds, ok := ts.(*dataset.DataSet)
+	if !ok {
+		return timeseries.ErrUnknownFormat
+	}
+	var of byte
+	if rlo != nil {
+		of = rlo.OutputFormat
+	}
+	marshaler, ok := marshalers[of]
+	if !ok {
+		return timeseries.ErrUnknownFormat
+	}
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rw.Header().Set(headers.NameContentType, headers.ValueApplicationJSON+"; charset=UTF-8")
+		rw.WriteHeader(status)
+	}
+	return marshaler(ds, rlo, status, w)
+}
+
+func marshalMultiSearch(ds *dataset.DataSet, rlo *timeseries.RequestOptions,
+	status int, w io.Writer) error {
+	responses := make([]interface{}, len(ds.Results))
+	for i, r := range ds.Results {
+		responses[i] = newSearchResponse(r)
+	}
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"took":      0,
+		"responses": responses,
+	})
+}
+
+func marshalSearch(ds *dataset.DataSet, rlo *timeseries.RequestOptions,
+	status int, w io.Writer) error {
+	var r *dataset.Result
+	if len(ds.Results) > 0 {
+		r = ds.Results[0]
+	}
+	return json.NewEncoder(w).Encode(newSearchResponse(r))
+}
+
+// aggNode is a bucket aggregation being rebuilt from the Series in a Result
+type aggNode struct {
+	buckets []*bucketNode
+	lookup  map[string]*bucketNode
+}
+
+// bucketNode is a terms bucket and its sub-aggregations
+type bucketNode struct {
+	key         json.RawMessage
+	keyAsString string
+	docCount    int64
+	aggs        map[string]interface{}
+}
+
+func (b *bucketNode) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(b.aggs)+3)
+	for k, v := range b.aggs {
+		m[k] = v
+	}
+	m["key"] = b.key
+	m[FieldDocCount] = b.docCount
+	if b.keyAsString != "" {
+		m[FieldKeyAsString] = b.keyAsString
+	}
+	return json.Marshal(m)
+}
+
+func (n *aggNode) MarshalJSON() ([]byte, error) {
+	buckets := n.buckets
+	if buckets == nil {
+		buckets = []*bucketNode{}
+	}
+	return json.Marshal(map[string]interface{}{
+		"doc_count_error_upper_bound": 0,
+		"sum_other_doc_count":         0,
+		"buckets":                     buckets,
+	})
+}
+
+// newSearchResponse builds a search response document from the Result, reconstructing
+// the nested bucket aggregations from each Series' aggregation path and Tags
+func newSearchResponse(r *dataset.Result) map[string]interface{} {
+	aggs := make(map[string]interface{})
+	var total int64
+	if r != nil {
+		for _, s := range r.SeriesList {
+			if s == nil {
+				continue
+			}
+			path := strings.Split(s.Header.Name, AggPathSeparator)
+			hist, docs := marshalHistogram(s)
+			total += docs
+			level := aggs
+			for _, name := range path[:len(path)-1] {
+				n, ok := level[name].(*aggNode)
+				if !ok {
+					n = &aggNode{lookup: make(map[string]*bucketNode)}
+					level[name] = n
+				}
+				key := s.Header.Tags[name]
+				b, ok := n.lookup[key]
+				if !ok {
+					b = &bucketNode{
+						key:         json.RawMessage(key),
+						keyAsString: s.Header.Tags[name+"."+FieldKeyAsString],
+						aggs:        make(map[string]interface{}),
+					}
+					n.lookup[key] = b
+					n.buckets = append(n.buckets, b)
+				}
+				b.docCount += docs
+				level = b.aggs
+			}
+			level[path[len(path)-1]] = hist
+		}
+	}
+	return map[string]interface{}{
+		"took":      0,
+		"timed_out": false,
+		"_shards": map[string]interface{}{
+			"total":      0,
+			"successful": 0,
+			"skipped":    0,
+			"failed":     0,
+		},
+		"hits": map[string]interface{}{
+			"total":     map[string]interface{}{"value": total, "relation": "eq"},
+			"max_score": nil,
+			"hits":      []interface{}{},
+		},
+		"aggregations": aggs,
+		"status":       http.StatusOK,
+	}
+}
+
+// marshalHistogram returns the date_histogram aggregation for the Series,
+// along with the total doc_count of its buckets
+func marshalHistogram(s *dataset.Series) (map[string]interface{}, int64) {
+	var total int64
+	buckets := make([]interface{}, len(s.Points))
+	for i, p := range s.Points {
+		b := map[string]interface{}{"key": int64(p.Epoch) / 1000000}
+		for j, fd := range s.Header.FieldsList {
+			if j >= len(p.Values) {
+				break
+			}
+			v := p.Values[j]
+			switch fd.Name {
+			case FieldDocCount:
+				if n, ok := v.(int64); ok {
+					total += n
+				}
+				b[fd.Name] = v
+				continue
+			case FieldKeyAsString:
+				b[fd.Name] = v
+				continue
+			}
+			parts := strings.SplitN(fd.Name, ".", 3)
+			if len(parts) < 2 {
+				continue
+			}
+			m, ok := b[parts[0]].(map[string]interface{})
+			if !ok {
+				m = make(map[string]interface{})
+				b[parts[0]] = m
+			}
+			if len(parts) == 2 {
+				m[parts[1]] = v
+				continue
+			}
+			sm, ok := m[parts[1]].(map[string]interface{})
+			if !ok {
+				sm = make(map[string]interface{})
+				m[parts[1]] = sm
+			}
+			sm[parts[2]] = v
+		}
+		buckets[i] = b
+	}
+	return map[string]interface{}{"buckets": buckets}, total
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/dataset"
+)
+
+const testDoc01 = `{"took":5,"responses":[{"took":5,"timed_out":false,` +
+	`"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},` +
+	`"hits":{"total":{"value":6,"relation":"eq"},"max_score":null,"hits":[]},` +
+	`"aggregations":{"3":{"doc_count_error_upper_bound":0,"sum_other_doc_count":0,"buckets":[` +
+	`{"key":"web-01","doc_count":4,"2":{"buckets":[` +
+	`{"key_as_string":"1577836815000","key":1577836815000,"doc_count":3,` +
+	`"1":{"value":0.484},"4":{"values":{"95.0":0.9,"99.0":null}}},` +
+	`{"key_as_string":"1577836800000","key":1577836800000,"doc_count":1,` +
+	`"1":{"value":null},"4":{"values":{"95.0":null,"99.0":null}}}]}},` +
+	`{"key":42,"doc_count":2,"2":{"buckets":[` +
+	`{"key_as_string":"1577836800000","key":1577836800000,"doc_count":2,` +
+	`"1":{"value":1.5},"4":{"values":{"95.0":1,"99.0":2}}}]}}` +
+	`]}},"status":200},` +
+	`{"took":1,"timed_out":false,"hits":{"total":{"value":0,"relation":"eq"},"hits":[]},` +
+	`"aggregations":{"2":{"buckets":[]}},"status":200}]}`
+
+const testDocError = `{"responses":[{"error":{"type":"search_phase_execution_exception"},"status":400}]}`
+
+func testTRQ() *timeseries.TimeRangeQuery {
+	return &timeseries.TimeRangeQuery{
+		Statement: "test",
+		Extent:    timeseries.Extent{Start: time.Unix(1577836800, 0), End: time.Unix(1577836815, 0)},
+		Step:      15 * time.Second,
+	}
+}
+
+func TestUnmarshalTimeseries(t *testing.T) {
+
+	_, err := UnmarshalTimeseries([]byte(testDoc01), nil)
+	if err != timeseries.ErrNoTimerangeQuery {
+		t.Error("expected ErrNoTimerangeQuery got", err)
+	}
+
+	_, err = UnmarshalTimeseries([]byte("{}"), testTRQ())
+	if err != timeseries.ErrInvalidBody {
+		t.Error("expected ErrInvalidBody got", err)
+	}
+
+	_, err = UnmarshalTimeseries([]byte(testDocError), testTRQ())
+	if err != ErrSearchFailed {
+		t.Error("expected ErrSearchFailed got", err)
+	}
+
+	ts, err := UnmarshalTimeseries([]byte(testDoc01), testTRQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := ts.(*dataset.DataSet)
+	if len(ds.Results) != 2 {
+		t.Fatalf("expected %d got %d", 2, len(ds.Results))
+	}
+	if len(ds.Results[0].SeriesList) != 2 {
+		t.Fatalf("expected %d got %d", 2, len(ds.Results[0].SeriesList))
+	}
+	s := ds.Results[0].SeriesList[0]
+	if s.Header.Name != "3>2" {
+		t.Errorf("expected %s got %s", "3>2", s.Header.Name)
+	}
+	if s.Header.Tags["3"] != `"web-01"` {
+		t.Errorf("expected %s got %s", `"web-01"`, s.Header.Tags["3"])
+	}
+	if ds.Results[0].SeriesList[1].Header.Tags["3"] != "42" {
+		t.Errorf("expected %s got %s", "42", ds.Results[0].SeriesList[1].Header.Tags["3"])
+	}
+	const expectedFields = 5 // 1.value, 4.values.95.0, 4.values.99.0, doc_count, key_as_string
+	if len(s.Header.FieldsList) != expectedFields {
+		t.Errorf("expected %d got %d", expectedFields, len(s.Header.FieldsList))
+	}
+	if len(s.Points) != 2 || s.Points[0].Epoch != 1577836800000000000 {
+		t.Errorf("unexpected points %v", s.Points)
+	}
+	if ts.ValueCount() != 3 {
+		t.Errorf("expected %d got %d", 3, ts.ValueCount())
+	}
+}
+
+func TestMarshalTimeseries(t *testing.T) {
+
+	ts, err := UnmarshalTimeseries([]byte(testDoc01), testTRQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	err = MarshalTimeseriesWriter(ts, &timeseries.RequestOptions{}, 200, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the marshaled document should unmarshal into the same DataSet
+	ts2, err := UnmarshalTimeseries(w.Body.Bytes(), testTRQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts2.SeriesCount() != ts.SeriesCount() || ts2.ValueCount() != ts.ValueCount() {
+		t.Errorf("expected %d series and %d values got %d and %d", ts.SeriesCount(),
+			ts.ValueCount(), ts2.SeriesCount(), ts2.ValueCount())
+	}
+
+	var doc struct {
+		Responses []struct {
+			Aggregations map[string]struct {
+				Buckets []struct {
+					Key      interface{} `json:"key"`
+					DocCount int64       `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"aggregations"`
+		} `json:"responses"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	buckets := doc.Responses[0].Aggregations["3"].Buckets
+	if len(buckets) != 2 || buckets[0].Key != "web-01" || buckets[0].DocCount != 4 {
+		t.Errorf("unexpected terms buckets %v", buckets)
+	}
+
+	w = httptest.NewRecorder()
+	err = MarshalTimeseriesWriter(ts, &timeseries.RequestOptions{OutputFormat: OutputFormatSearch}, 200, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts2, err = UnmarshalTimeseries(w.Body.Bytes(), testTRQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts2.SeriesCount() != 2 {
+		t.Errorf("expected %d got %d", 2, ts2.SeriesCount())
+	}
+
+	err = MarshalTimeseriesWriter(ts, &timeseries.RequestOptions{OutputFormat: 9}, 200, w)
+	if err != timeseries.ErrUnknownFormat {
+		t.Error("expected ErrUnknownFormat got", err)
+	}
+
+	if _, err = MarshalTimeseries(nil, nil, 200); err != timeseries.ErrUnknownFormat {
+		t.Error("expected ErrUnknownFormat got", err)
+	}
+}
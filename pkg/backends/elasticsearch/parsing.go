@@ -0,0 +1,422 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/util/timeconv"
+)
+
+// tokens that replace the time range values in a templatized search body
+const (
+	tkStart = "<$START$>" // the first bucket of the extent, inclusive
+	tkEnd   = "<$END$>"   // the end of the last bucket of the extent, exclusive
+	tkMax   = "<$MAX$>"   // the last bucket of the extent, inclusive
+)
+
+const formatEpochMillis = "epoch_millis"
+
+// bucketAggs are the bucket aggregations that may contain the date_histogram
+var bucketAggs = map[string]bool{
+	"terms": true,
+}
+
+// metricAggs are the aggregations whose values are calculated independently for each
+// date_histogram bucket, and can therefore be cached. Pipeline aggregations that depend on
+// other buckets (e.g., derivative or cumulative_sum) are not included.
+var metricAggs = map[string]bool{
+	"avg":                       true,
+	"bucket_script":             true,
+	"cardinality":               true,
+	"extended_stats":            true,
+	"max":                       true,
+	"median_absolute_deviation": true,
+	"min":                       true,
+	"percentiles":               true,
+	"rate":                      true,
+	"stats":                     true,
+	"sum":                       true,
+	"value_count":               true,
+}
+
+// search is a parsed search body
+type search struct {
+	extent timeseries.Extent
+	step   time.Duration
+	field  string
+}
+
+// parseSearches parses the time range and step from the provided search body, which is
+// newline-delimited header and body pairs when multi is true. Every search must request
+// the same time range and step. The returned statement is the request body, with the time
+// range values replaced by tokens
+func parseSearches(body []byte, multi bool, now time.Time) (*timeseries.TimeRangeQuery, error) {
+
+	var lines [][]byte
+	for _, l := range bytes.Split(body, []byte("\n")) {
+		if len(bytes.TrimSpace(l)) > 0 {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) == 0 || (multi && len(lines)%2 != 0) || (!multi && len(lines) != 1) {
+		return nil, errors.ErrNotTimeRangeQuery
+	}
+
+	var sb strings.Builder
+	trq := &timeseries.TimeRangeQuery{}
+	for i, l := range lines {
+		if multi && i%2 == 0 {
+			// the header line is included as-is
+			sb.Write(l)
+			sb.WriteString("\n")
+			continue
+		}
+		b, s, err := parseSearch(l, now)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 || (multi && i == 1) {
+			trq.Extent = s.extent
+			trq.Step = s.step
+			trq.TimestampDefinition = timeseries.FieldDefinition{Name: s.field,
+				DataType: timeseries.Int64}
+		} else if !trq.Extent.Start.Equal(s.extent.Start) || !trq.Extent.End.Equal(s.extent.End) ||
+			trq.Step != s.step {
+			return nil, errors.ErrNotTimeRangeQuery
+		}
+		sb.Write(b)
+		sb.WriteString("\n")
+	}
+	trq.Statement = sb.String()
+	return trq, nil
+}
+
+// parseSearch parses a single search body, returning the templatized body
+func parseSearch(b []byte, now time.Time) ([]byte, *search, error) {
+
+	var doc map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return nil, nil, errors.ErrNotTimeRangeQuery
+	}
+
+	// searches that return documents are not time series
+	if size, ok := doc["size"].(json.Number); !ok || size.String() != "0" {
+		return nil, nil, errors.ErrNotTimeRangeQuery
+	}
+
+	aggs, ok := subAggs(doc)
+	if !ok {
+		return nil, nil, errors.ErrNotTimeRangeQuery
+	}
+	h, err := findHistogram(aggs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := &search{}
+	if s.field, ok = h["field"].(string); !ok {
+		return nil, nil, errors.ErrNotTimeRangeQuery
+	}
+	if s.step, err = parseInterval(h); err != nil {
+		return nil, nil, err
+	}
+	if _, ok := h["offset"]; ok {
+		return nil, nil, errors.ErrStepParse
+	}
+	if tz, ok := h["time_zone"].(string); ok && !isUTC(tz) {
+		// time zone offsets are multiples of 15m, so only steps that evenly
+		// divide 15m are aligned to the same buckets as UTC
+		if s.step > 15*time.Minute || (15*time.Minute)%s.step != 0 {
+			return nil, nil, errors.ErrStepParse
+		}
+	}
+	for _, k := range []string{"extended_bounds", "hard_bounds"} {
+		if _, ok := h[k]; ok {
+			h[k] = map[string]interface{}{"min": tkStart, "max": tkMax}
+		}
+	}
+
+	ranges := findRanges(doc["query"], s.field, nil)
+	if len(ranges) != 1 {
+		return nil, nil, errors.ErrNotTimeRangeQuery
+	}
+	if s.extent, err = parseRange(ranges[0], now); err != nil {
+		return nil, nil, err
+	}
+	if !s.extent.End.After(s.extent.Start) {
+		return nil, nil, errors.ErrNotTimeRangeQuery
+	}
+
+	// the tokens must not be escaped, so that they can be found by interpolateSearch
+	buf := bytes.NewBuffer(make([]byte, 0, len(b)+32))
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, nil, errors.ErrNotTimeRangeQuery
+	}
+	return bytes.TrimSpace(buf.Bytes()), s, nil
+}
+
+// subAggs returns the aggregations map of the provided search body or aggregation
+func subAggs(m map[string]interface{}) (map[string]interface{}, bool) {
+	if v, ok := m["aggs"].(map[string]interface{}); ok {
+		return v, true
+	}
+	v, ok := m["aggregations"].(map[string]interface{})
+	return v, ok
+}
+
+// aggType returns the type of the provided aggregation (e.g., terms)
+func aggType(agg map[string]interface{}) (string, map[string]interface{}) {
+	for k, v := range agg {
+		switch k {
+		case "aggs", "aggregations", "meta":
+			continue
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			return k, m
+		}
+	}
+	return "", nil
+}
+
+// findHistogram returns the date_histogram from the provided aggregations. Each level of
+// the aggregations must have exactly one bucket aggregation, each of which must be a terms
+// aggregation until the date_histogram, whose sub-aggregations must be cacheable metrics
+func findHistogram(aggs map[string]interface{}) (map[string]interface{}, error) {
+	var h map[string]interface{}
+	var found bool
+	for _, v := range aggs {
+		agg, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.ErrNotTimeRangeQuery
+		}
+		t, body := aggType(agg)
+		switch {
+		case t == "date_histogram":
+			if found {
+				return nil, errors.ErrNotTimeRangeQuery
+			}
+			found, h = true, body
+			if sub, ok := subAggs(agg); ok {
+				for _, sv := range sub {
+					sagg, ok := sv.(map[string]interface{})
+					if !ok {
+						return nil, errors.ErrNotTimeRangeQuery
+					}
+					if st, _ := aggType(sagg); !metricAggs[st] {
+						return nil, errors.ErrNotTimeRangeQuery
+					}
+					if _, ok := subAggs(sagg); ok {
+						return nil, errors.ErrNotTimeRangeQuery
+					}
+				}
+			}
+		case bucketAggs[t]:
+			if found {
+				return nil, errors.ErrNotTimeRangeQuery
+			}
+			sub, ok := subAggs(agg)
+			if !ok {
+				return nil, errors.ErrNotTimeRangeQuery
+			}
+			var err error
+			if h, err = findHistogram(sub); err != nil {
+				return nil, err
+			}
+			found = true
+		case metricAggs[t]:
+			// metrics alongside a bucket aggregation are used for ordering
+		default:
+			return nil, errors.ErrNotTimeRangeQuery
+		}
+	}
+	if !found {
+		return nil, errors.ErrNotTimeRangeQuery
+	}
+	return h, nil
+}
+
+// parseInterval returns the step of the date_histogram
+func parseInterval(h map[string]interface{}) (time.Duration, error) {
+	var v string
+	if s, ok := h["fixed_interval"].(string); ok {
+		v = s
+	} else if s, ok := h["interval"].(string); ok {
+		v = s
+	} else {
+		// calendar intervals vary in duration
+		return 0, errors.ErrStepParse
+	}
+	// years and months are calendar units in elasticsearch
+	if strings.HasSuffix(v, "y") || strings.HasSuffix(v, "M") {
+		return 0, errors.ErrStepParse
+	}
+	d, err := timeconv.ParseDuration(strings.Replace(v, "H", "h", 1))
+	if err != nil || d <= 0 {
+		return 0, errors.ErrStepParse
+	}
+	return d, nil
+}
+
+func isUTC(tz string) bool {
+	switch strings.ToUpper(tz) {
+	case "UTC", "Z", "+00:00", "-00:00", "GMT", "ETC/UTC":
+		return true
+	}
+	return false
+}
+
+// findRanges returns the range queries on the provided field within the query
+func findRanges(q interface{}, field string, out []map[string]interface{}) []map[string]interface{} {
+	switch t := q.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			if k == "range" {
+				if m, ok := v.(map[string]interface{}); ok {
+					if r, ok := m[field].(map[string]interface{}); ok {
+						out = append(out, r)
+						continue
+					}
+				}
+			}
+			out = findRanges(v, field, out)
+		}
+	case []interface{}:
+		for _, v := range t {
+			out = findRanges(v, field, out)
+		}
+	}
+	return out
+}
+
+// parseRange returns the extent of the range query, and replaces its bounds with tokens
+func parseRange(r map[string]interface{}, now time.Time) (timeseries.Extent, error) {
+	var e timeseries.Extent
+	format, _ := r["format"].(string)
+	var hasStart, hasEnd bool
+	for _, k := range []string{"gte", "gt", "from", "lte", "lt", "to"} {
+		v, ok := r[k]
+		if !ok || v == nil {
+			continue
+		}
+		t, err := parseTime(v, format, now)
+		if err != nil {
+			return e, err
+		}
+		switch k {
+		case "gte", "from":
+			e.Start, hasStart = t, true
+		case "gt":
+			e.Start, hasStart = t.Add(time.Millisecond), true
+		case "lte", "to":
+			e.End, hasEnd = t, true
+		case "lt":
+			e.End, hasEnd = t.Add(-time.Millisecond), true
+		}
+	}
+	if !hasStart {
+		return e, errors.ErrNotTimeRangeQuery
+	}
+	if !hasEnd {
+		e.End = now
+	}
+	for _, k := range []string{"gte", "gt", "from", "lte", "lt", "to", "include_lower",
+		"include_upper", "format", "time_zone"} {
+		delete(r, k)
+	}
+	r["gte"] = tkStart
+	r["lt"] = tkEnd
+	r["format"] = formatEpochMillis
+	return e, nil
+}
+
+// parseTime returns the time represented by the provided range query value, which may be
+// epoch milliseconds (or seconds when the format is epoch_second), RFC3339, or date math
+// relative to now (e.g., now-6h)
+func parseTime(v interface{}, format string, now time.Time) (time.Time, error) {
+	var s string
+	switch t := v.(type) {
+	case json.Number:
+		s = t.String()
+	case string:
+		s = t
+	default:
+		return time.Time{}, errors.ErrNotTimeRangeQuery
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if strings.HasPrefix(format, "epoch_second") {
+			return time.Unix(i, 0), nil
+		}
+		return time.Unix(0, i*int64(time.Millisecond)), nil
+	}
+	if strings.HasPrefix(s, "now") {
+		s = s[3:]
+		if s == "" {
+			return now, nil
+		}
+		if strings.Contains(s, "/") {
+			// rounded date math is not supported
+			return time.Time{}, errors.ErrNotTimeRangeQuery
+		}
+		var neg bool
+		switch s[0] {
+		case '-':
+			neg = true
+		case '+':
+		default:
+			return time.Time{}, errors.ErrNotTimeRangeQuery
+		}
+		s = s[1:]
+		if strings.HasSuffix(s, "y") || strings.HasSuffix(s, "M") {
+			return time.Time{}, errors.ErrNotTimeRangeQuery
+		}
+		d, err := timeconv.ParseDuration(strings.Replace(s, "H", "h", 1))
+		if err != nil {
+			return time.Time{}, errors.ErrNotTimeRangeQuery
+		}
+		if neg {
+			d = -d
+		}
+		return now.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.ErrNotTimeRangeQuery
+}
+
+// interpolateSearch returns the search statement with its time range set to the extent
+func interpolateSearch(statement string, extent *timeseries.Extent, step time.Duration) string {
+	ms := func(t time.Time) string {
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	}
+	return strings.NewReplacer(
+		`"`+tkStart+`"`, ms(extent.Start),
+		`"`+tkEnd+`"`, ms(extent.End.Add(step)),
+		`"`+tkMax+`"`, ms(extent.End),
+	).Replace(statement)
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticsearch
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestParseSearches(t *testing.T) {
+
+	now := time.Unix(1600003600, 0)
+
+	const header = `{"index":"metrics-*"}`
+	const terms = `{"size":0,"query":{"range":{"ts":{"gte":"now-1h","lte":"now"}}},` +
+		`"aggs":{"3":{"terms":{"field":"host","size":10,"order":{"1":"desc"}},` +
+		`"aggs":{"1":{"max":{"field":"v"}},"2":{"date_histogram":{"field":"ts",` +
+		`"interval":"1m"},"aggs":{"1":{"max":{"field":"v"}}}}}}}}`
+
+	tests := []struct {
+		body     string
+		multi    bool
+		extent   timeseries.Extent
+		step     time.Duration
+		contains []string
+		err      error
+	}{
+		{ // 0
+			body:   testMultiSearch,
+			multi:  true,
+			extent: timeseries.Extent{Start: time.Unix(1600000000, 0), End: now},
+			step:   30 * time.Second,
+			contains: []string{`"index":"metrics-*"`, `"range":{"@timestamp":{"format":"epoch_millis",` +
+				`"gte":"<$START$>","lt":"<$END$>"}}`,
+				`"extended_bounds":{"max":"<$MAX$>","min":"<$START$>"}`},
+		},
+		{ // 1
+			body:     terms,
+			extent:   timeseries.Extent{Start: now.Add(-time.Hour), End: now},
+			step:     time.Minute,
+			contains: []string{`"gte":"<$START$>"`},
+		},
+		{ // 2 - multiple searches with the same range
+			body:   header + "\n" + terms + "\n" + header + "\n" + terms + "\n",
+			multi:  true,
+			extent: timeseries.Extent{Start: now.Add(-time.Hour), End: now},
+			step:   time.Minute,
+		},
+		{ // 3 - multiple searches with different ranges
+			body: header + "\n" + terms + "\n" + header + "\n" +
+				strings.Replace(terms, "now-1h", "now-2h", 1) + "\n",
+			multi: true,
+			err:   errors.ErrNotTimeRangeQuery,
+		},
+		{ // 4 - epoch seconds
+			body: `{"size":0,"query":{"range":{"ts":{"gt":1600000000,"lt":1600003600,` +
+				`"format":"epoch_second"}}},"aggs":{"2":{"date_histogram":{"field":"ts",` +
+				`"fixed_interval":"1h"}}}}`,
+			extent: timeseries.Extent{Start: time.Unix(1600000000, 0).Add(time.Millisecond),
+				End: now.Add(-time.Millisecond)},
+			step: time.Hour,
+		},
+		{ // 5 - documents
+			body: `{"size":10,"query":{"match_all":{}}}`,
+			err:  errors.ErrNotTimeRangeQuery,
+		},
+		{ // 6 - no histogram
+			body: `{"size":0,"aggs":{"1":{"avg":{"field":"v"}}}}`,
+			err:  errors.ErrNotTimeRangeQuery,
+		},
+		{ // 7 - unsupported pipeline aggregation
+			body: strings.Replace(terms, `"aggs":{"1":{"max":{"field":"v"}}}}`,
+				`"aggs":{"1":{"derivative":{"buckets_path":"_count"}}}}`, 1),
+			err: errors.ErrNotTimeRangeQuery,
+		},
+		{ // 8 - calendar interval
+			body: `{"size":0,"query":{"range":{"ts":{"gte":"now-1h"}}},"aggs":{"2":` +
+				`{"date_histogram":{"field":"ts","calendar_interval":"1d"}}}}`,
+			err: errors.ErrStepParse,
+		},
+		{ // 9 - time zone with a step that is not aligned to utc
+			body: `{"size":0,"query":{"range":{"ts":{"gte":"now-1h"}}},"aggs":{"2":` +
+				`{"date_histogram":{"field":"ts","fixed_interval":"1h","time_zone":"America/New_York"}}}}`,
+			err: errors.ErrStepParse,
+		},
+		{ // 10 - no range on the histogram field
+			body: `{"size":0,"query":{"range":{"other":{"gte":"now-1h"}}},"aggs":{"2":` +
+				`{"date_histogram":{"field":"ts","fixed_interval":"1m"}}}}`,
+			err: errors.ErrNotTimeRangeQuery,
+		},
+		{ // 11 - rounded date math
+			body: `{"size":0,"query":{"range":{"ts":{"gte":"now-1d/d"}}},"aggs":{"2":` +
+				`{"date_histogram":{"field":"ts","fixed_interval":"1m"}}}}`,
+			err: errors.ErrNotTimeRangeQuery,
+		},
+		{ // 12 - odd number of multi search lines
+			body:  header + "\n",
+			multi: true,
+			err:   errors.ErrNotTimeRangeQuery,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			trq, err := parseSearches([]byte(test.body), test.multi, now)
+			if err != test.err {
+				t.Fatalf("expected error %v got %v", test.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if !trq.Extent.Start.Equal(test.extent.Start) || !trq.Extent.End.Equal(test.extent.End) {
+				t.Errorf("expected extent %s got %s", test.extent.String(), trq.Extent.String())
+			}
+			if trq.Step != test.step {
+				t.Errorf("expected step %s got %s", test.step, trq.Step)
+			}
+			for _, s := range test.contains {
+				if !strings.Contains(trq.Statement, s) {
+					t.Errorf("expected statement to contain %s got %s", s, trq.Statement)
+				}
+			}
+		})
+	}
+}
+
+func TestInterpolateSearch(t *testing.T) {
+
+	trq, err := parseSearches([]byte(testSearch), false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &timeseries.Extent{Start: time.Unix(1600000000, 0), End: time.Unix(1600001800, 0)}
+	s := interpolateSearch(trq.Statement, e, trq.Step)
+	for _, v := range []string{`"gte":1600000000000`, `"lt":1600001830000`, `"max":1600001800000`} {
+		if !strings.Contains(s, v) {
+			t.Errorf("expected %s in %s", v, s)
+		}
+	}
+	if strings.Contains(s, "<$") {
+		t.Errorf("expected all tokens to be replaced in %s", s)
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package elasticsearch
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// Elasticsearch API Method Names
+const (
+	mnSearch      = "_search"
+	mnMultiSearch = "_msearch"
+)
+
+// Common URL Parameter Names
+const (
+	// upBody is used only in the cache key template url, to include the templatized
+	// search body in the cache key
+	upBody = "body"
+)
+
+// SetExtent will change the upstream request body to use the provided Extent
+func (c *Client) SetExtent(r *http.Request, trq *timeseries.TimeRangeQuery, extent *timeseries.Extent) {
+	if extent == nil || r == nil || trq == nil {
+		return
+	}
+	b := []byte(interpolateSearch(trq.Statement, extent, trq.Step))
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	r.ContentLength = int64(len(b))
+	r.Header.Set(headers.NameContentLength, strconv.Itoa(len(b)))
+}
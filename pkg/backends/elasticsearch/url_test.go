@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticsearch
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestSetExtent(t *testing.T) {
+
+	client := &Client{}
+	trq := &timeseries.TimeRangeQuery{
+		Statement: `{"gte":"<$START$>","lt":"<$END$>","max":"<$MAX$>"}`,
+		Step:      time.Minute,
+	}
+	e := &timeseries.Extent{Start: time.Unix(3600, 0), End: time.Unix(7200, 0)}
+	r, _ := http.NewRequest(http.MethodPost, "http://0/_search", nil)
+
+	const expected = `{"gte":3600000,"lt":7260000,"max":7200000}`
+	client.SetExtent(r, trq, e)
+	b, _ := io.ReadAll(r.Body)
+	if string(b) != expected {
+		t.Errorf("expected %s got %s", expected, string(b))
+	}
+	if r.ContentLength != int64(len(expected)) {
+		t.Errorf("expected %d got %d", len(expected), r.ContentLength)
+	}
+
+	r.Body = nil
+	client.SetExtent(r, trq, nil)
+	if r.Body != nil {
+		t.Error("expected nil body")
+	}
+}
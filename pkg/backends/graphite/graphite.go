@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package graphite provides the Graphite backend provider
+package graphite
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/backends"
+	gro "github.com/tricksterproxy/trickster/pkg/backends/graphite/options"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+var _ backends.TimeseriesBackend = (*Client)(nil)
+
+// Client Implements the Proxy Client Interface
+type Client struct {
+	backends.TimeseriesBackend
+}
+
+// NewClient returns a new Client Instance
+func NewClient(name string, o *bo.Options, router http.Handler,
+	cache cache.Cache, modeler *timeseries.Modeler) (backends.TimeseriesBackend, error) {
+	if o != nil {
+		o.FastForwardDisable = true
+	}
+	c := &Client{}
+	b, err := backends.NewTimeseriesBackend(name, o, c.RegisterHandlers, router, cache, modeler)
+	c.TimeseriesBackend = b
+	return c, err
+}
+
+// ParseTimeRangeQuery parses the key parts of a TimeRangeQuery from the inbound HTTP Request
+func (c *Client) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuery,
+	*timeseries.RequestOptions, bool, error) {
+
+	trq := &timeseries.TimeRangeQuery{Extent: timeseries.Extent{}}
+	rlo := &timeseries.RequestOptions{}
+
+	qp, _, _ := params.GetRequestValues(r)
+
+	targets := qp[upTarget]
+	if len(targets) == 0 {
+		return nil, nil, false, errors.MissingURLParam(upTarget)
+	}
+	trq.Statement = strings.Join(targets, "\n")
+
+	now := time.Now()
+	var err error
+	// Graphite's defaults when from or until are not provided
+	from, until := "-24h", "now"
+	if p := qp.Get(upFrom); p != "" {
+		from = p
+	}
+	if p := qp.Get(upUntil); p != "" {
+		until = p
+	}
+	if trq.Extent.Start, err = parseTime(from, now); err != nil {
+		return nil, nil, false, err
+	}
+	if trq.Extent.End, err = parseTime(until, now); err != nil {
+		return nil, nil, false, err
+	}
+
+	// the render API does not accept a step, so the configured step is used
+	trq.Step = time.Duration(gro.DefaultStepMS) * time.Millisecond
+	if rsc := request.GetResources(r); rsc != nil && rsc.BackendOptions != nil &&
+		rsc.BackendOptions.Graphite != nil && rsc.BackendOptions.Graphite.Step > 0 {
+		trq.Step = rsc.BackendOptions.Graphite.Step
+	}
+
+	// the cache key uses only the first value of each parameter, so the templatized
+	// query includes all targets in a single value
+	trq.TemplateURL = urls.Clone(r.URL)
+	qt := url.Values(http.Header(qp).Clone())
+	qt.Set(upTarget, trq.Statement)
+	trq.TemplateURL.RawQuery = qt.Encode()
+
+	return trq, rlo, false, nil
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/cmd/trickster/config"
+	"github.com/tricksterproxy/trickster/pkg/backends"
+	"github.com/tricksterproxy/trickster/pkg/backends/graphite/model"
+	gro "github.com/tricksterproxy/trickster/pkg/backends/graphite/options"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
+	cr "github.com/tricksterproxy/trickster/pkg/cache/registration"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+)
+
+var testModeler = model.NewModeler()
+
+func TestGraphiteClientInterfacing(t *testing.T) {
+
+	// this test ensures the client will properly conform to the
+	// Client and TimeseriesBackend interfaces
+
+	c, err := NewClient("test", nil, nil, nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var oc backends.Backend = c
+	var tc backends.TimeseriesBackend = c
+
+	if oc.Name() != "test" {
+		t.Errorf("expected %s got %s", "test", oc.Name())
+	}
+
+	if tc.Name() != "test" {
+		t.Errorf("expected %s got %s", "test", tc.Name())
+	}
+}
+
+func TestNewClient(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-provider", "graphite", "-origin-url", "http://1"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := cr.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer cr.CloseCaches(caches)
+	cache, ok := caches["default"]
+	if !ok {
+		t.Errorf("Could not find default configuration")
+	}
+
+	o := &bo.Options{Provider: "TEST_CLIENT"}
+	c, err := NewClient("default", o, nil, cache, testModeler)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if c.Name() != "default" {
+		t.Errorf("expected %s got %s", "default", c.Name())
+	}
+
+	if c.Cache().Configuration().Provider != "memory" {
+		t.Errorf("expected %s got %s", "memory", c.Cache().Configuration().Provider)
+	}
+
+	if !o.FastForwardDisable {
+		t.Error("expected fast forward to be disabled")
+	}
+}
+
+func TestParseTimeRangeQuery(t *testing.T) {
+
+	client := &Client{}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://blah.com/render?target=web.cpu"+
+		"&target=sumSeries(db.*.cpu)&from=1577836800&until=1577840400&format=json", nil)
+	trq, _, canOPC, err := client.ParseTimeRangeQuery(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canOPC {
+		t.Error("expected false")
+	}
+	if trq.Statement != "web.cpu\nsumSeries(db.*.cpu)" {
+		t.Errorf("unexpected statement %s", trq.Statement)
+	}
+	if trq.Extent.Start.Unix() != 1577836800 || trq.Extent.End.Unix() != 1577840400 {
+		t.Errorf("unexpected extent %s", trq.Extent)
+	}
+	if trq.Step != time.Duration(gro.DefaultStepMS)*time.Millisecond {
+		t.Errorf("expected %d got %s", gro.DefaultStepMS, trq.Step)
+	}
+	if v := trq.TemplateURL.Query()[upTarget]; len(v) != 1 || v[0] != trq.Statement {
+		t.Errorf("expected all targets in template url got %s", trq.TemplateURL.RawQuery)
+	}
+
+	// form bodies, relative times and the configured step
+	body := url.Values{upTarget: {"web.cpu"}, upFrom: {"-1h"}, upFormat: {"json"}}.Encode()
+	req, _ = http.NewRequest(http.MethodPost, "http://blah.com/render", bytes.NewBufferString(body))
+	req.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+	o := bo.New()
+	o.Graphite = &gro.Options{StepMS: 10000, Step: 10 * time.Second}
+	req = request.SetResources(req, request.NewResources(o, nil, nil, nil, client, nil, nil))
+	trq, _, _, err = client.ParseTimeRangeQuery(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := trq.Extent.End.Sub(trq.Extent.Start); d != time.Hour {
+		t.Errorf("expected %s got %s", time.Hour, d)
+	}
+	if trq.Step != 10*time.Second {
+		t.Errorf("expected %s got %s", 10*time.Second, trq.Step)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "http://blah.com/render?format=json", nil)
+	_, _, _, err = client.ParseTimeRangeQuery(req)
+	if err == nil {
+		t.Error("expected error for missing target")
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "http://blah.com/render?target=a&from=x", nil)
+	_, _, _, err = client.ParseTimeRangeQuery(req)
+	if err == nil {
+		t.Error("expected error for invalid from")
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "http://blah.com/render?target=a&until=x", nil)
+	_, _, _, err = client.ParseTimeRangeQuery(req)
+	if err == nil {
+		t.Error("expected error for invalid until")
+	}
+}
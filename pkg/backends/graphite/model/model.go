@@ -0,0 +1,295 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package model provides the Graphite render API response modeling
+// for the Graphite backend provider
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/dataset"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/epoch"
+)
+
+// FieldValue is the name of the field holding the value of each Graphite datapoint
+const FieldValue = "value"
+
+// pointSize is the size of a Point with a single float64 value: 8 bytes for the epoch,
+// 8 bytes for the size and 16 bytes for the value's interface header
+const pointSize = 32
+
+// NewModeler returns a collection of modeling functions for graphite interoperability
+func NewModeler() *timeseries.Modeler {
+	return &timeseries.Modeler{
+		WireUnmarshalerReader: UnmarshalTimeseriesReader,
+		WireMarshaler:         MarshalTimeseries,
+		WireMarshalWriter:     MarshalTimeseriesWriter,
+		WireUnmarshaler:       UnmarshalTimeseries,
+		CacheMarshaler:        dataset.MarshalDataSet,
+		CacheUnmarshaler:      dataset.UnmarshalDataSet,
+	}
+}
+
+// renderSeries is a series in a Graphite render API response with format=json
+type renderSeries struct {
+	Target     string          `json:"target"`
+	Tags       dataset.Tags    `json:"tags"`
+	Datapoints [][]interface{} `json:"datapoints"`
+}
+
+// UnmarshalTimeseries converts a JSON blob into a Timeseries
+func UnmarshalTimeseries(data []byte, trq *timeseries.TimeRangeQuery) (timeseries.Timeseries, error) {
+	return UnmarshalTimeseriesReader(bytes.NewReader(data), trq)
+}
+
+// UnmarshalTimeseriesReader converts a render API response into a Timeseries. Each target
+// becomes a Series in a single Result. Null datapoints are not stored, since the render
+// response is null-filled across the requested range when it is marshaled. Datapoints
+// before the start of the time range query, which are requested as lookback, are dropped
+func UnmarshalTimeseriesReader(reader io.Reader, trq *timeseries.TimeRangeQuery) (timeseries.Timeseries, error) {
+	if trq == nil {
+		return nil, timeseries.ErrNoTimerangeQuery
+	}
+
+	var doc []*renderSeries
+	d := json.NewDecoder(reader)
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	start := epoch.Epoch(trq.Extent.Start.UnixNano())
+	r := &dataset.Result{SeriesList: make([]*dataset.Series, len(doc))}
+	ds := &dataset.DataSet{
+		TimeRangeQuery: trq,
+		ExtentList:     timeseries.ExtentList{trq.Extent},
+		Results:        []*dataset.Result{r},
+	}
+
+	for i, rs := range doc {
+		if rs == nil {
+			return nil, timeseries.ErrInvalidBody
+		}
+		sh := dataset.SeriesHeader{
+			Name: rs.Target,
+			Tags: rs.Tags,
+			FieldsList: []timeseries.FieldDefinition{
+				{Name: FieldValue, DataType: timeseries.Float64},
+			},
+			QueryStatement: trq.Statement,
+		}
+		sh.CalculateSize()
+		s := &dataset.Series{
+			Header: sh,
+			Points: make(dataset.Points, 0, len(rs.Datapoints)),
+		}
+		for _, dp := range rs.Datapoints {
+			p, ok, err := pointFromDatapoint(dp)
+			if err != nil {
+				return nil, err
+			}
+			if !ok || p.Epoch < start {
+				continue
+			}
+			s.Points = append(s.Points, p)
+			s.PointSize += int64(p.Size)
+		}
+		sort.Sort(s.Points)
+		r.SeriesList[i] = s
+	}
+
+	return ds, nil
+}
+
+// pointFromDatapoint converts a Graphite [value, timestamp] datapoint into a Point.
+// ok is false when the datapoint's value is null
+func pointFromDatapoint(dp []interface{}) (dataset.Point, bool, error) {
+	if len(dp) != 2 {
+		return dataset.Point{}, false, timeseries.ErrInvalidBody
+	}
+	tn, ok := dp[1].(json.Number)
+	if !ok {
+		return dataset.Point{}, false, timeseries.ErrInvalidTimeFormat
+	}
+	ts, err := tn.Float64()
+	if err != nil {
+		return dataset.Point{}, false, timeseries.ErrInvalidTimeFormat
+	}
+	if dp[0] == nil {
+		return dataset.Point{}, false, nil
+	}
+	vn, ok := dp[0].(json.Number)
+	if !ok {
+		return dataset.Point{}, false, timeseries.ErrInvalidBody
+	}
+	v, err := vn.Float64()
+	if err != nil {
+		return dataset.Point{}, false, timeseries.ErrInvalidBody
+	}
+	return dataset.Point{
+		Epoch:  epoch.Epoch(int64(ts)) * 1000000000,
+		Size:   pointSize,
+		Values: []interface{}{v},
+	}, true, nil
+}
+
+// MarshalTimeseries converts a Timeseries into a JSON blob
+func MarshalTimeseries(ts timeseries.Timeseries, rlo *timeseries.RequestOptions, status int) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	err := MarshalTimeseriesWriter(ts, rlo, status, buf)
+	return buf.Bytes(), err
+}
+
+// MarshalTimeseriesWriter converts a Timeseries into a render API JSON response via an
+// io.Writer. Each Series is written with a datapoint at every step of the DataSet's
+// extents, and steps with no Point are written as null, as Graphite does
+func MarshalTimeseriesWriter(ts timeseries.Timeseries, rlo *timeseries.RequestOptions,
+	status int, w io.Writer) error {
+	if w == nil {
+		return timeseries.ErrUnknownFormat
+	}
+	ds, ok := ts.(*dataset.DataSet)
+	if !ok || ds == nil {
+		return timeseries.ErrUnknownFormat
+	}
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rw.Header().Set(headers.NameContentType, headers.ValueApplicationJSON+"; charset=UTF-8")
+		rw.WriteHeader(status)
+	}
+
+	var step, start, end epoch.Epoch
+	if ds.TimeRangeQuery != nil && ds.TimeRangeQuery.Step > 0 && len(ds.ExtentList) > 0 {
+		step = epoch.Epoch(ds.TimeRangeQuery.Step)
+		start = epoch.Epoch(ds.ExtentList[0].Start.UnixNano())
+		end = epoch.Epoch(ds.ExtentList[len(ds.ExtentList)-1].End.UnixNano())
+		// the first datapoint is at the first step boundary within the extents
+		if r := start % step; r != 0 {
+			start += step - r
+		}
+	}
+
+	w.Write([]byte("["))
+	sep := ""
+	for _, r := range ds.Results {
+		if r == nil {
+			continue
+		}
+		for _, s := range r.SeriesList {
+			if s == nil {
+				continue
+			}
+			target, _ := json.Marshal(s.Header.Name)
+			w.Write([]byte(sep + `{"target":` + string(target)))
+			if len(s.Header.Tags) > 0 {
+				tags, _ := json.Marshal(s.Header.Tags)
+				w.Write([]byte(`,"tags":` + string(tags)))
+			}
+			w.Write([]byte(`,"datapoints":[`))
+			writeDatapoints(w, s, start, end, step)
+			w.Write([]byte("]}"))
+			sep = ","
+		}
+	}
+	_, err := w.Write([]byte("]"))
+	return err
+}
+
+// writeDatapoints writes the Series' Points as Graphite datapoints, writing a null
+// datapoint for each step between start and end that has no Point
+func writeDatapoints(w io.Writer, s *dataset.Series, start, end, step epoch.Epoch) {
+	pts := s.Points
+	if !sort.IsSorted(pts) {
+		pts = pts.Clone()
+		sort.Sort(pts)
+	}
+	vi := valueIndex(s.Header.FieldsList)
+	sep := ""
+	next := start
+	for _, p := range pts {
+		for ; step > 0 && next < p.Epoch; next += step {
+			w.Write([]byte(sep + "[null," + strconv.FormatInt(int64(next/1000000000), 10) + "]"))
+			sep = ","
+		}
+		var v interface{}
+		if vi < len(p.Values) {
+			v = p.Values[vi]
+		}
+		w.Write([]byte(sep + "[" + formatValue(v) + "," +
+			strconv.FormatInt(int64(p.Epoch/1000000000), 10) + "]"))
+		sep = ","
+		if step > 0 && next <= p.Epoch {
+			next = (p.Epoch/step + 1) * step
+		}
+	}
+	for ; step > 0 && next <= end; next += step {
+		w.Write([]byte(sep + "[null," + strconv.FormatInt(int64(next/1000000000), 10) + "]"))
+		sep = ","
+	}
+}
+
+// valueIndex returns the index of the field holding the datapoint values, so that Series
+// translated from other providers are written using their value field
+func valueIndex(fl []timeseries.FieldDefinition) int {
+	for i, fd := range fl {
+		if fd.Name == FieldValue {
+			return i
+		}
+	}
+	return 0
+}
+
+// formatValue returns the JSON representation of a datapoint value, which is null
+// for values that are not numeric
+func formatValue(v interface{}) string {
+	var f float64
+	switch t := v.(type) {
+	case float64:
+		f = t
+	case float32:
+		f = float64(t)
+	case int64:
+		f = float64(t)
+	case int:
+		f = float64(t)
+	case json.Number:
+		n, err := t.Float64()
+		if err != nil {
+			return "null"
+		}
+		f = n
+	case string:
+		n, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return "null"
+		}
+		f = n
+	default:
+		return "null"
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "null"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
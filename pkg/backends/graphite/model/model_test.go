@@ -0,0 +1,206 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/dataset"
+)
+
+const testDoc01 = `[{"target":"web.cpu","tags":{"name":"web.cpu"},` +
+	`"datapoints":[[1.5,1577836860],[null,1577836800],[2,1577836920]]},` +
+	`{"target":"db.cpu","datapoints":[[null,1577836800],[null,1577836860]]}]`
+
+func testTRQ() *timeseries.TimeRangeQuery {
+	return &timeseries.TimeRangeQuery{
+		Statement: "web.cpu\ndb.cpu",
+		Extent:    timeseries.Extent{Start: time.Unix(1577836800, 0), End: time.Unix(1577836980, 0)},
+		Step:      time.Minute,
+	}
+}
+
+func TestNewModeler(t *testing.T) {
+	m := NewModeler()
+	if m.WireUnmarshaler == nil || m.WireMarshalWriter == nil {
+		t.Error("expected non-nil modeler funcs")
+	}
+}
+
+func TestUnmarshalTimeseries(t *testing.T) {
+
+	_, err := UnmarshalTimeseries([]byte(testDoc01), nil)
+	if err != timeseries.ErrNoTimerangeQuery {
+		t.Error("expected ErrNoTimerangeQuery got", err)
+	}
+
+	_, err = UnmarshalTimeseries([]byte("{}"), testTRQ())
+	if err == nil {
+		t.Error("expected error for invalid document")
+	}
+
+	_, err = UnmarshalTimeseries([]byte(`[{"target":"a","datapoints":[[1]]}]`), testTRQ())
+	if err != timeseries.ErrInvalidBody {
+		t.Error("expected ErrInvalidBody got", err)
+	}
+
+	_, err = UnmarshalTimeseries([]byte(`[{"target":"a","datapoints":[[1,"x"]]}]`), testTRQ())
+	if err != timeseries.ErrInvalidTimeFormat {
+		t.Error("expected ErrInvalidTimeFormat got", err)
+	}
+
+	_, err = UnmarshalTimeseries([]byte(`[{"target":"a","datapoints":[["x",1]]}]`), testTRQ())
+	if err != timeseries.ErrInvalidBody {
+		t.Error("expected ErrInvalidBody got", err)
+	}
+
+	ts, err := UnmarshalTimeseries([]byte(testDoc01), testTRQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := ts.(*dataset.DataSet)
+	if len(ds.Results) != 1 || len(ds.Results[0].SeriesList) != 2 {
+		t.Fatal("expected 1 result with 2 series")
+	}
+	s := ds.Results[0].SeriesList[0]
+	if s.Header.Name != "web.cpu" || s.Header.Tags["name"] != "web.cpu" {
+		t.Errorf("unexpected header %v", s.Header)
+	}
+	if len(s.Points) != 2 {
+		t.Fatalf("expected %d got %d", 2, len(s.Points))
+	}
+	if s.Points[0].Epoch != 1577836860000000000 || s.Points[0].Values[0] != 1.5 {
+		t.Errorf("unexpected point %v", s.Points[0])
+	}
+	if s.PointSize != 2*pointSize {
+		t.Errorf("expected %d got %d", 2*pointSize, s.PointSize)
+	}
+	if len(ds.Results[0].SeriesList[1].Points) != 0 {
+		t.Error("expected null datapoints to be skipped")
+	}
+
+	// the lookback datapoint preceding the time range query is dropped
+	ts, err = UnmarshalTimeseries([]byte(`[{"target":"a","datapoints":`+
+		`[[1,1577836740],[2,1577836800]]}]`), testTRQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = ts.(*dataset.DataSet).Results[0].SeriesList[0]
+	if len(s.Points) != 1 || s.Points[0].Epoch != 1577836800000000000 {
+		t.Errorf("expected lookback datapoint to be dropped got %v", s.Points)
+	}
+}
+
+func TestMarshalTimeseries(t *testing.T) {
+
+	_, err := MarshalTimeseries(nil, nil, 200)
+	if err != timeseries.ErrUnknownFormat {
+		t.Error("expected ErrUnknownFormat got", err)
+	}
+
+	err = MarshalTimeseriesWriter(&dataset.DataSet{}, nil, 200, nil)
+	if err != timeseries.ErrUnknownFormat {
+		t.Error("expected ErrUnknownFormat got", err)
+	}
+
+	ts, err := UnmarshalTimeseries([]byte(testDoc01), testTRQ())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	err = MarshalTimeseriesWriter(ts, nil, 200, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 200 {
+		t.Errorf("expected %d got %d", 200, w.Code)
+	}
+
+	expected := `[{"target":"web.cpu","tags":{"name":"web.cpu"},"datapoints":[` +
+		`[null,1577836800],[1.5,1577836860],[2,1577836920],[null,1577836980]]},` +
+		`{"target":"db.cpu","datapoints":[[null,1577836800],[null,1577836860],` +
+		`[null,1577836920],[null,1577836980]]}]`
+	if w.Body.String() != expected {
+		t.Errorf("expected %s got %s", expected, w.Body.String())
+	}
+
+	var v []interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Error(err)
+	}
+
+	// a DataSet without a step is written without null-filling
+	ds := ts.(*dataset.DataSet)
+	ds.TimeRangeQuery.Step = 0
+	b, err := MarshalTimeseries(ds, nil, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `[{"target":"web.cpu","tags":{"name":"web.cpu"},"datapoints":` +
+		`[[1.5,1577836860],[2,1577836920]]},{"target":"db.cpu","datapoints":[]}]`
+	if string(b) != expected {
+		t.Errorf("expected %s got %s", expected, b)
+	}
+}
+
+func TestWriteDatapointsUnsorted(t *testing.T) {
+	s := &dataset.Series{
+		Header: dataset.SeriesHeader{
+			FieldsList: []timeseries.FieldDefinition{{Name: "other"}, {Name: FieldValue}},
+		},
+		Points: dataset.Points{
+			{Epoch: 120000000000, Values: []interface{}{"x", "3"}},
+			{Epoch: 0, Values: []interface{}{"x", int64(1)}},
+		},
+	}
+	w := httptest.NewRecorder()
+	writeDatapoints(w, s, 0, 180000000000, 60000000000)
+	expected := `[1,0],[null,60],[3,120],[null,180]`
+	if w.Body.String() != expected {
+		t.Errorf("expected %s got %s", expected, w.Body.String())
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v        interface{}
+		expected string
+	}{
+		{1.25, "1.25"},
+		{float32(2), "2"},
+		{3, "3"},
+		{json.Number("4.5"), "4.5"},
+		{json.Number("x"), "null"},
+		{"6", "6"},
+		{"x", "null"},
+		{math.NaN(), "null"},
+		{math.Inf(1), "null"},
+		{nil, "null"},
+		{true, "null"},
+	}
+	for i, test := range tests {
+		if v := formatValue(test.v); v != test.expected {
+			t.Errorf("test %d: expected %s got %s", i, test.expected, v)
+		}
+	}
+}
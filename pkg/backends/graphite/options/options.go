@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
+)
+
+// DefaultStepMS is the default step, in milliseconds, used to align Graphite render requests
+const DefaultStepMS = 60000
+
+// ErrInvalidStep is returned when the configured step is not a positive value
+var ErrInvalidStep = errors.New("graphite step_ms must be greater than 0")
+
+// Options stores information about Graphite Options
+type Options struct {
+	// StepMS is the resolution, in milliseconds, at which Trickster aligns and caches the
+	// datapoints of render requests. Graphite does not accept a step in the render API, so
+	// this should match the finest retention of the metrics served by the backend
+	StepMS int `yaml:"step_ms,omitempty"`
+	// Step is the time.Duration representation of StepMS
+	Step time.Duration `yaml:"-"`
+}
+
+// New returns a new Graphite Options with the default values
+func New() *Options {
+	return &Options{
+		StepMS: DefaultStepMS,
+		Step:   time.Duration(DefaultStepMS) * time.Millisecond,
+	}
+}
+
+// Clone returns a perfect copy of the Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		StepMS: o.StepMS,
+		Step:   o.Step,
+	}
+}
+
+// SetDefaults overlays the Graphite options defined in the configuration onto the defaults
+func SetDefaults(name string, options *Options, metadata yamlx.KeyLookup) (*Options, error) {
+
+	o := New()
+
+	if metadata == nil || options == nil || !metadata.IsDefined("backends", name, "graphite") {
+		return o, nil
+	}
+
+	if metadata.IsDefined("backends", name, "graphite", "step_ms") {
+		if options.StepMS <= 0 {
+			return nil, ErrInvalidStep
+		}
+		o.StepMS = options.StepMS
+		o.Step = time.Duration(o.StepMS) * time.Millisecond
+	}
+
+	return o, nil
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
+
+	"gopkg.in/yaml.v2"
+)
+
+type testOptions1 struct {
+	Backends map[string]*testOptions2 `yaml:"backends,omitempty"`
+}
+
+type testOptions2 struct {
+	Graphite *Options `yaml:"graphite,omitempty"`
+}
+
+func fromYAML(conf string) (*Options, yamlx.KeyLookup, error) {
+
+	to := &testOptions1{}
+	err := yaml.Unmarshal([]byte(conf), to)
+	if err != nil {
+		return nil, nil, err
+	}
+	md, err := yamlx.GetKeyList(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, v := range to.Backends {
+		if v != nil && v.Graphite != nil {
+			return v.Graphite, md, nil
+		}
+	}
+	return nil, md, nil
+}
+
+func TestNew(t *testing.T) {
+	o := New()
+	if o.StepMS != DefaultStepMS || o.Step != time.Minute {
+		t.Errorf("expected %d got %d", DefaultStepMS, o.StepMS)
+	}
+}
+
+func TestClone(t *testing.T) {
+	o := New()
+	o.StepMS = 10000
+	o.Step = 10 * time.Second
+	co := o.Clone()
+	if co.StepMS != 10000 || co.Step != 10*time.Second {
+		t.Error("clone mismatch")
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+
+	tests := []struct {
+		conf     string
+		expected time.Duration
+		err      error
+	}{
+		{ // 0
+			conf:     testGraphiteStep,
+			expected: 10 * time.Second,
+		},
+		{ // 1
+			conf:     testGraphiteNoStep,
+			expected: time.Minute,
+		},
+		{ // 2
+			conf: testGraphiteInvalidStep,
+			err:  ErrInvalidStep,
+		},
+	}
+
+	for i, test := range tests {
+		o, md, err := fromYAML(test.conf)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		o, err = SetDefaults("test", o, md)
+		if err != test.err {
+			t.Errorf("test %d: expected %v got %v", i, test.err, err)
+			continue
+		}
+		if err == nil && o.Step != test.expected {
+			t.Errorf("test %d: expected %s got %s", i, test.expected, o.Step)
+		}
+	}
+
+	o, err := SetDefaults("test", nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if o.Step != time.Minute {
+		t.Errorf("expected %s got %s", time.Minute, o.Step)
+	}
+}
+
+const testGraphiteStep = `
+backends:
+  test:
+    provider: graphite
+    graphite:
+      step_ms: 10000
+`
+
+const testGraphiteNoStep = `
+backends:
+  test:
+    provider: graphite
+    graphite: {}
+`
+
+const testGraphiteInvalidStep = `
+backends:
+  test:
+    provider: graphite
+    graphite:
+      step_ms: -1
+`
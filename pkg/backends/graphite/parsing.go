@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// absoluteLayouts are the absolute time formats accepted by the Graphite render API
+var absoluteLayouts = []string{"15:04_20060102", "20060102", "01/02/06"}
+
+// offsetUnits maps the prefixes of Graphite's relative time units to their durations.
+// Graphite matches units by prefix, so 'min', 'mins' and 'minutes' are all minutes,
+// while 'm' alone is ambiguous and is rejected
+var offsetUnits = []struct {
+	prefix string
+	d      time.Duration
+}{
+	{"s", time.Second},
+	{"min", time.Minute},
+	{"h", time.Hour},
+	{"d", 24 * time.Hour},
+	{"w", 7 * 24 * time.Hour},
+	{"mon", 30 * 24 * time.Hour},
+	{"y", 365 * 24 * time.Hour},
+}
+
+// parseTime parses a Graphite from or until value relative to now. Supported values are
+// 'now', relative offsets like '-6h' or 'now-7d', epoch seconds, and the absolute
+// HH:MM_YYYYMMDD, YYYYMMDD and MM/DD/YY formats, which are interpreted as UTC
+func parseTime(s string, now time.Time) (time.Time, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	if v == "now" {
+		return now, nil
+	}
+	if strings.HasPrefix(v, "now") {
+		v = v[3:]
+	}
+	if strings.HasPrefix(v, "-") || strings.HasPrefix(v, "+") {
+		d, err := parseOffset(v)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil && !isYYYYMMDD(v) {
+		return time.Unix(i, 0), nil
+	}
+	for _, layout := range absoluteLayouts {
+		if t, err := time.ParseInLocation(layout, v, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+// parseOffset parses a signed relative time offset like -6h or +30min
+func parseOffset(s string) (time.Duration, error) {
+	sign := time.Duration(1)
+	if s[0] == '-' {
+		sign = -1
+	}
+	s = s[1:]
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i < 1 {
+		return 0, fmt.Errorf("cannot parse %q to a valid time offset", s)
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return 0, err
+	}
+	unit := s[i:]
+	for _, u := range offsetUnits {
+		if strings.HasPrefix(unit, u.prefix) {
+			return sign * time.Duration(n) * u.d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time offset unit %q", unit)
+}
+
+// isYYYYMMDD returns true if the all-digit value is a YYYYMMDD date rather than
+// epoch seconds, using the same rule as Graphite
+func isYYYYMMDD(s string) bool {
+	if len(s) != 8 {
+		return false
+	}
+	y, _ := strconv.Atoi(s[:4])
+	m, _ := strconv.Atoi(s[4:6])
+	d, _ := strconv.Atoi(s[6:])
+	return y > 1900 && m < 13 && d < 32
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+
+	now := time.Unix(1577836800, 0)
+
+	tests := []struct {
+		s        string
+		expected time.Time
+		err      bool
+	}{
+		{s: "now", expected: now},
+		{s: " NOW ", expected: now},
+		{s: "-6h", expected: now.Add(-6 * time.Hour)},
+		{s: "now-7d", expected: now.Add(-7 * 24 * time.Hour)},
+		{s: "-30min", expected: now.Add(-30 * time.Minute)},
+		{s: "-30minutes", expected: now.Add(-30 * time.Minute)},
+		{s: "-15s", expected: now.Add(-15 * time.Second)},
+		{s: "-2w", expected: now.Add(-14 * 24 * time.Hour)},
+		{s: "-1mon", expected: now.Add(-30 * 24 * time.Hour)},
+		{s: "-1y", expected: now.Add(-365 * 24 * time.Hour)},
+		{s: "now+1h", expected: now.Add(time.Hour)},
+		{s: "1577836800", expected: now},
+		{s: "20200101", expected: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{s: "12:30_20200101", expected: time.Date(2020, 1, 1, 12, 30, 0, 0, time.UTC)},
+		{s: "01/02/20", expected: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{s: "-1m", err: true},
+		{s: "-h", err: true},
+		{s: "-", err: true},
+		{s: "yesterday-ish", err: true},
+		{s: "", err: true},
+	}
+
+	for i, test := range tests {
+		tm, err := parseTime(test.s, now)
+		if test.err {
+			if err == nil {
+				t.Errorf("test %d: expected error for %q", i, test.s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if !tm.Equal(test.expected) {
+			t.Errorf("test %d: expected %s got %s", i, test.expected, tm)
+		}
+	}
+}
+
+func TestIsYYYYMMDD(t *testing.T) {
+	if !isYYYYMMDD("20200101") {
+		t.Error("expected true")
+	}
+	// 8-digit epochs from the early 1970s are not dates
+	if isYYYYMMDD("12345678") {
+		t.Error("expected false")
+	}
+	if isYYYYMMDD("1577836800") {
+		t.Error("expected false")
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// Graphite API Method Names
+const (
+	mnRender = "render"
+	mnFind   = "metrics/find"
+)
+
+// Common URL Parameter Names
+const (
+	upTarget        = "target"
+	upFrom          = "from"
+	upUntil         = "until"
+	upFormat        = "format"
+	upJSONP         = "jsonp"
+	upMaxDataPoints = "maxDataPoints"
+	upQuery         = "query"
+	upWildcards     = "wildcards"
+)
+
+// formatJSON is the render API format that is processed by the delta proxy cache
+const formatJSON = "json"
+
+// SetExtent will change the upstream request query to use the provided Extent
+func (c *Client) SetExtent(r *http.Request, trq *timeseries.TimeRangeQuery, extent *timeseries.Extent) {
+	if extent == nil || r == nil {
+		return
+	}
+	v, _, _ := params.GetRequestValues(r)
+	// Graphite returns datapoints after from, through until. from is moved back one step,
+	// and one second, so that the datapoint preceding the extent is also returned. Functions
+	// without a lookback window, like derivative and perSecond, return null for their first
+	// datapoint, so this keeps the first step of the extent from being left null. The extra
+	// datapoint is dropped when the response is unmarshaled
+	from := extent.Start.Unix() - 1
+	if trq != nil && trq.Step > 0 {
+		from -= int64(trq.Step / time.Second)
+	}
+	v.Set(upFrom, strconv.FormatInt(from, 10))
+	v.Set(upUntil, strconv.FormatInt(extent.End.Unix(), 10))
+	// maxDataPoints consolidates datapoints based on the requested range, so the cached
+	// datapoints would differ by range, and it is removed from upstream requests
+	v.Del(upMaxDataPoints)
+	params.SetRequestValues(r, v)
+}
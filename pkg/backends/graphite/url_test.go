@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestSetExtent(t *testing.T) {
+
+	client := &Client{}
+	trq := &timeseries.TimeRangeQuery{Statement: "web.cpu", Step: time.Minute}
+	e := &timeseries.Extent{Start: time.Unix(3600, 0), End: time.Unix(7200, 0)}
+	r, _ := http.NewRequest(http.MethodGet,
+		"http://0/render?target=web.cpu&from=-1h&until=now&maxDataPoints=100&format=json", nil)
+
+	client.SetExtent(r, trq, e)
+	qp := r.URL.Query()
+	if qp.Get(upFrom) != "3539" {
+		t.Errorf("expected %s got %s", "3539", qp.Get(upFrom))
+	}
+	if qp.Get(upUntil) != "7200" {
+		t.Errorf("expected %s got %s", "7200", qp.Get(upUntil))
+	}
+	if _, ok := qp[upMaxDataPoints]; ok {
+		t.Error("expected maxDataPoints to be removed")
+	}
+	if qp.Get(upTarget) != "web.cpu" {
+		t.Errorf("expected %s got %s", "web.cpu", qp.Get(upTarget))
+	}
+
+	const raw = "target=a"
+	r.URL.RawQuery = raw
+	client.SetExtent(r, trq, nil)
+	if r.URL.RawQuery != raw {
+		t.Errorf("expected %s got %s", raw, r.URL.RawQuery)
+	}
+}
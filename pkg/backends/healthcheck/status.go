@@ -33,6 +33,8 @@ type Status struct {
 	name         string
 	description  string
 	status       int32
+	circuitOpen  int32
+	circuitSince int64
 	detail       string
 	failingSince time.Time
 	subscribers  []chan bool
@@ -58,9 +60,10 @@ func (s *Status) String() string {
 // Headers returns a header set indicating the Status
 func (s *Status) Headers() http.Header {
 	h := http.Header{}
-	h.Set(headers.NameTrkHCStatus, strconv.Itoa(int(s.status)))
-	if s.status < 1 {
-		h.Set(headers.NameTrkHCDetail, s.detail)
+	i := s.Get()
+	h.Set(headers.NameTrkHCStatus, strconv.Itoa(i))
+	if i < 1 {
+		h.Set(headers.NameTrkHCDetail, s.Detail())
 	}
 	return h
 }
@@ -68,23 +71,60 @@ func (s *Status) Headers() http.Header {
 // Set updates the status
 func (s *Status) Set(i int32) {
 	atomic.StoreInt32(&s.status, i)
-	for _, ch := range s.subscribers {
+	for _, ch := range s.subscriberList() {
 		ch <- i == i
 	}
 }
 
+// subscriberList returns the registered subscribers, so they can be notified
+// without holding the lock
+func (s *Status) subscriberList() []chan bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.subscribers
+}
+
 // Prober returns the Prober func
 func (s *Status) Prober() func(http.ResponseWriter) {
 	return s.prober
 }
 
-// Get provides the current status
+// Get provides the current status, which is always -1 (unavailable) while the
+// backend's circuit breaker is open
 func (s *Status) Get() int {
+	if atomic.LoadInt32(&s.circuitOpen) == 1 {
+		return -1
+	}
 	return int(atomic.LoadInt32(&s.status))
 }
 
+// SetCircuitOpen updates the circuit breaker state and notifies subscribers when it changes
+func (s *Status) SetCircuitOpen(open bool) {
+	var i int32
+	if open {
+		i = 1
+	}
+	if !atomic.CompareAndSwapInt32(&s.circuitOpen, 1-i, i) {
+		return
+	}
+	if open {
+		atomic.StoreInt64(&s.circuitSince, time.Now().UnixNano())
+	}
+	for _, ch := range s.subscriberList() {
+		ch <- open
+	}
+}
+
+// IsCircuitOpen returns true if the backend's circuit breaker is open
+func (s *Status) IsCircuitOpen() bool {
+	return atomic.LoadInt32(&s.circuitOpen) == 1
+}
+
 // Detail provides the current detail
 func (s *Status) Detail() string {
+	if s.IsCircuitOpen() {
+		return "circuit breaker is open"
+	}
 	return s.detail
 }
 
@@ -95,6 +135,9 @@ func (s *Status) Description() string {
 
 // FailingSince provides the failing since time
 func (s *Status) FailingSince() time.Time {
+	if s.IsCircuitOpen() && s.failingSince.IsZero() {
+		return time.Unix(0, atomic.LoadInt64(&s.circuitSince))
+	}
 	return s.failingSince
 }
 
@@ -97,3 +97,40 @@ func TestFailingSince(t *testing.T) {
 		t.Error("expected 0 got", status.FailingSince().Unix())
 	}
 }
+
+func TestSetCircuitOpen(t *testing.T) {
+
+	status := &Status{status: 1, detail: "trickster"}
+	ch := make(chan bool, 1)
+	status.RegisterSubscriber(ch)
+
+	status.SetCircuitOpen(true)
+	<-ch
+	if !status.IsCircuitOpen() {
+		t.Error("expected open circuit")
+	}
+	if status.Get() != -1 {
+		t.Error("expected -1 got", status.Get())
+	}
+	if status.Detail() != "circuit breaker is open" {
+		t.Error("expected circuit breaker is open got", status.Detail())
+	}
+	if status.FailingSince().IsZero() {
+		t.Error("expected non-zero failing since")
+	}
+	if v := status.Headers().Get(headers.NameTrkHCStatus); v != "-1" {
+		t.Error("expected -1 got", v)
+	}
+
+	// an unchanged state does not notify subscribers
+	status.SetCircuitOpen(true)
+
+	status.SetCircuitOpen(false)
+	<-ch
+	if status.Get() != 1 {
+		t.Error("expected 1 got", status.Get())
+	}
+	if status.Detail() != "trickster" {
+		t.Error("expected trickster got", status.Detail())
+	}
+}
@@ -0,0 +1,519 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tpe "github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/util/timeconv"
+)
+
+// tokens that replace the time range values in a templatized flux query
+const (
+	fluxTokenStart = "<$START$>"
+	fluxTokenStop  = "<$STOP$>"
+)
+
+// ErrUnsupportedFluxExpression indicates a flux expression could not be evaluated by Trickster
+var ErrUnsupportedFluxExpression = errors.New("unsupported flux expression")
+
+var fluxOptionV = regexp.MustCompile(`^\s+v\s*=\s*\{`)
+var fluxTimeCall = regexp.MustCompile(`^time\(\s*v\s*:\s*"([^"]+)"\s*\)$`)
+
+// fluxQuery is a Flux query that has been parsed for its time range and step
+type fluxQuery struct {
+	// template is the query with its time range values replaced by tokens
+	template string
+	// extent is the time range of the query
+	extent timeseries.Extent
+	// step is the aggregateWindow interval of the query
+	step time.Duration
+	// timeSrcStart is true when aggregateWindow uses the window start as the timestamp
+	timeSrcStart bool
+}
+
+// fluxArg is a named argument in a flux function call or object
+type fluxArg struct {
+	name       string
+	value      string
+	start, end int // position of the value in the query
+}
+
+// fluxReplacement replaces the text between the provided positions in a query
+type fluxReplacement struct {
+	start, end int
+	text       string
+}
+
+// fluxCall is a function call in a flux query
+type fluxCall struct {
+	args  map[string]*fluxArg
+	close int // position of the closing parenthesis in the query
+}
+
+// parseFluxQuery parses the time range and step from the provided flux query. vars
+// provides the values of any externally-provided options (e.g., "v.timeRangeStart")
+func parseFluxQuery(query string, vars map[string]string, now time.Time) (*fluxQuery, error) {
+
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	var reps []fluxReplacement
+
+	// option v = { timeRangeStart: ..., timeRangeStop: ..., windowPeriod: ... }
+	for _, o := range scanFlux(query, "option") {
+		o += len("option")
+		loc := fluxOptionV.FindStringIndex(query[o:])
+		if loc == nil {
+			continue
+		}
+		open := o + loc[1] - 1
+		close := matchFluxBracket(query, open)
+		if close < 0 {
+			return nil, tpe.ErrNotTimeRangeQuery
+		}
+		for _, a := range splitFluxArgs(query, open+1, close) {
+			vars["v."+a.name] = a.value
+			switch a.name {
+			case "timeRangeStart":
+				reps = append(reps, fluxReplacement{a.start, a.end, fluxTokenStart})
+			case "timeRangeStop":
+				reps = append(reps, fluxReplacement{a.start, a.end, fluxTokenStop})
+			}
+		}
+	}
+
+	q := &fluxQuery{}
+	ranges := fluxCalls(query, "range")
+	if len(ranges) == 0 {
+		return nil, tpe.ErrNotTimeRangeQuery
+	}
+
+	for i, c := range ranges {
+		sa, ok := c.args["start"]
+		if !ok {
+			return nil, tpe.ErrNotTimeRangeQuery
+		}
+		start, err := evalFluxTime(sa.value, vars, now)
+		if err != nil {
+			return nil, err
+		}
+		reps = append(reps, fluxReplacement{sa.start, sa.end, fluxTokenStart})
+		stop := now
+		if ea, ok := c.args["stop"]; ok {
+			if stop, err = evalFluxTime(ea.value, vars, now); err != nil {
+				return nil, err
+			}
+			reps = append(reps, fluxReplacement{ea.start, ea.end, fluxTokenStop})
+		} else {
+			// the stop parameter defaults to now(), so it is added to the template
+			reps = append(reps, fluxReplacement{c.close, c.close, ", stop: " + fluxTokenStop})
+		}
+		e := timeseries.Extent{Start: start, End: stop}
+		if i == 0 {
+			q.extent = e
+		} else if !q.extent.Start.Equal(e.Start) || !q.extent.End.Equal(e.End) {
+			// multiple ranges in the query must be identical
+			return nil, tpe.ErrNotTimeRangeQuery
+		}
+	}
+	if !q.extent.End.After(q.extent.Start) {
+		return nil, tpe.ErrNotTimeRangeQuery
+	}
+
+	windows := fluxCalls(query, "aggregateWindow")
+	if len(windows) == 0 {
+		return nil, tpe.ErrStepParse
+	}
+	for i, c := range windows {
+		a, ok := c.args["every"]
+		if !ok {
+			return nil, tpe.ErrStepParse
+		}
+		step, err := evalFluxDuration(a.value, vars)
+		if err != nil || step <= 0 {
+			return nil, tpe.ErrStepParse
+		}
+		// windows that are offset or overlapping do not align to the step
+		if _, ok := c.args["offset"]; ok {
+			return nil, tpe.ErrStepParse
+		}
+		if p, ok := c.args["period"]; ok {
+			if d, err := evalFluxDuration(p.value, vars); err != nil || d != step {
+				return nil, tpe.ErrStepParse
+			}
+		}
+		var timeSrcStart bool
+		if ts, ok := c.args["timeSrc"]; ok {
+			timeSrcStart = ts.value == `"_start"`
+		}
+		if i == 0 {
+			q.step, q.timeSrcStart = step, timeSrcStart
+		} else if q.step != step || q.timeSrcStart != timeSrcStart {
+			return nil, tpe.ErrStepParse
+		}
+	}
+
+	q.template = templatizeFlux(query, reps)
+	return q, nil
+}
+
+// render returns the flux query with its time range set to the provided extent
+func (q *fluxQuery) render(e timeseries.Extent) string {
+	// aggregateWindow timestamps each window with its stop time by default, so
+	// the range is shifted by one step to include the windows ending within the extent
+	start, stop := e.Start.Add(-q.step), e.End
+	if q.timeSrcStart {
+		start, stop = e.Start, e.End.Add(q.step)
+	}
+	return strings.NewReplacer(
+		fluxTokenStart, start.UTC().Format(time.RFC3339Nano),
+		fluxTokenStop, stop.UTC().Format(time.RFC3339Nano),
+	).Replace(q.template)
+}
+
+// templatizeFlux applies the replacements to the query
+func templatizeFlux(query string, reps []fluxReplacement) string {
+	sort.Slice(reps, func(i, j int) bool { return reps[i].start < reps[j].start })
+	var sb strings.Builder
+	var i int
+	for _, r := range reps {
+		if r.start < i {
+			continue // overlapping replacements are skipped
+		}
+		sb.WriteString(query[i:r.start])
+		sb.WriteString(r.text)
+		i = r.end
+	}
+	sb.WriteString(query[i:])
+	return sb.String()
+}
+
+// evalFluxTime evaluates a flux expression that represents a point in time
+func evalFluxTime(expr string, vars map[string]string, now time.Time) (time.Time, error) {
+	expr = strings.TrimSpace(expr)
+	if v, ok := vars[expr]; ok {
+		delete(vars, expr) // prevents recursion
+		defer func() { vars[expr] = v }()
+		return evalFluxTime(v, vars, now)
+	}
+	if expr == "now()" {
+		return now, nil
+	}
+	if m := fluxTimeCall.FindStringSubmatch(expr); len(m) == 2 {
+		expr = m[1]
+	}
+	if t, err := time.Parse(time.RFC3339Nano, expr); err == nil {
+		return t, nil
+	}
+	if i, err := strconv.ParseInt(expr, 10, 64); err == nil {
+		return time.Unix(i, 0), nil
+	}
+	d, err := parseFluxDuration(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(d), nil
+}
+
+// evalFluxDuration evaluates a flux expression that represents a duration
+func evalFluxDuration(expr string, vars map[string]string) (time.Duration, error) {
+	expr = strings.TrimSpace(expr)
+	if v, ok := vars[expr]; ok {
+		delete(vars, expr)
+		defer func() { vars[expr] = v }()
+		return evalFluxDuration(v, vars)
+	}
+	return parseFluxDuration(expr)
+}
+
+// parseFluxDuration parses a flux duration literal such as 1h30m. Calendar-based units
+// (mo, y) are not supported, since their durations vary
+func parseFluxDuration(s string) (time.Duration, error) {
+	var neg bool
+	if strings.HasPrefix(s, "-") {
+		neg, s = true, s[1:]
+	}
+	if s == "" {
+		return 0, ErrUnsupportedFluxExpression
+	}
+	var d time.Duration
+	for len(s) > 0 {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		j := i
+		for j < len(s) && (s[j] < '0' || s[j] > '9') {
+			j++
+		}
+		if i == 0 || s[i:j] == "mo" || s[i:j] == "y" {
+			return 0, ErrUnsupportedFluxExpression
+		}
+		v, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			return 0, ErrUnsupportedFluxExpression
+		}
+		x, err := timeconv.ParseDurationParts(v, s[i:j])
+		if err != nil {
+			return 0, ErrUnsupportedFluxExpression
+		}
+		d += x
+		s = s[j:]
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// fluxCalls returns the calls to the named function in the query
+func fluxCalls(query, name string) []*fluxCall {
+	var calls []*fluxCall
+	for _, i := range scanFlux(query, name) {
+		open := i + len(name)
+		for open < len(query) && isFluxSpace(query[open]) {
+			open++
+		}
+		if open >= len(query) || query[open] != '(' {
+			continue
+		}
+		close := matchFluxBracket(query, open)
+		if close < 0 {
+			continue
+		}
+		c := &fluxCall{args: make(map[string]*fluxArg), close: close}
+		for _, a := range splitFluxArgs(query, open+1, close) {
+			c.args[a.name] = a
+		}
+		calls = append(calls, c)
+	}
+	return calls
+}
+
+// scanFlux returns the positions of the provided identifier in the query,
+// excluding any occurrences within string literals, comments or member expressions
+func scanFlux(query, ident string) []int {
+	var out []int
+	for i := 0; i < len(query); i++ {
+		switch {
+		case query[i] == '"':
+			i = skipFluxString(query, i)
+		case query[i] == '/' && i+1 < len(query) && query[i+1] == '/':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case isFluxIdentChar(query[i]):
+			j := i
+			for j < len(query) && isFluxIdentChar(query[j]) {
+				j++
+			}
+			if query[i:j] == ident && (i == 0 || query[i-1] != '.') {
+				out = append(out, i)
+			}
+			i = j - 1
+		}
+	}
+	return out
+}
+
+// splitFluxArgs splits the named arguments between the provided positions
+func splitFluxArgs(query string, start, end int) []*fluxArg {
+	var args []*fluxArg
+	var depth int
+	part := start
+	for i := start; i <= end; i++ {
+		if i < end {
+			switch query[i] {
+			case '"':
+				i = skipFluxString(query, i)
+				continue
+			case '(', '[', '{':
+				depth++
+				continue
+			case ')', ']', '}':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		if a := newFluxArg(query, part, i); a != nil {
+			args = append(args, a)
+		}
+		part = i + 1
+	}
+	return args
+}
+
+func newFluxArg(query string, start, end int) *fluxArg {
+	c := strings.IndexByte(query[start:end], ':')
+	if c < 0 {
+		return nil
+	}
+	vs := start + c + 1
+	for vs < end && isFluxSpace(query[vs]) {
+		vs++
+	}
+	ve := end
+	for ve > vs && isFluxSpace(query[ve-1]) {
+		ve--
+	}
+	return &fluxArg{
+		name:  strings.TrimSpace(query[start : start+c]),
+		value: query[vs:ve],
+		start: vs,
+		end:   ve,
+	}
+}
+
+// matchFluxBracket returns the position of the bracket that closes the one at
+// the provided position, or -1 if it is not closed
+func matchFluxBracket(query string, open int) int {
+	var depth int
+	for i := open; i < len(query); i++ {
+		switch query[i] {
+		case '"':
+			i = skipFluxString(query, i)
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// skipFluxString returns the position of the quote that closes the string literal
+// beginning at the provided position
+func skipFluxString(query string, i int) int {
+	for i++; i < len(query); i++ {
+		if query[i] == '\\' {
+			i++
+			continue
+		}
+		if query[i] == '"' {
+			return i
+		}
+	}
+	return i
+}
+
+func isFluxIdentChar(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+func isFluxSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// fluxExternVars returns the option values that are defined in the extern
+// section of an InfluxDB 2.x query request, such as those provided by Chronograf
+func fluxExternVars(extern interface{}) map[string]string {
+	vars := make(map[string]string)
+	f, ok := extern.(map[string]interface{})
+	if !ok {
+		return vars
+	}
+	body, _ := f["body"].([]interface{})
+	for _, s := range body {
+		st, _ := s.(map[string]interface{})
+		if st == nil || st["type"] != "OptionStatement" {
+			continue
+		}
+		as, _ := st["assignment"].(map[string]interface{})
+		if as == nil {
+			continue
+		}
+		id, _ := as["id"].(map[string]interface{})
+		init, _ := as["init"].(map[string]interface{})
+		if id == nil || init == nil || init["type"] != "ObjectExpression" {
+			continue
+		}
+		props, _ := init["properties"].([]interface{})
+		for _, p := range props {
+			prop, _ := p.(map[string]interface{})
+			if prop == nil {
+				continue
+			}
+			key, _ := prop["key"].(map[string]interface{})
+			value, _ := prop["value"].(map[string]interface{})
+			if key == nil || value == nil {
+				continue
+			}
+			if s, err := fluxExternExpr(value); err == nil {
+				vars[fmt.Sprintf("%v.%v", id["name"], key["name"])] = s
+			}
+		}
+	}
+	return vars
+}
+
+// fluxExternExpr converts a time or duration expression from a flux AST to flux source
+func fluxExternExpr(expr map[string]interface{}) (string, error) {
+	switch expr["type"] {
+	case "DateTimeLiteral":
+		if s, ok := expr["value"].(string); ok {
+			return s, nil
+		}
+	case "DurationLiteral":
+		values, _ := expr["values"].([]interface{})
+		if len(values) == 0 {
+			break
+		}
+		var sb strings.Builder
+		for _, v := range values {
+			m, _ := v.(map[string]interface{})
+			if m == nil {
+				return "", ErrUnsupportedFluxExpression
+			}
+			sb.WriteString(fmt.Sprintf("%v%v", m["magnitude"], m["unit"]))
+		}
+		return sb.String(), nil
+	case "UnaryExpression":
+		arg, _ := expr["argument"].(map[string]interface{})
+		if expr["operator"] != "-" || arg == nil {
+			break
+		}
+		s, err := fluxExternExpr(arg)
+		if err != nil {
+			return "", err
+		}
+		return "-" + s, nil
+	case "CallExpression":
+		callee, _ := expr["callee"].(map[string]interface{})
+		if callee != nil && callee["name"] == "now" {
+			return "now()", nil
+		}
+	}
+	return "", ErrUnsupportedFluxExpression
+}
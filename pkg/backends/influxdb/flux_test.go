@@ -0,0 +1,201 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+const testFluxQuery = `from(bucket: "telegraf")
+  |> range(start: -6h, stop: now())
+  |> filter(fn: (r) => r._measurement == "cpu" and r.host =~ /range\(start: 0/)
+  // |> range(start: -1h)
+  |> aggregateWindow(every: 1m, fn: mean)`
+
+func TestParseFluxQuery(t *testing.T) {
+
+	now := time.Unix(1600000000, 0)
+
+	tests := []struct {
+		query    string
+		vars     map[string]string
+		extent   timeseries.Extent
+		step     time.Duration
+		template string
+		err      error
+	}{
+		{
+			query:  testFluxQuery,
+			extent: timeseries.Extent{Start: now.Add(-6 * time.Hour), End: now},
+			step:   time.Minute,
+			template: `from(bucket: "telegraf")
+  |> range(start: <$START$>, stop: <$STOP$>)
+  |> filter(fn: (r) => r._measurement == "cpu" and r.host =~ /range\(start: 0/)
+  // |> range(start: -1h)
+  |> aggregateWindow(every: 1m, fn: mean)`,
+		},
+		{
+			query: `from(bucket: "b") |> range(start: 2020-09-13T00:00:00Z) ` +
+				`|> aggregateWindow(every: 1h30m, fn: last, timeSrc: "_start")`,
+			extent: timeseries.Extent{Start: time.Unix(1599955200, 0), End: now},
+			step:   90 * time.Minute,
+			template: `from(bucket: "b") |> range(start: <$START$>, stop: <$STOP$>) ` +
+				`|> aggregateWindow(every: 1h30m, fn: last, timeSrc: "_start")`,
+		},
+		{
+			query: `option v = {timeRangeStart: -1h, timeRangeStop: now(), windowPeriod: 10s}
+from(bucket: "b") |> range(start: v.timeRangeStart, stop: v.timeRangeStop)
+  |> aggregateWindow(every: v.windowPeriod, fn: mean)`,
+			extent: timeseries.Extent{Start: now.Add(-time.Hour), End: now},
+			step:   10 * time.Second,
+			template: `option v = {timeRangeStart: <$START$>, timeRangeStop: <$STOP$>, windowPeriod: 10s}
+from(bucket: "b") |> range(start: <$START$>, stop: <$STOP$>)
+  |> aggregateWindow(every: v.windowPeriod, fn: mean)`,
+		},
+		{
+			query: `from(bucket: "b") |> range(start: v.timeRangeStart, stop: v.timeRangeStop)
+  |> aggregateWindow(every: v.windowPeriod, fn: mean)`,
+			vars: map[string]string{"v.timeRangeStart": "1599996400",
+				"v.timeRangeStop": `time(v: "2020-09-13T12:26:40Z")`, "v.windowPeriod": "15s"},
+			extent: timeseries.Extent{Start: time.Unix(1599996400, 0), End: now},
+			step:   15 * time.Second,
+			template: `from(bucket: "b") |> range(start: <$START$>, stop: <$STOP$>)
+  |> aggregateWindow(every: v.windowPeriod, fn: mean)`,
+		},
+		{ // no range
+			query: `from(bucket: "b") |> aggregateWindow(every: 1m, fn: mean)`,
+			err:   errors.ErrNotTimeRangeQuery,
+		},
+		{ // no aggregateWindow
+			query: `from(bucket: "b") |> range(start: -1h)`,
+			err:   errors.ErrStepParse,
+		},
+		{ // mismatched ranges
+			query: `a = from(bucket: "b") |> range(start: -1h) |> aggregateWindow(every: 1m, fn: mean)
+b = from(bucket: "b") |> range(start: -2h) |> aggregateWindow(every: 1m, fn: mean)`,
+			err: errors.ErrNotTimeRangeQuery,
+		},
+		{ // offset windows
+			query: `from(bucket: "b") |> range(start: -1h) |> aggregateWindow(every: 1m, offset: 5s, fn: mean)`,
+			err:   errors.ErrStepParse,
+		},
+		{ // calendar durations
+			query: `from(bucket: "b") |> range(start: -1y) |> aggregateWindow(every: 1mo, fn: mean)`,
+			err:   ErrUnsupportedFluxExpression,
+		},
+	}
+
+	for i, test := range tests {
+		fq, err := parseFluxQuery(test.query, test.vars, now)
+		if err != test.err {
+			t.Errorf("test %d: expected error %v got %v", i, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !fq.extent.Start.Equal(test.extent.Start) || !fq.extent.End.Equal(test.extent.End) {
+			t.Errorf("test %d: expected extent %s got %s", i, test.extent.String(), fq.extent.String())
+		}
+		if fq.step != test.step {
+			t.Errorf("test %d: expected step %s got %s", i, test.step, fq.step)
+		}
+		if fq.template != test.template {
+			t.Errorf("test %d: expected template:\n%s\ngot:\n%s", i, test.template, fq.template)
+		}
+	}
+}
+
+func TestFluxQueryRender(t *testing.T) {
+
+	fq := &fluxQuery{template: `range(start: <$START$>, stop: <$STOP$>)`, step: time.Minute}
+	e := timeseries.Extent{Start: time.Unix(3600, 0), End: time.Unix(7200, 0)}
+
+	expected := `range(start: 1970-01-01T00:59:00Z, stop: 1970-01-01T02:00:00Z)`
+	if s := fq.render(e); s != expected {
+		t.Errorf("expected %s got %s", expected, s)
+	}
+
+	fq.timeSrcStart = true
+	expected = `range(start: 1970-01-01T01:00:00Z, stop: 1970-01-01T02:01:00Z)`
+	if s := fq.render(e); s != expected {
+		t.Errorf("expected %s got %s", expected, s)
+	}
+}
+
+func TestParseFluxDuration(t *testing.T) {
+
+	tests := []struct {
+		val      string
+		expected time.Duration
+		err      error
+	}{
+		{"1m", time.Minute, nil},
+		{"1h30m", 90 * time.Minute, nil},
+		{"2w", 14 * 24 * time.Hour, nil},
+		{"100ms", 100 * time.Millisecond, nil},
+		{"1mo", 0, ErrUnsupportedFluxExpression},
+		{"x", 0, ErrUnsupportedFluxExpression},
+	}
+
+	for i, test := range tests {
+		d, err := parseFluxDuration(test.val)
+		if err != test.err {
+			t.Errorf("test %d: expected error %v got %v", i, test.err, err)
+		}
+		if d != test.expected {
+			t.Errorf("test %d: expected %s got %s", i, test.expected, d)
+		}
+	}
+}
+
+func TestFluxExternVars(t *testing.T) {
+
+	const extern = `{"type":"File","package":null,"imports":null,"body":[{"type":"OptionStatement",
+"assignment":{"type":"VariableAssignment","id":{"type":"Identifier","name":"v"},
+"init":{"type":"ObjectExpression","properties":[
+{"type":"Property","key":{"type":"Identifier","name":"timeRangeStart"},
+"value":{"type":"UnaryExpression","operator":"-","argument":{"type":"DurationLiteral",
+"values":[{"magnitude":1,"unit":"h"}]}}},
+{"type":"Property","key":{"type":"Identifier","name":"timeRangeStop"},
+"value":{"type":"CallExpression","callee":{"type":"Identifier","name":"now"}}},
+{"type":"Property","key":{"type":"Identifier","name":"windowPeriod"},
+"value":{"type":"DurationLiteral","values":[{"magnitude":10000,"unit":"ms"}]}}]}}}]}`
+
+	var doc interface{}
+	if err := json.Unmarshal([]byte(extern), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	vars := fluxExternVars(doc)
+	expected := map[string]string{"v.timeRangeStart": "-1h", "v.timeRangeStop": "now()",
+		"v.windowPeriod": "10000ms"}
+	for k, v := range expected {
+		if vars[k] != v {
+			t.Errorf("expected %s for %s got %s", v, k, vars[k])
+		}
+	}
+
+	if vars := fluxExternVars(nil); len(vars) != 0 {
+		t.Errorf("expected empty vars got %v", vars)
+	}
+}
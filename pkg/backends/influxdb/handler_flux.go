@@ -0,0 +1,197 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/backends/influxdb/model"
+	"github.com/tricksterproxy/trickster/pkg/proxy/engines"
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/response/merge"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// FluxHandler handles Flux queries to the InfluxDB 2.x Query API and processes them
+// through the delta proxy cache
+func (c *Client) FluxHandler(w http.ResponseWriter, r *http.Request) {
+	// if this request is part of a scatter/gather, provide a reconstitution function
+	if rsc := request.GetResources(r); rsc != nil && rsc.IsMergeMember {
+		rsc.ResponseMergeFunc = merge.Timeseries
+	}
+
+	if r.Method != http.MethodPost {
+		c.ProxyHandler(w, r)
+		return
+	}
+	if _, err := readFluxRequest(r); err != nil {
+		c.ProxyHandler(w, r)
+		return
+	}
+	r.URL = urls.BuildUpstreamURL(r, c.BaseUpstreamURL())
+	engines.DeltaProxyCacheRequest(w, r, c.Modeler())
+}
+
+// readFluxRequest returns the JSON document describing the flux query in the request body.
+// Queries provided as raw flux (application/vnd.flux) are converted to the JSON format,
+// so that all Flux requests can be handled the same way
+func readFluxRequest(r *http.Request) (map[string]interface{}, error) {
+	if r.Body == nil {
+		return nil, errors.ErrNotTimeRangeQuery
+	}
+	b, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := make(map[string]interface{})
+	ct := r.Header.Get(headers.NameContentType)
+	switch {
+	case strings.HasPrefix(ct, headers.ValueApplicationFlux):
+		doc["query"] = string(b)
+		doc["type"] = "flux"
+	case strings.HasPrefix(ct, headers.ValueApplicationJSON):
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.ErrNotTimeRangeQuery
+	}
+
+	if t, ok := doc["type"].(string); ok && t != "flux" {
+		return nil, errors.ErrNotTimeRangeQuery
+	}
+	if q, ok := doc["query"].(string); !ok || q == "" {
+		return nil, errors.MissingURLParam("query")
+	}
+
+	if ct != headers.ValueApplicationJSON {
+		setFluxRequest(r, doc)
+	}
+	return doc, nil
+}
+
+// setFluxRequest sets the request body to the provided JSON document
+func setFluxRequest(r *http.Request, doc map[string]interface{}) {
+	b, _ := json.Marshal(doc)
+	r.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+	r.Header.Del(headers.NameContentLength)
+	r.ContentLength = int64(len(b))
+	r.Body = io.NopCloser(bytes.NewReader(b))
+}
+
+// parseFluxTimeRangeQuery parses the key parts of a TimeRangeQuery from a Flux request
+func parseFluxTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuery,
+	*timeseries.RequestOptions, bool, error) {
+
+	doc, err := readFluxRequest(r)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	now := time.Now()
+	if s, ok := doc["now"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			now = t
+		}
+	}
+
+	q := doc["query"].(string)
+	trq := &timeseries.TimeRangeQuery{Statement: q}
+	rlo := &timeseries.RequestOptions{OutputFormat: model.OutputFormatFluxCSV}
+
+	extern := fluxExternVars(doc["extern"])
+	vars := make(map[string]string, len(extern))
+	for k, v := range extern {
+		vars[k] = v
+	}
+	fq, cacheError := parseFluxQuery(q, vars, now)
+	if cacheError == nil {
+		trq.Statement = fq.template
+		trq.Extent = fq.extent
+		trq.Step = fq.step
+		trq.ParsedQuery = fq
+	}
+
+	// the query is included in the cache key from the template url, since the
+	// cache key params are not otherwise read from the request body
+	trq.TemplateURL = urls.Clone(r.URL)
+	qt := trq.TemplateURL.Query()
+	qt.Set(upFluxQuery, fluxKeyStatement(trq.Statement, trq.Step, extern))
+	trq.TemplateURL.RawQuery = qt.Encode()
+
+	if cacheError != nil {
+		// queries that are not time series can still be object cached
+		return trq, rlo, true, cacheError
+	}
+
+	return trq, rlo, false, nil
+}
+
+// fluxKeyStatement returns the statement used in the cache key. The step and the values of
+// extern options referenced by the query (e.g., Grafana's v.windowPeriod) are not part of
+// the templatized statement, so they are appended in order for queries at different
+// resolutions to be cached separately
+func fluxKeyStatement(statement string, step time.Duration, extern map[string]string) string {
+	var sb strings.Builder
+	sb.WriteString(statement)
+	if step > 0 {
+		sb.WriteString("\n// step: " + step.String())
+	}
+	keys := make([]string, 0, len(extern))
+	for k := range extern {
+		// the time range is tokenized in the statement
+		if k == "v.timeRangeStart" || k == "v.timeRangeStop" || !strings.Contains(statement, k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString("\n// " + k + ": " + extern[k])
+	}
+	return sb.String()
+}
+
+// setFluxExtent updates the Flux request to query the provided extent
+func setFluxExtent(r *http.Request, fq *fluxQuery, extent *timeseries.Extent) {
+	doc, err := readFluxRequest(r)
+	if err != nil {
+		return
+	}
+	doc["query"] = fq.render(*extent)
+	// the annotations are required in order to unmarshal the typed response
+	doc["dialect"] = map[string]interface{}{
+		"header":      true,
+		"delimiter":   ",",
+		"annotations": []string{"datatype", "group", "default"},
+	}
+	delete(doc, "now")
+	setFluxRequest(r, doc)
+	r.Header.Set(headers.NameAccept, headers.ValueTextCSV)
+}
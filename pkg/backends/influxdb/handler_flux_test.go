@@ -0,0 +1,216 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/backends/influxdb/model"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
+)
+
+const testFluxCSV = `#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string
+#group,false,false,true,true,false,false,true,true
+#default,_result,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,host
+,,0,2020-09-13T06:26:40Z,2020-09-13T12:26:40Z,2020-09-13T12:25:00Z,1.5,usage,a
+,,0,2020-09-13T06:26:40Z,2020-09-13T12:26:40Z,2020-09-13T12:26:00Z,2.5,usage,a
+
+`
+
+func TestReadFluxRequest(t *testing.T) {
+
+	r, _ := http.NewRequest(http.MethodPost, "http://0/api/v2/query",
+		bytes.NewBufferString(testFluxQuery))
+	r.Header.Set(headers.NameContentType, headers.ValueApplicationFlux)
+
+	doc, err := readFluxRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["query"] != testFluxQuery {
+		t.Errorf("expected %s got %v", testFluxQuery, doc["query"])
+	}
+	if ct := r.Header.Get(headers.NameContentType); ct != headers.ValueApplicationJSON {
+		t.Errorf("expected %s got %s", headers.ValueApplicationJSON, ct)
+	}
+
+	// the converted body should be readable again
+	if _, err = readFluxRequest(r); err != nil {
+		t.Error(err)
+	}
+
+	b, _ := json.Marshal(map[string]interface{}{"query": "buckets()", "type": "influxql"})
+	r, _ = http.NewRequest(http.MethodPost, "http://0/api/v2/query", bytes.NewReader(b))
+	r.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+	if _, err = readFluxRequest(r); err == nil {
+		t.Error("expected error for non-flux query type")
+	}
+
+	r, _ = http.NewRequest(http.MethodPost, "http://0/api/v2/query", nil)
+	if _, err = readFluxRequest(r); err == nil {
+		t.Error("expected error for missing body")
+	}
+}
+
+func TestParseFluxTimeRangeQuery(t *testing.T) {
+
+	b, _ := json.Marshal(map[string]interface{}{"query": testFluxQuery,
+		"now": "2020-09-13T12:26:40Z"})
+	r, _ := http.NewRequest(http.MethodPost, "http://0/api/v2/query?org=test", bytes.NewReader(b))
+	r.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+
+	client := &Client{}
+	trq, rlo, canOPC, err := client.ParseTimeRangeQuery(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canOPC {
+		t.Error("expected false")
+	}
+	if rlo.OutputFormat != model.OutputFormatFluxCSV {
+		t.Errorf("expected %d got %d", model.OutputFormatFluxCSV, rlo.OutputFormat)
+	}
+	if trq.Step.Seconds() != 60 {
+		t.Errorf("expected %d got %d", 60, int(trq.Step.Seconds()))
+	}
+	if trq.Extent.End.Unix() != 1600000000 {
+		t.Errorf("expected %d got %d", 1600000000, trq.Extent.End.Unix())
+	}
+	if q := trq.TemplateURL.Query().Get(upFluxQuery); !strings.Contains(q, fluxTokenStart) {
+		t.Errorf("expected templatized query got %s", q)
+	}
+
+	extent := &timeseries.Extent{Start: trq.Extent.Start, End: trq.Extent.End}
+	client.SetExtent(r, trq, extent)
+	doc, err := readFluxRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := doc["query"].(string); !strings.Contains(q, "stop: 2020-09-13T12:26:40Z") {
+		t.Errorf("expected rendered query got %s", q)
+	}
+	if _, ok := doc["dialect"]; !ok {
+		t.Error("expected dialect")
+	}
+
+	// non-aggregated queries are object cacheable
+	b, _ = json.Marshal(map[string]interface{}{"query": `from(bucket: "b") |> range(start: -1h)`})
+	r, _ = http.NewRequest(http.MethodPost, "http://0/api/v2/query", bytes.NewReader(b))
+	r.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+	_, _, canOPC, err = client.ParseTimeRangeQuery(r)
+	if err == nil {
+		t.Error("expected error for query without aggregateWindow")
+	}
+	if !canOPC {
+		t.Error("expected true")
+	}
+}
+
+func TestParseFluxTimeRangeQueryWindowPeriod(t *testing.T) {
+
+	const query = `from(bucket: "b")
+  |> range(start: v.timeRangeStart, stop: v.timeRangeStop)
+  |> aggregateWindow(every: v.windowPeriod, fn: mean)`
+
+	templateQuery := func(windowPeriod int) string {
+		extern := map[string]interface{}{"type": "File", "body": []interface{}{
+			map[string]interface{}{"type": "OptionStatement", "assignment": map[string]interface{}{
+				"type": "VariableAssignment", "id": map[string]interface{}{"name": "v"},
+				"init": map[string]interface{}{"type": "ObjectExpression", "properties": []interface{}{
+					fluxTestProperty("timeRangeStart", map[string]interface{}{"type": "DateTimeLiteral",
+						"value": "2020-09-13T06:26:40Z"}),
+					fluxTestProperty("timeRangeStop", map[string]interface{}{"type": "DateTimeLiteral",
+						"value": "2020-09-13T12:26:40Z"}),
+					fluxTestProperty("windowPeriod", map[string]interface{}{"type": "DurationLiteral",
+						"values": []interface{}{map[string]interface{}{"magnitude": windowPeriod,
+							"unit": "ms"}}}),
+				}}}}}}
+		b, _ := json.Marshal(map[string]interface{}{"query": query, "extern": extern})
+		r, _ := http.NewRequest(http.MethodPost, "http://0/api/v2/query?org=test", bytes.NewReader(b))
+		r.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+		trq, _, _, err := (&Client{}).ParseTimeRangeQuery(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trq.TemplateURL.Query().Get(upFluxQuery)
+	}
+
+	q1, q5 := templateQuery(60000), templateQuery(300000)
+	if q1 == q5 {
+		t.Errorf("expected different cache key queries for different windowPeriods got %s", q1)
+	}
+	if !strings.Contains(q1, "v.windowPeriod: 60000ms") {
+		t.Errorf("expected windowPeriod in cache key query got %s", q1)
+	}
+	if templateQuery(60000) != q1 {
+		t.Error("expected the same cache key query for the same windowPeriod")
+	}
+}
+
+func fluxTestProperty(name string, value map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "Property",
+		"key": map[string]interface{}{"type": "Identifier", "name": name}, "value": value}
+}
+
+func TestFluxHandler(t *testing.T) {
+
+	backendClient, err := NewClient("test", nil, nil, nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	ts, w, r, _, err := tu.NewTestInstance("", backendClient.DefaultPathConfigs, 200, testFluxCSV,
+		nil, "influxdb", "/api/v2/query?org=test", "debug")
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer ts.Close()
+	}
+	rsc := request.GetResources(r)
+	backendClient, err = NewClient("test", rsc.BackendOptions, nil, nil, model.NewModeler())
+	if err != nil {
+		t.Error(err)
+	}
+	client := backendClient.(*Client)
+	rsc.BackendClient = client
+	rsc.BackendOptions.HTTPClient = backendClient.HTTPClient()
+
+	r.Method = http.MethodPost
+	r.Header.Set(headers.NameContentType, headers.ValueApplicationFlux)
+	r.Body = io.NopCloser(bytes.NewBufferString(testFluxQuery))
+
+	client.FluxHandler(w, r)
+
+	resp := w.Result()
+
+	// it should return 200 OK
+	if resp.StatusCode != 200 {
+		t.Errorf("expected 200 got %d.", resp.StatusCode)
+	}
+
+	if ct := resp.Header.Get(headers.NameContentType); !strings.HasPrefix(ct, headers.ValueTextCSV) {
+		t.Errorf("expected %s got %s", headers.ValueTextCSV, ct)
+	}
+}
@@ -0,0 +1,392 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/dataset"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/epoch"
+)
+
+// OutputFormatFluxCSV is the RequestOptions.OutputFormat value for Flux Annotated CSV
+const OutputFormatFluxCSV byte = 3
+
+// Flux Annotated CSV column names and annotations
+const (
+	fluxColResult = "result"
+	fluxColTable  = "table"
+	fluxColStart  = "_start"
+	fluxColStop   = "_stop"
+	fluxColTime   = "_time"
+
+	fluxAnnotationDatatype = "#datatype"
+	fluxAnnotationGroup    = "#group"
+	fluxAnnotationDefault  = "#default"
+
+	fluxTypeString   = "string"
+	fluxTypeLong     = "long"
+	fluxTypeUnsigned = "unsignedLong"
+	fluxTypeDouble   = "double"
+	fluxTypeBool     = "boolean"
+	fluxTypeDateTime = "dateTime:RFC3339"
+)
+
+// fluxTable holds the annotations and header of a table in a Flux Annotated CSV document
+type fluxTable struct {
+	datatypes []string
+	groups    []string
+	defaults  []string
+	columns   []string
+}
+
+func (t *fluxTable) annotation(i int, list []string) string {
+	if i < len(list) {
+		return list[i]
+	}
+	return ""
+}
+
+// unmarshalFluxCSV converts a Flux Annotated CSV document into a Timeseries. Each Flux table
+// becomes a Series whose Tags are the table's group key columns (excluding _start and _stop,
+// which vary with the query's time range), and whose fields are the table's remaining columns.
+// The Series Name is the name of the Flux result that contains the table
+func unmarshalFluxCSV(reader io.Reader, trq *timeseries.TimeRangeQuery) (timeseries.Timeseries, error) {
+
+	cr := csv.NewReader(reader)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = false
+
+	ds := &dataset.DataSet{
+		TimeRangeQuery: trq,
+		ExtentList:     timeseries.ExtentList{trq.Extent},
+	}
+
+	results := make(map[string]*dataset.Result)
+	series := make(map[string]*dataset.Series)
+	t := &fluxTable{}
+	var inData bool
+
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) == 0 || (len(rec) == 1 && rec[0] == "") {
+			continue
+		}
+		if strings.HasPrefix(rec[0], "#") {
+			if inData {
+				// annotations following data rows begin a new table schema
+				t, inData = &fluxTable{}, false
+			}
+			switch rec[0] {
+			case fluxAnnotationDatatype:
+				t.datatypes = rec
+			case fluxAnnotationGroup:
+				t.groups = rec
+			case fluxAnnotationDefault:
+				t.defaults = rec
+			}
+			continue
+		}
+		if !inData {
+			t.columns = rec
+			inData = true
+			continue
+		}
+
+		var resultName, tableID string
+		var ts time.Time
+		var hasTime bool
+		tags := make(dataset.Tags)
+		fields := make([]timeseries.FieldDefinition, 0, len(rec))
+		values := make([]interface{}, 0, len(rec))
+		var ti int
+		size := 12
+		for i := 1; i < len(rec) && i < len(t.columns); i++ {
+			v := rec[i]
+			if v == "" {
+				v = t.annotation(i, t.defaults)
+			}
+			switch name := t.columns[i]; name {
+			case fluxColResult:
+				resultName = v
+			case fluxColTable:
+				tableID = v
+			case fluxColStart, fluxColStop:
+			case "error":
+				if v != "" {
+					ds.Error = v
+				}
+			case "reference":
+			case fluxColTime:
+				if ts, err = time.Parse(time.RFC3339Nano, v); err != nil {
+					return nil, timeseries.ErrInvalidTimeFormat
+				}
+				hasTime = true
+				ti = len(fields)
+			default:
+				if t.annotation(i, t.groups) == "true" {
+					tags[name] = v
+					continue
+				}
+				fd := timeseries.FieldDefinition{Name: name, SDataType: t.annotation(i, t.datatypes)}
+				var pv interface{}
+				pv, fd.DataType, err = fluxValue(v, fd.SDataType)
+				if err != nil {
+					return nil, err
+				}
+				size += fluxValueSize(pv)
+				fields = append(fields, fd)
+				values = append(values, pv)
+			}
+		}
+		if !hasTime {
+			if ds.Error != "" {
+				continue
+			}
+			return nil, timeseries.ErrInvalidBody
+		}
+
+		r, ok := results[resultName]
+		if !ok {
+			r = &dataset.Result{StatementID: len(ds.Results)}
+			results[resultName] = r
+			ds.Results = append(ds.Results, r)
+		}
+		sk := resultName + "." + tableID
+		s, ok := series[sk]
+		if !ok {
+			sh := dataset.SeriesHeader{
+				Name:           resultName,
+				Tags:           tags,
+				FieldsList:     fields,
+				TimestampIndex: ti,
+				QueryStatement: trq.Statement,
+			}
+			sh.CalculateSize()
+			s = &dataset.Series{Header: sh}
+			series[sk] = s
+			r.SeriesList = append(r.SeriesList, s)
+		}
+		s.Points = append(s.Points, dataset.Point{
+			Epoch:  epoch.Epoch(ts.UnixNano()),
+			Size:   size,
+			Values: values,
+		})
+		s.PointSize += int64(size)
+	}
+
+	for _, s := range series {
+		sort.Sort(s.Points)
+	}
+	return ds, nil
+}
+
+// isFluxCSV returns true if the first non-whitespace byte in the reader
+// begins a Flux Annotated CSV annotation or header row
+func isFluxCSV(br *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		b, err := br.Peek(i)
+		if err != nil || len(b) < i {
+			return false
+		}
+		switch b[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '#', ',':
+			return true
+		}
+		return false
+	}
+}
+
+// fluxValue converts a Flux Annotated CSV value to its native type
+func fluxValue(v, datatype string) (interface{}, timeseries.FieldDataType, error) {
+	switch datatype {
+	case fluxTypeLong, fluxTypeUnsigned:
+		if v == "" {
+			return nil, timeseries.Int64, nil
+		}
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, timeseries.Int64, timeseries.ErrInvalidBody
+		}
+		return i, timeseries.Int64, nil
+	case fluxTypeDouble:
+		if v == "" {
+			return nil, timeseries.Float64, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, timeseries.Float64, timeseries.ErrInvalidBody
+		}
+		return f, timeseries.Float64, nil
+	case fluxTypeBool:
+		if v == "" {
+			return nil, timeseries.Bool, nil
+		}
+		return v == "true", timeseries.Bool, nil
+	}
+	// strings and any other types (e.g., durations) are retained in their serialized form
+	return v, timeseries.String, nil
+}
+
+func fluxValueSize(v interface{}) int {
+	switch t := v.(type) {
+	case string:
+		return len(t)
+	case bool:
+		return 1
+	case nil:
+		return 0
+	}
+	return 8
+}
+
+func writeFluxValue(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	return ""
+}
+
+// marshalTimeseriesFluxCSV writes the DataSet as a Flux Annotated CSV document
+func marshalTimeseriesFluxCSV(ds *dataset.DataSet, rlo *timeseries.RequestOptions,
+	status int, w io.Writer) error {
+	if ds == nil {
+		return nil
+	}
+	if rw, ok := w.(http.ResponseWriter); ok {
+		h := rw.Header()
+		h.Set(headers.NameContentType, headers.ValueTextCSV+"; charset=utf-8")
+		rw.WriteHeader(status)
+	}
+
+	var start, stop string
+	if ds.TimeRangeQuery != nil {
+		start = ds.TimeRangeQuery.Extent.Start.UTC().Format(time.RFC3339Nano)
+		stop = ds.TimeRangeQuery.Extent.End.Add(ds.TimeRangeQuery.Step).UTC().Format(time.RFC3339Nano)
+	}
+
+	cw := csv.NewWriter(w)
+	var prev string
+	for _, r := range ds.Results {
+		if r == nil {
+			continue
+		}
+		for tableID, s := range r.SeriesList {
+			if s == nil {
+				continue
+			}
+			keys := s.Header.Tags.Keys()
+			n := len(s.Header.FieldsList) + len(keys) + 6
+			datatypes := append(make([]string, 0, n), fluxAnnotationDatatype, fluxTypeString,
+				fluxTypeLong, fluxTypeDateTime, fluxTypeDateTime, fluxTypeDateTime)
+			groups := append(make([]string, 0, n), fluxAnnotationGroup, "false", "false",
+				"true", "true", "false")
+			defaults := append(make([]string, 0, n), fluxAnnotationDefault, s.Header.Name,
+				"", "", "", "")
+			columns := append(make([]string, 0, n), "", fluxColResult, fluxColTable,
+				fluxColStart, fluxColStop, fluxColTime)
+			for _, fd := range s.Header.FieldsList {
+				dt := fd.SDataType
+				if dt == "" {
+					dt = fluxDataType(fd.DataType)
+				}
+				datatypes = append(datatypes, dt)
+				groups = append(groups, "false")
+				defaults = append(defaults, "")
+				columns = append(columns, fd.Name)
+			}
+			for _, k := range keys {
+				datatypes = append(datatypes, fluxTypeString)
+				groups = append(groups, "true")
+				defaults = append(defaults, "")
+				columns = append(columns, k)
+			}
+
+			// annotations and the header are only written when the table schema changes
+			if sig := strings.Join(datatypes, ",") + strings.Join(groups, ",") +
+				strings.Join(defaults, ",") + strings.Join(columns, ","); sig != prev {
+				if prev != "" {
+					cw.Flush()
+					w.Write([]byte("\n"))
+				}
+				cw.Write(datatypes)
+				cw.Write(groups)
+				cw.Write(defaults)
+				cw.Write(columns)
+				prev = sig
+			}
+
+			tid := strconv.Itoa(tableID)
+			for _, p := range s.Points {
+				row := append(make([]string, 0, n), "", "", tid, start, stop,
+					time.Unix(0, int64(p.Epoch)).UTC().Format(time.RFC3339Nano))
+				if start == "" {
+					row[3] = row[5]
+					row[4] = row[5]
+				}
+				for _, v := range p.Values {
+					row = append(row, writeFluxValue(v))
+				}
+				for _, k := range keys {
+					row = append(row, s.Header.Tags[k])
+				}
+				cw.Write(row)
+			}
+		}
+	}
+	cw.Flush()
+	if prev != "" {
+		w.Write([]byte("\n"))
+	}
+	return cw.Error()
+}
+
+func fluxDataType(t timeseries.FieldDataType) string {
+	switch t {
+	case timeseries.Int64:
+		return fluxTypeLong
+	case timeseries.Float64:
+		return fluxTypeDouble
+	case timeseries.Bool:
+		return fluxTypeBool
+	}
+	return fluxTypeString
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/dataset"
+)
+
+const testFluxDoc01 = `#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string
+#group,false,false,true,true,false,false,true,true
+#default,_result,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,host
+,,0,2020-01-01T00:00:00Z,2020-01-01T00:01:00Z,2020-01-01T00:00:30Z,0.452,usage,a
+,,0,2020-01-01T00:00:00Z,2020-01-01T00:01:00Z,2020-01-01T00:00:15Z,0.484,usage,a
+,,1,2020-01-01T00:00:00Z,2020-01-01T00:01:00Z,2020-01-01T00:00:15Z,,usage,b
+
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,long,string
+#group,false,false,true,true,false,false,true
+#default,count,,,,,,
+,result,table,_start,_stop,_time,_value,_field
+,,0,2020-01-01T00:00:00Z,2020-01-01T00:01:00Z,2020-01-01T00:00:15Z,8,requests
+
+`
+
+const testFluxDocError = `#datatype,string,string
+#group,true,true
+#default,,
+,error,reference
+,error in query,
+`
+
+func TestUnmarshalFluxCSV(t *testing.T) {
+
+	trq := &timeseries.TimeRangeQuery{
+		Statement: "hello",
+		Extent:    timeseries.Extent{Start: time.Unix(1577836800, 0), End: time.Unix(1577836830, 0)},
+		Step:      15 * time.Second,
+	}
+
+	ts, err := UnmarshalTimeseries([]byte(testFluxDoc01), trq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := ts.(*dataset.DataSet)
+	if len(ds.Results) != 2 {
+		t.Fatalf("expected %d got %d", 2, len(ds.Results))
+	}
+	if len(ds.Results[0].SeriesList) != 2 {
+		t.Fatalf("expected %d got %d", 2, len(ds.Results[0].SeriesList))
+	}
+	s := ds.Results[0].SeriesList[0]
+	if s.Header.Name != "_result" {
+		t.Errorf("expected %s got %s", "_result", s.Header.Name)
+	}
+	if s.Header.Tags["host"] != "a" || s.Header.Tags["_field"] != "usage" {
+		t.Errorf("unexpected tags %v", s.Header.Tags)
+	}
+	if len(s.Points) != 2 || s.Points[0].Epoch != 1577836815000000000 {
+		t.Errorf("unexpected points %v", s.Points)
+	}
+	if s.Header.FieldsList[0].SDataType != "double" || s.Header.FieldsList[0].DataType != timeseries.Float64 {
+		t.Errorf("unexpected field definition %v", s.Header.FieldsList[0])
+	}
+	if v := ds.Results[0].SeriesList[1].Points[0].Values[0]; v != nil {
+		t.Errorf("expected nil value got %v", v)
+	}
+	if v := ds.Results[1].SeriesList[0].Points[0].Values[0]; v != int64(8) {
+		t.Errorf("expected %d got %v", 8, v)
+	}
+
+	ts, err = UnmarshalTimeseries([]byte(testFluxDocError), trq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds = ts.(*dataset.DataSet); ds.Error != "error in query" {
+		t.Errorf("expected %s got %s", "error in query", ds.Error)
+	}
+
+	_, err = UnmarshalTimeseries([]byte(strings.Replace(testFluxDoc01,
+		"2020-01-01T00:00:30Z", "x", 1)), trq)
+	if err != timeseries.ErrInvalidTimeFormat {
+		t.Error("expected ErrInvalidTimeFormat, got", err)
+	}
+}
+
+func TestMarshalTimeseriesFluxCSV(t *testing.T) {
+
+	trq := &timeseries.TimeRangeQuery{
+		Statement: "hello",
+		Extent:    timeseries.Extent{Start: time.Unix(1577836800, 0), End: time.Unix(1577836845, 0)},
+		Step:      15 * time.Second,
+	}
+	ts, err := UnmarshalTimeseries([]byte(testFluxDoc01), trq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	rlo := &timeseries.RequestOptions{OutputFormat: OutputFormatFluxCSV}
+	if err = MarshalTimeseriesWriter(ts, rlo, 200, w); err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("expected %s got %s", "text/csv; charset=utf-8", ct)
+	}
+
+	// the output should unmarshal to the same data
+	ts2, err := UnmarshalTimeseries(w.Body.Bytes(), trq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts2.SeriesCount() != ts.SeriesCount() || ts2.ValueCount() != ts.ValueCount() {
+		t.Errorf("expected %d series and %d values got %d and %d", ts.SeriesCount(),
+			ts.ValueCount(), ts2.SeriesCount(), ts2.ValueCount())
+	}
+	if !strings.Contains(w.Body.String(), ",,0,2020-01-01T00:00:00Z,2020-01-01T00:01:00Z,"+
+		"2020-01-01T00:00:15Z,0.484,usage,a\n") {
+		t.Errorf("unexpected output:\n%s", w.Body.String())
+	}
+}
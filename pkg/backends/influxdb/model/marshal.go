@@ -32,9 +32,10 @@ import (
 )
 
 var marshalers = map[byte]dataset.Marshaler{
-	0: marshalTimeseriesJSON,
-	1: marshalTimeseriesJSONPretty,
-	2: marshalTimeseriesCSV,
+	0:                   marshalTimeseriesJSON,
+	1:                   marshalTimeseriesJSONPretty,
+	2:                   marshalTimeseriesCSV,
+	OutputFormatFluxCSV: marshalTimeseriesFluxCSV,
 }
 
 // MarshalTimeseries converts a Timeseries into a JSON blob
@@ -32,11 +32,21 @@ func (c *Client) RegisterHandlers(map[string]http.Handler) {
 			// and are able to be referenced by name (map key) in Config Files
 			"health": http.HandlerFunc(c.HealthHandler),
 			"query":  http.HandlerFunc(c.QueryHandler),
+			"flux":   http.HandlerFunc(c.FluxHandler),
 			"proxy":  http.HandlerFunc(c.ProxyHandler),
 		},
 	)
 }
 
+// MergeablePaths returns the list of InfluxDB Paths for which Trickster supports
+// merging multiple documents into a single response
+func (c *Client) MergeablePaths() []string {
+	return []string{
+		"/" + mnQuery,
+		"/" + mnFluxQuery,
+	}
+}
+
 // DefaultPathConfigs returns the default PathConfigs for the given Provider
 func (c *Client) DefaultPathConfigs(o *bo.Options) map[string]*po.Options {
 
@@ -50,6 +60,15 @@ func (c *Client) DefaultPathConfigs(o *bo.Options) map[string]*po.Options {
 			MatchTypeName:   "exact",
 			MatchType:       matching.PathMatchTypeExact,
 		},
+		"/" + mnFluxQuery: {
+			Path:            "/" + mnFluxQuery,
+			HandlerName:     "flux",
+			Methods:         []string{http.MethodPost},
+			CacheKeyParams:  []string{"org", "orgID", upFluxQuery},
+			CacheKeyHeaders: []string{},
+			MatchTypeName:   "exact",
+			MatchType:       matching.PathMatchTypeExact,
+		},
 		"/": {
 			Path:          "/",
 			HandlerName:   "proxy",
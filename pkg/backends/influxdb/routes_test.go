@@ -59,9 +59,26 @@ func TestDefaultPathConfigs(t *testing.T) {
 		t.Errorf("expected to find path named: %s", "/")
 	}
 
-	const expectedLen = 2
+	const expectedLen = 3
 	if len(rsc.BackendOptions.Paths) != expectedLen {
 		t.Errorf("expected ordered length to be: %d", expectedLen)
 	}
 
 }
+
+func TestMergeablePaths(t *testing.T) {
+	c, err := NewClient("test", nil, nil, nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	mp := c.MergeablePaths()
+	if len(mp) != 2 {
+		t.Errorf("expected %d got %d", 2, len(mp))
+	}
+	dp := c.DefaultPathConfigs(nil)
+	for _, p := range mp {
+		if _, ok := dp[p]; !ok {
+			t.Errorf("expected default path config for mergeable path %s", p)
+		}
+	}
+}
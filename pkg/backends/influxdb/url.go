@@ -27,7 +27,8 @@ import (
 
 // Upstream Endpoints
 const (
-	mnQuery = "query"
+	mnQuery     = "query"
+	mnFluxQuery = "api/v2/query"
 )
 
 // Common URL Parameter Names
@@ -37,6 +38,8 @@ const (
 	upEpoch   = "epoch"
 	upPretty  = "pretty"
 	upChunked = "chunked"
+
+	upFluxQuery = "query"
 )
 
 // SetExtent will change the upstream request query to use the provided Extent
@@ -51,6 +54,11 @@ func (c *Client) SetExtent(r *http.Request, trq *timeseries.TimeRangeQuery, exte
 		trq.ParsedQuery = t2.ParsedQuery
 	}
 
+	if fq, ok := trq.ParsedQuery.(*fluxQuery); ok {
+		setFluxExtent(r, fq, extent)
+		return
+	}
+
 	q, ok := trq.ParsedQuery.(*influxql.Query)
 	if !ok {
 		return
@@ -42,6 +42,19 @@ func (c *Client) RegisterHandlers(map[string]http.Handler) {
 	)
 }
 
+// MergeablePaths returns the list of IRONdb Paths for which Trickster supports
+// merging multiple documents into a single response
+func (c *Client) MergeablePaths() []string {
+	return []string{
+		"/" + mnRaw + "/",
+		"/" + mnRollup + "/",
+		"/" + mnFetch,
+		"/" + mnRead + "/",
+		"/" + mnHistogram + "/",
+		"/" + mnCAQL,
+	}
+}
+
 // DefaultPathConfigs returns the default PathConfigs for the given Provider
 func (c *Client) DefaultPathConfigs(o *bo.Options) map[string]*po.Options {
 
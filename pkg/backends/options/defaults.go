@@ -49,6 +49,9 @@ const (
 	DefaultBackfillToleranceMS = 0
 	// DefaultBackfillTolerancePoints is the default Backfill Tolerance setting for Backends
 	DefaultBackfillTolerancePoints = 0
+	// DefaultShardMaxConcurrency is the default limit of concurrent upstream requests for the
+	// shards of a single time range query
+	DefaultShardMaxConcurrency = 4
 	// DefaultKeepAliveTimeoutMS is the default Keep Alive Timeout for Backends' upstream client pools
 	DefaultKeepAliveTimeoutMS = 300000
 	// DefaultMaxIdleConns is the default number of Idle Connections in Backends' upstream client pools
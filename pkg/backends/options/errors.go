@@ -91,6 +91,34 @@ func NewErrInvalidALBOptions(albName, backendName string) error {
 	return e
 }
 
+// ErrInvalidCompressionCodec is an error type for invalid cache compression codec
+type ErrInvalidCompressionCodec struct {
+	error
+}
+
+// NewErrInvalidCompressionCodec returns a new invalid cache compression codec error
+func NewErrInvalidCompressionCodec(codecName, backendName string) error {
+	var e *ErrInvalidCompressionCodec = &ErrInvalidCompressionCodec{
+		error: fmt.Errorf(`invalid cache compression codec "%s" provided in backend options "%s"`,
+			codecName, backendName),
+	}
+	return e
+}
+
+// ErrInvalidCompressionLevel is an error type for invalid cache compression level
+type ErrInvalidCompressionLevel struct {
+	error
+}
+
+// NewErrInvalidCompressionLevel returns a new invalid cache compression level error
+func NewErrInvalidCompressionLevel(level int, backendName string) error {
+	var e *ErrInvalidCompressionLevel = &ErrInvalidCompressionLevel{
+		error: fmt.Errorf(`invalid cache compression level "%d" provided in backend options "%s"`,
+			level, backendName),
+	}
+	return e
+}
+
 // ErrInvalidCacheName is an error type for invalid cache name
 type ErrInvalidCacheName struct {
 	error
@@ -78,6 +78,24 @@ func TestInvalidCacheName(t *testing.T) {
 	}
 }
 
+func TestInvalidCompressionCodec(t *testing.T) {
+	err := NewErrInvalidCompressionCodec("testCodec", "testBackend")
+	var e *ErrInvalidCompressionCodec
+	ok := errors.As(err, &e)
+	if !ok {
+		t.Error("invalid type assertion")
+	}
+}
+
+func TestInvalidCompressionLevel(t *testing.T) {
+	err := NewErrInvalidCompressionLevel(10, "testBackend")
+	var e *ErrInvalidCompressionLevel
+	ok := errors.As(err, &e)
+	if !ok {
+		t.Error("invalid type assertion")
+	}
+}
+
 func TestInvalidBackendName(t *testing.T) {
 	err := NewErrInvalidBackendName("testBackend")
 	var e *ErrInvalidBackendName
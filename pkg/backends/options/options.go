@@ -23,15 +23,21 @@ import (
 	"time"
 
 	ao "github.com/tricksterproxy/trickster/pkg/backends/alb/options"
+	cbo "github.com/tricksterproxy/trickster/pkg/backends/breaker/options"
+	gro "github.com/tricksterproxy/trickster/pkg/backends/graphite/options"
 	ho "github.com/tricksterproxy/trickster/pkg/backends/healthcheck/options"
 	prop "github.com/tricksterproxy/trickster/pkg/backends/prometheus/options"
 	ro "github.com/tricksterproxy/trickster/pkg/backends/rule/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/codec"
 	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
 	"github.com/tricksterproxy/trickster/pkg/cache/negative"
 	co "github.com/tricksterproxy/trickster/pkg/cache/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	rlo "github.com/tricksterproxy/trickster/pkg/proxy/ratelimit/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	resprewriter "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter"
+	rto "github.com/tricksterproxy/trickster/pkg/proxy/retry/options"
 	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
 	"github.com/tricksterproxy/trickster/pkg/timeseries/dataset"
 	"github.com/tricksterproxy/trickster/pkg/util/copiers"
@@ -68,8 +74,22 @@ type Options struct {
 	CacheName string `yaml:"cache_name,omitempty"`
 	// CacheKeyPrefix defines the cache key prefix the backend will use when writing objects to the cache
 	CacheKeyPrefix string `yaml:"cache_key_prefix,omitempty"`
+	// CacheCompressionCodec overrides the compression codec of the backend's cache for
+	// the objects this backend writes to it. The cache's codec is used when empty
+	CacheCompressionCodec string `yaml:"cache_compression_codec,omitempty"`
+	// CacheCompressionLevel is the compression level of the CacheCompressionCodec, and is
+	// only used when CacheCompressionCodec is set. 0 uses the codec's default level
+	CacheCompressionLevel int `yaml:"cache_compression_level,omitempty"`
 	// HealthCheck is the health check options reference for this backend
 	HealthCheck *ho.Options `yaml:"healthcheck,omitempty"`
+	// Retry provides the retry and hedging options for upstream requests
+	Retry *rto.Options `yaml:"retry,omitempty"`
+	// CircuitBreaker provides the circuit breaker options for the backend. The circuit
+	// breaker is disabled when not set
+	CircuitBreaker *cbo.Options `yaml:"circuit_breaker,omitempty"`
+	// RateLimit provides the rate limit and in-flight request quota options for the
+	// backend. No limits are applied when not set
+	RateLimit *rlo.Options `yaml:"rate_limit,omitempty"`
 	// Object Proxy Cache and Delta Proxy Cache Configurations
 	// TimeseriesRetentionFactor limits the maximum the number of chronological
 	// timestamps worth of data to store in cache for each query
@@ -85,6 +105,18 @@ type Options struct {
 	// on the query step value to determine the relative duration of backfill tolerance per-query
 	// When both are set, the higher of the two values is used
 	BackfillTolerancePoints int `yaml:"backfill_tolerance_points,omitempty"`
+	// ShardMaxSizePoints limits the number of timestamps requested from the backend in a single
+	// upstream request. Uncached time ranges needing more are split into multiple requests
+	ShardMaxSizePoints int `yaml:"shard_max_size_points,omitempty"`
+	// ShardMaxSizeTimeMS is similar to the Points version, except that it limits the duration of
+	// the time range in a single upstream request. When both are set, the smaller limit is used
+	ShardMaxSizeTimeMS int64 `yaml:"shard_max_size_time_ms,omitempty"`
+	// ShardMaxConcurrency limits the number of concurrent upstream requests for the shards
+	// of a single time range query
+	ShardMaxConcurrency int `yaml:"shard_max_concurrency,omitempty"`
+	// TimeseriesDegradedServing, when true, serves the cached portion of a timeseries when the
+	// backend fails to provide the uncached portion, rather than the backend's error response
+	TimeseriesDegradedServing bool `yaml:"timeseries_degraded_serving,omitempty"`
 	// PathList is a list of Path Options that control the behavior of the given paths when requested
 	Paths map[string]*po.Options `yaml:"paths,omitempty"`
 	// NegativeCacheName provides the name of the Negative Cache Config to be used by this Backend
@@ -111,11 +143,16 @@ type Options struct {
 	// ReqRewriterName is the name of a configured Rewriter that will modify the request prior to
 	// processing by the backend client
 	ReqRewriterName string `yaml:"req_rewriter_name,omitempty"`
+	// RespRewriterName is the name of a configured Response Rewriter that will modify the
+	// response prior to it being sent to the client
+	RespRewriterName string `yaml:"resp_rewriter_name,omitempty"`
 
 	// ALBOptions holds the options for ALBs
 	ALBOptions *ao.Options `yaml:"alb,omitempty"`
 	// Prometheus holds options specific to prometheus backends
 	Prometheus *prop.Options `yaml:"prometheus,omitempty"`
+	// Graphite holds options specific to graphite backends
+	Graphite *gro.Options `yaml:"graphite,omitempty"`
 
 	// TLS is the TLS Configuration for the Frontend and Backend
 	TLS *to.Options `yaml:"tls,omitempty"`
@@ -151,6 +188,8 @@ type Options struct {
 	Timeout time.Duration `yaml:"-"`
 	// BackfillTolerance is the time.Duration representation of BackfillToleranceMS
 	BackfillTolerance time.Duration `yaml:"-"`
+	// ShardMaxSizeTime is the time.Duration representation of ShardMaxSizeTimeMS
+	ShardMaxSizeTime time.Duration `yaml:"-"`
 	// ValueRetention is the time.Duration representation of ValueRetentionSecs
 	ValueRetention time.Duration `yaml:"-"`
 	// Scheme is the layer 7 protocol indicator (e.g. 'http'), derived from OriginURL
@@ -180,10 +219,14 @@ type Options struct {
 	HTTPClient *http.Client `yaml:"-"`
 	// CompressibleTypes is the map version of CompressibleTypeList for fast lookup
 	CompressibleTypes map[string]interface{} `yaml:"-"`
+	// CacheCompressionCodecID is the internal constant for the provided CacheCompressionCodec
+	CacheCompressionCodecID codec.ID `yaml:"-"`
 	// RuleOptions is the reference to the Rule Options as indicated by RuleName
 	RuleOptions *ro.Options `yaml:"-"`
 	// ReqRewriter is the rewriter handler as indicated by RuleName
 	ReqRewriter rewriter.RewriteInstructions
+	// RespRewriter is the response rewriter as indicated by RespRewriterName
+	RespRewriter *resprewriter.Rewriter `yaml:"-"`
 
 	//
 	md yamlx.KeyLookup `yaml:"-"`
@@ -211,6 +254,8 @@ func New() *Options {
 		NegativeCacheName:            DefaultBackendNegativeCacheName,
 		Paths:                        make(map[string]*po.Options),
 		RevalidationFactor:           DefaultRevalidationFactor,
+		Retry:                        rto.New(),
+		ShardMaxConcurrency:          DefaultShardMaxConcurrency,
 		TLS:                          &to.Options{},
 		Timeout:                      time.Millisecond * DefaultBackendTimeoutMS,
 		TimeoutMS:                    DefaultBackendTimeoutMS,
@@ -234,6 +279,9 @@ func (o *Options) Clone() *Options {
 	no.BackfillTolerancePoints = o.BackfillTolerancePoints
 	no.CacheName = o.CacheName
 	no.CacheKeyPrefix = o.CacheKeyPrefix
+	no.CacheCompressionCodec = o.CacheCompressionCodec
+	no.CacheCompressionCodecID = o.CacheCompressionCodecID
+	no.CacheCompressionLevel = o.CacheCompressionLevel
 	no.FastForwardDisable = o.FastForwardDisable
 	no.FastForwardTTL = o.FastForwardTTL
 	no.FastForwardTTLMS = o.FastForwardTTLMS
@@ -251,11 +299,17 @@ func (o *Options) Clone() *Options {
 	no.OriginURL = o.OriginURL
 	no.PathPrefix = o.PathPrefix
 	no.ReqRewriterName = o.ReqRewriterName
+	no.RespRewriterName = o.RespRewriterName
 	no.RevalidationFactor = o.RevalidationFactor
 	no.RuleName = o.RuleName
 	no.Scheme = o.Scheme
+	no.ShardMaxConcurrency = o.ShardMaxConcurrency
+	no.ShardMaxSizePoints = o.ShardMaxSizePoints
+	no.ShardMaxSizeTime = o.ShardMaxSizeTime
+	no.ShardMaxSizeTimeMS = o.ShardMaxSizeTimeMS
 	no.Timeout = o.Timeout
 	no.TimeoutMS = o.TimeoutMS
+	no.TimeseriesDegradedServing = o.TimeseriesDegradedServing
 	no.TimeseriesRetention = o.TimeseriesRetention
 	no.TimeseriesRetentionFactor = o.TimeseriesRetentionFactor
 	no.TimeseriesEvictionMethodName = o.TimeseriesEvictionMethodName
@@ -270,6 +324,18 @@ func (o *Options) Clone() *Options {
 		no.HealthCheck = o.HealthCheck.Clone()
 	}
 
+	if o.Retry != nil {
+		no.Retry = o.Retry.Clone()
+	}
+
+	if o.CircuitBreaker != nil {
+		no.CircuitBreaker = o.CircuitBreaker.Clone()
+	}
+
+	if o.RateLimit != nil {
+		no.RateLimit = o.RateLimit.Clone()
+	}
+
 	no.Hosts = copiers.CopyStrings(o.Hosts)
 	no.CompressibleTypeList = copiers.CopyStrings(no.CompressibleTypeList)
 
@@ -318,6 +384,10 @@ func (o *Options) Clone() *Options {
 		}
 	}
 
+	if o.Graphite != nil {
+		no.Graphite = o.Graphite.Clone()
+	}
+
 	return no
 }
 
@@ -343,6 +413,7 @@ func (l Lookup) Validate(ncl negative.Lookups) error {
 		o.PathPrefix = url.Path
 		o.Timeout = time.Duration(o.TimeoutMS) * time.Millisecond
 		o.BackfillTolerance = time.Duration(o.BackfillToleranceMS) * time.Millisecond
+		o.ShardMaxSizeTime = time.Duration(o.ShardMaxSizeTimeMS) * time.Millisecond
 		o.TimeseriesRetention = time.Duration(o.TimeseriesRetentionFactor)
 		o.TimeseriesTTL = time.Duration(o.TimeseriesTTLMS) * time.Millisecond
 		o.FastForwardTTL = time.Duration(o.FastForwardTTLMS) * time.Millisecond
@@ -446,6 +517,7 @@ func SetDefaults(
 	o *Options,
 	metadata yamlx.KeyLookup,
 	crw map[string]rewriter.RewriteInstructions,
+	crsw map[string]*resprewriter.Rewriter,
 	backends Lookup,
 	activeCaches map[string]interface{},
 ) (*Options, error) {
@@ -466,6 +538,15 @@ func SetDefaults(
 		no.ReqRewriter = ri
 	}
 
+	if metadata.IsDefined("backends", name, "resp_rewriter_name") && o.RespRewriterName != "" {
+		no.RespRewriterName = o.RespRewriterName
+		rw, ok := crsw[no.RespRewriterName]
+		if !ok {
+			return nil, NewErrInvalidRewriterName(no.RespRewriterName, name)
+		}
+		no.RespRewriter = rw
+	}
+
 	if metadata.IsDefined("backends", name, "provider") {
 		no.Provider = o.Provider
 	}
@@ -507,6 +588,23 @@ func SetDefaults(
 		no.CacheKeyPrefix = o.CacheKeyPrefix
 	}
 
+	if metadata.IsDefined("backends", name, "cache_compression_codec") {
+		id, ok := codec.Lookup(o.CacheCompressionCodec)
+		if !ok {
+			return nil, NewErrInvalidCompressionCodec(o.CacheCompressionCodec, name)
+		}
+		no.CacheCompressionCodec = strings.ToLower(o.CacheCompressionCodec)
+		no.CacheCompressionCodecID = id
+	}
+
+	if metadata.IsDefined("backends", name, "cache_compression_level") {
+		if no.CacheCompressionCodec != "" &&
+			!codec.ValidLevel(no.CacheCompressionCodecID, o.CacheCompressionLevel) {
+			return nil, NewErrInvalidCompressionLevel(o.CacheCompressionLevel, name)
+		}
+		no.CacheCompressionLevel = o.CacheCompressionLevel
+	}
+
 	if metadata.IsDefined("backends", name, "origin_url") {
 		no.OriginURL = o.OriginURL
 	}
@@ -562,11 +660,28 @@ func SetDefaults(
 		no.BackfillTolerancePoints = o.BackfillTolerancePoints
 	}
 
+	if metadata.IsDefined("backends", name, "shard_max_size_points") {
+		no.ShardMaxSizePoints = o.ShardMaxSizePoints
+	}
+
+	if metadata.IsDefined("backends", name, "shard_max_size_time_ms") {
+		no.ShardMaxSizeTimeMS = o.ShardMaxSizeTimeMS
+	}
+
+	if metadata.IsDefined("backends", name, "shard_max_concurrency") {
+		no.ShardMaxConcurrency = o.ShardMaxConcurrency
+	}
+
+	if metadata.IsDefined("backends", name, "timeseries_degraded_serving") {
+		no.TimeseriesDegradedServing = o.TimeseriesDegradedServing
+	}
+
 	if metadata.IsDefined("backends", name, "paths") {
-		err := po.SetDefaults(name, metadata, o.Paths, crw)
+		err := po.SetDefaults(name, metadata, o.Paths, crw, crsw)
 		if err != nil {
 			return nil, err
 		}
+		no.Paths = o.Paths
 	}
 
 	if metadata.IsDefined("backends", name, "alb") {
@@ -577,6 +692,14 @@ func SetDefaults(
 		no.ALBOptions = opts
 	}
 
+	if metadata.IsDefined("backends", name, "graphite") {
+		opts, err := gro.SetDefaults(name, o.Graphite, metadata)
+		if err != nil {
+			return nil, err
+		}
+		no.Graphite = opts
+	}
+
 	if metadata.IsDefined("backends", name, "negative_cache_name") {
 		no.NegativeCacheName = o.NegativeCacheName
 	}
@@ -594,6 +717,30 @@ func SetDefaults(
 		}
 	}
 
+	if metadata.IsDefined("backends", name, "retry") {
+		opts, err := rto.SetDefaults(name, o.Retry, metadata)
+		if err != nil {
+			return nil, err
+		}
+		no.Retry = opts
+	}
+
+	if metadata.IsDefined("backends", name, "circuit_breaker") {
+		opts, err := cbo.SetDefaults(name, o.CircuitBreaker, metadata)
+		if err != nil {
+			return nil, err
+		}
+		no.CircuitBreaker = opts
+	}
+
+	if metadata.IsDefined("backends", name, "rate_limit") {
+		opts, err := rlo.SetDefaults(name, o.RateLimit, metadata)
+		if err != nil {
+			return nil, err
+		}
+		no.RateLimit = opts
+	}
+
 	if metadata.IsDefined("backends", name, "max_object_size_bytes") {
 		no.MaxObjectSizeBytes = o.MaxObjectSizeBytes
 	}
@@ -649,6 +796,25 @@ func (o *Options) ToYAML() string {
 	return string(b)
 }
 
+// ShardMaxPoints returns the maximum number of timestamps to request from the backend in a
+// single upstream request for a query with the provided step, or 0 when sharding is disabled
+func (o *Options) ShardMaxPoints(step time.Duration) int {
+	n := o.ShardMaxSizePoints
+	if o.ShardMaxSizeTime > 0 && step > 0 {
+		p := int(o.ShardMaxSizeTime / step)
+		if p < 1 {
+			p = 1
+		}
+		if n <= 0 || p < n {
+			n = p
+		}
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
 // HasTransformations returns true if the backend will artificially transform payloads
 // based on the running configuration (e.g., insert labels into prometheus response)
 func (o *Options) HasTransformations() bool {
@@ -17,6 +17,7 @@
 package options
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/negative"
@@ -53,6 +54,10 @@ backends:
     fast_forward_disable: true
     backfill_tolerance_ms: 301000
     backfill_tolerance_points: 2
+    shard_max_size_points: 1000
+    shard_max_size_time_ms: 3600000
+    shard_max_concurrency: 2
+    timeseries_degraded_serving: true
     timeout_ms: 37000
     health_check_endpoint: /test_health
     health_check_upstream_path: /test/upstream/endpoint
@@ -64,6 +69,7 @@ backends:
     require_tls: true
     max_object_size_bytes: 999
     cache_key_prefix: test-prefix
+    cache_compression_codec: snappy
     path_routing_disabled: false
     forwarded_headers: x
     negative_cache_name: test
@@ -71,6 +77,14 @@ backends:
     healthcheck:
       headers:
         Authorization: Basic SomeHash
+    retry:
+      max_attempts: 3
+      hedge_percentile: 95
+    circuit_breaker:
+      error_rate_threshold: 25
+    rate_limit:
+      requests_per_second: 10
+      key_by: client_ip
     paths:
       series:
         path: /series
@@ -121,6 +135,11 @@ func fromTestYAMLWithReqRewriter() (*Options, error) {
 	return fromYAML(conf)
 }
 
+func fromTestYAMLWithRespRewriter() (*Options, error) {
+	conf := strings.Replace(testYAML, "    rule_name: ''", "    rule_name: ''\n    resp_rewriter_name: test", -1)
+	return fromYAML(conf)
+}
+
 func fromTestYAMLWithALB() (*Options, error) {
 	conf := strings.Replace(strings.Replace(testYAML, "    rule_name: ''", `
     rule_name: ''
@@ -130,3 +149,18 @@ func fromTestYAMLWithALB() (*Options, error) {
         `, -1), "    provider: test_type", "    provider: 'alb'", -1)
 	return fromYAML(conf)
 }
+
+func fromTestYAMLWithCompressionLevel(level int) (*Options, error) {
+	conf := strings.Replace(testYAML, "    cache_compression_codec: snappy",
+		"    cache_compression_codec: gzip\n    cache_compression_level: "+strconv.Itoa(level), -1)
+	return fromYAML(conf)
+}
+
+func fromTestYAMLWithGraphite() (*Options, error) {
+	conf := strings.Replace(strings.Replace(testYAML, "    rule_name: ''", `
+    rule_name: ''
+    graphite:
+      step_ms: 10000
+        `, -1), "    provider: test_type", "    provider: 'graphite'", -1)
+	return fromYAML(conf)
+}
@@ -26,11 +26,13 @@ import (
 
 	ho "github.com/tricksterproxy/trickster/pkg/backends/healthcheck/options"
 	ro "github.com/tricksterproxy/trickster/pkg/backends/rule/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/codec"
 	"github.com/tricksterproxy/trickster/pkg/cache/negative"
 	co "github.com/tricksterproxy/trickster/pkg/cache/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	resprewriter "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter"
 	tlstest "github.com/tricksterproxy/trickster/pkg/util/testing/tls"
 	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
 
@@ -269,12 +271,28 @@ func TestSetDefaults(t *testing.T) {
 
 	backends := Lookup{o.Name: o}
 
-	_, err = SetDefaults("test", o, o.md, nil, backends, map[string]interface{}{})
+	no, err := SetDefaults("test", o, o.md, nil, nil, backends, map[string]interface{}{})
 	if err != nil {
 		t.Error(err)
 	}
+	if !no.TimeseriesDegradedServing {
+		t.Error("expected timeseries_degraded_serving true")
+	}
+	if no.Retry == nil || no.Retry.MaxAttempts != 3 || no.Retry.HedgePercentile != 95 {
+		t.Error("expected retry max_attempts 3 and hedge_percentile 95")
+	}
+	if no.CircuitBreaker == nil || no.CircuitBreaker.ErrorRateThreshold != 25 {
+		t.Error("expected circuit_breaker error_rate_threshold 25")
+	}
+	if no.CacheCompressionCodec != "snappy" || no.CacheCompressionCodecID != codec.IDSnappy {
+		t.Error("expected cache_compression_codec snappy")
+	}
+	if no.RateLimit == nil || no.RateLimit.RequestsPerSecond != 10 || no.RateLimit.Burst != 10 ||
+		no.RateLimit.KeyBy != "client_ip" {
+		t.Error("expected rate_limit requests_per_second 10 and key_by client_ip")
+	}
 
-	_, err = SetDefaults("test", o, nil, nil, backends, map[string]interface{}{})
+	_, err = SetDefaults("test", o, nil, nil, nil, backends, map[string]interface{}{})
 	if err != ErrInvalidMetadata {
 		t.Error("expected invalid metadata, got", err)
 	}
@@ -284,13 +302,13 @@ func TestSetDefaults(t *testing.T) {
 		t.Error(err)
 	}
 
-	_, err = SetDefaults("test", o2, o2.md, nil, backends, map[string]interface{}{})
+	_, err = SetDefaults("test", o2, o2.md, nil, nil, backends, map[string]interface{}{})
 	if err != nil {
 		t.Error(err)
 	}
 
 	o.Paths["series"].ReqRewriterName = "invalid"
-	_, err = SetDefaults("test", o, o.md, nil, backends, map[string]interface{}{})
+	_, err = SetDefaults("test", o, o.md, nil, nil, backends, map[string]interface{}{})
 	if err == nil {
 		t.Error("expected error for invalid rewriter name")
 	}
@@ -301,28 +319,76 @@ func TestSetDefaults(t *testing.T) {
 	}
 
 	_, err = SetDefaults("test", o2, o2.md, map[string]rewriter.RewriteInstructions{"test": nil},
-		backends, map[string]interface{}{})
+		nil, backends, map[string]interface{}{})
 	if err != nil {
 		t.Error(err)
 	}
 
 	_, err = SetDefaults("test", o2, o2.md, map[string]rewriter.RewriteInstructions{"not-test": nil},
-		backends, map[string]interface{}{})
+		nil, backends, map[string]interface{}{})
 	if err == nil {
 		t.Error("expected error for invalid rewriter name")
 	}
 
+	o2, err = fromTestYAMLWithRespRewriter()
+	if err != nil {
+		t.Error(err)
+	}
+
+	rsw := map[string]*resprewriter.Rewriter{"test": {Name: "test"}}
+	no, err = SetDefaults("test", o2, o2.md, nil, rsw, backends, map[string]interface{}{})
+	if err != nil {
+		t.Error(err)
+	} else if no.RespRewriter != rsw["test"] {
+		t.Error("expected response rewriter")
+	}
+
+	_, err = SetDefaults("test", o2, o2.md, nil, map[string]*resprewriter.Rewriter{},
+		backends, map[string]interface{}{})
+	if err == nil {
+		t.Error("expected error for invalid response rewriter name")
+	}
+
 	o2, err = fromTestYAMLWithALB()
 	if err != nil {
 		t.Error(err)
 	}
 
-	_, err = SetDefaults("test", o2, o2.md, nil,
+	_, err = SetDefaults("test", o2, o2.md, nil, nil,
 		backends, map[string]interface{}{})
 	if err != nil {
 		t.Error(err)
 	}
 
+	o2, err = fromTestYAMLWithGraphite()
+	if err != nil {
+		t.Error(err)
+	}
+
+	no, err = SetDefaults("test", o2, o2.md, nil, nil,
+		backends, map[string]interface{}{})
+	if err != nil {
+		t.Error(err)
+	} else if no.Graphite == nil || no.Graphite.Step != 10*time.Second {
+		t.Error("expected graphite step 10s")
+	} else if no.Clone().Graphite.StepMS != 10000 {
+		t.Error("expected cloned graphite step_ms 10000")
+	}
+
+	for _, level := range []int{9, 10} {
+		o2, err = fromTestYAMLWithCompressionLevel(level)
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = SetDefaults("test", o2, o2.md, nil, nil,
+			backends, map[string]interface{}{})
+		if level == 9 && err != nil {
+			t.Error(err)
+		} else if level == 10 && err == nil {
+			t.Error("expected error for invalid cache_compression_level")
+		}
+	}
+
 }
 
 func TestValidateTLSConfigs(t *testing.T) {
@@ -409,6 +475,28 @@ func TestToYAML(t *testing.T) {
 	}
 }
 
+func TestShardMaxPoints(t *testing.T) {
+	tests := []struct {
+		points   int
+		sizeTime time.Duration
+		step     time.Duration
+		expected int
+	}{
+		{0, 0, time.Minute, 0},
+		{100, 0, time.Minute, 100},
+		{0, time.Hour, time.Minute, 60},
+		{100, time.Hour, time.Minute, 60},
+		{30, time.Hour, time.Minute, 30},
+		{0, time.Second, time.Minute, 1},
+	}
+	for i, test := range tests {
+		o := &Options{ShardMaxSizePoints: test.points, ShardMaxSizeTime: test.sizeTime}
+		if n := o.ShardMaxPoints(test.step); n != test.expected {
+			t.Errorf("(%d) expected %d got %d", i, test.expected, n)
+		}
+	}
+}
+
 func TestHasTransformations(t *testing.T) {
 	o := &Options{}
 	if o.HasTransformations() {
@@ -110,17 +110,17 @@ func MergeAndWriteAlerts(w http.ResponseWriter, r *http.Request, rgs merge.Respo
 
 	var a *WFAlerts
 
-	responses := make([]int, len(rgs))
+	responses := make([]int, 0, len(rgs))
 	var bestResp *http.Response
 
-	for i, rg := range rgs {
+	for _, rg := range rgs {
 
 		if rg == nil {
 			continue
 		}
 		if rg.Resources != nil && rg.Resources.Response != nil {
 			resp := rg.Resources.Response
-			responses[i] = resp.StatusCode
+			responses = append(responses, resp.StatusCode)
 
 			if resp.Body != nil {
 				defer resp.Body.Close()
@@ -163,6 +163,12 @@ func MergeAndWriteAlerts(w http.ResponseWriter, r *http.Request, rgs merge.Respo
 
 	sort.Ints(responses)
 	statusCode = responses[0]
+	if wr := rgs.Warnings(); len(wr) > 0 {
+		if a.Envelope == nil {
+			a.Envelope = &Envelope{}
+		}
+		a.Warnings = append(a.Warnings, wr...)
+	}
 	a.StartMarshal(w, statusCode)
 
 	var sep string
@@ -60,16 +60,16 @@ func (ld *WFLabelData) Merge(results ...*WFLabelData) {
 func MergeAndWriteLabelData(w http.ResponseWriter, r *http.Request, rgs merge.ResponseGates) {
 	var ld *WFLabelData
 
-	responses := make([]int, len(rgs))
+	responses := make([]int, 0, len(rgs))
 	var bestResp *http.Response
 
-	for i, rg := range rgs {
+	for _, rg := range rgs {
 		if rg == nil {
 			continue
 		}
 		if rg.Resources != nil && rg.Resources.Response != nil {
 			resp := rg.Resources.Response
-			responses[i] = resp.StatusCode
+			responses = append(responses, resp.StatusCode)
 			if resp.Body != nil {
 				defer resp.Body.Close()
 			}
@@ -111,6 +111,12 @@ func MergeAndWriteLabelData(w http.ResponseWriter, r *http.Request, rgs merge.Re
 
 	sort.Ints(responses)
 	statusCode = responses[0]
+	if wr := rgs.Warnings(); len(wr) > 0 {
+		if ld.Envelope == nil {
+			ld.Envelope = &Envelope{}
+		}
+		ld.Warnings = append(ld.Warnings, wr...)
+	}
 	ld.StartMarshal(w, statusCode)
 
 	if len(ld.Data) > 0 {
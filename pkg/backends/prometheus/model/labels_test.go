@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
@@ -117,6 +118,23 @@ func TestMergeAndWriteLabelData(t *testing.T) {
 
 }
 
+func TestMergeAndWriteLabelDataWarnings(t *testing.T) {
+
+	// a nil gate represents a pool member dropped from the merge
+	rgs := append(testResponseGates3(), nil)
+	rgs[0].Resources.Warnings = []string{"1 of 4 pool members did not respond"}
+
+	w := httptest.NewRecorder()
+	MergeAndWriteLabelData(w, nil, rgs)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	const expected = `"warnings":["1 of 4 pool members did not respond"]`
+	if !strings.Contains(w.Body.String(), expected) {
+		t.Errorf("expected %s in %s", expected, w.Body.String())
+	}
+}
+
 func testResponseGates3() merge.ResponseGates {
 
 	b1 := []byte(`{"status":"success","data":["test", "trickster"]}`)
@@ -67,16 +67,16 @@ func (s *WFSeries) Merge(results ...*WFSeries) {
 func MergeAndWriteSeries(w http.ResponseWriter, r *http.Request, rgs merge.ResponseGates) {
 	var s *WFSeries
 
-	responses := make([]int, len(rgs))
+	responses := make([]int, 0, len(rgs))
 	var bestResp *http.Response
 
-	for i, rg := range rgs {
+	for _, rg := range rgs {
 		if rg == nil {
 			continue
 		}
 		if rg.Resources != nil && rg.Resources.Response != nil {
 			resp := rg.Resources.Response
-			responses[i] = resp.StatusCode
+			responses = append(responses, resp.StatusCode)
 
 			if resp.Body != nil {
 				defer resp.Body.Close()
@@ -119,6 +119,12 @@ func MergeAndWriteSeries(w http.ResponseWriter, r *http.Request, rgs merge.Respo
 
 	sort.Ints(responses)
 	statusCode = responses[0]
+	if wr := rgs.Warnings(); len(wr) > 0 {
+		if s.Envelope == nil {
+			s.Envelope = &Envelope{}
+		}
+		s.Warnings = append(s.Warnings, wr...)
+	}
 	s.StartMarshal(w, statusCode)
 
 	var sep string
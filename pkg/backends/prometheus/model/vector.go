@@ -36,18 +36,18 @@ func MergeAndWriteVector(w http.ResponseWriter, r *http.Request, rgs merge.Respo
 	var ts *dataset.DataSet
 	var trq *timeseries.TimeRangeQuery
 
-	responses := make([]int, len(rgs))
+	responses := make([]int, 0, len(rgs))
 	var bestResp *http.Response
 
 	statusCode := 0
 
-	for i, rg := range rgs {
+	for _, rg := range rgs {
 		if rg == nil {
 			continue
 		}
 		if rg.Resources != nil && rg.Resources.Response != nil {
 			resp := rg.Resources.Response
-			responses[i] = resp.StatusCode
+			responses = append(responses, resp.StatusCode)
 
 			if resp.Body != nil {
 				defer resp.Body.Close()
@@ -107,6 +107,8 @@ func MergeAndWriteVector(w http.ResponseWriter, r *http.Request, rgs merge.Respo
 		return
 	}
 
+	merge.DeduplicateReplicas(r, ts)
+	ts.Warnings = append(ts.Warnings, rgs.Warnings()...)
 	marshalTSOrVectorWriter(ts, nil, statusCode, w, true)
 
 }
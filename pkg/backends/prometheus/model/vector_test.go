@@ -18,11 +18,16 @@ package model
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	ao "github.com/tricksterproxy/trickster/pkg/backends/alb/options"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/proxy/response/merge"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
@@ -59,6 +64,42 @@ func TestMergeAndWriteVector(t *testing.T) {
 	}
 }
 
+func TestMergeAndWriteVectorReplicaLabels(t *testing.T) {
+
+	const replica = `{"status":"success","data":{"resultType":"vector","result":[` +
+		`{"metric":{"__name__":"up","job":"trickster","replica":"%s"},` +
+		`"value":[1577836800,"1"]}]}}`
+
+	rgs := make(merge.ResponseGates, 0, 2)
+	for _, name := range []string{"a", "b"} {
+		b := []byte(fmt.Sprintf(replica, name))
+		rsc := request.NewResources(nil, nil, nil, nil, nil, nil, nil)
+		rsc.Response = &http.Response{
+			Body:       io.NopCloser(bytes.NewReader(b)),
+			StatusCode: 200,
+		}
+		rsc.TimeRangeQuery = &timeseries.TimeRangeQuery{}
+		rg := merge.NewResponseGate(nil, nil, rsc)
+		rg.Write(b)
+		rgs = append(rgs, rg)
+	}
+
+	o := bo.New()
+	o.ALBOptions = &ao.Options{ReplicaLabels: []string{"replica"}, ReplicaPenalty: 5 * time.Second}
+	r := httptest.NewRequest(http.MethodGet, "http://0/api/v1/query?query=up", nil)
+	r = request.SetResources(r, request.NewResources(o, nil, nil, nil, nil, nil, nil))
+
+	w := httptest.NewRecorder()
+	MergeAndWriteVector(w, r, rgs)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	const expected = `"result":[{"metric":{"__name__":"up","job":"trickster"},"value":[1577836800,"1"]}]`
+	if !strings.Contains(w.Body.String(), expected) {
+		t.Errorf("expected %s got %s", expected, w.Body.String())
+	}
+}
+
 func testResponseGates7() merge.ResponseGates {
 
 	b1 := []byte(testVector)
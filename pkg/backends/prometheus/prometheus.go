@@ -28,10 +28,12 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/backends"
 	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
 	po "github.com/tricksterproxy/trickster/pkg/backends/prometheus/options"
+	"github.com/tricksterproxy/trickster/pkg/backends/prometheus/promql"
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/params"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
 	tt "github.com/tricksterproxy/trickster/pkg/util/timeconv"
 )
@@ -168,25 +170,27 @@ func (c *Client) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuer
 		return nil, nil, false, errors.MissingURLParam(upStep)
 	}
 
-	if strings.Contains(trq.Statement, " offset ") {
-		trq.IsOffset = true
-		rlo.FastForwardDisable = true
-	}
-
-	rlo.ExtractFastForwardDisabled(trq.Statement)
-	trq.ExtractBackfillTolerance(trq.Statement)
-
-	if x := strings.Index(trq.Statement, timeseries.BackfillToleranceFlag); x > 1 {
-		x += 29
-		y := x
-		for ; y < len(trq.Statement); y++ {
-			if trq.Statement[y] < 48 || trq.Statement[y] > 57 {
-				break
-			}
-		}
-		if i, err := strconv.Atoi(trq.Statement[x:y]); err == nil {
-			trq.BackfillTolerance = time.Second * time.Duration(i)
+	stmt := parseStatement(trq, rlo)
+	if stmt != nil {
+		// @ modifiers are relative to the start and end of the overall query, and negative
+		// offsets read data after each step, so neither can be cached in partial ranges
+		if stmt.HasAtModifier() || stmt.HasNegativeOffset() {
+			return nil, nil, false, errors.ErrUncacheableModifier
 		}
+		// the normalized statement is used for the cache key, so that queries that differ
+		// only in whitespace, comments or label matcher order share a cache entry
+		qp.Set(upQuery, stmt.String())
+		trq.TemplateURL = urls.Clone(r.URL)
+		trq.TemplateURL.RawQuery = qp.Encode()
+		// the value at each timestamp of a range query does not depend on its step,
+		// so the query is the same at any step once the step parameter is removed
+		sqp := trq.TemplateURL.Query()
+		sqp.Del(upStep)
+		trq.StepTemplateURL = urls.Clone(r.URL)
+		trq.StepTemplateURL.RawQuery = sqp.Encode()
+	}
+	if trq.IsOffset {
+		rlo.FastForwardDisable = true
 	}
 
 	return trq, rlo, true, nil
@@ -223,9 +227,27 @@ func parseVectorQuery(r *http.Request, rounder time.Duration) (*timeseries.TimeR
 		trq.Extent.Start = time.Now().Truncate(rounder)
 	}
 
-	if strings.Contains(trq.Statement, " offset ") {
-		trq.IsOffset = true
-	}
+	parseStatement(trq, &timeseries.RequestOptions{})
 
 	return trq, nil
 }
+
+// parseStatement parses the PromQL statement in trq and applies its offset and
+// per-query instructions to trq and rlo. If the statement is not valid PromQL,
+// the upstream will report the error, so the raw statement is inspected instead
+// and parseStatement returns nil.
+func parseStatement(trq *timeseries.TimeRangeQuery, rlo *timeseries.RequestOptions) *promql.Statement {
+	stmt, err := promql.Parse(trq.Statement)
+	if err != nil {
+		trq.IsOffset = strings.Contains(trq.Statement, " offset ")
+		rlo.ExtractFastForwardDisabled(trq.Statement)
+		trq.ExtractBackfillTolerance(trq.Statement)
+		return nil
+	}
+	trq.ParsedQuery = stmt
+	trq.IsOffset = stmt.HasOffset()
+	instructions := strings.Join(stmt.Comments, "\n")
+	rlo.ExtractFastForwardDisabled(instructions)
+	trq.ExtractBackfillTolerance(instructions)
+	return stmt
+}
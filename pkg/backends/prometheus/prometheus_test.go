@@ -170,6 +170,35 @@ func TestParseTimeRangeQuery(t *testing.T) {
 	}
 }
 
+func TestParseTimeRangeQueryStepTemplate(t *testing.T) {
+	qp := url.Values{
+		"query": {`sum(rate(up[5m]))`},
+		"start": {strconv.Itoa(int(time.Now().Add(time.Duration(-6) * time.Hour).Unix()))},
+		"end":   {strconv.Itoa(int(time.Now().Unix()))},
+	}
+	templates := make([]string, 0, 2)
+	for _, step := range []string{"15", "60"} {
+		qp.Set("step", step)
+		req := &http.Request{URL: &url.URL{Scheme: "https", Host: "blah.com", Path: "/",
+			RawQuery: qp.Encode()}}
+		client := &Client{}
+		trq, _, _, err := client.ParseTimeRangeQuery(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trq.StepTemplateURL == nil {
+			t.Fatal("expected non-nil step template url")
+		}
+		if trq.StepTemplateURL.Query().Get("step") != "" {
+			t.Errorf("unexpected step template url %s", trq.StepTemplateURL.String())
+		}
+		templates = append(templates, trq.StepTemplateURL.String())
+	}
+	if templates[0] != templates[1] {
+		t.Errorf("expected %s got %s", templates[0], templates[1])
+	}
+}
+
 func TestParseTimeRangeQueryMissingQuery(t *testing.T) {
 	expected := pe.MissingURLParam(upQuery).Error()
 	req := &http.Request{URL: &url.URL{
@@ -364,6 +393,105 @@ func TestParseTimeRangeQueryWithOffset(t *testing.T) {
 
 }
 
+func TestParseTimeRangeQueryModifiers(t *testing.T) {
+
+	newRequest := func(query string) *http.Request {
+		return &http.Request{URL: &url.URL{
+			Scheme: "https",
+			Host:   "blah.com",
+			Path:   "/",
+			RawQuery: url.Values(map[string][]string{
+				"query": {query},
+				"start": {strconv.Itoa(int(time.Now().Add(time.Duration(-6) * time.Hour).Unix()))},
+				"end":   {strconv.Itoa(int(time.Now().Unix()))},
+				"step":  {"15"},
+			}).Encode(),
+		}}
+	}
+
+	tests := []struct {
+		query       string
+		err         error
+		isOffset    bool
+		ffDisabled  bool
+		backfill    time.Duration
+		templateURL bool
+	}{
+		{query: `rate(up[5m]offset 1h)`, isOffset: true, ffDisabled: true, templateURL: true},
+		{query: `max_over_time(rate(up[1m])[1h:1m] offset 1d)`, isOffset: true, ffDisabled: true,
+			templateURL: true},
+		{query: `up{job="offset 5m"}`, templateURL: true},
+		{query: `up # trickster-fast-forward:off`, ffDisabled: true, templateURL: true},
+		{query: "up # trickster-backfill-tolerance:30", backfill: 30 * time.Second, templateURL: true},
+		{query: `up offset -5m`, err: pe.ErrUncacheableModifier},
+		{query: `sum(up @ start())`, err: pe.ErrUncacheableModifier},
+		{query: `up @ 1609746000`, err: pe.ErrUncacheableModifier},
+		{query: `up and has offset `, isOffset: true, ffDisabled: true},
+	}
+
+	client := &Client{}
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			trq, rlo, _, err := client.ParseTimeRangeQuery(newRequest(test.query))
+			if err != test.err {
+				t.Fatalf("expected %v got %v", test.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if trq.IsOffset != test.isOffset {
+				t.Errorf("expected %t got %t", test.isOffset, trq.IsOffset)
+			}
+			if rlo.FastForwardDisable != test.ffDisabled {
+				t.Errorf("expected %t got %t", test.ffDisabled, rlo.FastForwardDisable)
+			}
+			if trq.BackfillTolerance != test.backfill {
+				t.Errorf("expected %s got %s", test.backfill, trq.BackfillTolerance)
+			}
+			if (trq.TemplateURL != nil) != test.templateURL {
+				t.Errorf("expected %t got %t", test.templateURL, trq.TemplateURL != nil)
+			}
+		})
+	}
+}
+
+func TestParseTimeRangeQueryNormalized(t *testing.T) {
+
+	queries := []string{
+		`sum by (job, instance) (rate(http_requests_total{code="200",method="GET"}[5m]))`,
+		"sum(\n  rate(http_requests_total{ method='GET', code=\"200\" }[300s])\n) by (instance,job) # comment",
+	}
+
+	client := &Client{}
+	var expected string
+	for i, q := range queries {
+		req := &http.Request{URL: &url.URL{
+			Scheme: "https",
+			Host:   "blah.com",
+			Path:   "/",
+			RawQuery: url.Values(map[string][]string{
+				"query": {q},
+				"start": {"1600000000"},
+				"end":   {"1600003600"},
+				"step":  {"15"},
+			}).Encode(),
+		}}
+		trq, _, _, err := client.ParseTimeRangeQuery(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trq.Statement != q {
+			t.Errorf("expected %s got %s", q, trq.Statement)
+		}
+		v := trq.TemplateURL.Query().Get(upQuery)
+		if i == 0 {
+			expected = v
+		} else if v != expected {
+			t.Errorf("expected %s got %s", expected, v)
+		}
+	}
+}
+
 func TestParseVectorQuery(t *testing.T) {
 
 	req := &http.Request{URL: &url.URL{
@@ -0,0 +1,352 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package promql
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a node in a parsed PromQL expression. The String() form of
+// every Expr is normalized, so that semantically identical expressions
+// render identically regardless of whitespace, comments or label order.
+type Expr interface {
+	String() string
+}
+
+// NumberLiteral is a scalar number
+type NumberLiteral struct {
+	Val float64
+}
+
+// StringLiteral is a quoted string
+type StringLiteral struct {
+	Val string
+}
+
+// MatchType is the comparison used by a LabelMatcher
+type MatchType string
+
+// Label Matcher Types
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// LabelMatcher filters series by the value of a label
+type LabelMatcher struct {
+	Type  MatchType
+	Name  string
+	Value string
+}
+
+// AtModifier pins the evaluation time of a selector or subquery, either to
+// a fixed Timestamp or to the start() or end() of the query range
+type AtModifier struct {
+	Preprocessor string
+	Timestamp    float64
+}
+
+// VectorSelector selects an instant vector of series
+type VectorSelector struct {
+	Name     string
+	Matchers []*LabelMatcher
+	Offset   time.Duration
+	At       *AtModifier
+}
+
+// MatrixSelector selects a range vector of series
+type MatrixSelector struct {
+	Vector *VectorSelector
+	Range  time.Duration
+}
+
+// SubqueryExpr evaluates an instant vector expression over a range
+type SubqueryExpr struct {
+	Expr   Expr
+	Range  time.Duration
+	Step   time.Duration
+	Offset time.Duration
+	At     *AtModifier
+}
+
+// ParenExpr is an expression wrapped in parentheses
+type ParenExpr struct {
+	Expr Expr
+}
+
+// UnaryExpr is an expression with a leading + or -
+type UnaryExpr struct {
+	Op   string
+	Expr Expr
+}
+
+// VectorMatching describes the on/ignoring and group_left/group_right
+// modifiers of a BinaryExpr
+type VectorMatching struct {
+	On      bool
+	Labels  []string
+	Card    string
+	Include []string
+}
+
+// BinaryExpr is a binary operation on two expressions
+type BinaryExpr struct {
+	Op         string
+	LHS, RHS   Expr
+	ReturnBool bool
+	Matching   *VectorMatching
+}
+
+// AggregateExpr is an aggregation operation like sum or topk
+type AggregateExpr struct {
+	Op       string
+	Param    Expr
+	Expr     Expr
+	Without  bool
+	Grouping []string
+}
+
+// Call is a function call
+type Call struct {
+	Func string
+	Args []Expr
+}
+
+func (n *NumberLiteral) String() string {
+	switch {
+	case math.IsInf(n.Val, 1):
+		return "+Inf"
+	case math.IsInf(n.Val, -1):
+		return "-Inf"
+	case math.IsNaN(n.Val):
+		return "NaN"
+	}
+	return strconv.FormatFloat(n.Val, 'f', -1, 64)
+}
+
+func (n *StringLiteral) String() string {
+	return strconv.Quote(n.Val)
+}
+
+func (m *LabelMatcher) String() string {
+	return m.Name + string(m.Type) + strconv.Quote(m.Value)
+}
+
+func (a *AtModifier) String() string {
+	if a == nil {
+		return ""
+	}
+	if a.Preprocessor != "" {
+		return " @ " + a.Preprocessor + "()"
+	}
+	return " @ " + strconv.FormatFloat(a.Timestamp, 'f', 3, 64)
+}
+
+func offsetString(d time.Duration) string {
+	switch {
+	case d > 0:
+		return " offset " + FormatDuration(d)
+	case d < 0:
+		return " offset -" + FormatDuration(-d)
+	}
+	return ""
+}
+
+func (n *VectorSelector) String() string {
+	return n.selectorString() + n.At.String() + offsetString(n.Offset)
+}
+
+func (n *VectorSelector) selectorString() string {
+	if len(n.Matchers) == 0 {
+		return n.Name
+	}
+	l := make([]string, len(n.Matchers))
+	for i, m := range n.Matchers {
+		l[i] = m.String()
+	}
+	return n.Name + "{" + strings.Join(l, ", ") + "}"
+}
+
+func (n *MatrixSelector) String() string {
+	return n.Vector.selectorString() + "[" + FormatDuration(n.Range) + "]" +
+		n.Vector.At.String() + offsetString(n.Vector.Offset)
+}
+
+func (n *SubqueryExpr) String() string {
+	var step string
+	if n.Step > 0 {
+		step = FormatDuration(n.Step)
+	}
+	return n.Expr.String() + "[" + FormatDuration(n.Range) + ":" + step + "]" +
+		n.At.String() + offsetString(n.Offset)
+}
+
+func (n *ParenExpr) String() string {
+	return "(" + n.Expr.String() + ")"
+}
+
+func (n *UnaryExpr) String() string {
+	return n.Op + n.Expr.String()
+}
+
+func (n *BinaryExpr) String() string {
+	op := n.Op
+	if n.ReturnBool {
+		op += " bool"
+	}
+	if m := n.Matching; m != nil {
+		if m.On {
+			op += " on (" + strings.Join(m.Labels, ", ") + ")"
+		} else if len(m.Labels) > 0 || m.Card != "" {
+			op += " ignoring (" + strings.Join(m.Labels, ", ") + ")"
+		}
+		if m.Card != "" {
+			op += " " + m.Card + " (" + strings.Join(m.Include, ", ") + ")"
+		}
+	}
+	return n.LHS.String() + " " + op + " " + n.RHS.String()
+}
+
+func (n *AggregateExpr) String() string {
+	s := n.Op
+	if n.Without {
+		s += " without (" + strings.Join(n.Grouping, ", ") + ") "
+	} else if len(n.Grouping) > 0 {
+		s += " by (" + strings.Join(n.Grouping, ", ") + ") "
+	}
+	if n.Param != nil {
+		return s + "(" + n.Param.String() + ", " + n.Expr.String() + ")"
+	}
+	return s + "(" + n.Expr.String() + ")"
+}
+
+func (n *Call) String() string {
+	l := make([]string, len(n.Args))
+	for i, a := range n.Args {
+		l[i] = a.String()
+	}
+	return n.Func + "(" + strings.Join(l, ", ") + ")"
+}
+
+// Inspect traverses the expression depth-first, calling f for each node
+func Inspect(e Expr, f func(Expr)) {
+	if e == nil {
+		return
+	}
+	f(e)
+	switch n := e.(type) {
+	case *MatrixSelector:
+		Inspect(n.Vector, f)
+	case *SubqueryExpr:
+		Inspect(n.Expr, f)
+	case *ParenExpr:
+		Inspect(n.Expr, f)
+	case *UnaryExpr:
+		Inspect(n.Expr, f)
+	case *BinaryExpr:
+		Inspect(n.LHS, f)
+		Inspect(n.RHS, f)
+	case *AggregateExpr:
+		Inspect(n.Param, f)
+		Inspect(n.Expr, f)
+	case *Call:
+		for _, a := range n.Args {
+			Inspect(a, f)
+		}
+	}
+}
+
+// normalizeMatchers sorts and de-duplicates the label matchers, and moves
+// a lone __name__ equality matcher into the selector's Name
+func (n *VectorSelector) normalizeMatchers() {
+	if n.Name == "" {
+		for i, m := range n.Matchers {
+			if m.Name == labelMetricName && m.Type == MatchEqual && isMetricName(m.Value) {
+				n.Name = m.Value
+				n.Matchers = append(n.Matchers[:i:i], n.Matchers[i+1:]...)
+				break
+			}
+		}
+	}
+	sort.Slice(n.Matchers, func(i, j int) bool {
+		a, b := n.Matchers[i], n.Matchers[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Value < b.Value
+	})
+	out := n.Matchers[:0]
+	for i, m := range n.Matchers {
+		if i > 0 && *m == *n.Matchers[i-1] {
+			continue
+		}
+		out = append(out, m)
+	}
+	n.Matchers = out
+}
+
+func isMetricName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(i > 0 && r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatDuration renders d in PromQL duration syntax (e.g., 1h30m)
+func FormatDuration(d time.Duration) string {
+	ms := int64(d / time.Millisecond)
+	if ms == 0 {
+		return "0s"
+	}
+	var sb strings.Builder
+	for _, u := range durationUnits {
+		if v := ms / u.ms; v > 0 {
+			sb.WriteString(strconv.FormatInt(v, 10))
+			sb.WriteString(u.name)
+			ms -= v * u.ms
+		}
+	}
+	return sb.String()
+}
+
+var durationUnits = []struct {
+	name string
+	ms   int64
+}{
+	{"y", 365 * 24 * 60 * 60 * 1000},
+	{"w", 7 * 24 * 60 * 60 * 1000},
+	{"d", 24 * 60 * 60 * 1000},
+	{"h", 60 * 60 * 1000},
+	{"m", 60 * 1000},
+	{"s", 1000},
+	{"ms", 1},
+}
@@ -0,0 +1,350 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package promql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type itemType int
+
+const (
+	itemEOF itemType = iota
+	itemIdentifier
+	itemMetricIdentifier
+	itemNumber
+	itemDuration
+	itemString
+	itemLeftParen
+	itemRightParen
+	itemLeftBrace
+	itemRightBrace
+	itemLeftBracket
+	itemRightBracket
+	itemComma
+	itemColon
+	itemAt
+	itemAssign // = in a label matcher
+	itemOperator
+)
+
+// item is a single lexed token in a PromQL statement
+type item struct {
+	typ itemType
+	pos int
+	val string
+}
+
+func (i item) String() string {
+	if i.typ == itemEOF {
+		return "EOF"
+	}
+	return fmt.Sprintf("%q", i.val)
+}
+
+// operators lists the PromQL symbolic operators, longest first so that
+// prefixes like = and ! do not shadow the multi-character forms
+var operators = []string{"==", "!=", ">=", "<=", "=~", "!~", "+", "-", "*", "/", "%", "^", ">", "<"}
+
+// keywordOperators are the binary operators that are spelled as words
+var keywordOperators = map[string]bool{
+	"and":    true,
+	"or":     true,
+	"unless": true,
+	"atan2":  true,
+}
+
+// lexer splits a PromQL statement into items and collects its comments
+type lexer struct {
+	input    string
+	pos      int
+	items    []item
+	comments []string
+	// braceOpen and bracketOpen track the context needed to distinguish
+	// label matchers from comparisons and subquery steps from metric names
+	braceOpen   bool
+	bracketOpen bool
+}
+
+func lex(input string) ([]item, []string, error) {
+	l := &lexer{input: input}
+	for {
+		it, err := l.next()
+		if err != nil {
+			return nil, nil, err
+		}
+		l.items = append(l.items, it)
+		if it.typ == itemEOF {
+			return l.items, l.comments, nil
+		}
+	}
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return &ParseError{Pos: l.pos, Err: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) peek() rune {
+	if l.pos >= len(l.input) {
+		return -1
+	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.pos:])
+	return r
+}
+
+func (l *lexer) skipSpaceAndComments() {
+	for l.pos < len(l.input) {
+		r, w := utf8.DecodeRuneInString(l.input[l.pos:])
+		switch {
+		case unicode.IsSpace(r):
+			l.pos += w
+		case r == '#':
+			end := strings.IndexAny(l.input[l.pos:], "\r\n")
+			if end < 0 {
+				end = len(l.input) - l.pos
+			}
+			l.comments = append(l.comments, strings.TrimSpace(l.input[l.pos+1:l.pos+end]))
+			l.pos += end
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (item, error) {
+
+	l.skipSpaceAndComments()
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return item{typ: itemEOF, pos: start}, nil
+	}
+
+	r := l.peek()
+	emit := func(t itemType, w int) (item, error) {
+		l.pos += w
+		return item{typ: t, pos: start, val: l.input[start:l.pos]}, nil
+	}
+
+	switch {
+	case r == '(':
+		return emit(itemLeftParen, 1)
+	case r == ')':
+		return emit(itemRightParen, 1)
+	case r == '{':
+		if l.braceOpen {
+			return item{}, l.errorf("unexpected left brace")
+		}
+		l.braceOpen = true
+		return emit(itemLeftBrace, 1)
+	case r == '}':
+		if !l.braceOpen {
+			return item{}, l.errorf("unexpected right brace")
+		}
+		l.braceOpen = false
+		return emit(itemRightBrace, 1)
+	case r == '[':
+		if l.bracketOpen {
+			return item{}, l.errorf("unexpected left bracket")
+		}
+		l.bracketOpen = true
+		return emit(itemLeftBracket, 1)
+	case r == ']':
+		if !l.bracketOpen {
+			return item{}, l.errorf("unexpected right bracket")
+		}
+		l.bracketOpen = false
+		return emit(itemRightBracket, 1)
+	case r == ',':
+		return emit(itemComma, 1)
+	case r == ':' && l.bracketOpen:
+		return emit(itemColon, 1)
+	case r == '@':
+		return emit(itemAt, 1)
+	case r == '"' || r == '\'' || r == '`':
+		return l.lexString(r)
+	case r == '.' || (r >= '0' && r <= '9'):
+		return l.lexNumberOrDuration()
+	case r == '_' || r == ':' || unicode.IsLetter(r):
+		return l.lexIdentifier()
+	}
+
+	if l.braceOpen && r == '=' && !strings.HasPrefix(l.input[l.pos:], "==") &&
+		!strings.HasPrefix(l.input[l.pos:], "=~") {
+		return emit(itemAssign, 1)
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(l.input[l.pos:], op) {
+			return emit(itemOperator, len(op))
+		}
+	}
+
+	return item{}, l.errorf("unexpected character %q", r)
+}
+
+func (l *lexer) lexString(quote rune) (item, error) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == '\\' && quote != '`':
+			l.pos += 2
+			continue
+		case rune(c) == quote:
+			l.pos++
+			return item{typ: itemString, pos: start, val: l.input[start:l.pos]}, nil
+		case (c == '\n' || c == '\r') && quote != '`':
+			return item{}, l.errorf("unterminated quoted string")
+		}
+		l.pos++
+	}
+	return item{}, l.errorf("unterminated quoted string")
+}
+
+// lexNumberOrDuration scans a number or, when the digits are followed
+// by a time unit, a duration like 5m or 1h30m
+func (l *lexer) lexNumberOrDuration() (item, error) {
+	start := l.pos
+	if d, ok := l.scanDuration(); ok {
+		l.pos += d
+		return item{typ: itemDuration, pos: start, val: l.input[start:l.pos]}, nil
+	}
+	n := scanNumber(l.input[l.pos:])
+	if n == 0 {
+		return item{}, l.errorf("bad number or duration syntax")
+	}
+	l.pos += n
+	if l.pos < len(l.input) {
+		if r, _ := utf8.DecodeRuneInString(l.input[l.pos:]); r == '_' || unicode.IsLetter(r) {
+			return item{}, l.errorf("bad number or duration syntax: %q",
+				l.input[start:l.pos+1])
+		}
+	}
+	return item{typ: itemNumber, pos: start, val: l.input[start:l.pos]}, nil
+}
+
+// scanDuration returns the length of the duration at the lexer's position
+func (l *lexer) scanDuration() (int, bool) {
+	s := l.input[l.pos:]
+	i := 0
+	units := 0
+	for i < len(s) {
+		j := i
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		if j == i {
+			break
+		}
+		u := durationUnitLen(s[j:])
+		if u == 0 {
+			break
+		}
+		i = j + u
+		units++
+	}
+	if units == 0 {
+		return 0, false
+	}
+	if i < len(s) {
+		if r, _ := utf8.DecodeRuneInString(s[i:]); r == '_' || r == '.' ||
+			unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return 0, false
+		}
+	}
+	return i, true
+}
+
+func durationUnitLen(s string) int {
+	if strings.HasPrefix(s, "ms") {
+		return 2
+	}
+	if len(s) > 0 && strings.IndexByte("smhdwy", s[0]) >= 0 {
+		return 1
+	}
+	return 0
+}
+
+// scanNumber returns the length of the decimal, hexadecimal or
+// scientific notation number at the start of s
+func scanNumber(s string) int {
+	i := 0
+	if len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		i = 2
+		for i < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[i]) >= 0 {
+			i++
+		}
+		if i == 2 {
+			return 0
+		}
+		return i
+	}
+	digits := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		k := j
+		for k < len(s) && s[k] >= '0' && s[k] <= '9' {
+			k++
+		}
+		if k > j {
+			i = k
+		}
+	}
+	return i
+}
+
+func (l *lexer) lexIdentifier() (item, error) {
+	start := l.pos
+	colon := false
+	for l.pos < len(l.input) {
+		r, w := utf8.DecodeRuneInString(l.input[l.pos:])
+		if r == ':' {
+			colon = true
+		} else if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		l.pos += w
+	}
+	val := l.input[start:l.pos]
+	if keywordOperators[strings.ToLower(val)] {
+		return item{typ: itemOperator, pos: start, val: val}, nil
+	}
+	if colon {
+		return item{typ: itemMetricIdentifier, pos: start, val: val}, nil
+	}
+	return item{typ: itemIdentifier, pos: start, val: val}, nil
+}
@@ -0,0 +1,703 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package promql provides a parser for the Prometheus Query Language that
+// produces a normalized syntax tree suitable for cache key derivation
+package promql
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const labelMetricName = "__name__"
+
+// ParseError describes a failure to parse a PromQL statement
+type ParseError struct {
+	Pos int
+	Err string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("promql parse error at position %d: %s", e.Pos, e.Err)
+}
+
+// Statement is a parsed PromQL statement
+type Statement struct {
+	// Expr is the root of the expression tree
+	Expr Expr
+	// Comments holds the text of each # comment in the statement
+	Comments []string
+}
+
+// String returns the normalized form of the statement, without comments
+func (s *Statement) String() string {
+	return s.Expr.String()
+}
+
+// HasOffset returns true if any selector or subquery uses an offset modifier
+func (s *Statement) HasOffset() bool {
+	return s.any(func(o time.Duration, _ *AtModifier) bool { return o != 0 })
+}
+
+// HasNegativeOffset returns true if any selector or subquery uses a negative offset,
+// and therefore reads data from after each evaluation timestamp
+func (s *Statement) HasNegativeOffset() bool {
+	return s.any(func(o time.Duration, _ *AtModifier) bool { return o < 0 })
+}
+
+// HasAtModifier returns true if any selector or subquery uses an @ modifier
+func (s *Statement) HasAtModifier() bool {
+	return s.any(func(_ time.Duration, a *AtModifier) bool { return a != nil })
+}
+
+func (s *Statement) any(f func(time.Duration, *AtModifier) bool) bool {
+	var found bool
+	Inspect(s.Expr, func(e Expr) {
+		switch n := e.(type) {
+		case *VectorSelector:
+			found = found || f(n.Offset, n.At)
+		case *SubqueryExpr:
+			found = found || f(n.Offset, n.At)
+		}
+	})
+	return found
+}
+
+// aggregators lists the PromQL aggregation operators, and whether each
+// takes a parameter ahead of the aggregated expression
+var aggregators = map[string]bool{
+	"avg":          false,
+	"bottomk":      true,
+	"count":        false,
+	"count_values": true,
+	"group":        false,
+	"limitk":       true,
+	"limit_ratio":  true,
+	"max":          false,
+	"min":          false,
+	"quantile":     true,
+	"stddev":       false,
+	"stdvar":       false,
+	"sum":          false,
+	"topk":         true,
+}
+
+// precedence returns the binding strength of a binary operator, or 0 if
+// op is not a binary operator
+func precedence(op string) int {
+	switch op {
+	case "or":
+		return 1
+	case "and", "unless":
+		return 2
+	case "==", "!=", ">=", "<=", ">", "<":
+		return 3
+	case "+", "-":
+		return 4
+	case "*", "/", "%", "atan2":
+		return 5
+	case "^":
+		return 6
+	}
+	return 0
+}
+
+func isComparison(op string) bool {
+	return precedence(op) == 3
+}
+
+func isSetOperator(op string) bool {
+	return op == "and" || op == "or" || op == "unless"
+}
+
+type parser struct {
+	items []item
+	pos   int
+}
+
+// Parse parses a PromQL statement into a Statement
+func Parse(input string) (*Statement, error) {
+	items, comments, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{items: items}
+	e, err := p.parseExpr(1)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != itemEOF {
+		return nil, p.unexpected(t)
+	}
+	return &Statement{Expr: e, Comments: comments}, nil
+}
+
+func (p *parser) peek() item {
+	return p.items[p.pos]
+}
+
+func (p *parser) next() item {
+	t := p.items[p.pos]
+	if t.typ != itemEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(typ itemType, context string) (item, error) {
+	t := p.next()
+	if t.typ != typ {
+		return t, &ParseError{Pos: t.pos, Err: fmt.Sprintf("unexpected %s in %s", t, context)}
+	}
+	return t, nil
+}
+
+func (p *parser) unexpected(t item) error {
+	return &ParseError{Pos: t.pos, Err: fmt.Sprintf("unexpected %s", t)}
+}
+
+// isKeyword reports whether the next item is an identifier matching kw
+func (p *parser) isKeyword(kw string) bool {
+	t := p.peek()
+	return (t.typ == itemIdentifier || t.typ == itemOperator) && strings.EqualFold(t.val, kw)
+}
+
+func (p *parser) parseExpr(minPrec int) (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.typ != itemOperator {
+			return lhs, nil
+		}
+		op := strings.ToLower(t.val)
+		prec := precedence(op)
+		if prec < minPrec {
+			return lhs, nil
+		}
+		p.next()
+		b := &BinaryExpr{Op: op, LHS: lhs}
+		if err := p.parseBinaryModifiers(b); err != nil {
+			return nil, err
+		}
+		next := prec + 1
+		if op == "^" {
+			next = prec // right associative
+		}
+		if b.RHS, err = p.parseExpr(next); err != nil {
+			return nil, err
+		}
+		lhs = b
+	}
+}
+
+func (p *parser) parseBinaryModifiers(b *BinaryExpr) error {
+	if p.isKeyword("bool") {
+		if !isComparison(b.Op) {
+			return &ParseError{Pos: p.peek().pos, Err: "bool modifier can only be used on comparison operators"}
+		}
+		p.next()
+		b.ReturnBool = true
+	}
+	if p.isKeyword("on") || p.isKeyword("ignoring") {
+		on := strings.EqualFold(p.next().val, "on")
+		labels, err := p.parseLabelList()
+		if err != nil {
+			return err
+		}
+		if on || len(labels) > 0 {
+			b.Matching = &VectorMatching{On: on, Labels: labels}
+		}
+		if p.isKeyword("group_left") || p.isKeyword("group_right") {
+			t := p.next()
+			if isSetOperator(b.Op) {
+				return &ParseError{Pos: t.pos, Err: "no grouping allowed for set operations"}
+			}
+			if b.Matching == nil {
+				b.Matching = &VectorMatching{}
+			}
+			b.Matching.Card = strings.ToLower(t.val)
+			b.Matching.Include = []string{}
+			if p.peek().typ == itemLeftParen {
+				if b.Matching.Include, err = p.parseLabelList(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseLabelList parses a parenthesized, comma-separated list of label
+// names and returns it sorted
+func (p *parser) parseLabelList() ([]string, error) {
+	if _, err := p.expect(itemLeftParen, "label list"); err != nil {
+		return nil, err
+	}
+	labels := []string{}
+	for {
+		t := p.next()
+		switch {
+		case t.typ == itemRightParen:
+			sort.Strings(labels)
+			return labels, nil
+		case t.typ == itemIdentifier || (t.typ == itemOperator && keywordOperators[strings.ToLower(t.val)]):
+			labels = append(labels, t.val)
+		default:
+			return nil, &ParseError{Pos: t.pos, Err: fmt.Sprintf("unexpected %s in label list", t)}
+		}
+		switch t = p.next(); t.typ {
+		case itemComma:
+		case itemRightParen:
+			sort.Strings(labels)
+			return labels, nil
+		default:
+			return nil, &ParseError{Pos: t.pos, Err: fmt.Sprintf("unexpected %s in label list", t)}
+		}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t := p.peek()
+	if t.typ == itemOperator && (t.val == "-" || t.val == "+") {
+		p.next()
+		// unary operators bind more tightly than all binary operators but ^
+		e, err := p.parseExpr(precedence("^"))
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := e.(*NumberLiteral); ok {
+			if t.val == "-" {
+				n.Val = -n.Val
+			}
+			return n, nil
+		}
+		return &UnaryExpr{Op: t.val, Expr: e}, nil
+	}
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return p.parsePostfix(e)
+}
+
+// parsePostfix applies any range, subquery, offset and @ modifiers that
+// follow an expression
+func (p *parser) parsePostfix(e Expr) (Expr, error) {
+	for {
+		t := p.peek()
+		switch {
+		case t.typ == itemLeftBracket:
+			p.next()
+			rng, err := p.parseDuration()
+			if err != nil {
+				return nil, err
+			}
+			if p.peek().typ == itemRightBracket {
+				p.next()
+				vs, ok := e.(*VectorSelector)
+				if !ok || vs.Offset != 0 || vs.At != nil {
+					return nil, &ParseError{Pos: t.pos,
+						Err: "ranges only allowed for vector selectors"}
+				}
+				e = &MatrixSelector{Vector: vs, Range: rng}
+				continue
+			}
+			if _, err := p.expect(itemColon, "subquery"); err != nil {
+				return nil, err
+			}
+			sq := &SubqueryExpr{Expr: e, Range: rng}
+			if p.peek().typ != itemRightBracket {
+				if sq.Step, err = p.parseDuration(); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := p.expect(itemRightBracket, "subquery"); err != nil {
+				return nil, err
+			}
+			e = sq
+		case p.isKeyword("offset"):
+			p.next()
+			neg := false
+			if n := p.peek(); n.typ == itemOperator && (n.val == "-" || n.val == "+") {
+				neg = n.val == "-"
+				p.next()
+			}
+			d, err := p.parseDuration()
+			if err != nil {
+				return nil, err
+			}
+			if neg {
+				d = -d
+			}
+			off := offsetTarget(e)
+			if off == nil {
+				return nil, &ParseError{Pos: t.pos, Err: "offset modifier must be preceded by " +
+					"an instant vector selector or range vector selector or a subquery"}
+			}
+			if *off != 0 {
+				return nil, &ParseError{Pos: t.pos, Err: "offset may not be set multiple times"}
+			}
+			*off = d
+		case t.typ == itemAt:
+			p.next()
+			at, err := p.parseAt()
+			if err != nil {
+				return nil, err
+			}
+			target := atTarget(e)
+			if target == nil {
+				return nil, &ParseError{Pos: t.pos, Err: "@ modifier must be preceded by " +
+					"an instant vector selector or range vector selector or a subquery"}
+			}
+			if *target != nil {
+				return nil, &ParseError{Pos: t.pos, Err: "@ <timestamp> may not be set multiple times"}
+			}
+			*target = at
+		default:
+			return e, nil
+		}
+	}
+}
+
+func offsetTarget(e Expr) *time.Duration {
+	switch n := e.(type) {
+	case *VectorSelector:
+		return &n.Offset
+	case *MatrixSelector:
+		return &n.Vector.Offset
+	case *SubqueryExpr:
+		return &n.Offset
+	}
+	return nil
+}
+
+func atTarget(e Expr) **AtModifier {
+	switch n := e.(type) {
+	case *VectorSelector:
+		return &n.At
+	case *MatrixSelector:
+		return &n.Vector.At
+	case *SubqueryExpr:
+		return &n.At
+	}
+	return nil
+}
+
+func (p *parser) parseAt() (*AtModifier, error) {
+	t := p.next()
+	switch {
+	case t.typ == itemIdentifier && (t.val == "start" || t.val == "end"):
+		if _, err := p.expect(itemLeftParen, "@ modifier"); err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(itemRightParen, "@ modifier"); err != nil {
+			return nil, err
+		}
+		return &AtModifier{Preprocessor: t.val}, nil
+	case t.typ == itemOperator && (t.val == "-" || t.val == "+"):
+		n, err := p.expect(itemNumber, "@ modifier")
+		if err != nil {
+			return nil, err
+		}
+		v, err := parseNumber(n.val)
+		if err != nil {
+			return nil, &ParseError{Pos: n.pos, Err: err.Error()}
+		}
+		if t.val == "-" {
+			v = -v
+		}
+		return &AtModifier{Timestamp: v}, nil
+	case t.typ == itemNumber:
+		v, err := parseNumber(t.val)
+		if err != nil {
+			return nil, &ParseError{Pos: t.pos, Err: err.Error()}
+		}
+		return &AtModifier{Timestamp: v}, nil
+	}
+	return nil, &ParseError{Pos: t.pos, Err: fmt.Sprintf("unexpected %s in @ modifier", t)}
+}
+
+// parseDuration parses a duration, or a number of seconds, as used by
+// ranges, subquery steps and offsets
+func (p *parser) parseDuration() (time.Duration, error) {
+	t := p.next()
+	switch t.typ {
+	case itemDuration:
+		d, err := ParseDuration(t.val)
+		if err != nil {
+			return 0, &ParseError{Pos: t.pos, Err: err.Error()}
+		}
+		return d, nil
+	case itemNumber:
+		v, err := parseNumber(t.val)
+		if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return 0, &ParseError{Pos: t.pos, Err: fmt.Sprintf("invalid duration %s", t.val)}
+		}
+		return time.Duration(v * float64(time.Second)), nil
+	}
+	return 0, &ParseError{Pos: t.pos, Err: fmt.Sprintf("unexpected %s, expected duration", t)}
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch t.typ {
+	case itemNumber:
+		v, err := parseNumber(t.val)
+		if err != nil {
+			return nil, &ParseError{Pos: t.pos, Err: err.Error()}
+		}
+		return &NumberLiteral{Val: v}, nil
+	case itemString:
+		s, err := unquote(t.val)
+		if err != nil {
+			return nil, &ParseError{Pos: t.pos, Err: err.Error()}
+		}
+		return &StringLiteral{Val: s}, nil
+	case itemLeftParen:
+		e, err := p.parseExpr(1)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(itemRightParen, "paren expression"); err != nil {
+			return nil, err
+		}
+		return &ParenExpr{Expr: e}, nil
+	case itemLeftBrace:
+		return p.parseVectorSelector("")
+	case itemMetricIdentifier:
+		return p.parseVectorSelector(t.val)
+	case itemIdentifier:
+		lower := strings.ToLower(t.val)
+		if lower == "inf" || lower == "nan" {
+			v, _ := strconv.ParseFloat(lower, 64)
+			return &NumberLiteral{Val: v}, nil
+		}
+		if _, ok := aggregators[lower]; ok && (p.peek().typ == itemLeftParen ||
+			p.isKeyword("by") || p.isKeyword("without")) {
+			return p.parseAggregate(lower)
+		}
+		if p.peek().typ == itemLeftParen {
+			return p.parseCall(t.val)
+		}
+		return p.parseVectorSelector(t.val)
+	}
+	return nil, p.unexpected(t)
+}
+
+func (p *parser) parseCall(name string) (Expr, error) {
+	if _, err := p.expect(itemLeftParen, "call to "+name); err != nil {
+		return nil, err
+	}
+	c := &Call{Func: name, Args: []Expr{}}
+	if p.peek().typ == itemRightParen {
+		p.next()
+		return c, nil
+	}
+	for {
+		e, err := p.parseExpr(1)
+		if err != nil {
+			return nil, err
+		}
+		c.Args = append(c.Args, e)
+		t := p.next()
+		switch t.typ {
+		case itemComma:
+			continue
+		case itemRightParen:
+			return c, nil
+		}
+		return nil, &ParseError{Pos: t.pos, Err: fmt.Sprintf("unexpected %s in call to %s", t, name)}
+	}
+}
+
+func (p *parser) parseAggregate(op string) (Expr, error) {
+	a := &AggregateExpr{Op: op}
+	var grouped bool
+	parseGrouping := func() error {
+		if !(p.isKeyword("by") || p.isKeyword("without")) {
+			return nil
+		}
+		t := p.next()
+		if grouped {
+			return &ParseError{Pos: t.pos, Err: "aggregation may not have multiple groupings"}
+		}
+		grouped = true
+		a.Without = strings.EqualFold(t.val, "without")
+		var err error
+		a.Grouping, err = p.parseLabelList()
+		return err
+	}
+	if err := parseGrouping(); err != nil {
+		return nil, err
+	}
+	c, err := p.parseCall(op)
+	if err != nil {
+		return nil, err
+	}
+	args := c.(*Call).Args
+	want := 1
+	if aggregators[op] {
+		want = 2
+	}
+	if len(args) != want {
+		return nil, &ParseError{Pos: p.peek().pos,
+			Err: fmt.Sprintf("wrong number of arguments for aggregate expression provided, expected %d, got %d",
+				want, len(args))}
+	}
+	if want == 2 {
+		a.Param = args[0]
+	}
+	a.Expr = args[want-1]
+	if err := parseGrouping(); err != nil {
+		return nil, err
+	}
+	if !a.Without && len(a.Grouping) == 0 {
+		a.Grouping = nil
+	}
+	return a, nil
+}
+
+func (p *parser) parseVectorSelector(name string) (Expr, error) {
+	vs := &VectorSelector{Name: name}
+	if name != "" {
+		if p.peek().typ != itemLeftBrace {
+			return vs, nil
+		}
+		p.next()
+	}
+	for {
+		t := p.next()
+		if t.typ == itemRightBrace {
+			break
+		}
+		if t.typ != itemIdentifier && !(t.typ == itemOperator && keywordOperators[strings.ToLower(t.val)]) {
+			return nil, &ParseError{Pos: t.pos, Err: fmt.Sprintf("unexpected %s in label matching", t)}
+		}
+		m := &LabelMatcher{Name: t.val}
+		op := p.next()
+		switch {
+		case op.typ == itemAssign:
+			m.Type = MatchEqual
+		case op.typ == itemOperator && (op.val == "!=" || op.val == "=~" || op.val == "!~"):
+			m.Type = MatchType(op.val)
+		default:
+			return nil, &ParseError{Pos: op.pos, Err: fmt.Sprintf("unexpected %s in label matching, "+
+				"expected label matching operator", op)}
+		}
+		v, err := p.expect(itemString, "label matching")
+		if err != nil {
+			return nil, err
+		}
+		if m.Value, err = unquote(v.val); err != nil {
+			return nil, &ParseError{Pos: v.pos, Err: err.Error()}
+		}
+		if m.Name == labelMetricName && m.Type == MatchEqual && vs.Name != "" {
+			return nil, &ParseError{Pos: t.pos, Err: "metric name must not be set twice"}
+		}
+		vs.Matchers = append(vs.Matchers, m)
+		t = p.next()
+		if t.typ == itemRightBrace {
+			break
+		}
+		if t.typ != itemComma {
+			return nil, &ParseError{Pos: t.pos, Err: fmt.Sprintf("unexpected %s in label matching", t)}
+		}
+	}
+	vs.normalizeMatchers()
+	return vs, nil
+}
+
+func parseNumber(s string) (float64, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		i, err := strconv.ParseInt(s, 0, 64)
+		return float64(i), err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// unquote returns the value of a double, single or back-quoted string
+func unquote(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '\'' {
+		// convert to a double-quoted string so strconv can handle escapes
+		var sb strings.Builder
+		sb.WriteByte('"')
+		for i := 1; i < len(s)-1; i++ {
+			switch {
+			case s[i] == '\\' && i+1 < len(s)-1 && s[i+1] == '\'':
+				sb.WriteByte('\'')
+				i++
+			case s[i] == '\\' && i+1 < len(s)-1:
+				sb.WriteString(s[i : i+2])
+				i++
+			case s[i] == '"':
+				sb.WriteString(`\"`)
+			default:
+				sb.WriteByte(s[i])
+			}
+		}
+		sb.WriteByte('"')
+		s = sb.String()
+	}
+	return strconv.Unquote(s)
+}
+
+// ParseDuration parses a PromQL duration string like 5m or 1h30m
+func ParseDuration(s string) (time.Duration, error) {
+	var d time.Duration
+	rest := s
+	for rest != "" {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		u := durationUnitLen(rest[i:])
+		if i == 0 || u == 0 {
+			return 0, fmt.Errorf("not a valid duration string: %q", s)
+		}
+		n, err := strconv.ParseInt(rest[:i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("not a valid duration string: %q", s)
+		}
+		var unit time.Duration
+		switch rest[i : i+u] {
+		case "ms":
+			unit = time.Millisecond
+		case "s":
+			unit = time.Second
+		case "m":
+			unit = time.Minute
+		case "h":
+			unit = time.Hour
+		case "d":
+			unit = 24 * time.Hour
+		case "w":
+			unit = 7 * 24 * time.Hour
+		case "y":
+			unit = 365 * 24 * time.Hour
+		}
+		d += time.Duration(n) * unit
+		rest = rest[i+u:]
+	}
+	return d, nil
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package promql
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`up`, `up`},
+		{` up{ job = "prometheus" , instance=~'local.*' } `,
+			`up{instance=~"local.*", job="prometheus"}`},
+		{`{__name__="up",job="a"}`, `up{job="a"}`},
+		{`{__name__=~"up|down"}`, `{__name__=~"up|down"}`},
+		{`up{job="a",job="a",}`, `up{job="a"}`},
+		{`rate(http_requests_total{code="200"}[5m])`, `rate(http_requests_total{code="200"}[5m])`},
+		{`rate(x[90m])`, `rate(x[1h30m])`},
+		{`rate(x[300])`, `rate(x[5m])`},
+		{`up offset 5m`, `up offset 5m`},
+		{`up offset -5m`, `up offset -5m`},
+		{`up offset-5m`, `up offset -5m`},
+		{`rate(x[5m]offset 1h)`, `rate(x[5m] offset 1h)`},
+		{`up @ start()`, `up @ start()`},
+		{`up offset 1m @ 1609746000`, `up @ 1609746000.000 offset 1m`},
+		{`max_over_time(rate(x[1m])[1h:5m])`, `max_over_time(rate(x[1m])[1h:5m])`},
+		{`max_over_time(rate(x[1m])[1h:] offset 1d)`, `max_over_time(rate(x[1m])[1h:] offset 1d)`},
+		{`sum by (b, a) (rate(x[5m]))`, `sum by (a, b) (rate(x[5m]))`},
+		{`sum(rate(x[5m])) by (job)`, `sum by (job) (rate(x[5m]))`},
+		{`sum by () (x)`, `sum(x)`},
+		{`sum without () (x)`, `sum without () (x)`},
+		{`topk(5, x) without (a)`, `topk without (a) (5, x)`},
+		{`count_values("v", x)`, `count_values("v", x)`},
+		{`a + b * c`, `a + b * c`},
+		{`(a + b) * c`, `(a + b) * c`},
+		{`-a ^ 2`, `-a ^ 2`},
+		{`2 ^ 3 ^ 2`, `2 ^ 3 ^ 2`},
+		{`-1.5e3`, `-1500`},
+		{`0x1F`, `31`},
+		{`+Inf`, `+Inf`},
+		{`a > bool 5`, `a > bool 5`},
+		{`a AND b`, `a and b`},
+		{`a / on(z, y) group_left(c) b`, `a / on (y, z) group_left (c) b`},
+		{`a * ignoring() group_right b`, `a * ignoring () group_right () b`},
+		{`a or ignoring() b`, `a or b`},
+		{"up # trickster-fast-forward:off", `up`},
+		{"sum(\n  up # comment\n)", `sum(up)`},
+		{`absent(nonexistent{job="myjob", instance=~".*"})`, `absent(nonexistent{instance=~".*", job="myjob"})`},
+		{`label_replace(up, "foo", "$1", "service", "(.*):.*")`,
+			`label_replace(up, "foo", "$1", "service", "(.*):.*")`},
+		{`time()`, `time()`},
+		{`job:request_latency_seconds:mean5m{job="a"}`, `job:request_latency_seconds:mean5m{job="a"}`},
+		{`up{and="x"}`, `up{and="x"}`},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := Parse(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.String() != test.expected {
+				t.Errorf("expected %s got %s", test.expected, s.String())
+			}
+			// the normalized form must itself parse to the same normalized form
+			s2, err := Parse(s.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s2.String() != s.String() {
+				t.Errorf("expected %s got %s", s.String(), s2.String())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	tests := []string{
+		``,
+		`up{`,
+		`up{job="a"`,
+		`up{job=a}`,
+		`up}`,
+		`rate(x[5m]`,
+		`(a + b`,
+		`a +`,
+		`a and group_left b`,
+		`a and on() group_left b`,
+		`a + bool b`,
+		`sum(a, b)`,
+		`topk(x)`,
+		`sum by (a) (x) by (b)`,
+		`sum by (a) x`,
+		`(a + b)[5m]`,
+		`up offset 5m [10m]`,
+		`rate(x[5m]) offset 5m`,
+		`up offset 5m offset 10m`,
+		`up @ 1 @ 2`,
+		`up @ now()`,
+		`up offset x`,
+		`up[5x]`,
+		`"unterminated`,
+		`1abc`,
+		`up $`,
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			_, err := Parse(test)
+			if err == nil {
+				t.Errorf("expected error for %s", test)
+			} else if _, ok := err.(*ParseError); !ok {
+				t.Errorf("expected ParseError got %T", err)
+			}
+		})
+	}
+}
+
+func TestStatementModifiers(t *testing.T) {
+
+	tests := []struct {
+		input                         string
+		offset, negativeOffset, atMod bool
+	}{
+		{`up`, false, false, false},
+		{`up offset 5m`, true, false, false},
+		{`up offset-5m`, true, true, false},
+		{`rate(x[5m] offset -1h)`, true, true, false},
+		{`max_over_time(x[1h:1m] offset 1d)`, true, false, false},
+		{`sum(up @ end())`, false, false, true},
+		{`a / (b @ 1609746000)`, false, false, true},
+		{`label_replace(up, "x", "offset 5m", "y", "@ start()")`, false, false, false},
+		{"up # offset 5m", false, false, false},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := Parse(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.HasOffset() != test.offset {
+				t.Errorf("expected %t got %t", test.offset, s.HasOffset())
+			}
+			if s.HasNegativeOffset() != test.negativeOffset {
+				t.Errorf("expected %t got %t", test.negativeOffset, s.HasNegativeOffset())
+			}
+			if s.HasAtModifier() != test.atMod {
+				t.Errorf("expected %t got %t", test.atMod, s.HasAtModifier())
+			}
+		})
+	}
+}
+
+func TestStatementComments(t *testing.T) {
+	s, err := Parse("sum(up) # trickster-fast-forward:off\n# trickster-backfill-tolerance:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Comments) != 2 || s.Comments[0] != "trickster-fast-forward:off" ||
+		s.Comments[1] != "trickster-backfill-tolerance:30" {
+		t.Errorf("unexpected comments %v", s.Comments)
+	}
+}
+
+func TestDuration(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		expected time.Duration
+		str      string
+	}{
+		{"5m", 5 * time.Minute, "5m"},
+		{"1h30m", 90 * time.Minute, "1h30m"},
+		{"90s", 90 * time.Second, "1m30s"},
+		{"1w2d", 9 * 24 * time.Hour, "1w2d"},
+		{"1y", 365 * 24 * time.Hour, "1y"},
+		{"250ms", 250 * time.Millisecond, "250ms"},
+	}
+
+	for _, test := range tests {
+		d, err := ParseDuration(test.input)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if d != test.expected {
+			t.Errorf("expected %s got %s", test.expected, d)
+		}
+		if s := FormatDuration(d); s != test.str {
+			t.Errorf("expected %s got %s", test.str, s)
+		}
+	}
+
+	if _, err := ParseDuration("5x"); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+	if s := FormatDuration(0); s != "0s" {
+		t.Errorf("expected %s got %s", "0s", s)
+	}
+}
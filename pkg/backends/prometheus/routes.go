@@ -45,7 +45,7 @@ func (c *Client) RegisterHandlers(map[string]http.Handler) {
 
 // MergeablePaths returns the list of Prometheus Paths for which Trickster supports
 // merging multiple documents into a single response
-func MergeablePaths() []string {
+func (c *Client) MergeablePaths() []string {
 	return []string{
 		"/api/v1/query_range",
 		"/api/v1/query",
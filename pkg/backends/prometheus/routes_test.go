@@ -70,7 +70,8 @@ func TestDefaultPathConfigs(t *testing.T) {
 }
 
 func TestMergeablePaths(t *testing.T) {
-	if len(MergeablePaths()) != 6 {
-		t.Errorf("expected %d got %d", 6, len(MergeablePaths()))
+	c := &Client{}
+	if len(c.MergeablePaths()) != 6 {
+		t.Errorf("expected %d got %d", 6, len(c.MergeablePaths()))
 	}
 }
@@ -38,6 +38,10 @@ const (
 	IronDB
 	// ClickHouse represents the ClickHouse backend provider
 	ClickHouse
+	// Elasticsearch represents the Elasticsearch (and OpenSearch) backend provider
+	Elasticsearch
+	// Graphite represents the Graphite backend provider
+	Graphite
 )
 
 // Names is a map of Providers keyed by string name
@@ -50,6 +54,9 @@ var Names = map[string]Provider{
 	"influxdb":          InfluxDB,
 	"irondb":            IronDB,
 	"clickhouse":        ClickHouse,
+	"elasticsearch":     Elasticsearch,
+	"opensearch":        Elasticsearch,
+	"graphite":          Graphite,
 	"proxy":             RP,
 	"reverseproxy":      RP,
 	"rp":                RP,
@@ -62,17 +69,21 @@ func init() {
 	for k, v := range Names {
 		Values[v] = k
 	}
-	// ensure consistent reverse mapping for reverseproxycache as rpc
-	// and "rp" for proxy
+	// ensure consistent reverse mapping for reverseproxycache as rpc,
+	// "rp" for proxy and "elasticsearch" for opensearch
 	Values[RPC] = "rpc"
 	Values[RP] = "rp"
+	Values[Elasticsearch] = "elasticsearch"
 }
 
 var supportedTimeSeries = map[string]Provider{
-	"prometheus": Prometheus,
-	"influxdb":   InfluxDB,
-	"clickhouse": ClickHouse,
-	"irondb":     IronDB,
+	"prometheus":    Prometheus,
+	"influxdb":      InfluxDB,
+	"clickhouse":    ClickHouse,
+	"irondb":        IronDB,
+	"elasticsearch": Elasticsearch,
+	"opensearch":    Elasticsearch,
+	"graphite":      Graphite,
 }
 
 // IsSupportedTimeSeriesProvider returns true if the provided time series is supported by Trickster
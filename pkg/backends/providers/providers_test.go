@@ -53,6 +53,9 @@ func TestIsValidProvider(t *testing.T) {
 		{"invalid", false},
 		{"influxdb", true},
 		{"irondb", true},
+		{"elasticsearch", true},
+		{"opensearch", true},
+		{"graphite", true},
 	}
 
 	for i, test := range tests {
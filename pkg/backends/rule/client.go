@@ -27,6 +27,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	resprewriter "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter"
 )
 
 // Client Implements the Proxy Client Interface
@@ -62,7 +63,7 @@ type Clients []*Client
 // until all backends are processed, so the rule's destination origin names
 // can be mapped to their respective clients
 func ValidateOptions(clients backends.Backends,
-	rwi map[string]rewriter.RewriteInstructions) error {
+	rwi map[string]rewriter.RewriteInstructions, rsw map[string]*resprewriter.Rewriter) error {
 	ruleClients := make(Clients, 0, len(clients))
 	for _, c := range clients {
 		if rc, ok := c.(*Client); ok {
@@ -70,7 +71,7 @@ func ValidateOptions(clients backends.Backends,
 		}
 	}
 	if len(ruleClients) > 0 {
-		if err := ruleClients.validate(rwi); err != nil {
+		if err := ruleClients.validate(rwi, rsw); err != nil {
 			return err
 		}
 	}
@@ -79,14 +80,15 @@ func ValidateOptions(clients backends.Backends,
 
 // Validate will fully load the Clients from their options and return an error if the options
 // could not be validated
-func (rc Clients) validate(rwi map[string]rewriter.RewriteInstructions) error {
+func (rc Clients) validate(rwi map[string]rewriter.RewriteInstructions,
+	rsw map[string]*resprewriter.Rewriter) error {
 	for _, c := range rc {
 		if c == nil {
 			return errors.ErrInvalidRuleOptions
 		}
 		cfg := c.Configuration()
 		if cfg != nil {
-			if err := c.parseOptions(cfg.RuleOptions, rwi); err != nil {
+			if err := c.parseOptions(cfg.RuleOptions, rwi, rsw); err != nil {
 				return err
 			}
 		} else {
@@ -61,7 +61,7 @@ func TestValidate(t *testing.T) {
 		t.Error(err)
 	}
 	c := Clients{backendClient.(*Client)}
-	err = c.validate(nil)
+	err = c.validate(nil, nil)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -72,7 +72,7 @@ func TestValidate(t *testing.T) {
 	}
 
 	c = Clients{}
-	err = c.validate(nil)
+	err = c.validate(nil, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -87,7 +87,7 @@ func TestValidateOptions(t *testing.T) {
 	}
 
 	cl := backends.Backends{"test": backendClient}
-	err = ValidateOptions(cl, nil)
+	err = ValidateOptions(cl, nil, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -92,6 +92,9 @@ type CaseOptions struct {
 	// ReqRewriterName is the name of a configured Rewriter that will modify the request in this case
 	// prior to handing off to the NextRoute
 	ReqRewriterName string `yaml:"req_rewriter_name,omitempty"`
+	// RespRewriterName is the name of a configured Response Rewriter that will modify the
+	// response of the NextRoute in this case, prior to it being sent to the client
+	RespRewriterName string `yaml:"resp_rewriter_name,omitempty"`
 	// NextRoute is the name of the next BackendOptions destination for the request in this case
 	NextRoute string `yaml:"next_route,omitempty"`
 	// RedirectURL provides a URL to redirect the request in this case, rather than
@@ -26,11 +26,13 @@ import (
 	ro "github.com/tricksterproxy/trickster/pkg/backends/rule/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	resprewriter "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter"
 )
 
 var ErrInvalidRegularExpression = errors.New("invalid regular expression")
 
-func (c *Client) parseOptions(o *ro.Options, rwi map[string]rewriter.RewriteInstructions) error {
+func (c *Client) parseOptions(o *ro.Options, rwi map[string]rewriter.RewriteInstructions,
+	rsw map[string]*resprewriter.Rewriter) error {
 
 	name := c.Name()
 
@@ -174,6 +176,16 @@ func (c *Client) parseOptions(o *ro.Options, rwi map[string]rewriter.RewriteInst
 				ri = i
 			}
 
+			var rw *resprewriter.Rewriter
+			if v.RespRewriterName != "" {
+				i, ok := rsw[v.RespRewriterName]
+				if !ok {
+					return fmt.Errorf("invalid response rewriter %s in rule %s case %s",
+						v.RespRewriterName, o.Name, k)
+				}
+				rw = i
+			}
+
 			if v.NextRoute == "" && v.RedirectURL == "" && v.ReqRewriterName == "" {
 				return fmt.Errorf("missing next_route in rule %s case %s", o.Name, k)
 			}
@@ -195,10 +207,15 @@ func (c *Client) parseOptions(o *ro.Options, rwi map[string]rewriter.RewriteInst
 				nr = no.Router()
 			}
 
+			cr := nr
+			if rw != nil {
+				cr = resprewriter.Rewrite(rw, nr)
+			}
+
 			for _, m := range v.Matches {
 				rc := &ruleCase{
 					matchValue:   m,
-					router:       nr,
+					router:       cr,
 					redirectURL:  v.RedirectURL,
 					redirectCode: rc,
 					rewriter:     ri,
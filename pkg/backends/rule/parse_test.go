@@ -17,8 +17,12 @@
 package rule
 
 import (
+	"net/http"
 	"strings"
 	"testing"
+
+	resprewriter "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter"
+	rso "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter/options"
 )
 
 func TestParseOptions(t *testing.T) {
@@ -28,7 +32,7 @@ func TestParseOptions(t *testing.T) {
 		t.Error(err)
 	}
 
-	err = c.parseOptions(nil, nil)
+	err = c.parseOptions(nil, nil, nil)
 	expected := "failed to parse nil options"
 	if err == nil || !strings.Contains(err.Error(), expected) {
 		t.Errorf("expected error for %s", expected)
@@ -39,7 +43,7 @@ func TestParseOptions(t *testing.T) {
 
 	temp := ropts.InputSource
 	ropts.InputSource = ""
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.InputSource = temp
 	expected = "options missing input_source"
 	if err == nil || !strings.Contains(err.Error(), expected) {
@@ -48,7 +52,7 @@ func TestParseOptions(t *testing.T) {
 
 	temp = ropts.InputType
 	ropts.InputType = ""
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.InputType = temp
 	expected = "options missing input_type"
 	if err == nil || !strings.Contains(err.Error(), expected) {
@@ -57,7 +61,7 @@ func TestParseOptions(t *testing.T) {
 
 	temp = ropts.Operation
 	ropts.Operation = ""
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.Operation = temp
 	expected = "options missing operation"
 	if err == nil || !strings.Contains(err.Error(), expected) {
@@ -66,7 +70,7 @@ func TestParseOptions(t *testing.T) {
 
 	temp = ropts.EgressReqRewriterName
 	ropts.EgressReqRewriterName = "x"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.EgressReqRewriterName = temp
 	expected = "invalid egress rewriter"
 	if err == nil || !strings.Contains(err.Error(), expected) {
@@ -75,7 +79,7 @@ func TestParseOptions(t *testing.T) {
 
 	temp = ropts.IngressReqRewriterName
 	ropts.IngressReqRewriterName = "x"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.IngressReqRewriterName = temp
 	expected = "invalid ingress rewriter"
 	if err == nil || !strings.Contains(err.Error(), expected) {
@@ -84,7 +88,7 @@ func TestParseOptions(t *testing.T) {
 
 	temp = ropts.NoMatchReqRewriterName
 	ropts.NoMatchReqRewriterName = "x"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.NoMatchReqRewriterName = temp
 	expected = "invalid default rewriter"
 	if err == nil || !strings.Contains(err.Error(), expected) {
@@ -93,7 +97,7 @@ func TestParseOptions(t *testing.T) {
 
 	temp = ropts.NextRoute
 	ropts.NextRoute = "x"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.NextRoute = temp
 	expected = "invalid default rule route"
 	if err == nil || !strings.Contains(err.Error(), expected) {
@@ -105,7 +109,7 @@ func TestParseOptions(t *testing.T) {
 	temp2 := ropts.NextRoute
 	ropts.NextRoute = ""
 	ropts.RedirectURL = expected
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.RedirectURL = temp
 	ropts.NextRoute = temp2
 	if err != nil {
@@ -118,7 +122,7 @@ func TestParseOptions(t *testing.T) {
 	temp2 = ropts.NextRoute
 	ropts.NextRoute = ""
 	ropts.RedirectURL = ""
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.RedirectURL = temp
 	ropts.NextRoute = temp2
 	expected = "invalid default rule route"
@@ -128,7 +132,7 @@ func TestParseOptions(t *testing.T) {
 
 	temp = ropts.InputSource
 	ropts.InputSource = "something-invalid"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.InputSource = temp
 	expected = "invalid source name"
 	if err == nil || !strings.Contains(err.Error(), expected) {
@@ -139,7 +143,7 @@ func TestParseOptions(t *testing.T) {
 	temp3 := ropts.InputIndex
 	ropts.InputIndex = 1
 	ropts.InputDelimiter = ","
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.InputDelimiter = temp
 	ropts.InputIndex = temp3
 	if err != nil {
@@ -151,7 +155,7 @@ func TestParseOptions(t *testing.T) {
 
 	temp = ropts.InputEncoding
 	ropts.InputEncoding = "base64"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.InputEncoding = temp
 	if err != nil {
 		t.Error(err)
@@ -163,7 +167,7 @@ func TestParseOptions(t *testing.T) {
 	expected = "invalid encoding name"
 	temp = ropts.InputEncoding
 	ropts.InputEncoding = "invalid"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.InputEncoding = temp
 	if err == nil || !strings.Contains(err.Error(), expected) {
 		t.Errorf("expected error for %s", expected)
@@ -171,7 +175,7 @@ func TestParseOptions(t *testing.T) {
 
 	temp = ropts.Operation
 	ropts.Operation = "!eq"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.Operation = temp
 	if err != nil {
 		t.Error(err)
@@ -182,7 +186,7 @@ func TestParseOptions(t *testing.T) {
 	expected = "invalid operation"
 	temp = ropts.Operation
 	ropts.Operation = "invalid"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.Operation = temp
 	if err == nil || !strings.Contains(err.Error(), expected) {
 		t.Errorf("expected error for %s", expected)
@@ -191,7 +195,7 @@ func TestParseOptions(t *testing.T) {
 	expected = "invalid rewriter"
 	temp = ropts.CaseOptions["1"].ReqRewriterName
 	ropts.CaseOptions["1"].ReqRewriterName = "invalid"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.CaseOptions["1"].ReqRewriterName = temp
 	if err == nil || !strings.Contains(err.Error(), expected) {
 		t.Errorf("expected error for %s", expected)
@@ -204,7 +208,7 @@ func TestParseOptions(t *testing.T) {
 	ropts.CaseOptions["1"].ReqRewriterName = ""
 	ropts.CaseOptions["1"].RedirectURL = ""
 	ropts.CaseOptions["1"].NextRoute = ""
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.CaseOptions["1"].ReqRewriterName = temp
 	ropts.CaseOptions["1"].RedirectURL = temp2
 	ropts.CaseOptions["1"].NextRoute = temp4
@@ -215,16 +219,32 @@ func TestParseOptions(t *testing.T) {
 	expected = "missing matches in rule"
 	temp5 := ropts.CaseOptions["1"].Matches
 	ropts.CaseOptions["1"].Matches = []string{}
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.CaseOptions["1"].Matches = temp5
 	if err == nil || !strings.Contains(err.Error(), expected) {
 		t.Errorf("expected error for %s", expected)
 	}
 
+	expected = "invalid response rewriter"
+	ropts.CaseOptions["1"].RespRewriterName = "invalid"
+	err = c.parseOptions(ropts, rwi, nil)
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error for %s", expected)
+	}
+
+	rsw, _ := resprewriter.ProcessConfigs(map[string]*rso.Options{"invalid": {}})
+	err = c.parseOptions(ropts, rwi, rsw)
+	ropts.CaseOptions["1"].RespRewriterName = ""
+	if err != nil {
+		t.Error(err)
+	} else if _, ok := c.rule.cases["trickster"].router.(http.HandlerFunc); !ok {
+		t.Error("expected response rewriter to wrap the case router")
+	}
+
 	expected = "unknown next_route"
 	temp = ropts.CaseOptions["1"].NextRoute
 	ropts.CaseOptions["1"].NextRoute = "invalid"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	ropts.CaseOptions["1"].NextRoute = temp
 	if err == nil || !strings.Contains(err.Error(), expected) {
 		t.Errorf("expected error for %s", expected)
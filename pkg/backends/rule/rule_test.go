@@ -162,7 +162,7 @@ func newTestRules() ([]*rule, error) {
 	rules := make([]*rule, 0, 2)
 
 	// This creates an OpArg test rule
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -170,7 +170,7 @@ func newTestRules() ([]*rule, error) {
 
 	// These modifications create a CaseArg test rule
 	ropts.OperationArg = "trickster"
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -208,7 +208,7 @@ func newTestClient() (*Client, error) {
 	}
 	c := backendClient.(*Client)
 	// This creates an OpArg test rule
-	err = c.parseOptions(ropts, rwi)
+	err = c.parseOptions(ropts, rwi, nil)
 	if err != nil {
 		return nil, err
 	}
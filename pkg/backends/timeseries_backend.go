@@ -20,11 +20,13 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/tricksterproxy/trickster/pkg/backends/breaker"
 	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
 	ho "github.com/tricksterproxy/trickster/pkg/backends/healthcheck/options"
 	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/ratelimit"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
 )
 
@@ -62,6 +64,9 @@ type TimeseriesBackend interface {
 	BaseUpstreamURL() *url.URL
 	// Modeler returns the Modeler for converting between Datasets and wire documents
 	Modeler() *timeseries.Modeler
+	// MergeablePaths returns the list of Paths for which the Backend supports merging
+	// responses from multiple pool members into a single response (ALB tsmerge)
+	MergeablePaths() []string
 	// DefaultHealthCheckConfig returns the default HealthCHeck Config for the given Provider
 	DefaultHealthCheckConfig() *ho.Options
 	// SetHealthCheckProbe sets the Health Check Status Prober for the Client
@@ -70,6 +75,12 @@ type TimeseriesBackend interface {
 	HealthHandler(http.ResponseWriter, *http.Request)
 	// HealthCheckHTTPClient returns the HTTP Client used for Health Checking
 	HealthCheckHTTPClient() *http.Client
+	// SetBreaker sets the Circuit Breaker for the Backend
+	SetBreaker(*breaker.Breaker)
+	// Breaker returns the Circuit Breaker for the Backend, if any
+	Breaker() *breaker.Breaker
+	// Limiter returns the Rate Limiter for the Backend, if any
+	Limiter() *ratelimit.Limiter
 }
 
 var _ TimeseriesBackend = (*timeseriesBackend)(nil)
@@ -105,3 +116,8 @@ func (b *timeseriesBackend) SetExtent(r *http.Request, trq *timeseries.TimeRange
 func (b *timeseriesBackend) Modeler() *timeseries.Modeler {
 	return b.modeler
 }
+
+// MergeablePaths returns no Paths, as time series providers must opt into merging
+func (b *timeseriesBackend) MergeablePaths() []string {
+	return nil
+}
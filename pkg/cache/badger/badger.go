@@ -136,6 +136,35 @@ func (c *Cache) BulkRemove(cacheKeys []string) {
 	})
 }
 
+// RemainingTTL returns the time remaining before the object expires
+func (c *Cache) RemainingTTL(cacheKey string) (time.Duration, bool) {
+	expires, err := c.getExpires(cacheKey)
+	if err == badger.ErrKeyNotFound {
+		return 0, true
+	}
+	if err != nil || expires == 0 {
+		return 0, false
+	}
+	return time.Until(time.Unix(int64(expires), 0)), true
+}
+
+// Keys returns the keys of the objects in the cache that begin with the provided prefix
+func (c *Cache) Keys(prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	err := c.dbh.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		p := []byte(prefix)
+		for it.Seek(p); it.ValidForPrefix(p); it.Next() {
+			keys = append(keys, string(it.Item().KeyCopy(nil)))
+		}
+		return nil
+	})
+	return keys, err
+}
+
 // Close closes the Badger Cache
 func (c *Cache) Close() error {
 	return c.dbh.Close()
@@ -273,3 +273,25 @@ func TestLocker(t *testing.T) {
 		t.Errorf("error setting locker")
 	}
 }
+
+func TestBadgerCache_Keys(t *testing.T) {
+	testDbPath := t.TempDir() + "/test.db"
+	cacheConfig := newCacheConfig(testDbPath)
+	bc := Cache{Config: cacheConfig, Logger: tl.ConsoleLogger("error")}
+	if err := bc.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	for _, k := range []string{"test.dpc.1", "test.opc.2", "other.3"} {
+		if err := bc.Store(k, []byte("data"), time.Duration(60)*time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys, err := bc.Keys("test.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "test.dpc.1" || keys[1] != "test.opc.2" {
+		t.Errorf("expected %s got %v", "[test.dpc.1 test.opc.2]", keys)
+	}
+}
@@ -228,6 +228,16 @@ func (c *Cache) BulkRemove(cacheKeys []string) {
 	wg.Wait()
 }
 
+// RemainingTTL returns the time remaining before the object expires
+func (c *Cache) RemainingTTL(cacheKey string) (time.Duration, bool) {
+	return c.Index.RemainingTTL(cacheKey)
+}
+
+// Keys returns the keys of the objects in the cache that begin with the provided prefix
+func (c *Cache) Keys(prefix string) ([]string, error) {
+	return c.Index.Keys(prefix), nil
+}
+
 // Close closes the Cache
 func (c *Cache) Close() error {
 	if c.Index != nil {
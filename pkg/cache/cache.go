@@ -62,6 +62,38 @@ type MemoryCache interface {
 	SetLocker(locks.NamedLocker)
 }
 
+// TieredCache is the interface for a cache that fronts an L2 cache with an L1 cache.
+// The tiers are exposed so that callers can store references in a memory L1 cache
+// while storing serialized objects in the L2 cache
+type TieredCache interface {
+	Cache
+	L1() Cache
+	L2() Cache
+	// StoreL2 places an object in the L2 cache only, honoring the configured write mode
+	StoreL2(cacheKey string, data []byte, ttl time.Duration) error
+	// L1TTL returns the TTL to use for an object in the L1 cache, given its overall TTL.
+	// A ttl of 0 returns the TTL for objects backfilled into the L1 cache from the L2 cache
+	L1TTL(ttl time.Duration) time.Duration
+	// BackfillTTL returns the TTL with which an object retrieved from the L2 cache is
+	// backfilled into the L1 cache, and false when it has expired from L2 and must not be
+	BackfillTTL(cacheKey string) (time.Duration, bool)
+}
+
+// KeyLister is an optional interface for caches that are able to enumerate the keys of
+// the objects they hold. It is required in order to purge cache objects by key prefix.
+type KeyLister interface {
+	Keys(prefix string) ([]string, error)
+}
+
+// TTLReporter is an optional interface for caches that are able to report how long an
+// object has left before it expires. Tiered caches use it to cap the TTL of objects
+// backfilled into the L1 cache from the L2 cache.
+type TTLReporter interface {
+	// RemainingTTL returns the time remaining before the object expires. ok is false when
+	// the object does not expire, or the time remaining is otherwise unknown
+	RemainingTTL(cacheKey string) (ttl time.Duration, ok bool)
+}
+
 // ReferenceObject defines an interface for a cache object possessing the ability to report
 // the approximate comprehensive byte size of its members, to assist with cache size management
 type ReferenceObject interface {
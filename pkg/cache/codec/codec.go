@@ -0,0 +1,194 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package codec compresses and decompresses serialized cache objects. The codec
+// used to compress an object is recorded in its leading byte, so that objects
+// remain readable after the configured codec changes
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/encoding/providers"
+	"github.com/tricksterproxy/trickster/pkg/encoding/zstd"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Codec Names
+const (
+	// None stores objects uncompressed
+	None = "none"
+	// Brotli compresses objects with brotli
+	Brotli = "brotli"
+	// Zstd compresses objects with Zstandard
+	Zstd = "zstd"
+	// Snappy compresses objects with snappy
+	Snappy = "snappy"
+	// GZip compresses objects with gzip
+	GZip = "gzip"
+	// Deflate compresses objects with deflate
+	Deflate = "deflate"
+)
+
+// DefaultCodec is the default codec for compressing cache objects
+const DefaultCodec = Brotli
+
+// ID is the leading byte of a serialized cache object, identifying its codec.
+// 0 and 1 (brotli) predate configurable codecs and must not change
+type ID byte
+
+// Codec IDs
+const (
+	IDNone ID = iota
+	IDBrotli
+	IDZstd
+	IDSnappy
+	IDGZip
+	IDDeflate
+)
+
+var names = map[string]ID{
+	None:                        IDNone,
+	Brotli:                      IDBrotli,
+	providers.BrotliValue:       IDBrotli,
+	Zstd:                        IDZstd,
+	providers.ZstandardAltValue: IDZstd,
+	Snappy:                      IDSnappy,
+	GZip:                        IDGZip,
+	Deflate:                     IDDeflate,
+}
+
+var encodingProviders = map[ID]providers.Provider{
+	IDBrotli:  providers.Brotli,
+	IDZstd:    providers.Zstandard,
+	IDSnappy:  providers.Snappy,
+	IDGZip:    providers.GZip,
+	IDDeflate: providers.Deflate,
+}
+
+// levels are the minimum and maximum compression levels of each codec that has them.
+// 0 is always permitted, and selects the codec's default level
+var levels = map[ID][2]int{
+	IDBrotli:  {0, 11},
+	IDZstd:    {0, 22},
+	IDGZip:    {0, 9},
+	IDDeflate: {0, 9},
+}
+
+// defaultLevels are the levels used in place of 0 for codecs whose encoders would otherwise
+// select a different default. brotli objects have always been written at brotli's default
+// level of 6, while the brotli encoding provider otherwise defaults to a faster level
+var defaultLevels = map[ID]int{
+	IDBrotli: brotli.DefaultCompression,
+}
+
+// ErrUnsupportedCodec is returned when a cache object's codec is not supported
+var ErrUnsupportedCodec = errors.New("unsupported cache object codec")
+
+// ErrInvalidData is returned when a compressed cache object cannot be decoded
+var ErrInvalidData = errors.New("invalid compressed cache object")
+
+// ErrInvalidLevel is returned when a compression level is out of range for its codec
+var ErrInvalidLevel = errors.New("invalid compression level for cache object codec")
+
+// Lookup returns the ID for the named codec, and true if the name is supported
+func Lookup(name string) (ID, bool) {
+	id, ok := names[strings.ToLower(name)]
+	return id, ok
+}
+
+// IsSupported returns true if the named codec is supported
+func IsSupported(name string) bool {
+	_, ok := Lookup(name)
+	return ok
+}
+
+// ValidLevel returns true if level is a supported compression level for the codec.
+// Codecs without compression levels ignore the level, so any level is valid for them
+func ValidLevel(id ID, level int) bool {
+	r, ok := levels[id]
+	if !ok {
+		return true
+	}
+	return level >= r[0] && level <= r[1]
+}
+
+// Encode returns b prefixed with the codec ID and, unless the codec is None,
+// compressed with the codec at the provided level. A level of 0 uses the
+// codec's default level
+func Encode(id ID, level int, b []byte) ([]byte, error) {
+	if id == IDNone {
+		return append([]byte{byte(IDNone)}, b...), nil
+	}
+	p, ok := encodingProviders[id]
+	if !ok {
+		return nil, ErrUnsupportedCodec
+	}
+	if !ValidLevel(id, level) {
+		return nil, ErrInvalidLevel
+	}
+	if level == 0 {
+		if l, ok := defaultLevels[id]; ok {
+			level = l
+		} else {
+			level = -1
+		}
+	}
+	ei, _ := providers.SelectEncoderInitializer(p)
+	buf := bytes.NewBuffer(make([]byte, 0, len(b)/2))
+	buf.WriteByte(byte(id))
+	w := ei(buf, level)
+	if w == nil {
+		return nil, ErrUnsupportedCodec
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode returns the decompressed contents of b, which must be prefixed with a codec ID
+func Decode(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return b, nil
+	}
+	id := ID(b[0])
+	b = b[1:]
+	if id == IDNone {
+		return b, nil
+	}
+	p, ok := encodingProviders[id]
+	if !ok {
+		return nil, ErrUnsupportedCodec
+	}
+	// zstd decoders run background goroutines, so the shared decoder is used
+	if id == IDZstd {
+		return zstd.Decode(b)
+	}
+	r := providers.SelectDecoderInitializer(p)(bytes.NewReader(b))
+	if r == nil {
+		return nil, ErrInvalidData
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
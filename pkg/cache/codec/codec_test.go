@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package codec
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected ID
+		ok       bool
+	}{
+		{"none", IDNone, true},
+		{"br", IDBrotli, true},
+		{"ZSTD", IDZstd, true},
+		{"zstandard", IDZstd, true},
+		{"snappy", IDSnappy, true},
+		{"gzip", IDGZip, true},
+		{"deflate", IDDeflate, true},
+		{"lz4", IDNone, false},
+	}
+	for _, test := range tests {
+		id, ok := Lookup(test.name)
+		if id != test.expected || ok != test.ok {
+			t.Errorf("%s: expected %d %t got %d %t", test.name, test.expected, test.ok, id, ok)
+		}
+	}
+	if IsSupported("lz4") {
+		t.Error("expected false")
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+
+	in := bytes.Repeat([]byte("trickster "), 100)
+
+	for _, id := range []ID{IDNone, IDBrotli, IDZstd, IDSnappy, IDGZip, IDDeflate} {
+		for _, level := range []int{0, 1, 9} {
+			b, err := Encode(id, level, in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ID(b[0]) != id {
+				t.Errorf("expected codec %d got %d", id, b[0])
+			}
+			if id != IDNone && len(b) >= len(in) {
+				t.Errorf("codec %d: expected compressed data", id)
+			}
+			out, err := Decode(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(in, out) {
+				t.Errorf("codec %d: decoded data mismatch", id)
+			}
+		}
+	}
+
+	if _, err := Encode(ID(99), 0, in); err != ErrUnsupportedCodec {
+		t.Error("expected unsupported codec error")
+	}
+	if _, err := Decode([]byte{99, 1}); err != ErrUnsupportedCodec {
+		t.Error("expected unsupported codec error")
+	}
+	if _, err := Decode([]byte{byte(IDGZip), 1, 2, 3}); err == nil {
+		t.Error("expected decode error")
+	}
+	if b, err := Decode(nil); err != nil || len(b) != 0 {
+		t.Error("expected empty data")
+	}
+}
+
+// objects written before codecs were configurable remain readable
+func TestDecodeLegacyBrotli(t *testing.T) {
+	in := []byte("trickster")
+	buf := bytes.NewBuffer([]byte{1})
+	w := brotli.NewWriter(buf)
+	w.Write(in)
+	w.Close()
+	out, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(in, out) {
+		t.Errorf("expected %s got %s", string(in), string(out))
+	}
+}
+
+func TestEncodeBrotliDefaultLevel(t *testing.T) {
+
+	in := bytes.Repeat([]byte("trickster brotli "), 100)
+
+	// level 0 writes brotli objects just as brotli.NewWriter did before levels were configurable
+	buf := &bytes.Buffer{}
+	bw := brotli.NewWriter(buf)
+	bw.Write(in)
+	bw.Close()
+
+	b, err := Encode(IDBrotli, 0, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[1:], buf.Bytes()) {
+		t.Error("expected brotli default level output")
+	}
+}
+
+func TestEncodeInvalidLevel(t *testing.T) {
+	in := []byte("trickster")
+	for _, id := range []ID{IDBrotli, IDZstd, IDGZip, IDDeflate} {
+		if _, err := Encode(id, 23, in); err != ErrInvalidLevel {
+			t.Errorf("codec %d: expected invalid level error got %v", id, err)
+		}
+		if _, err := Encode(id, -3, in); err != ErrInvalidLevel {
+			t.Errorf("codec %d: expected invalid level error got %v", id, err)
+		}
+	}
+	// codecs without levels ignore them
+	if _, err := Encode(IDSnappy, 23, in); err != nil {
+		t.Error(err)
+	}
+	if !ValidLevel(IDGZip, 9) || ValidLevel(IDGZip, 10) || !ValidLevel(IDBrotli, 11) {
+		t.Error("unexpected level validity")
+	}
+}
+
+func TestDecodeDoesNotLeak(t *testing.T) {
+	b, err := Encode(IDZstd, 0, []byte("trickster"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Decode(b)
+	n := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		if _, err := Decode(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if m := runtime.NumGoroutine(); m > n+5 {
+		t.Errorf("expected about %d goroutines got %d", n, m)
+	}
+}
@@ -19,6 +19,7 @@ package index
 
 import (
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -36,7 +37,8 @@ import (
 const IndexKey = "cache.index"
 
 // Index maintains metadata about a Cache when Retention enforcement is managed internally,
-// like memory or bbolt. It is not used for independently managed caches like Redis.
+// like memory or bbolt. It is not used for independently managed caches like Redis, which
+// instead provides its own distributed index when enabled.
 type Index struct {
 	// CacheSize represents the size of the cache in bytes
 	CacheSize int64 `msg:"cache_size"`
@@ -240,6 +242,36 @@ func (idx *Index) RemoveObjects(keys []string, noLock bool) {
 	}
 }
 
+// Keys returns the keys of all Objects in the Index that begin with the provided prefix
+func (idx *Index) Keys(prefix string) []string {
+	idx.mtx.Lock()
+	keys := make([]string, 0, len(idx.Objects))
+	for k := range idx.Objects {
+		if k == IndexKey || !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	idx.mtx.Unlock()
+	sort.Strings(keys)
+	return keys
+}
+
+// RemainingTTL returns the time remaining before the object of the given key expires.
+// ok is false when the object does not expire. Objects not in the index have no time remaining
+func (idx *Index) RemainingTTL(cacheKey string) (time.Duration, bool) {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+	o, ok := idx.Objects[cacheKey]
+	if !ok {
+		return 0, true
+	}
+	if o.Expiration.IsZero() {
+		return 0, false
+	}
+	return time.Until(o.Expiration), true
+}
+
 // GetExpiration returns the cache index's expiration for the object of the given key
 func (idx *Index) GetExpiration(cacheKey string) time.Time {
 	idx.mtx.Lock()
@@ -305,6 +305,33 @@ func TestUpdateObjectTTL(t *testing.T) {
 
 }
 
+func TestRemainingTTL(t *testing.T) {
+
+	cacheKey := "test-remaining-ttl-key"
+	obj := Object{Key: cacheKey, Value: []byte("test_value")}
+	cacheConfig := &co.Options{Provider: "test",
+		Index: &io.Options{ReapInterval: time.Second * time.Duration(10),
+			FlushInterval: time.Second * time.Duration(10)}}
+	idx := NewIndex("test", "test", nil, cacheConfig.Index, testBulkRemoveFunc, fakeFlusherFunc, testLogger)
+
+	ttl, ok := idx.RemainingTTL(cacheKey)
+	if !ok || ttl != 0 {
+		t.Errorf("expected 0/true for missing key, got %v/%t", ttl, ok)
+	}
+
+	idx.UpdateObject(&obj)
+	_, ok = idx.RemainingTTL(cacheKey)
+	if ok {
+		t.Error("expected false for object without expiration")
+	}
+
+	idx.UpdateObjectTTL(cacheKey, time.Duration(3600)*time.Second)
+	ttl, ok = idx.RemainingTTL(cacheKey)
+	if !ok || ttl <= 0 || ttl > time.Duration(3600)*time.Second {
+		t.Errorf("expected remaining ttl <= 1h, got %v/%t", ttl, ok)
+	}
+
+}
 func TestUpdateOptions(t *testing.T) {
 
 	cacheConfig := &co.Options{Provider: "test",
@@ -333,3 +360,25 @@ func TestRemoveObjects(t *testing.T) {
 		t.Error("key should not be in map")
 	}
 }
+
+func TestKeys(t *testing.T) {
+
+	cacheConfig := &co.Options{Provider: "test",
+		Index: &io.Options{ReapInterval: time.Second * time.Duration(10),
+			FlushInterval: time.Second * time.Duration(10)}}
+	idx := NewIndex("test", "test", nil, cacheConfig.Index, testBulkRemoveFunc, fakeFlusherFunc, testLogger)
+
+	for _, k := range []string{"test.opc.2", "test.dpc.1", "other.3", IndexKey} {
+		idx.UpdateObject(&Object{Key: k, Value: []byte("test_value")})
+	}
+
+	keys := idx.Keys("test.")
+	if len(keys) != 2 || keys[0] != "test.dpc.1" || keys[1] != "test.opc.2" {
+		t.Errorf("expected %s got %v", "[test.dpc.1 test.opc.2]", keys)
+	}
+
+	keys = idx.Keys("")
+	if len(keys) != 3 {
+		t.Errorf("expected %d got %d", 3, len(keys))
+	}
+}
@@ -467,3 +467,21 @@ func TestLocker(t *testing.T) {
 		t.Errorf("error setting locker")
 	}
 }
+
+func TestCache_Keys(t *testing.T) {
+	cacheConfig := newCacheConfig(t)
+	mc := Cache{Config: &cacheConfig, Logger: tl.ConsoleLogger("error"), locker: testLocker}
+	if err := mc.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer mc.Close()
+	mc.Store("test.dpc.1", []byte("data"), time.Duration(60)*time.Second)
+	mc.Store("other.2", []byte("data"), time.Duration(60)*time.Second)
+	keys, err := mc.Keys("test.")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(keys) != 1 || keys[0] != "test.dpc.1" {
+		t.Errorf("expected %s got %v", "[test.dpc.1]", keys)
+	}
+}
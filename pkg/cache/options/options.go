@@ -20,14 +20,17 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	badger "github.com/tricksterproxy/trickster/pkg/cache/badger/options"
 	bbolt "github.com/tricksterproxy/trickster/pkg/cache/bbolt/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/codec"
 	filesystem "github.com/tricksterproxy/trickster/pkg/cache/filesystem/options"
 	index "github.com/tricksterproxy/trickster/pkg/cache/index/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/options/defaults"
 	"github.com/tricksterproxy/trickster/pkg/cache/providers"
 	redis "github.com/tricksterproxy/trickster/pkg/cache/redis/options"
+	tiered "github.com/tricksterproxy/trickster/pkg/cache/tiered/options"
 	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
 )
 
@@ -38,7 +41,8 @@ type Lookup map[string]*Options
 type Options struct {
 	// Name is the Name of the cache, taken from the Key in the Caches map[string]*CacheConfig
 	Name string `yaml:"-"`
-	// Provider represents the type of cache that we wish to use: "boltdb", "memory", "filesystem", or "redis"
+	// Provider represents the type of cache that we wish to use: "bbolt", "badger", "memory",
+	// "filesystem", "redis", or "tiered"
 	Provider string `yaml:"provider,omitempty"`
 	// Index provides options for the Cache Index
 	Index *index.Options `yaml:"index,omitempty"`
@@ -50,12 +54,25 @@ type Options struct {
 	BBolt *bbolt.Options `yaml:"bbolt,omitempty"`
 	// Badger provides options for BadgerDB caching
 	Badger *badger.Options `yaml:"badger,omitempty"`
+	// Tiered provides options for Tiered caching
+	Tiered *tiered.Options `yaml:"tiered,omitempty"`
+	// CompressionCodec is the codec used to compress cacheable content types before storing
+	// them: "brotli" (default), "zstd", "snappy", "gzip", "deflate" or "none"
+	CompressionCodec string `yaml:"compression_codec,omitempty"`
+	// CompressionLevel is the compression level of the CompressionCodec. 0 (default) uses
+	// the codec's default level
+	CompressionLevel int `yaml:"compression_level,omitempty"`
+	// CompactTimeseries, when true, stores timeseries in a memory cache, or in the memory L1
+	// of a tiered cache, in their compact serialized form rather than by object reference
+	CompactTimeseries bool `yaml:"compact_timeseries,omitempty"`
 
 	//  Synthetic Values
 
 	// ProviderID represents the internal constant for the provided Provider string
 	// and is automatically populated at startup
 	ProviderID providers.Provider `yaml:"-"`
+	// CompressionCodecID represents the internal constant for the provided CompressionCodec
+	CompressionCodecID codec.ID `yaml:"-"`
 }
 
 // New will return a pointer to a CacheOptions with the default configuration settings
@@ -68,6 +85,10 @@ func New() *Options {
 		BBolt:      bbolt.New(),
 		Badger:     badger.New(),
 		Index:      index.New(),
+		Tiered:     tiered.New(),
+
+		CompressionCodec:   codec.DefaultCodec,
+		CompressionCodecID: codec.IDBrotli,
 	}
 }
 
@@ -78,6 +99,10 @@ func (cc *Options) Clone() *Options {
 	c.Name = cc.Name
 	c.Provider = cc.Provider
 	c.ProviderID = cc.ProviderID
+	c.CompressionCodec = cc.CompressionCodec
+	c.CompressionCodecID = cc.CompressionCodecID
+	c.CompressionLevel = cc.CompressionLevel
+	c.CompactTimeseries = cc.CompactTimeseries
 
 	c.Index.FlushInterval = cc.Index.FlushInterval
 	c.Index.FlushIntervalMS = cc.Index.FlushIntervalMS
@@ -115,6 +140,14 @@ func (cc *Options) Clone() *Options {
 	c.Redis.ReadTimeoutMS = cc.Redis.ReadTimeoutMS
 	c.Redis.SentinelMaster = cc.Redis.SentinelMaster
 	c.Redis.WriteTimeoutMS = cc.Redis.WriteTimeoutMS
+	c.Redis.DistributedIndex = cc.Redis.DistributedIndex
+
+	c.Tiered.L1 = cc.Tiered.L1
+	c.Tiered.L2 = cc.Tiered.L2
+	c.Tiered.WriteMode = cc.Tiered.WriteMode
+	c.Tiered.L1TTLMS = cc.Tiered.L1TTLMS
+	c.Tiered.L1TTL = cc.Tiered.L1TTL
+	c.Tiered.WriteBehindQueueSize = cc.Tiered.WriteBehindQueueSize
 
 	return c
 
@@ -130,7 +163,10 @@ func (cc *Options) Equal(cc2 *Options) bool {
 
 	return cc.Name == cc2.Name &&
 		cc.Provider == cc2.Provider &&
-		cc.ProviderID == cc2.ProviderID
+		cc.ProviderID == cc2.ProviderID &&
+		cc.CompressionCodec == cc2.CompressionCodec &&
+		cc.CompressionLevel == cc2.CompressionLevel &&
+		cc.CompactTimeseries == cc2.CompactTimeseries
 
 }
 
@@ -141,6 +177,37 @@ func (l Lookup) SetDefaults(metadata yamlx.KeyLookup, activeCaches map[string]in
 
 	lw := make([]string, 0)
 
+	// the l1 and l2 caches of an active tiered cache are also active, even
+	// when they are not used directly by any backend
+	for k, v := range l {
+		if _, ok := activeCaches[k]; !ok || v == nil ||
+			strings.ToLower(v.Provider) != providers.Tiered.String() {
+			continue
+		}
+		if v.Tiered == nil {
+			return nil, fmt.Errorf("tiered cache %s requires an l1 and l2 cache", k)
+		}
+		for _, name := range []string{v.Tiered.L1, v.Tiered.L2} {
+			if name == "" {
+				return nil, fmt.Errorf("tiered cache %s requires an l1 and l2 cache", k)
+			}
+			if name == k {
+				return nil, fmt.Errorf("tiered cache %s cannot use itself as a tier", k)
+			}
+			t, ok := l[name]
+			if !ok {
+				return nil, fmt.Errorf("tiered cache %s references unknown cache %s", k, name)
+			}
+			if t != nil && strings.ToLower(t.Provider) == providers.Tiered.String() {
+				return nil, fmt.Errorf("tiered cache %s cannot use tiered cache %s as a tier", k, name)
+			}
+			activeCaches[name] = true
+		}
+		if v.Tiered.L1 == v.Tiered.L2 {
+			return nil, fmt.Errorf("tiered cache %s must use different l1 and l2 caches", k)
+		}
+	}
+
 	for k, v := range l {
 
 		if _, ok := activeCaches[k]; !ok {
@@ -159,6 +226,26 @@ func (l Lookup) SetDefaults(metadata yamlx.KeyLookup, activeCaches map[string]in
 			}
 		}
 
+		if metadata.IsDefined("caches", k, "compression_codec") {
+			id, ok := codec.Lookup(v.CompressionCodec)
+			if !ok {
+				return nil, fmt.Errorf("invalid compression_codec for cache %s: %s", k, v.CompressionCodec)
+			}
+			cc.CompressionCodec = strings.ToLower(v.CompressionCodec)
+			cc.CompressionCodecID = id
+		}
+
+		if metadata.IsDefined("caches", k, "compression_level") {
+			if !codec.ValidLevel(cc.CompressionCodecID, v.CompressionLevel) {
+				return nil, fmt.Errorf("invalid compression_level for cache %s: %d", k, v.CompressionLevel)
+			}
+			cc.CompressionLevel = v.CompressionLevel
+		}
+
+		if metadata.IsDefined("caches", k, "compact_timeseries") {
+			cc.CompactTimeseries = v.CompactTimeseries
+		}
+
 		if metadata.IsDefined("caches", k, "index", "reap_interval_ms") {
 			cc.Index.ReapIntervalMS = v.Index.ReapIntervalMS
 		}
@@ -286,6 +373,36 @@ func (l Lookup) SetDefaults(metadata yamlx.KeyLookup, activeCaches map[string]in
 			if metadata.IsDefined("caches", k, "redis", "idle_check_frequency_ms") {
 				cc.Redis.IdleCheckFrequencyMS = v.Redis.IdleCheckFrequencyMS
 			}
+
+			if metadata.IsDefined("caches", k, "redis", "distributed_index") {
+				cc.Redis.DistributedIndex = v.Redis.DistributedIndex
+			}
+		}
+
+		if cc.ProviderID == providers.Tiered {
+
+			// tiers were validated above
+			cc.Tiered.L1 = v.Tiered.L1
+			cc.Tiered.L2 = v.Tiered.L2
+
+			if metadata.IsDefined("caches", k, "tiered", "write_mode") {
+				cc.Tiered.WriteMode = strings.ToLower(v.Tiered.WriteMode)
+			}
+
+			if cc.Tiered.WriteMode != tiered.WriteModeBoth &&
+				cc.Tiered.WriteMode != tiered.WriteModeWriteBehind {
+				return nil, fmt.Errorf("invalid write_mode for tiered cache %s: %s", k, cc.Tiered.WriteMode)
+			}
+
+			if metadata.IsDefined("caches", k, "tiered", "l1_ttl_ms") {
+				cc.Tiered.L1TTLMS = v.Tiered.L1TTLMS
+			}
+
+			if metadata.IsDefined("caches", k, "tiered", "write_behind_queue_size") {
+				cc.Tiered.WriteBehindQueueSize = v.Tiered.WriteBehindQueueSize
+			}
+
+			cc.Tiered.L1TTL = time.Duration(cc.Tiered.L1TTLMS) * time.Millisecond
 		}
 
 		if metadata.IsDefined("caches", k, "filesystem", "cache_path") {
@@ -16,7 +16,16 @@
 
 package options
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache/codec"
+	"github.com/tricksterproxy/trickster/pkg/cache/providers"
+	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
+
+	"gopkg.in/yaml.v2"
+)
 
 func TestNew(t *testing.T) {
 	o := New()
@@ -39,3 +48,141 @@ func TestCloneAndEqual(t *testing.T) {
 	}
 
 }
+
+const testTieredConfig = `
+caches:
+  default:
+    provider: tiered
+    tiered:
+      l1: local
+      l2: shared
+      write_mode: write_behind
+      l1_ttl_ms: 5000
+  local:
+    provider: memory
+  shared:
+    provider: filesystem
+  unused:
+    provider: memory
+`
+
+func TestSetDefaultsTiered(t *testing.T) {
+
+	load := func(yml string) (Lookup, yamlx.KeyLookup) {
+		c := struct {
+			Caches Lookup `yaml:"caches"`
+		}{}
+		if err := yaml.Unmarshal([]byte(yml), &c); err != nil {
+			t.Fatal(err)
+		}
+		md, err := yamlx.GetKeyList(yml)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.Caches, md
+	}
+
+	l, md := load(testTieredConfig)
+	active := map[string]interface{}{"default": true}
+	if _, err := l.SetDefaults(md, active); err != nil {
+		t.Fatal(err)
+	}
+
+	// the tiers of an active tiered cache are themselves active
+	for _, k := range []string{"default", "local", "shared"} {
+		if _, ok := l[k]; !ok {
+			t.Errorf("expected cache %s to be active", k)
+		}
+	}
+	if _, ok := l["unused"]; ok {
+		t.Error("expected cache unused to be removed")
+	}
+
+	o := l["default"]
+	if o.ProviderID != providers.Tiered {
+		t.Errorf("expected %s got %s", providers.Tiered, o.ProviderID)
+	}
+	if o.Tiered.L1 != "local" || o.Tiered.L2 != "shared" || o.Tiered.WriteMode != "write_behind" {
+		t.Errorf("unexpected tiered options %v", o.Tiered)
+	}
+	if o.Tiered.L1TTL != 5*time.Second {
+		t.Errorf("expected %s got %s", 5*time.Second, o.Tiered.L1TTL)
+	}
+	if o2 := o.Clone(); o2.Tiered.L1 != "local" || o2.Tiered.L1TTL != 5*time.Second {
+		t.Errorf("unexpected cloned tiered options %v", o2.Tiered)
+	}
+
+	errorConfigs := []string{
+		"caches:\n  default:\n    provider: tiered\n",
+		"caches:\n  default:\n    provider: tiered\n    tiered:\n      l1: local\n      l2: missing\n  local:\n    provider: memory\n",
+		"caches:\n  default:\n    provider: tiered\n    tiered:\n      l1: local\n      l2: local\n  local:\n    provider: memory\n",
+		"caches:\n  default:\n    provider: tiered\n    tiered:\n      l1: local\n      l2: default\n  local:\n    provider: memory\n",
+		"caches:\n  default:\n    provider: tiered\n    tiered:\n      l1: local\n      l2: other\n  local:\n    provider: memory\n  other:\n    provider: tiered\n",
+		"caches:\n  default:\n    provider: tiered\n    tiered:\n      l1: local\n      l2: other\n      write_mode: sometimes\n  local:\n    provider: memory\n  other:\n    provider: memory\n",
+	}
+
+	for i, yml := range errorConfigs {
+		l, md := load(yml)
+		if _, err := l.SetDefaults(md, map[string]interface{}{"default": true}); err == nil {
+			t.Errorf("expected error for config %d", i)
+		}
+	}
+}
+
+func TestSetDefaultsCompression(t *testing.T) {
+
+	load := func(yml string) (Lookup, yamlx.KeyLookup) {
+		c := struct {
+			Caches Lookup `yaml:"caches"`
+		}{}
+		if err := yaml.Unmarshal([]byte(yml), &c); err != nil {
+			t.Fatal(err)
+		}
+		md, err := yamlx.GetKeyList(yml)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.Caches, md
+	}
+
+	l, md := load("caches:\n  default:\n    provider: memory\n    compression_codec: ZSTD\n" +
+		"    compression_level: 1\n    compact_timeseries: true\n")
+	if _, err := l.SetDefaults(md, map[string]interface{}{"default": true}); err != nil {
+		t.Fatal(err)
+	}
+	o := l["default"]
+	if o.CompressionCodec != codec.Zstd || o.CompressionCodecID != codec.IDZstd ||
+		o.CompressionLevel != 1 {
+		t.Errorf("unexpected compression options %s %d %d",
+			o.CompressionCodec, o.CompressionCodecID, o.CompressionLevel)
+	}
+	if !o.CompactTimeseries {
+		t.Errorf("expected %t got %t", true, o.CompactTimeseries)
+	}
+	if o2 := o.Clone(); !o.Equal(o2) || o2.CompressionCodecID != codec.IDZstd {
+		t.Error("clone mismatch")
+	}
+
+	// the default codec is brotli
+	l, md = load("caches:\n  default:\n    provider: memory\n")
+	if _, err := l.SetDefaults(md, map[string]interface{}{"default": true}); err != nil {
+		t.Fatal(err)
+	}
+	if o := l["default"]; o.CompressionCodecID != codec.IDBrotli {
+		t.Errorf("expected %d got %d", codec.IDBrotli, o.CompressionCodecID)
+	}
+
+	l, md = load("caches:\n  default:\n    provider: memory\n    compression_codec: lz4\n")
+	if _, err := l.SetDefaults(md, map[string]interface{}{"default": true}); err == nil {
+		t.Error("expected error for invalid codec")
+	}
+
+	// the level is validated against the codec, including the default codec
+	for _, yml := range []string{"    compression_codec: gzip\n    compression_level: 10\n",
+		"    compression_level: 12\n"} {
+		l, md = load("caches:\n  default:\n    provider: memory\n" + yml)
+		if _, err := l.SetDefaults(md, map[string]interface{}{"default": true}); err == nil {
+			t.Error("expected error for invalid level")
+		}
+	}
+}
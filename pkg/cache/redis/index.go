@@ -0,0 +1,331 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	io "github.com/tricksterproxy/trickster/pkg/cache/index/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/metrics"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+	gm "github.com/tricksterproxy/trickster/pkg/observability/metrics"
+
+	"github.com/go-redis/redis"
+)
+
+// indexKeyPrefix is the prefix for the Redis keys that hold a distributed index.
+// The cache name is wrapped in a hash tag so all keys for a given index are placed
+// in the same slot when using Redis Cluster, which is required by the index scripts.
+const indexKeyPrefix = "trickster.index."
+
+// reapBatchSize is the maximum number of index entries examined per reap query
+const reapBatchSize = 1000
+
+// distributedIndex maintains metadata about the objects in a Redis cache within Redis
+// itself, so that every Trickster instance sharing the cache observes the same usage and
+// size limits are enforced across all of them.
+//
+// It uses the following keys:
+//
+//	atime:   a sorted set of cache keys scored by their last access time in ms
+//	expires: a sorted set of cache keys scored by their expiration time in ms
+//	sizes:   a hash of cache keys to their size in bytes
+//	bytes:   the total size in bytes of all indexed objects
+//	lock:    held by the instance currently performing a size-based eviction
+type distributedIndex struct {
+	name     string
+	provider string
+	client   redis.Cmdable
+	options  *io.Options
+	logger   interface{}
+
+	prefix  string
+	keys    []string // atime, expires, sizes, bytes
+	lockKey string
+	owner   string
+
+	isClosing int32
+}
+
+// updateScript writes the metadata for an object and adjusts the total byte count
+// KEYS: atime, expires, sizes, bytes; ARGV: cacheKey, size, now, expiration (0 for none)
+var updateScript = redis.NewScript(`
+local old = redis.call('HGET', KEYS[3], ARGV[1])
+redis.call('HSET', KEYS[3], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+if ARGV[4] == '0' then
+	redis.call('ZREM', KEYS[2], ARGV[1])
+else
+	redis.call('ZADD', KEYS[2], ARGV[4], ARGV[1])
+end
+return redis.call('INCRBY', KEYS[4], tonumber(ARGV[2]) - (tonumber(old) or 0))
+`)
+
+// removeScript removes the metadata for a list of objects and adjusts the total byte count
+// KEYS: atime, expires, sizes, bytes; ARGV: cacheKeys
+var removeScript = redis.NewScript(`
+local freed = 0
+for _, k in ipairs(ARGV) do
+	local old = redis.call('HGET', KEYS[3], k)
+	if old then
+		redis.call('HDEL', KEYS[3], k)
+		freed = freed + tonumber(old)
+	end
+	redis.call('ZREM', KEYS[1], k)
+	redis.call('ZREM', KEYS[2], k)
+end
+if freed > 0 then
+	redis.call('DECRBY', KEYS[4], freed)
+end
+return freed
+`)
+
+// unlockScript releases the eviction lock only if it is still held by the caller
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func newDistributedIndex(cacheName, cacheProvider string, client redis.Cmdable,
+	o *io.Options, logger interface{}) *distributedIndex {
+
+	prefix := indexKeyPrefix + "{" + cacheName + "}."
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	idx := &distributedIndex{
+		name:     cacheName,
+		provider: cacheProvider,
+		client:   client,
+		options:  o,
+		logger:   logger,
+		prefix:   prefix,
+		keys: []string{prefix + "atime", prefix + "expires",
+			prefix + "sizes", prefix + "bytes"},
+		lockKey: prefix + "lock",
+		owner:   hex.EncodeToString(b),
+	}
+
+	if o.ReapInterval > 0 {
+		go idx.reaper()
+	} else {
+		tl.Warn(logger, "cache reaper did not start",
+			tl.Pairs{"cacheName": cacheName, "reapInterval": o.ReapInterval})
+	}
+
+	gm.CacheMaxObjects.WithLabelValues(cacheName, cacheProvider).Set(float64(o.MaxSizeObjects))
+	gm.CacheMaxBytes.WithLabelValues(cacheName, cacheProvider).Set(float64(o.MaxSizeBytes))
+
+	return idx
+}
+
+// isIndexKey returns true if the provided key is used by the index itself
+func (idx *distributedIndex) isIndexKey(key string) bool {
+	return strings.HasPrefix(key, idx.prefix)
+}
+
+func msec(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// update writes the metadata for the object with the provided key
+func (idx *distributedIndex) update(cacheKey string, size int, ttl time.Duration) error {
+	now := time.Now()
+	var exp int64
+	if ttl > 0 {
+		exp = msec(now.Add(ttl))
+	}
+	return updateScript.Run(idx.client, idx.keys, cacheKey, size, msec(now), exp).Err()
+}
+
+// touch updates the last access time for the object with the provided key
+func (idx *distributedIndex) touch(cacheKey string) error {
+	return idx.client.ZAddXX(idx.keys[0],
+		redis.Z{Score: float64(msec(time.Now())), Member: cacheKey}).Err()
+}
+
+// setTTL updates the expiration for the object with the provided key
+func (idx *distributedIndex) setTTL(cacheKey string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return idx.client.ZRem(idx.keys[1], cacheKey).Err()
+	}
+	return idx.client.ZAddXX(idx.keys[1],
+		redis.Z{Score: float64(msec(time.Now().Add(ttl))), Member: cacheKey}).Err()
+}
+
+// remove removes the metadata for the objects with the provided keys
+func (idx *distributedIndex) remove(cacheKeys ...string) error {
+	if len(cacheKeys) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(cacheKeys))
+	for i, k := range cacheKeys {
+		args[i] = k
+	}
+	return removeScript.Run(idx.client, idx.keys, args...).Err()
+}
+
+// usage returns the total size in bytes and the count of the objects in the index
+func (idx *distributedIndex) usage() (int64, int64, error) {
+	count, err := idx.client.HLen(idx.keys[2]).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := idx.client.Get(idx.keys[3]).Int64()
+	if err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+	return size, count, nil
+}
+
+func (idx *distributedIndex) close() {
+	atomic.StoreInt32(&idx.isClosing, 1)
+}
+
+// reaper continually removes expired objects from the index and evicts
+// least-recently-accessed objects to maintain the maximum allowed cache size
+func (idx *distributedIndex) reaper() {
+	for atomic.LoadInt32(&idx.isClosing) == 0 {
+		idx.reap()
+		time.Sleep(idx.options.ReapInterval)
+	}
+}
+
+// reap makes a single pass through the index. Redis expires the objects themselves,
+// so only their metadata is removed for expired objects.
+func (idx *distributedIndex) reap() {
+
+	expired, err := idx.client.ZRangeByScore(idx.keys[1], redis.ZRangeBy{
+		Min: "-inf", Max: strconv.FormatInt(msec(time.Now()), 10), Count: reapBatchSize,
+	}).Result()
+	if err != nil {
+		tl.Warn(idx.logger, "cache index reap failed",
+			tl.Pairs{"cacheName": idx.name, "detail": err.Error()})
+		return
+	}
+	if len(expired) > 0 {
+		metrics.ObserveCacheEvent(idx.name, idx.provider, "eviction", "ttl")
+		idx.remove(expired...)
+	}
+
+	size, count, err := idx.usage()
+	if err != nil {
+		tl.Warn(idx.logger, "cache index reap failed",
+			tl.Pairs{"cacheName": idx.name, "detail": err.Error()})
+		return
+	}
+
+	var evictionType string
+	var needed int64
+	if idx.options.MaxSizeBytes > 0 && size > idx.options.MaxSizeBytes {
+		evictionType = "size_bytes"
+		needed = size - idx.options.MaxSizeBytes
+		if idx.options.MaxSizeBytes > idx.options.MaxSizeBackoffBytes {
+			needed += idx.options.MaxSizeBackoffBytes
+		}
+	} else if idx.options.MaxSizeObjects > 0 && count > idx.options.MaxSizeObjects {
+		evictionType = "size_objects"
+		needed = count - idx.options.MaxSizeObjects
+		if idx.options.MaxSizeObjects > idx.options.MaxSizeBackoffObjects {
+			needed += idx.options.MaxSizeBackoffObjects
+		}
+	}
+
+	if evictionType != "" {
+		// only one instance sharing the index should evict at a time
+		ok, err := idx.client.SetNX(idx.lockKey, idx.owner, lockTTL(idx.options.ReapInterval)).Result()
+		if err == nil && ok {
+			idx.evict(evictionType, needed, size, count)
+			unlockScript.Run(idx.client, []string{idx.lockKey}, idx.owner)
+			size, count, _ = idx.usage()
+		}
+	}
+
+	metrics.ObserveCacheSizeChange(idx.name, idx.provider, size, count)
+}
+
+func lockTTL(reapInterval time.Duration) time.Duration {
+	if d := reapInterval * 10; d > time.Minute {
+		return d
+	}
+	return time.Minute
+}
+
+// evict removes least-recently-accessed objects from the cache until the needed
+// number of bytes or objects (depending on the evictionType) have been removed
+func (idx *distributedIndex) evict(evictionType string, needed, size, count int64) {
+
+	tl.Debug(idx.logger,
+		"max cache size reached. evicting least-recently-accessed records",
+		tl.Pairs{
+			"reason":         evictionType,
+			"cacheSizeBytes": size, "maxSizeBytes": idx.options.MaxSizeBytes,
+			"cacheSizeObjects": count, "maxSizeObjects": idx.options.MaxSizeObjects,
+		},
+	)
+
+	var selected int64
+	for selected < needed {
+		keys, err := idx.client.ZRange(idx.keys[0], 0, reapBatchSize-1).Result()
+		if err != nil || len(keys) == 0 {
+			break
+		}
+		sizes, err := idx.client.HMGet(idx.keys[2], keys...).Result()
+		if err != nil {
+			break
+		}
+		removals := make([]string, 0, len(keys))
+		for i, k := range keys {
+			if selected >= needed {
+				break
+			}
+			removals = append(removals, k)
+			if evictionType == "size_objects" {
+				selected++
+				continue
+			}
+			if s, ok := sizes[i].(string); ok {
+				n, _ := strconv.ParseInt(s, 10, 64)
+				selected += n
+			}
+		}
+		idx.deleteObjects(removals)
+		idx.remove(removals...)
+		metrics.ObserveCacheEvent(idx.name, idx.provider, "eviction", evictionType)
+	}
+
+	tl.Debug(idx.logger, "size-based cache eviction exercise completed",
+		tl.Pairs{"reason": evictionType, "selected": selected, "needed": needed})
+}
+
+// deleteObjects removes the objects from Redis. The objects are deleted individually,
+// since they may be stored in different slots when using Redis Cluster
+func (idx *distributedIndex) deleteObjects(cacheKeys []string) {
+	p := idx.client.Pipeline()
+	for _, k := range cacheKeys {
+		p.Del(k)
+	}
+	p.Exec()
+	metrics.ObserveCacheDel(idx.name, idx.provider, float64(len(cacheKeys)))
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	io "github.com/tricksterproxy/trickster/pkg/cache/index/options"
+)
+
+func setupIndexedRedisCache(t *testing.T, o *io.Options) (*Cache, func()) {
+	rc, close := setupRedisCache(clientTypeStandard)
+	rc.Name = "test"
+	rc.Config.Redis.DistributedIndex = true
+	rc.Config.Index = o
+	if err := rc.Connect(); err != nil {
+		close()
+		t.Fatal(err)
+	}
+	if rc.index == nil {
+		close()
+		t.Fatal("expected distributed index")
+	}
+	return rc, close
+}
+
+func checkUsage(t *testing.T, idx *distributedIndex, size, count int64) {
+	t.Helper()
+	s, c, err := idx.usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != size {
+		t.Errorf("expected %d bytes got %d", size, s)
+	}
+	if c != count {
+		t.Errorf("expected %d objects got %d", count, c)
+	}
+}
+
+func TestDistributedIndexUsage(t *testing.T) {
+
+	rc, close := setupIndexedRedisCache(t, &io.Options{})
+	defer close()
+	defer rc.Close()
+
+	rc.Store("key1", []byte("data"), time.Minute)
+	rc.Store("key2", []byte("more data"), time.Minute)
+	checkUsage(t, rc.index, 13, 2)
+
+	// overwriting an object adjusts the size by the difference
+	rc.Store("key1", []byte("data!"), time.Minute)
+	checkUsage(t, rc.index, 14, 2)
+
+	// a second instance sharing the endpoint reports the same usage
+	rc2 := &Cache{Name: "test", Config: rc.Config, Logger: rc.Logger}
+	if err := rc2.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer rc2.Close()
+	checkUsage(t, rc2.index, 14, 2)
+
+	rc2.Remove("key1")
+	checkUsage(t, rc.index, 9, 1)
+
+	rc.BulkRemove([]string{"key1", "key2"})
+	checkUsage(t, rc.index, 0, 0)
+
+	rc.Store("key3", []byte("data"), time.Minute)
+	keys, err := rc.Keys("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "key3" {
+		t.Errorf("expected [key3] got %v", keys)
+	}
+}
+
+func TestDistributedIndexReapExpired(t *testing.T) {
+
+	rc, close := setupIndexedRedisCache(t, &io.Options{})
+	defer close()
+	defer rc.Close()
+
+	rc.Store("key1", []byte("data"), time.Millisecond)
+	rc.Store("key2", []byte("data"), time.Minute)
+	rc.Store("key3", []byte("data"), 0)
+	rc.SetTTL("key2", time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	rc.index.reap()
+	checkUsage(t, rc.index, 4, 1)
+}
+
+func TestDistributedIndexEvict(t *testing.T) {
+
+	rc, close := setupIndexedRedisCache(t, &io.Options{MaxSizeBytes: 40, MaxSizeBackoffBytes: 10})
+	defer close()
+	defer rc.Close()
+
+	for i := 0; i < 5; i++ {
+		rc.Store("key"+strconv.Itoa(i), []byte("0123456789"), time.Minute)
+		time.Sleep(2 * time.Millisecond)
+	}
+	// key0 is the oldest write, but accessing it makes key1 the least-recently-accessed
+	if _, _, err := rc.Retrieve("key0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	rc.index.reap()
+	checkUsage(t, rc.index, 30, 3)
+
+	for _, k := range []string{"key1", "key2"} {
+		if _, _, err := rc.Retrieve(k, false); err == nil {
+			t.Errorf("expected %s to be evicted", k)
+		}
+	}
+	if _, _, err := rc.Retrieve("key0", false); err != nil {
+		t.Error(err)
+	}
+
+	rc, close = setupIndexedRedisCache(t, &io.Options{MaxSizeObjects: 3, MaxSizeBackoffObjects: 1})
+	defer close()
+	defer rc.Close()
+
+	for i := 0; i < 5; i++ {
+		rc.Store("key"+strconv.Itoa(i), []byte("0123456789"), time.Minute)
+	}
+
+	rc.index.reap()
+	checkUsage(t, rc.index, 20, 2)
+
+	// the eviction lock is released once the eviction completes
+	if n, _ := rc.client.Exists(rc.index.lockKey).Result(); n != 0 {
+		t.Error("expected eviction lock to be released")
+	}
+}
@@ -60,6 +60,9 @@ type Options struct {
 	IdleTimeoutMS int `yaml:"idle_timeout_ms,omitempty"`
 	// IdleCheckFrequencyMS is the frequency of idle checks made by idle connections reaper.
 	IdleCheckFrequencyMS int `yaml:"idle_check_frequency_ms,omitempty"`
+	// DistributedIndex enables a cache index that is stored in Redis and shared by all Trickster
+	// instances using the same Redis endpoint, so the cache's index size limits are enforced by them
+	DistributedIndex bool `yaml:"distributed_index,omitempty"`
 }
 
 // New returns a new Redis Options Reference with default values set
@@ -19,6 +19,8 @@
 package redis
 
 import (
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
@@ -43,6 +45,7 @@ type Cache struct {
 
 	client redis.Cmdable
 	closer func() error
+	index  *distributedIndex
 }
 
 // Locker returns the cache's locker
@@ -91,14 +94,29 @@ func (c *Cache) Connect() error {
 		c.closer = client.Close
 		c.client = client
 	}
-	return c.client.Ping().Err()
+	if err := c.client.Ping().Err(); err != nil {
+		return err
+	}
+	if c.Config.Redis.DistributedIndex && c.Config.Index != nil {
+		c.index = newDistributedIndex(c.Name, c.Config.Provider, c.client, c.Config.Index, c.Logger)
+	}
+	return nil
 }
 
 // Store places the the data into the Redis Cache using the provided Key and TTL
 func (c *Cache) Store(cacheKey string, data []byte, ttl time.Duration) error {
 	metrics.ObserveCacheOperation(c.Name, c.Config.Provider, "set", "none", float64(len(data)))
 	tl.Debug(c.Logger, "redis cache store", tl.Pairs{"key": cacheKey})
-	return c.client.Set(cacheKey, data, ttl).Err()
+	if err := c.client.Set(cacheKey, data, ttl).Err(); err != nil {
+		return err
+	}
+	if c.index != nil {
+		if err := c.index.update(cacheKey, len(data), ttl); err != nil {
+			tl.Warn(c.Logger, "redis cache index update failed",
+				tl.Pairs{"key": cacheKey, "detail": err.Error()})
+		}
+	}
+	return nil
 }
 
 // Retrieve gets data from the Redis Cache using the provided Key
@@ -110,6 +128,9 @@ func (c *Cache) Retrieve(cacheKey string, allowExpired bool) ([]byte, status.Loo
 		data := []byte(res)
 		tl.Debug(c.Logger, "redis cache retrieve", tl.Pairs{"key": cacheKey})
 		metrics.ObserveCacheOperation(c.Name, c.Config.Provider, "get", "hit", float64(len(data)))
+		if c.index != nil {
+			c.index.touch(cacheKey)
+		}
 		return data, status.LookupStatusHit, nil
 	}
 
@@ -128,24 +149,110 @@ func (c *Cache) Retrieve(cacheKey string, allowExpired bool) ([]byte, status.Loo
 func (c *Cache) Remove(cacheKey string) {
 	tl.Debug(c.Logger, "redis cache remove", tl.Pairs{"key": cacheKey})
 	c.client.Del(cacheKey)
+	if c.index != nil {
+		c.index.remove(cacheKey)
+	}
 	metrics.ObserveCacheDel(c.Name, c.Config.Provider, 0)
 }
 
 // SetTTL updates the TTL for the provided cache object
 func (c *Cache) SetTTL(cacheKey string, ttl time.Duration) {
 	c.client.Expire(cacheKey, ttl)
+	if c.index != nil {
+		c.index.setTTL(cacheKey, ttl)
+	}
 }
 
 // BulkRemove removes a list of objects from the cache. noLock is not used for Redis
 func (c *Cache) BulkRemove(cacheKeys []string) {
 	tl.Debug(c.Logger, "redis cache bulk remove", tl.Pairs{})
 	c.client.Del(cacheKeys...)
+	if c.index != nil {
+		c.index.remove(cacheKeys...)
+	}
 	metrics.ObserveCacheDel(c.Name, c.Config.Provider, float64(len(cacheKeys)))
 }
 
+// RemainingTTL returns the time remaining before the object expires
+func (c *Cache) RemainingTTL(cacheKey string) (time.Duration, bool) {
+	d, err := c.client.PTTL(cacheKey).Result()
+	if err != nil {
+		return 0, false
+	}
+	// Redis reports -1 for objects without an expiration, and -2 for missing objects
+	if d == -time.Millisecond {
+		return 0, false
+	}
+	if d < 0 {
+		return 0, true
+	}
+	return d, true
+}
+
+// Keys returns the keys of the objects in the cache that begin with the provided prefix.
+// In Cluster mode, each master node is scanned.
+func (c *Cache) Keys(prefix string) ([]string, error) {
+	keys, err := c.scan(escapeGlob(prefix) + "*")
+	if err != nil || c.index == nil {
+		return keys, err
+	}
+	out := keys[:0]
+	for _, k := range keys {
+		if !c.index.isIndexKey(k) {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+func (c *Cache) scan(match string) ([]string, error) {
+	if cc, ok := c.client.(*redis.ClusterClient); ok {
+		var mtx sync.Mutex
+		keys := make([]string, 0)
+		err := cc.ForEachMaster(func(client *redis.Client) error {
+			k, err := scanKeys(client, match)
+			if err != nil {
+				return err
+			}
+			mtx.Lock()
+			keys = append(keys, k...)
+			mtx.Unlock()
+			return nil
+		})
+		return keys, err
+	}
+	return scanKeys(c.client, match)
+}
+
+func scanKeys(client redis.Cmdable, match string) ([]string, error) {
+	keys := make([]string, 0)
+	var cursor uint64
+	for {
+		k, next, err := client.Scan(cursor, match, 1000).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k...)
+		if next == 0 {
+			return keys, nil
+		}
+		cursor = next
+	}
+}
+
+// escapeGlob escapes the Redis glob-style pattern characters in the provided string
+func escapeGlob(s string) string {
+	return globEscaper.Replace(s)
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
 // Close disconnects from the Redis Cache
 func (c *Cache) Close() error {
 	tl.Info(c.Logger, "closing redis connection", tl.Pairs{})
+	if c.index != nil {
+		c.index.close()
+	}
 	return c.closer()
 }
 
@@ -257,6 +257,34 @@ func TestRedisCache_SetTTL(t *testing.T) {
 
 }
 
+func TestRedisCache_RemainingTTL(t *testing.T) {
+
+	cache, closer := setupRedisCache(clientTypeStandard)
+	defer closer()
+
+	err := cache.Connect()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cache.Close()
+
+	ttl, ok := cache.RemainingTTL(cacheKey)
+	if !ok || ttl != 0 {
+		t.Errorf("expected 0/true for missing key, got %v/%t", ttl, ok)
+	}
+
+	err = cache.Store(cacheKey, []byte("data"), time.Duration(60)*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ttl, ok = cache.RemainingTTL(cacheKey)
+	if !ok || ttl <= 0 || ttl > time.Duration(60)*time.Second {
+		t.Errorf("expected remaining ttl <= 60s, got %v/%t", ttl, ok)
+	}
+
+}
+
 func BenchmarkCache_SetTTL(b *testing.B) {
 	rc, close := storeBenchmark(b)
 	defer close()
@@ -526,3 +554,30 @@ func TestLocker(t *testing.T) {
 		t.Errorf("error setting locker")
 	}
 }
+
+func TestCache_Keys(t *testing.T) {
+	rc, close := setupRedisCache(clientTypeStandard)
+	defer close()
+	if err := rc.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"test.dpc.1", "test.opc.2", "test*.3", "other.4"} {
+		if err := rc.Store(k, []byte("data"), time.Duration(60)*time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys, err := rc.Keys("test.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("expected %d got %d", 2, len(keys))
+	}
+	keys, err = rc.Keys("test*.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "test*.3" {
+		t.Errorf("expected %s got %v", "[test*.3]", keys)
+	}
+}
@@ -27,7 +27,9 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/cache/memory"
 	"github.com/tricksterproxy/trickster/pkg/cache/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/redis"
+	"github.com/tricksterproxy/trickster/pkg/cache/tiered"
 	"github.com/tricksterproxy/trickster/pkg/locks"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
 )
 
 // Cache Interface Types
@@ -36,6 +38,7 @@ const (
 	ctRedis      = "redis"
 	ctBBolt      = "bbolt"
 	ctBadger     = "badger"
+	ctTiered     = "tiered"
 )
 
 // Caches maintains a list of active caches
@@ -56,9 +59,28 @@ func LoadCachesFromConfig(conf *config.Config, logger interface{}) map[string]ca
 		c := NewCache(k, v, logger)
 		caches[k] = c
 	}
+	ConnectTiers(caches, logger)
 	return caches
 }
 
+// ConnectTiers binds each tiered cache in the provided map to its L1 and L2 caches
+// and connects it. It must be called once all caches in the map have been created.
+func ConnectTiers(caches map[string]cache.Cache, logger interface{}) {
+	for k, c := range caches {
+		tc, ok := c.(*tiered.Cache)
+		if !ok {
+			continue
+		}
+		if tc.Config.Tiered != nil {
+			tc.SetTiers(caches[tc.Config.Tiered.L1], caches[tc.Config.Tiered.L2])
+		}
+		if err := tc.Connect(); err != nil {
+			tl.Error(logger, "tiered cache setup failed",
+				tl.Pairs{"cacheName": k, "detail": err.Error()})
+		}
+	}
+}
+
 // CloseCaches iterates the set of caches and closes each
 func CloseCaches(caches map[string]cache.Cache) error {
 	for _, c := range caches {
@@ -83,6 +105,11 @@ func NewCache(cacheName string, cfg *options.Options, logger interface{}) cache.
 		c = &bbolt.Cache{Name: cacheName, Config: cfg, Logger: logger}
 	case ctBadger:
 		c = &badger.Cache{Name: cacheName, Config: cfg, Logger: logger}
+	case ctTiered:
+		// tiered caches are connected by ConnectTiers once their tiers exist
+		c = &tiered.Cache{Name: cacheName, Config: cfg, Logger: logger}
+		c.SetLocker(locks.NewNamedLocker())
+		return c
 	default:
 		// Default to MemoryCache
 		c = &memory.Cache{Name: cacheName, Config: cfg, Logger: logger}
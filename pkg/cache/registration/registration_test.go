@@ -27,6 +27,8 @@ import (
 	co "github.com/tricksterproxy/trickster/pkg/cache/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/providers"
 	ro "github.com/tricksterproxy/trickster/pkg/cache/redis/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/tiered"
+	to "github.com/tricksterproxy/trickster/pkg/cache/tiered/options"
 	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
 )
 
@@ -65,6 +67,13 @@ func TestLoadCachesFromConfig(t *testing.T) {
 		}
 	}
 
+	tc, ok := caches["tiered"].(*tiered.Cache)
+	if !ok {
+		t.Errorf("expected tiered cache got %T", caches["tiered"])
+	} else if tc.L1() != caches["memory"] || tc.L2() != caches["filesystem"] {
+		t.Error("expected tiered cache to be bound to its tiers")
+	}
+
 	_, ok = caches["foo"]
 	if ok {
 		t.Errorf("expected error")
@@ -95,6 +104,7 @@ func newCacheConfig(t *testing.T, cacheProvider string) *co.Options {
 		Filesystem: &flo.Options{CachePath: fd},
 		BBolt:      &bbo.Options{Filename: "/tmp/test.db", Bucket: "trickster_test"},
 		Badger:     &bao.Options{Directory: bd, ValueDirectory: bd},
+		Tiered:     &to.Options{L1: "memory", L2: "filesystem", WriteMode: to.WriteModeBoth},
 		Index: &io.Options{
 			ReapIntervalMS:        3000,
 			FlushIntervalMS:       5000,
@@ -49,6 +49,10 @@ const (
 	LookupStatusError
 	// LookupStatusProxyHit indicates that the request joined an existing proxy download of the same object
 	LookupStatusProxyHit
+	// LookupStatusStale indicates the cached object exceeded the freshness lifetime and was
+	// served to the client without being revalidated, as permitted by stale-while-revalidate
+	// or stale-if-error
+	LookupStatusStale
 )
 
 var cacheLookupStatusNames = map[string]LookupStatus{
@@ -63,6 +67,7 @@ var cacheLookupStatusNames = map[string]LookupStatus{
 	"nchit":       LookupStatusNegativeCacheHit,
 	"proxy-hit":   LookupStatusProxyHit,
 	"error":       LookupStatusError,
+	"stale":       LookupStatusStale,
 }
 
 var cacheLookupStatusValues = map[LookupStatus]string{
@@ -77,6 +82,7 @@ var cacheLookupStatusValues = map[LookupStatus]string{
 	LookupStatusNegativeCacheHit: "nchit",
 	LookupStatusProxyHit:         "proxy-hit",
 	LookupStatusError:            "error",
+	LookupStatusStale:            "stale",
 }
 
 func (s LookupStatus) String() string {
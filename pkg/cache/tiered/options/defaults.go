@@ -0,0 +1,27 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+const (
+	// DefaultWriteMode is the default mode for writing objects to the L2 cache
+	DefaultWriteMode = WriteModeBoth
+	// DefaultL1TTLMS is the default maximum TTL (in milliseconds) of objects in the L1 cache
+	DefaultL1TTLMS = 300000
+	// DefaultWriteBehindQueueSize is the default number of pending L2 writes that
+	// can be queued in write_behind mode before writes become synchronous
+	DefaultWriteBehindQueueSize = 1024
+)
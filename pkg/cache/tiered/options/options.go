@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "time"
+
+// Tiered Cache Write Modes
+const (
+	// WriteModeBoth writes objects to the L1 and L2 caches before returning
+	WriteModeBoth = "both"
+	// WriteModeWriteBehind writes objects to the L1 cache before returning, and
+	// queues the write to the L2 cache to be completed asynchronously
+	WriteModeWriteBehind = "write_behind"
+)
+
+// Options is a collection of Configurations for a Tiered Cache, which fronts
+// a (typically shared) L2 cache with a (typically local) L1 cache
+type Options struct {
+	// L1 is the name of the cache from the caches config that is consulted first
+	L1 string `yaml:"l1,omitempty"`
+	// L2 is the name of the cache from the caches config that is consulted on an L1 miss
+	L2 string `yaml:"l2,omitempty"`
+	// WriteMode is either 'both' or 'write_behind'
+	WriteMode string `yaml:"write_mode,omitempty"`
+	// L1TTLMS is the maximum TTL of objects written to the L1 cache, and the TTL of
+	// objects backfilled into the L1 cache from the L2 cache
+	L1TTLMS int `yaml:"l1_ttl_ms,omitempty"`
+	// WriteBehindQueueSize is the number of pending L2 writes that can be queued in
+	// write_behind mode before writes to the L2 cache become synchronous
+	WriteBehindQueueSize int `yaml:"write_behind_queue_size,omitempty"`
+
+	// L1TTL is the time.Duration representation of L1TTLMS
+	L1TTL time.Duration `yaml:"-"`
+}
+
+// New returns a reference to a new Tiered Cache Options
+func New() *Options {
+	return &Options{
+		WriteMode:            DefaultWriteMode,
+		L1TTLMS:              DefaultL1TTLMS,
+		WriteBehindQueueSize: DefaultWriteBehindQueueSize,
+		L1TTL:                time.Duration(DefaultL1TTLMS) * time.Millisecond,
+	}
+}
@@ -0,0 +1,314 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tiered is a Trickster Cache that fronts an L2 cache with an L1 cache,
+// such as a local memory cache in front of a shared Redis cache
+package tiered
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/metrics"
+	"github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/providers"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	to "github.com/tricksterproxy/trickster/pkg/cache/tiered/options"
+	"github.com/tricksterproxy/trickster/pkg/locks"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+)
+
+// ErrMissingTier is returned when a tiered cache is connected before its L1 and L2 caches are set
+var ErrMissingTier = errors.New("tiered cache requires an l1 and l2 cache")
+
+// ErrNestedTier is returned when a tiered cache is configured with a tiered cache as one of its tiers
+var ErrNestedTier = errors.New("tiered cache cannot use another tiered cache as a tier")
+
+// ErrKeysNotSupported is returned by Keys when a tier cannot enumerate its keys
+var ErrKeysNotSupported = errors.New("tiered cache tier does not support listing keys")
+
+// Cache represents a tiered cache object that conforms to the Cache interface
+type Cache struct {
+	Name   string
+	Config *options.Options
+	Logger interface{}
+	locker locks.NamedLocker
+
+	l1, l2 cache.Cache
+
+	mtx     sync.RWMutex
+	queue   chan *write
+	wg      sync.WaitGroup
+	closing bool
+
+	// pending maps each key with a queued write-behind write to the sequence of its most
+	// recent write, so that superseded or removed writes are dropped by the writer.
+	// wmtx guards pending, and rmtx sequences L2 writes with removals
+	wmtx    sync.Mutex
+	rmtx    sync.Mutex
+	pending map[string]uint64
+	seq     uint64
+}
+
+// write is an L2 write queued in write_behind mode
+type write struct {
+	key  string
+	data []byte
+	ttl  time.Duration
+	seq  uint64
+}
+
+// Locker returns the cache's locker
+func (c *Cache) Locker() locks.NamedLocker {
+	return c.locker
+}
+
+// SetLocker sets the cache's locker
+func (c *Cache) SetLocker(l locks.NamedLocker) {
+	c.locker = l
+}
+
+// Configuration returns the Configuration for the Cache object
+func (c *Cache) Configuration() *options.Options {
+	return c.Config
+}
+
+// SetTiers sets the L1 and L2 caches, and must be called prior to Connect
+func (c *Cache) SetTiers(l1, l2 cache.Cache) {
+	c.l1 = l1
+	c.l2 = l2
+}
+
+// L1 returns the cache that is consulted first
+func (c *Cache) L1() cache.Cache {
+	return c.l1
+}
+
+// L2 returns the cache that is consulted on an L1 miss
+func (c *Cache) L2() cache.Cache {
+	return c.l2
+}
+
+// Connect validates the tiers and starts the write-behind worker, if configured.
+// The tiers are connected independently, since they are also configured caches.
+func (c *Cache) Connect() error {
+	if c.l1 == nil || c.l2 == nil || c.Config.Tiered == nil {
+		return ErrMissingTier
+	}
+	if isTiered(c.l1) || isTiered(c.l2) {
+		return ErrNestedTier
+	}
+	o := c.Config.Tiered
+	tl.Info(c.Logger, "tiered cache setup", tl.Pairs{"name": c.Name,
+		"l1": o.L1, "l2": o.L2, "writeMode": o.WriteMode})
+	if o.WriteMode == to.WriteModeWriteBehind {
+		c.queue = make(chan *write, o.WriteBehindQueueSize)
+		c.pending = make(map[string]uint64)
+		c.wg.Add(1)
+		go c.writeBehind()
+	}
+	return nil
+}
+
+func isTiered(c cache.Cache) bool {
+	return c.Configuration() != nil && c.Configuration().ProviderID == providers.Tiered
+}
+
+// L1TTL returns the TTL to use for an object in the L1 cache, which is capped by the
+// configured l1_ttl_ms. A ttl of 0 returns the TTL for objects backfilled from L2.
+func (c *Cache) L1TTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > c.Config.Tiered.L1TTL {
+		return c.Config.Tiered.L1TTL
+	}
+	return ttl
+}
+
+// Store places an object in both tiers using the specified key and ttl
+func (c *Cache) Store(cacheKey string, data []byte, ttl time.Duration) error {
+	if err := c.l1.Store(cacheKey, data, c.L1TTL(ttl)); err != nil {
+		tl.Warn(c.Logger, "tiered cache l1 store failed",
+			tl.Pairs{"cacheName": c.Name, "cacheKey": cacheKey, "detail": err.Error()})
+	}
+	return c.StoreL2(cacheKey, data, ttl)
+}
+
+// StoreL2 places an object in the L2 cache only. In write_behind mode, the write is
+// queued, unless the queue is full, in which case it is written synchronously.
+func (c *Cache) StoreL2(cacheKey string, data []byte, ttl time.Duration) error {
+	if c.queue == nil {
+		return c.l2.Store(cacheKey, data, ttl)
+	}
+	c.mtx.RLock()
+	if !c.closing {
+		c.wmtx.Lock()
+		c.seq++
+		w := &write{key: cacheKey, data: data, ttl: ttl, seq: c.seq}
+		c.pending[cacheKey] = w.seq
+		c.wmtx.Unlock()
+		select {
+		case c.queue <- w:
+			c.mtx.RUnlock()
+			return nil
+		default:
+			metrics.ObserveCacheEvent(c.Name, c.Config.Provider, "write_behind", "queue_full")
+		}
+	}
+	c.mtx.RUnlock()
+	// the synchronous write supersedes any queued writes for the key
+	c.rmtx.Lock()
+	defer c.rmtx.Unlock()
+	c.cancelWrites(cacheKey)
+	return c.l2.Store(cacheKey, data, ttl)
+}
+
+// cancelWrites drops any queued write-behind writes for the provided keys
+func (c *Cache) cancelWrites(cacheKeys ...string) {
+	if c.pending == nil {
+		return
+	}
+	c.wmtx.Lock()
+	for _, k := range cacheKeys {
+		delete(c.pending, k)
+	}
+	c.wmtx.Unlock()
+}
+
+// writeBehind writes queued objects to the L2 cache until the queue is closed. Writes
+// that were superseded by a later write, or cancelled by a removal, are dropped
+func (c *Cache) writeBehind() {
+	defer c.wg.Done()
+	for w := range c.queue {
+		c.rmtx.Lock()
+		c.wmtx.Lock()
+		seq, ok := c.pending[w.key]
+		if ok && seq == w.seq {
+			delete(c.pending, w.key)
+		}
+		c.wmtx.Unlock()
+		if !ok || seq != w.seq {
+			c.rmtx.Unlock()
+			continue
+		}
+		err := c.l2.Store(w.key, w.data, w.ttl)
+		c.rmtx.Unlock()
+		if err != nil {
+			tl.Warn(c.Logger, "tiered cache write-behind failed",
+				tl.Pairs{"cacheName": c.Name, "cacheKey": w.key, "detail": err.Error()})
+		}
+	}
+}
+
+// Retrieve looks for an object in the L1 cache, and then the L2 cache, and returns it
+// (or an error if not found). Objects found in the L2 cache are backfilled into L1, for
+// no longer than the L2 cache retains them when the L2 cache can report it.
+func (c *Cache) Retrieve(cacheKey string, allowExpired bool) ([]byte, status.LookupStatus, error) {
+	b, s, err := c.l1.Retrieve(cacheKey, allowExpired)
+	if err == nil && s == status.LookupStatusHit {
+		return b, s, nil
+	}
+	b, s, err = c.l2.Retrieve(cacheKey, allowExpired)
+	if err != nil || s != status.LookupStatusHit {
+		return b, s, err
+	}
+	ttl, ok := c.BackfillTTL(cacheKey)
+	if !ok {
+		return b, s, nil
+	}
+	tl.Debug(c.Logger, "tiered cache l1 backfill", tl.Pairs{"cacheName": c.Name,
+		"cacheKey": cacheKey, "ttl": ttl})
+	c.l1.Store(cacheKey, b, ttl)
+	return b, s, nil
+}
+
+// BackfillTTL returns the TTL with which an object retrieved from the L2 cache is backfilled
+// into L1, which is no longer than the L2 cache retains the object when the L2 cache can
+// report it. ok is false when the object has expired from L2 and must not be backfilled
+func (c *Cache) BackfillTTL(cacheKey string) (ttl time.Duration, ok bool) {
+	if tr, ok := c.l2.(cache.TTLReporter); ok {
+		if rt, ok := tr.RemainingTTL(cacheKey); ok {
+			if rt <= 0 {
+				return 0, false
+			}
+			return c.L1TTL(rt), true
+		}
+	}
+	return c.L1TTL(0), true
+}
+
+// SetTTL updates the TTL for the provided cache object in both tiers
+func (c *Cache) SetTTL(cacheKey string, ttl time.Duration) {
+	c.l1.SetTTL(cacheKey, c.L1TTL(ttl))
+	c.l2.SetTTL(cacheKey, ttl)
+}
+
+// Remove removes an object from both tiers, and cancels any queued write-behind writes
+// for it, so that the object is not restored to L2 after it is removed. Queued writes are
+// cancelled right away, and the removal then waits for any write that is in flight
+func (c *Cache) Remove(cacheKey string) {
+	c.cancelWrites(cacheKey)
+	c.rmtx.Lock()
+	defer c.rmtx.Unlock()
+	c.l1.Remove(cacheKey)
+	c.l2.Remove(cacheKey)
+}
+
+// BulkRemove removes a list of objects from both tiers, and cancels any queued
+// write-behind writes for them
+func (c *Cache) BulkRemove(cacheKeys []string) {
+	c.cancelWrites(cacheKeys...)
+	c.rmtx.Lock()
+	defer c.rmtx.Unlock()
+	c.l1.BulkRemove(cacheKeys)
+	c.l2.BulkRemove(cacheKeys)
+}
+
+// Keys returns the keys of the objects in either tier that begin with the provided prefix
+func (c *Cache) Keys(prefix string) ([]string, error) {
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, t := range []cache.Cache{c.l1, c.l2} {
+		kl, ok := t.(cache.KeyLister)
+		if !ok {
+			return nil, ErrKeysNotSupported
+		}
+		k, err := kl.Keys(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range k {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// Close flushes any queued write-behind objects to the L2 cache. The tiers
+// themselves are closed independently, since they are also configured caches.
+func (c *Cache) Close() error {
+	c.mtx.Lock()
+	if c.queue != nil && !c.closing {
+		c.closing = true
+		close(c.queue)
+	}
+	c.mtx.Unlock()
+	c.wg.Wait()
+	return nil
+}
@@ -0,0 +1,298 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tiered
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	io "github.com/tricksterproxy/trickster/pkg/cache/index/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/memory"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/providers"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	to "github.com/tricksterproxy/trickster/pkg/cache/tiered/options"
+	"github.com/tricksterproxy/trickster/pkg/locks"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+)
+
+const cacheKey = "cacheKey"
+
+func newMemoryCache(t *testing.T, name string) *memory.Cache {
+	c := &memory.Cache{Name: name, Logger: tl.ConsoleLogger("error"),
+		Config: &co.Options{Name: name, Provider: "memory", Index: &io.Options{}}}
+	c.SetLocker(locks.NewNamedLocker())
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func newTieredCache(t *testing.T, writeMode string) (*Cache, cache.Cache, cache.Cache) {
+	l1, l2 := newMemoryCache(t, "l1"), newMemoryCache(t, "l2")
+	o := co.New()
+	o.Name = "tiered"
+	o.Provider = "tiered"
+	o.ProviderID = providers.Tiered
+	o.Tiered.L1, o.Tiered.L2 = "l1", "l2"
+	o.Tiered.WriteMode = writeMode
+	o.Tiered.L1TTL = time.Minute
+	c := &Cache{Name: "tiered", Config: o, Logger: tl.ConsoleLogger("error")}
+	c.SetLocker(locks.NewNamedLocker())
+	c.SetTiers(l1, l2)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return c, l1, l2
+}
+
+func TestConnect(t *testing.T) {
+
+	c := &Cache{Name: "tiered", Config: co.New(), Logger: tl.ConsoleLogger("error")}
+	if err := c.Connect(); err != ErrMissingTier {
+		t.Errorf("expected %v got %v", ErrMissingTier, err)
+	}
+
+	tc, l1, _ := newTieredCache(t, to.WriteModeBoth)
+	c.SetTiers(l1, tc)
+	if err := c.Connect(); err != ErrNestedTier {
+		t.Errorf("expected %v got %v", ErrNestedTier, err)
+	}
+
+	if c.L1() != l1 || c.L2() != tc {
+		t.Error("unexpected tiers")
+	}
+	if c.Configuration() != c.Config {
+		t.Error("unexpected configuration")
+	}
+	l := locks.NewNamedLocker()
+	c.SetLocker(l)
+	if c.Locker() != l {
+		t.Error("unexpected locker")
+	}
+}
+
+func TestStoreAndRetrieve(t *testing.T) {
+
+	c, l1, l2 := newTieredCache(t, to.WriteModeBoth)
+	defer c.Close()
+
+	if err := c.Store(cacheKey, []byte("data"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for _, tier := range []cache.Cache{l1, l2} {
+		if b, _, err := tier.Retrieve(cacheKey, false); err != nil || string(b) != "data" {
+			t.Errorf("expected data in %s got %s %v", tier.Configuration().Name, string(b), err)
+		}
+	}
+
+	// a miss in L1 falls through to L2, and is backfilled into L1
+	l1.Remove(cacheKey)
+	b, s, err := c.Retrieve(cacheKey, false)
+	if err != nil || s != status.LookupStatusHit || string(b) != "data" {
+		t.Errorf("expected hit got %s %s %v", s, string(b), err)
+	}
+	if b, _, err := l1.Retrieve(cacheKey, false); err != nil || string(b) != "data" {
+		t.Errorf("expected backfill got %s %v", string(b), err)
+	}
+
+	c.Remove(cacheKey)
+	_, s, err = c.Retrieve(cacheKey, false)
+	if err != cache.ErrKNF || s != status.LookupStatusKeyMiss {
+		t.Errorf("expected miss got %s %v", s, err)
+	}
+}
+
+func TestWriteBehind(t *testing.T) {
+
+	c, _, l2 := newTieredCache(t, to.WriteModeWriteBehind)
+
+	for _, k := range []string{"key1", "key2", "key3"} {
+		if err := c.Store(k, []byte("data"), time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// closing the cache flushes queued writes to L2
+	c.Close()
+	for _, k := range []string{"key1", "key2", "key3"} {
+		if _, _, err := l2.Retrieve(k, false); err != nil {
+			t.Errorf("expected %s in l2: %v", k, err)
+		}
+	}
+
+	// writes after close are synchronous
+	if err := c.StoreL2("key4", []byte("data"), time.Hour); err != nil {
+		t.Error(err)
+	}
+	if _, _, err := l2.Retrieve("key4", false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBackfillTTL(t *testing.T) {
+
+	c, l1, l2 := newTieredCache(t, to.WriteModeBoth)
+	defer c.Close()
+
+	// the L2 object expires before the configured l1 ttl, so it is backfilled for no longer
+	l2.Store(cacheKey, []byte("data"), 50*time.Millisecond)
+	if _, _, err := c.Retrieve(cacheKey, false); err != nil {
+		t.Fatal(err)
+	}
+	rt, ok := l1.(cache.TTLReporter).RemainingTTL(cacheKey)
+	if !ok || rt <= 0 || rt > 50*time.Millisecond {
+		t.Errorf("expected backfill ttl capped at l2 remaining ttl got %s", rt)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, _, err := l1.Retrieve(cacheKey, false); err == nil {
+		t.Error("expected the backfilled object to expire with the l2 object")
+	}
+
+	// objects that have expired from L2 are not backfilled
+	l1.Remove(cacheKey)
+	if _, _, err := c.Retrieve(cacheKey, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := l1.Retrieve(cacheKey, true); err == nil {
+		t.Error("expected expired object not to be backfilled")
+	}
+
+	// objects that outlive the l1 ttl are backfilled with the l1 ttl
+	l2.Store("key2", []byte("data"), time.Hour)
+	c.Retrieve("key2", false)
+	if rt, _ := l1.(cache.TTLReporter).RemainingTTL("key2"); rt > time.Minute {
+		t.Errorf("expected backfill ttl capped at l1 ttl got %s", rt)
+	}
+}
+
+// blockingCache blocks its Stores until released, to hold writes in the write-behind queue
+type blockingCache struct {
+	cache.Cache
+	release chan struct{}
+}
+
+func (c *blockingCache) Store(cacheKey string, data []byte, ttl time.Duration) error {
+	<-c.release
+	return c.Cache.Store(cacheKey, data, ttl)
+}
+
+func TestWriteBehindRemove(t *testing.T) {
+
+	c, l1, l2 := newTieredCache(t, to.WriteModeWriteBehind)
+	c.Close()
+	bc := &blockingCache{Cache: l2, release: make(chan struct{})}
+	c = &Cache{Name: "tiered", Config: c.Config, Logger: c.Logger}
+	c.SetLocker(locks.NewNamedLocker())
+	c.SetTiers(l1, bc)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// key0 is held by the writer while key1 and key2 wait in the queue
+	c.Store("key0", []byte("data"), time.Hour)
+	c.Store("key1", []byte("data"), time.Hour)
+	c.Store("key2", []byte("old"), time.Hour)
+	c.Store("key2", []byte("new"), time.Hour)
+
+	for len(c.queue) != 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// key1 is cancelled while queued, and the removal waits for the in-flight write
+	done := make(chan struct{})
+	go func() {
+		c.Remove("key1")
+		close(done)
+	}()
+	for {
+		c.wmtx.Lock()
+		_, ok := c.pending["key1"]
+		c.wmtx.Unlock()
+		if !ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	bc.release <- struct{}{}
+	close(bc.release)
+	<-done
+	c.Close()
+
+	if _, _, err := l2.Retrieve("key0", false); err != nil {
+		t.Errorf("expected key0 in l2: %v", err)
+	}
+	if _, _, err := l2.Retrieve("key1", false); err == nil {
+		t.Error("expected removed key1 not to be written to l2")
+	}
+	if b, _, _ := l2.Retrieve("key2", false); string(b) != "new" {
+		t.Errorf("expected %s got %s", "new", string(b))
+	}
+}
+
+func TestL1TTL(t *testing.T) {
+
+	c, _, _ := newTieredCache(t, to.WriteModeBoth)
+
+	tests := []struct {
+		ttl, expected time.Duration
+	}{
+		{0, time.Minute},
+		{time.Second, time.Second},
+		{time.Hour, time.Minute},
+	}
+
+	for _, test := range tests {
+		if v := c.L1TTL(test.ttl); v != test.expected {
+			t.Errorf("expected %s got %s", test.expected, v)
+		}
+	}
+}
+
+func TestKeysAndBulkRemove(t *testing.T) {
+
+	c, l1, l2 := newTieredCache(t, to.WriteModeBoth)
+	defer c.Close()
+
+	c.Store("a1", []byte("data"), time.Hour)
+	l1.Store("a2", []byte("data"), time.Hour)
+	l2.Store("a3", []byte("data"), time.Hour)
+	c.Store("b1", []byte("data"), time.Hour)
+	time.Sleep(10 * time.Millisecond)
+
+	keys, err := c.Keys("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 3 || keys[0] != "a1" || keys[1] != "a2" || keys[2] != "a3" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	c.SetTTL("b1", time.Hour)
+	c.BulkRemove(keys)
+	for _, k := range keys {
+		if _, _, err := c.Retrieve(k, false); err == nil {
+			t.Errorf("expected %s to be removed", k)
+		}
+	}
+	if _, _, err := c.Retrieve("b1", false); err != nil {
+		t.Error(err)
+	}
+}
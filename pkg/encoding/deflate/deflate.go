@@ -41,7 +41,10 @@ func Encode(in []byte) ([]byte, error) {
 }
 
 func NewEncoder(w io.Writer, level int) io.WriteCloser {
-	wc, _ := flate.NewWriter(w, level)
+	wc, err := flate.NewWriter(w, level)
+	if err != nil {
+		return nil
+	}
 	return wc
 }
 
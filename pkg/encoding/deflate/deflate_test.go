@@ -56,4 +56,8 @@ func TestNewEncoder(t *testing.T) {
 	if enc == nil {
 		t.Error("expected non-nil encoder")
 	}
+	// an invalid level returns a nil encoder rather than a nil writer
+	if enc = NewEncoder(w, 20); enc != nil {
+		t.Error("expected nil encoder")
+	}
 }
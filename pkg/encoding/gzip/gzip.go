@@ -47,7 +47,10 @@ func NewEncoder(w io.Writer, level int) io.WriteCloser {
 	if level == -1 {
 		level = 6
 	}
-	wc, _ := gzip.NewWriterLevel(w, level)
+	wc, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil
+	}
 	return wc
 }
 
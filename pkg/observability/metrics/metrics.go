@@ -104,6 +104,18 @@ var ProxyConnectionClosed prometheus.Counter
 // ProxyConnectionFailed is a counter for the total number of connections failed to connect for whatever reason
 var ProxyConnectionFailed prometheus.Counter
 
+// ProxyUpstreamRetries is a Counter of upstream requests that were retried
+var ProxyUpstreamRetries *prometheus.CounterVec
+
+// ProxyUpstreamHedges is a Counter of hedged upstream requests, labeled by which request won
+var ProxyUpstreamHedges *prometheus.CounterVec
+
+// ProxyRateLimitAdmitted is a Counter of requests admitted by a backend's rate limiter
+var ProxyRateLimitAdmitted *prometheus.CounterVec
+
+// ProxyRateLimitRejected is a Counter of requests rejected by a backend's rate limiter
+var ProxyRateLimitRejected *prometheus.CounterVec
+
 func init() {
 
 	BuildInfo = prometheus.NewGaugeVec(
@@ -249,6 +261,46 @@ func init() {
 		},
 	)
 
+	ProxyUpstreamRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upstream_retries_total",
+			Help:      "Count of upstream requests retried by Trickster, by retry reason.",
+		},
+		[]string{"backend_name", "provider", "reason"},
+	)
+
+	ProxyUpstreamHedges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upstream_hedged_requests_total",
+			Help:      "Count of hedged upstream requests sent by Trickster, by winning request.",
+		},
+		[]string{"backend_name", "provider", "winner"},
+	)
+
+	ProxyRateLimitAdmitted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "rate_limit_admitted_total",
+			Help:      "Count of requests admitted by Trickster's rate limiter, by limit.",
+		},
+		[]string{"backend_name", "provider", "limit"},
+	)
+
+	ProxyRateLimitRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "rate_limit_rejected_total",
+			Help:      "Count of requests rejected by Trickster's rate limiter, by limit.",
+		},
+		[]string{"backend_name", "provider", "limit"},
+	)
+
 	CacheObjectOperations = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: metricNamespace,
@@ -332,6 +384,10 @@ func init() {
 	prometheus.MustRegister(ProxyConnectionAccepted)
 	prometheus.MustRegister(ProxyConnectionClosed)
 	prometheus.MustRegister(ProxyConnectionFailed)
+	prometheus.MustRegister(ProxyUpstreamRetries)
+	prometheus.MustRegister(ProxyUpstreamHedges)
+	prometheus.MustRegister(ProxyRateLimitAdmitted)
+	prometheus.MustRegister(ProxyRateLimitRejected)
 	prometheus.MustRegister(CacheObjectOperations)
 	prometheus.MustRegister(CacheByteOperations)
 	prometheus.MustRegister(CacheEvents)
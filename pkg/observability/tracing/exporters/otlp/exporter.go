@@ -0,0 +1,165 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/otlp/options"
+	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlphttp"
+	exporttrace "go.opentelemetry.io/otel/sdk/export/trace"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // registers the gzip compressor for otlpgrpc
+)
+
+// Exporter exports spans to an OTLP collector over gRPC or HTTP
+type Exporter struct {
+	*otlp.Exporter
+	timeout time.Duration
+}
+
+// NewExporter returns a new OTLP Exporter for the provided collector URL and options
+func NewExporter(collectorURL, user, pass string, o *options.Options) (*Exporter, error) {
+
+	if o == nil {
+		o = options.New()
+	}
+
+	u, err := url.Parse(collectorURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid otlp collector_url: %s", collectorURL)
+	}
+
+	var gzip bool
+	switch o.Compression {
+	case options.CompressionGzip:
+		gzip = true
+	case options.CompressionNone, "":
+	default:
+		return nil, fmt.Errorf("invalid otlp compression: %s", o.Compression)
+	}
+
+	headers := make(map[string]string, len(o.Headers)+1)
+	for k, v := range o.Headers {
+		headers[k] = v
+	}
+	if user != "" || pass != "" {
+		headers["Authorization"] = "Basic " +
+			base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+	}
+
+	tc, err := tlsConfig(o.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	var driver otlp.ProtocolDriver
+	switch o.Protocol {
+	case options.ProtocolGRPC:
+		opts := []otlpgrpc.Option{otlpgrpc.WithEndpoint(u.Host), otlpgrpc.WithHeaders(headers)}
+		if u.Scheme == "http" {
+			opts = append(opts, otlpgrpc.WithInsecure())
+		} else {
+			if tc == nil {
+				tc = &tls.Config{}
+			}
+			opts = append(opts, otlpgrpc.WithTLSCredentials(credentials.NewTLS(tc)))
+		}
+		if gzip {
+			opts = append(opts, otlpgrpc.WithCompressor("gzip"))
+		}
+		driver = otlpgrpc.NewDriver(opts...)
+	case options.ProtocolHTTP, "":
+		opts := []otlphttp.Option{otlphttp.WithEndpoint(u.Host), otlphttp.WithHeaders(headers)}
+		if u.Path != "" && u.Path != "/" {
+			opts = append(opts, otlphttp.WithTracesURLPath(u.Path))
+		}
+		if u.Scheme == "http" {
+			opts = append(opts, otlphttp.WithInsecure())
+		} else if tc != nil {
+			opts = append(opts, otlphttp.WithTLSClientConfig(tc))
+		}
+		if gzip {
+			opts = append(opts, otlphttp.WithCompression(otlphttp.GzipCompression))
+		}
+		driver = otlphttp.NewDriver(opts...)
+	default:
+		return nil, fmt.Errorf("invalid otlp protocol: %s", o.Protocol)
+	}
+
+	exp, err := otlp.NewExporter(context.Background(), driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Exporter{Exporter: exp, timeout: time.Duration(o.TimeoutMS) * time.Millisecond}, nil
+}
+
+func tlsConfig(o *to.Options) (*tls.Config, error) {
+	if o == nil {
+		return nil, nil
+	}
+	tc := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.ClientCertPath != "" && o.ClientKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(o.ClientCertPath, o.ClientKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	if len(o.CertificateAuthorityPaths) > 0 {
+		rootCAs, _ := x509.SystemCertPool()
+		if rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		for _, path := range o.CertificateAuthorityPaths {
+			certs, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
+				return nil, fmt.Errorf("unable to append to CA Certs from file %s", path)
+			}
+		}
+		tc.RootCAs = rootCAs
+	}
+	return tc, nil
+}
+
+// ExportSpans exports the provided spans to the collector, within the configured timeout
+func (e *Exporter) ExportSpans(ctx context.Context, ss []*exporttrace.SpanSnapshot) error {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+	return e.Exporter.ExportSpans(ctx, ss)
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides OTLP-specific tracing options
+package options
+
+import (
+	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+	"github.com/tricksterproxy/trickster/pkg/util/copiers"
+)
+
+// OTLP Transport Protocols
+const (
+	// ProtocolGRPC exports spans to the collector's OTLP/gRPC endpoint
+	ProtocolGRPC = "grpc"
+	// ProtocolHTTP exports spans to the collector's OTLP/HTTP endpoint using protobuf encoding
+	ProtocolHTTP = "http/protobuf"
+)
+
+// OTLP Compression Types
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// DefaultTimeoutMS is the default timeout for a single export request
+const DefaultTimeoutMS = 10000
+
+// Options is a collection of OTLP-specific options
+type Options struct {
+	// Protocol is the OTLP transport protocol, either 'grpc' or 'http/protobuf' (default)
+	Protocol string `yaml:"protocol,omitempty"`
+	// Headers are included in each export request to the collector
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Compression is the compression applied to each export request, either 'none' (default) or 'gzip'
+	Compression string `yaml:"compression,omitempty"`
+	// TimeoutMS is the timeout for a single export request
+	TimeoutMS int `yaml:"timeout_ms,omitempty"`
+	// TLS provides the TLS options used to connect to the collector
+	TLS *to.Options `yaml:"tls,omitempty"`
+}
+
+// New returns a new *Options with the default values
+func New() *Options {
+	return &Options{
+		Protocol:    ProtocolHTTP,
+		Compression: CompressionNone,
+		TimeoutMS:   DefaultTimeoutMS,
+	}
+}
+
+// Clone returns a perfect copy of the subject *Options
+func (o *Options) Clone() *Options {
+	var tls *to.Options
+	if o.TLS != nil {
+		tls = o.TLS.Clone()
+	}
+	return &Options{
+		Protocol:    o.Protocol,
+		Headers:     copiers.CopyStringLookup(o.Headers),
+		Compression: o.Compression,
+		TimeoutMS:   o.TimeoutMS,
+		TLS:         tls,
+	}
+}
@@ -0,0 +1,38 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+
+	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+)
+
+func TestClone(t *testing.T) {
+
+	o := New()
+	o.Headers = map[string]string{"test": "test"}
+	o.TLS = &to.Options{InsecureSkipVerify: true}
+
+	o2 := o.Clone()
+	if o2.Protocol != ProtocolHTTP || o2.Compression != CompressionNone ||
+		o2.TimeoutMS != DefaultTimeoutMS || o2.Headers["test"] != "test" ||
+		o2.TLS == nil || !o2.TLS.InsecureSkipVerify {
+		t.Errorf("clone failed")
+	}
+
+}
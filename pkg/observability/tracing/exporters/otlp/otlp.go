@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package otlp provides an OpenTelemetry Protocol (OTLP) Tracer
+package otlp
+
+import (
+	"context"
+
+	"github.com/tricksterproxy/trickster/pkg/observability/tracing"
+	errs "github.com/tricksterproxy/trickster/pkg/observability/tracing/errors"
+	"github.com/tricksterproxy/trickster/pkg/observability/tracing/options"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// NewTracer returns a new OTLP Tracer based on the provided options
+func NewTracer(opts *options.Options) (*tracing.Tracer, error) {
+
+	if opts == nil {
+		return nil, errs.ErrNoTracerOptions
+	}
+
+	exp, err := NewExporter(opts.CollectorURL, opts.CollectorUser, opts.CollectorPass,
+		opts.OTLPOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var sampler sdktrace.Sampler
+	switch opts.SampleRate {
+	case 0:
+		sampler = sdktrace.NeverSample()
+	case 1:
+		sampler = sdktrace.AlwaysSample()
+	default:
+		sampler = sdktrace.TraceIDRatioBased(opts.SampleRate)
+	}
+
+	// Tags are included as Resource attributes, so they are exported once per batch
+	tags := make([]attribute.KeyValue, 1, len(opts.Tags)+1)
+	tags[0] = attribute.String("service.name", opts.ServiceName)
+	for k, v := range opts.Tags {
+		tags = append(tags, attribute.String(k, v))
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(resource.NewWithAttributes(tags...)),
+	)
+
+	return &tracing.Tracer{
+		Name:    opts.Name,
+		Tracer:  tp.Tracer(opts.Name),
+		Options: opts,
+		Flusher: func() { tp.Shutdown(context.Background()) },
+	}, nil
+
+}
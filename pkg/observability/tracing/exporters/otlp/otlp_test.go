@@ -0,0 +1,392 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/observability/tracing"
+	errs "github.com/tricksterproxy/trickster/pkg/observability/tracing/errors"
+	otlpopts "github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/otlp/options"
+	"github.com/tricksterproxy/trickster/pkg/observability/tracing/options"
+	"github.com/tricksterproxy/trickster/pkg/observability/tracing/span"
+	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlphttp"
+	exporttrace "go.opentelemetry.io/otel/sdk/export/trace"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// grpcExportPath is the OTLP/gRPC TraceService Export method path
+const grpcExportPath = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+
+type testSpan struct {
+	name  string
+	attrs map[string]string
+}
+
+type testExport struct {
+	header   http.Header
+	resource map[string]string
+	spans    []testSpan
+}
+
+// fields returns the fields of a protobuf message with the provided field number
+func fields(t *testing.T, b []byte, num protowire.Number) [][]byte {
+	var out [][]byte
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			t.Fatal(protowire.ParseError(l))
+		}
+		b = b[l:]
+		l = protowire.ConsumeFieldValue(n, typ, b)
+		if l < 0 {
+			t.Fatal(protowire.ParseError(l))
+		}
+		if n == num && typ == protowire.BytesType {
+			v, _ := protowire.ConsumeBytes(b)
+			out = append(out, v)
+		}
+		b = b[l:]
+	}
+	return out
+}
+
+func str(t *testing.T, b []byte, num protowire.Number) string {
+	if f := fields(t, b, num); len(f) > 0 {
+		return string(f[0])
+	}
+	return ""
+}
+
+// keyValues decodes the string-valued KeyValues in field num of the message
+func keyValues(t *testing.T, b []byte, num protowire.Number) map[string]string {
+	out := make(map[string]string)
+	for _, kv := range fields(t, b, num) {
+		out[str(t, kv, 1)] = str(t, fields(t, kv, 2)[0], 1)
+	}
+	return out
+}
+
+// decodeRequest decodes the ExportTraceServiceRequest fields used by the tests
+func decodeRequest(t *testing.T, b []byte, h http.Header) *testExport {
+	te := &testExport{header: h, resource: make(map[string]string)}
+	for _, rs := range fields(t, b, 1) {
+		for _, r := range fields(t, rs, 1) {
+			for k, v := range keyValues(t, r, 1) {
+				te.resource[k] = v
+			}
+		}
+		for _, ls := range fields(t, rs, 2) {
+			for _, s := range fields(t, ls, 2) {
+				te.spans = append(te.spans, testSpan{name: str(t, s, 5), attrs: keyValues(t, s, 9)})
+			}
+		}
+	}
+	return te
+}
+
+func gunzip(t *testing.T, b []byte) []byte {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// newHTTPReceiver returns an in-process OTLP/HTTP receiver for the provided path
+func newHTTPReceiver(t *testing.T, ch chan *testExport, path string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			b = gunzip(t, b)
+		}
+		ch <- decodeRequest(t, b, r.Header)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+}
+
+// newGRPCReceiver returns an in-process OTLP/gRPC receiver, which responds with the
+// provided grpc status, over TLS, or over h2c when plaintext is true
+func newGRPCReceiver(t *testing.T, ch chan *testExport, status string,
+	plaintext bool) *httptest.Server {
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.URL.Path != grpcExportPath ||
+			r.Header.Get("Content-Type") != "application/grpc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		if len(b) < 5 || int(binary.BigEndian.Uint32(b[1:5])) != len(b)-5 {
+			t.Error("invalid length-prefixed message")
+			return
+		}
+		msg := b[5:]
+		if b[0] == 1 {
+			msg = gunzip(t, msg)
+		}
+		ch <- decodeRequest(t, msg, r.Header)
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", status)
+	})
+	if plaintext {
+		return httptest.NewServer(h2c.NewHandler(h, &http2.Server{}))
+	}
+	s := httptest.NewUnstartedServer(h)
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	return s
+}
+
+func testTracer(t *testing.T, url string, oo *otlpopts.Options) *tracing.Tracer {
+	opts := options.New()
+	opts.Name = "test"
+	opts.Provider = "otlp"
+	opts.SampleRate = 1
+	opts.CollectorURL = url
+	opts.Tags = map[string]string{"region": "test-region"}
+	opts.OmitTagsList = []string{"omitted"}
+	opts.OTLPOptions = oo
+	options.ProcessTracingOptions(map[string]*options.Options{"test": opts}, nil)
+	tr, err := NewTracer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func exportTestSpan(tr *tracing.Tracer) {
+	_, s := span.NewChildSpan(context.Background(), tr, "test-span")
+	span.SetAttributes(tr, s, attribute.String("kept", "yes"), attribute.String("omitted", "yes"))
+	s.End()
+	tr.Flusher()
+}
+
+func testSnapshots() []*exporttrace.SpanSnapshot {
+	return []*exporttrace.SpanSnapshot{{Name: "test-span"}}
+}
+
+func checkExport(t *testing.T, te *testExport) {
+	t.Helper()
+	if te.resource["service.name"] != options.DefaultTracerServiceName {
+		t.Errorf("expected %s got %s", options.DefaultTracerServiceName, te.resource["service.name"])
+	}
+	if te.resource["region"] != "test-region" {
+		t.Errorf("expected %s got %s", "test-region", te.resource["region"])
+	}
+	if len(te.spans) != 1 {
+		t.Fatalf("expected %d got %d", 1, len(te.spans))
+	}
+	if te.spans[0].name != "test-span" {
+		t.Errorf("expected %s got %s", "test-span", te.spans[0].name)
+	}
+	if te.spans[0].attrs["kept"] != "yes" {
+		t.Error("expected kept attribute")
+	}
+	if _, ok := te.spans[0].attrs["omitted"]; ok {
+		t.Error("expected omitted attribute to be filtered")
+	}
+	if te.header.Get("X-Test") != "test" {
+		t.Errorf("expected %s got %s", "test", te.header.Get("X-Test"))
+	}
+}
+
+func TestNewTracer(t *testing.T) {
+
+	_, err := NewTracer(nil)
+	if err != errs.ErrNoTracerOptions {
+		t.Error("expected error for no tracer options")
+	}
+
+	opt := options.New()
+	opt.CollectorURL = "1.2.3.4:5"
+	_, err = NewTracer(opt)
+	if err == nil {
+		t.Error("expected error for invalid collector URL")
+	}
+
+	opt.CollectorURL = "http://1.2.3.4:4318"
+	for _, rate := range []float64{0, 0.5, 1} {
+		opt.SampleRate = rate
+		if _, err = NewTracer(opt); err != nil {
+			t.Error(err)
+		}
+	}
+
+}
+
+func TestNewExporter(t *testing.T) {
+
+	for _, o := range []*otlpopts.Options{nil, {Protocol: otlpopts.ProtocolGRPC},
+		{Protocol: otlpopts.ProtocolGRPC, Compression: otlpopts.CompressionGzip}} {
+		e, err := NewExporter("http://1.2.3.4:4318", "user", "pass", o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Shutdown(context.Background())
+	}
+
+	tests := []struct {
+		url string
+		o   *otlpopts.Options
+	}{
+		{"http://1.2.3.4:4318", &otlpopts.Options{Protocol: "http/json"}},
+		{"1.2.3.4:4318", nil},
+		{"http://1.2.3.4:4318", &otlpopts.Options{Compression: "zstd"}},
+		{"http://1.2.3.4:4318", &otlpopts.Options{TLS: &to.Options{
+			CertificateAuthorityPaths: []string{"/path/does/not/exist"}}}},
+		{"http://1.2.3.4:4318", &otlpopts.Options{TLS: &to.Options{
+			ClientCertPath: "/path/does/not/exist", ClientKeyPath: "/path/does/not/exist"}}},
+		{"%", nil},
+	}
+	for _, test := range tests {
+		if _, err := NewExporter(test.url, "", "", test.o); err == nil {
+			t.Errorf("expected error for %s %v", test.url, test.o)
+		}
+	}
+
+}
+
+func TestExportHTTP(t *testing.T) {
+
+	ch := make(chan *testExport, 4)
+	s := newHTTPReceiver(t, ch, otlphttp.DefaultTracesPath)
+	defer s.Close()
+
+	for _, c := range []string{otlpopts.CompressionNone, otlpopts.CompressionGzip} {
+		oo := otlpopts.New()
+		oo.Compression = c
+		oo.Headers = map[string]string{"X-Test": "test"}
+		exportTestSpan(testTracer(t, s.URL, oo))
+		select {
+		case te := <-ch:
+			checkExport(t, te)
+		default:
+			t.Fatal("expected export for compression " + c)
+		}
+	}
+
+	// nothing is exported when the sample rate is 0
+	tr := testTracer(t, s.URL, nil)
+	tr.Options.SampleRate = 0
+	tr, _ = NewTracer(tr.Options)
+	exportTestSpan(tr)
+	if len(ch) != 0 {
+		t.Errorf("expected %d got %d", 0, len(ch))
+	}
+
+	// a collector url path is used in place of the default traces path, and the
+	// collector credentials are sent as basic auth
+	s2 := newHTTPReceiver(t, ch, "/custom/traces")
+	defer s2.Close()
+	e, err := NewExporter(s2.URL+"/custom/traces", "user", "pass", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ExportSpans(context.Background(), testSnapshots()); err != nil {
+		t.Error(err)
+	}
+	select {
+	case te := <-ch:
+		if te.header.Get("Authorization") != "Basic dXNlcjpwYXNz" {
+			t.Errorf("unexpected authorization header %s", te.header.Get("Authorization"))
+		}
+	default:
+		t.Error("expected export to custom path")
+	}
+	e.Shutdown(context.Background())
+
+	e, _ = NewExporter(s.URL+"/invalid", "", "", nil)
+	if err := e.ExportSpans(context.Background(), testSnapshots()); err == nil {
+		t.Error("expected error for 404 response")
+	}
+	e.Shutdown(context.Background())
+
+}
+
+func TestExportGRPC(t *testing.T) {
+
+	ch := make(chan *testExport, 4)
+	s := newGRPCReceiver(t, ch, "0", false)
+	defer s.Close()
+
+	for _, c := range []string{otlpopts.CompressionNone, otlpopts.CompressionGzip} {
+		oo := otlpopts.New()
+		oo.Protocol = otlpopts.ProtocolGRPC
+		oo.Compression = c
+		oo.Headers = map[string]string{"X-Test": "test"}
+		oo.TLS = &to.Options{InsecureSkipVerify: true}
+		exportTestSpan(testTracer(t, s.URL, oo))
+		select {
+		case te := <-ch:
+			checkExport(t, te)
+		default:
+			t.Fatal("expected export for compression " + c)
+		}
+	}
+
+	s2 := newGRPCReceiver(t, ch, "3", false)
+	defer s2.Close()
+	e, err := NewExporter(s2.URL, "", "", &otlpopts.Options{Protocol: otlpopts.ProtocolGRPC,
+		TLS: &to.Options{InsecureSkipVerify: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ExportSpans(context.Background(), testSnapshots()); err == nil {
+		t.Error("expected error for invalid argument grpc status")
+	}
+	e.Shutdown(context.Background())
+	<-ch
+
+	// plaintext collectors are exported to over h2c
+	ch3 := make(chan *testExport, 4)
+	s3 := newGRPCReceiver(t, ch3, "0", true)
+	defer s3.Close()
+	oo := otlpopts.New()
+	oo.Protocol = otlpopts.ProtocolGRPC
+	oo.Headers = map[string]string{"X-Test": "test"}
+	exportTestSpan(testTracer(t, s3.URL, oo))
+	select {
+	case te := <-ch3:
+		checkExport(t, te)
+	default:
+		t.Fatal("expected export to plaintext collector")
+	}
+
+}
@@ -18,6 +18,7 @@ package options
 
 import (
 	jaegeropts "github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/jaeger/options"
+	otlpopts "github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/otlp/options"
 	stdoutopts "github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/stdout/options"
 	"github.com/tricksterproxy/trickster/pkg/util/copiers"
 	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
@@ -37,6 +38,7 @@ type Options struct {
 
 	StdOutOptions *stdoutopts.Options `yaml:"stdout,omitempty"`
 	JaegerOptions *jaegeropts.Options `yaml:"jaeger,omitempty"`
+	OTLPOptions   *otlpopts.Options   `yaml:"otlp,omitempty"`
 
 	OmitTags map[string]interface{} `yaml:"-"`
 	// for tracers that don't support WithProcess (e.g., Zipkin)
@@ -50,6 +52,7 @@ func New() *Options {
 		ServiceName:   DefaultTracerServiceName,
 		StdOutOptions: &stdoutopts.Options{},
 		JaegerOptions: &jaegeropts.Options{},
+		OTLPOptions:   otlpopts.New(),
 	}
 }
 
@@ -63,6 +66,10 @@ func (o *Options) Clone() *Options {
 	if o.JaegerOptions != nil {
 		jo = o.JaegerOptions.Clone()
 	}
+	var oo *otlpopts.Options
+	if o.OTLPOptions != nil {
+		oo = o.OTLPOptions.Clone()
+	}
 	return &Options{
 		Name:             o.Name,
 		Provider:         o.Provider,
@@ -76,6 +83,7 @@ func (o *Options) Clone() *Options {
 		OmitTagsList:     copiers.CopyStrings(o.OmitTagsList),
 		StdOutOptions:    so,
 		JaegerOptions:    jo,
+		OTLPOptions:      oo,
 		attachTagsToSpan: o.attachTagsToSpan,
 	}
 }
@@ -99,6 +107,26 @@ func ProcessTracingOptions(mo map[string]*Options, metadata yamlx.KeyLookup) {
 		}
 		v.generateOmitTags()
 		v.setAttachTags()
+		v.setOTLPDefaults()
+	}
+}
+
+func (o *Options) setOTLPDefaults() {
+	if o.Provider != "otlp" {
+		return
+	}
+	if o.OTLPOptions == nil {
+		o.OTLPOptions = otlpopts.New()
+		return
+	}
+	if o.OTLPOptions.Protocol == "" {
+		o.OTLPOptions.Protocol = otlpopts.ProtocolHTTP
+	}
+	if o.OTLPOptions.Compression == "" {
+		o.OTLPOptions.Compression = otlpopts.CompressionNone
+	}
+	if o.OTLPOptions.TimeoutMS <= 0 {
+		o.OTLPOptions.TimeoutMS = otlpopts.DefaultTimeoutMS
 	}
 }
 
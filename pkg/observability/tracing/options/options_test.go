@@ -19,6 +19,7 @@ package options
 import (
 	"testing"
 
+	otlpopts "github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/otlp/options"
 	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
 )
 
@@ -71,3 +72,27 @@ func TestAttachTagsToSpan(t *testing.T) {
 	}
 
 }
+
+func TestSetOTLPDefaults(t *testing.T) {
+
+	o := &Options{Provider: "otlp"}
+	o.setOTLPDefaults()
+	if o.OTLPOptions == nil || o.OTLPOptions.Protocol != otlpopts.ProtocolHTTP {
+		t.Error("expected default otlp options")
+	}
+
+	o.OTLPOptions = &otlpopts.Options{Protocol: otlpopts.ProtocolGRPC}
+	o.setOTLPDefaults()
+	if o.OTLPOptions.Protocol != otlpopts.ProtocolGRPC ||
+		o.OTLPOptions.Compression != otlpopts.CompressionNone ||
+		o.OTLPOptions.TimeoutMS != otlpopts.DefaultTimeoutMS {
+		t.Error("unexpected otlp options")
+	}
+
+	o = &Options{Provider: "zipkin"}
+	o.setOTLPDefaults()
+	if o.OTLPOptions != nil {
+		t.Error("expected nil otlp options")
+	}
+
+}
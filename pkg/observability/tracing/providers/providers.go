@@ -30,6 +30,8 @@ const (
 	Jaeger
 	// Zipkin indicates Zipkin tracing
 	Zipkin
+	// OTLP indicates OpenTelemetry Protocol tracing
+	OTLP
 )
 
 // Names is a map of tracing providers keyed by name
@@ -38,6 +40,7 @@ var Names = map[string]Provider{
 	"stdout": Stdout,
 	"jaeger": Jaeger,
 	"zipkin": Zipkin,
+	"otlp":   OTLP,
 }
 
 // Values is a map of tracing providers keyed by internal id
@@ -26,6 +26,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/observability/tracing"
 	"github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/jaeger"
 	"github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/noop"
+	"github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/otlp"
 	"github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/stdout"
 	"github.com/tricksterproxy/trickster/pkg/observability/tracing/exporters/zipkin"
 	"github.com/tricksterproxy/trickster/pkg/observability/tracing/options"
@@ -117,6 +118,9 @@ func GetTracer(options *options.Options, logger interface{}, isDryRun bool) (*tr
 	case providers.Zipkin.String():
 		logTracerRegistration()
 		return zipkin.NewTracer(options)
+	case providers.OTLP.String():
+		logTracerRegistration()
+		return otlp.NewTracer(options)
 	}
 
 	return nil, nil
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+)
+
+// WithPathCaptures returns a copy of the provided context that also includes
+// the named segments captured from the request path by a regex or template path match
+func WithPathCaptures(ctx context.Context, captures map[string]string) context.Context {
+	return context.WithValue(ctx, pathCapturesKey, captures)
+}
+
+// PathCaptures returns the named path segments captured for the request
+func PathCaptures(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	v := ctx.Value(pathCapturesKey)
+	if v != nil {
+		if c, ok := v.(map[string]string); ok {
+			return c
+		}
+	}
+	return nil
+}
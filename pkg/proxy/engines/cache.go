@@ -17,15 +17,15 @@
 package engines
 
 import (
-	"bytes"
 	"context"
-	"io"
 	"mime"
 	"net/http"
 	"strings"
 	"time"
 
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
 	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/codec"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
 	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
 	tspan "github.com/tricksterproxy/trickster/pkg/observability/tracing/span"
@@ -34,7 +34,6 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/ranges/byterange"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 
-	"github.com/andybalholm/brotli"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -78,6 +77,35 @@ func QueryCache(ctx context.Context, c cache.Cache, key string,
 			return d, status.LookupStatusKeyMiss, ranges, err
 		}
 
+	} else if tc, mc, ok := tieredMemoryCache(c); ok {
+
+		// check the memory L1 cache by reference, and fall through to the L2 cache
+		var ifc interface{}
+		ifc, lookupStatus, err = mc.RetrieveReference(key, true)
+		if err == nil && lookupStatus == status.LookupStatusHit && ifc != nil {
+			d, _ = ifc.(*HTTPDocument)
+		} else {
+			b, lookupStatus, err = tc.L2().Retrieve(key, true)
+			if err != nil || (lookupStatus != status.LookupStatusHit) {
+				var nr byterange.Ranges
+				if lookupStatus == status.LookupStatusKeyMiss && ranges != nil && len(ranges) > 0 {
+					nr = ranges
+				}
+				tspan.SetAttributes(rsc.Tracer, span, attribute.String("cache.status", lookupStatus.String()))
+				return d, lookupStatus, nr, err
+			}
+			d, err = decodeCacheDocument(rsc, key, b)
+			if err != nil {
+				tspan.SetAttributes(rsc.Tracer, span, attribute.String("cache.status", status.LookupStatusKeyMiss.String()))
+				return d, status.LookupStatusKeyMiss, ranges, err
+			}
+			// backfill the L1 cache so subsequent lookups are served by reference, for no
+			// longer than the L2 cache retains the object
+			if ttl, ok := tc.BackfillTTL(key); ok {
+				mc.StoreReference(key, d, ttl)
+			}
+		}
+
 	} else {
 
 		b, lookupStatus, err = c.Retrieve(key, true)
@@ -92,35 +120,8 @@ func QueryCache(ctx context.Context, c cache.Cache, key string,
 			return d, lookupStatus, nr, err
 		}
 
-		var inflate bool
-		// check and remove compression bit
-		if len(b) > 0 {
-			if b[0] == 1 {
-				inflate = true
-			}
-			b = b[1:]
-		}
-
-		if inflate {
-			tl.Debug(rsc.Logger, "decompressing cached data", tl.Pairs{"cacheKey": key})
-			decoder := brotli.NewReader(bytes.NewReader(b))
-			b, err = io.ReadAll(decoder)
-			if err != nil {
-				tl.Error(rsc.Logger, "error decoding cache document", tl.Pairs{
-					"cacheKey": key,
-					"detail":   err.Error(),
-				})
-				tspan.SetAttributes(rsc.Tracer, span, attribute.String("cache.status", status.LookupStatusKeyMiss.String()))
-				return d, status.LookupStatusKeyMiss, ranges, err
-			}
-
-		}
-		_, err = d.UnmarshalMsg(b)
+		d, err = decodeCacheDocument(rsc, key, b)
 		if err != nil {
-			tl.Error(rsc.Logger, "error unmarshaling cache document", tl.Pairs{
-				"cacheKey": key,
-				"detail":   err.Error(),
-			})
 			tspan.SetAttributes(rsc.Tracer, span, attribute.String("cache.status", status.LookupStatusKeyMiss.String()))
 			return d, status.LookupStatusKeyMiss, ranges, err
 		}
@@ -155,6 +156,65 @@ func QueryCache(ctx context.Context, c cache.Cache, key string,
 	return d, lookupStatus, delta, nil
 }
 
+// decodeCacheDocument decompresses (if needed) and unmarshals a serialized cache document
+func decodeCacheDocument(rsc *request.Resources, key string, b []byte) (*HTTPDocument, error) {
+
+	d := &HTTPDocument{}
+	var err error
+
+	// the leading byte identifies the codec, if any, used to compress the document
+	if len(b) > 0 && codec.ID(b[0]) != codec.IDNone {
+		tl.Debug(rsc.Logger, "decompressing cached data", tl.Pairs{"cacheKey": key})
+	}
+	b, err = codec.Decode(b)
+	if err != nil {
+		tl.Error(rsc.Logger, "error decoding cache document", tl.Pairs{
+			"cacheKey": key,
+			"detail":   err.Error(),
+		})
+		return d, err
+	}
+
+	_, err = d.UnmarshalMsg(b)
+	if err != nil {
+		tl.Error(rsc.Logger, "error unmarshaling cache document", tl.Pairs{
+			"cacheKey": key,
+			"detail":   err.Error(),
+		})
+	}
+	return d, err
+}
+
+// tieredMemoryCache returns the tiered cache and its L1 memory cache, if c is a
+// tiered cache with a memory L1, so that documents can be stored in L1 by reference
+func tieredMemoryCache(c cache.Cache) (cache.TieredCache, cache.MemoryCache, bool) {
+	tc, ok := c.(cache.TieredCache)
+	if !ok || tc.L1() == nil || tc.L1().Configuration().Provider != "memory" {
+		return nil, nil, false
+	}
+	mc, ok := tc.L1().(cache.MemoryCache)
+	if !ok {
+		return nil, nil, false
+	}
+	return tc, mc, true
+}
+
+// resetReference prepares a document to be stored by reference in a memory cache
+func resetReference(d *HTTPDocument) {
+	if d == nil {
+		return
+	}
+	// during unmarshal, these would come back as false, so lets set them as such even for direct access
+	d.rangePartsLoaded = false
+	d.isFulfillment = false
+	d.isLoaded = false
+	d.RangeParts = nil
+
+	if d.CachingPolicy != nil {
+		d.CachingPolicy.ResetClientConditionals()
+	}
+}
+
 func stripConditionalHeaders(h http.Header) {
 	h.Del(headers.NameIfMatch)
 	h.Del(headers.NameIfUnmodifiedSince)
@@ -162,6 +222,18 @@ func stripConditionalHeaders(h http.Header) {
 	h.Del(headers.NameIfModifiedSince)
 }
 
+// cacheCodec returns the codec and level used to compress documents the backend writes
+// to the cache. The backend's codec, when set, overrides the cache's codec
+func cacheCodec(c cache.Cache, o *bo.Options) (codec.ID, int) {
+	if o != nil && o.CacheCompressionCodec != "" {
+		return o.CacheCompressionCodecID, o.CacheCompressionLevel
+	}
+	if cc := c.Configuration(); cc != nil {
+		return cc.CompressionCodecID, cc.CompressionLevel
+	}
+	return codec.IDBrotli, 0
+}
+
 // WriteCache writes an HTTPDocument to the cache
 func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 	ttl time.Duration, compressTypes map[string]interface{}) error {
@@ -198,19 +270,7 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 	// for memory cache, don't serialize the document, since we can retrieve it by reference.
 	if c.Configuration().Provider == "memory" {
 		mc := c.(cache.MemoryCache)
-
-		if d != nil {
-			// during unmarshal, these would come back as false, so lets set them as such even for direct access
-			d.rangePartsLoaded = false
-			d.isFulfillment = false
-			d.isLoaded = false
-			d.RangeParts = nil
-
-			if d.CachingPolicy != nil {
-				d.CachingPolicy.ResetClientConditionals()
-			}
-		}
-
+		resetReference(d)
 		return mc.StoreReference(key, d, ttl)
 	}
 
@@ -223,18 +283,36 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 		})
 	}
 
+	id, level := codec.IDNone, 0
 	if compress {
-		tl.Debug(rsc.Logger, "compressing cache data", tl.Pairs{"cacheKey": key})
-		buf := bytes.NewBuffer([]byte{1})
-		encoder := brotli.NewWriter(buf)
-		encoder.Write(b)
-		encoder.Close()
-		b = buf.Bytes()
-	} else {
-		b = append([]byte{0}, b...)
+		id, level = cacheCodec(c, rsc.BackendOptions)
+		if id != codec.IDNone {
+			tl.Debug(rsc.Logger, "compressing cache data", tl.Pairs{"cacheKey": key})
+		}
+	}
+	b, err = codec.Encode(id, level, b)
+	if err != nil {
+		tl.Error(rsc.Logger, "error compressing cache document", tl.Pairs{
+			"cacheKey": key,
+			"detail":   err.Error(),
+		})
+		return err
 	}
 
-	err = c.Store(key, b, ttl)
+	// for a tiered cache with a memory L1, store the document by reference in L1
+	// and the serialized document in L2
+	if tc, mc, ok := tieredMemoryCache(c); ok {
+		resetReference(d)
+		if err = mc.StoreReference(key, d, tc.L1TTL(ttl)); err != nil {
+			tl.Warn(rsc.Logger, "error writing l1 cache document", tl.Pairs{
+				"cacheKey": key,
+				"detail":   err.Error(),
+			})
+		}
+		err = tc.StoreL2(key, b, ttl)
+	} else {
+		err = c.Store(key, b, ttl)
+	}
 	if err != nil {
 		if span != nil {
 			span.AddEvent(
@@ -29,7 +29,10 @@ import (
 	"time"
 
 	"github.com/tricksterproxy/trickster/cmd/trickster/config"
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/codec"
 	co "github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/providers"
 	"github.com/tricksterproxy/trickster/pkg/cache/registration"
 	cr "github.com/tricksterproxy/trickster/pkg/cache/registration"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
@@ -439,6 +442,126 @@ func TestQueryCache(t *testing.T) {
 
 }
 
+func TestQueryCacheTiered(t *testing.T) {
+
+	expected := "1234"
+
+	newCache := func(name, provider string) *co.Options {
+		o := co.New()
+		o.Name = name
+		o.Provider = provider
+		o.ProviderID = providers.Names[provider]
+		o.Filesystem.CachePath = t.TempDir()
+		return o
+	}
+
+	caches := map[string]cache.Cache{
+		"l1": registration.NewCache("l1", newCache("l1", "memory"), testLogger),
+		"l2": registration.NewCache("l2", newCache("l2", "filesystem"), testLogger),
+	}
+	o := newCache("tiered", "tiered")
+	o.Tiered.L1 = "l1"
+	o.Tiered.L2 = "l2"
+	caches["tiered"] = registration.NewCache("tiered", o, testLogger)
+	registration.ConnectTiers(caches, testLogger)
+	defer registration.CloseCaches(caches)
+	c := caches["tiered"]
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url", "http://1", "-provider", "test"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	resp := &http.Response{}
+	resp.Header = make(http.Header)
+	resp.StatusCode = 200
+	resp.Header.Add(headers.NameContentLength, "4")
+	d := DocumentFromHTTPResponse(resp, []byte(expected), nil, testLogger)
+	d.ContentType = "text/plain"
+
+	ctx := context.Background()
+	ctx = tc.WithResources(ctx, &request.Resources{BackendOptions: conf.Backends["default"], Tracer: tu.NewTestTracer(), Logger: testLogger})
+
+	err = WriteCache(ctx, c, "testKey", d, time.Duration(60)*time.Second, map[string]interface{}{"text/plain": true})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the L2 cache holds a serialized copy of the document
+	if _, _, err = caches["l2"].Retrieve("testKey", false); err != nil {
+		t.Error(err)
+	}
+
+	// an L1 hit is served by reference
+	d2, s, _, err := QueryCache(ctx, c, "testKey", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if s != status.LookupStatusHit {
+		t.Errorf("expected %s got %s", status.LookupStatusHit, s)
+	}
+	if d2 != d {
+		t.Error("expected L1 hit to return the stored reference")
+	}
+
+	// an L1 miss falls through to L2 and backfills L1
+	caches["l1"].Remove("testKey")
+	d2, s, _, err = QueryCache(ctx, c, "testKey", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if s != status.LookupStatusHit {
+		t.Errorf("expected %s got %s", status.LookupStatusHit, s)
+	}
+	if d2 == d || string(d2.Body) != expected {
+		t.Errorf("expected a copy of the document with body %s got %s", expected, string(d2.Body))
+	}
+
+	d3, _, _, err := QueryCache(ctx, c, "testKey", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if d3 != d2 {
+		t.Error("expected backfilled L1 hit to return the stored reference")
+	}
+
+	c.Remove("testKey")
+	_, s, _, err = QueryCache(ctx, c, "testKey", nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	if s != status.LookupStatusKeyMiss {
+		t.Errorf("expected %s got %s", status.LookupStatusKeyMiss, s)
+	}
+
+	// an object that expires from L2 before the L1 TTL is backfilled for no longer
+	// than L2 retains it
+	err = WriteCache(ctx, c, "testKey", d, time.Duration(2)*time.Second, map[string]interface{}{"text/plain": true})
+	if err != nil {
+		t.Error(err)
+	}
+	caches["l1"].Remove("testKey")
+	if _, _, _, err = QueryCache(ctx, c, "testKey", nil); err != nil {
+		t.Error(err)
+	}
+	rt, ok := caches["l1"].(cache.TTLReporter).RemainingTTL("testKey")
+	if !ok || rt <= 0 || rt > time.Duration(2)*time.Second {
+		t.Errorf("expected backfilled L1 ttl no longer than 2s got %s", rt)
+	}
+
+	// an object that has expired from L2 is not backfilled
+	err = WriteCache(ctx, c, "testKey", d, time.Duration(50)*time.Millisecond, map[string]interface{}{"text/plain": true})
+	if err != nil {
+		t.Error(err)
+	}
+	caches["l1"].Remove("testKey")
+	time.Sleep(time.Duration(100) * time.Millisecond)
+	QueryCache(ctx, c, "testKey", nil)
+	if _, s, _ := caches["l1"].Retrieve("testKey", true); s == status.LookupStatusHit {
+		t.Error("expected object expired from L2 not to be backfilled into L1")
+	}
+}
+
 // Mock Cache for testing error conditions
 type testCache struct {
 	configuration *co.Options
@@ -466,3 +589,71 @@ func (tc *testCache) Close() error                              { return errTest
 func (tc *testCache) Configuration() *co.Options                { return tc.configuration }
 func (tc *testCache) Locker() locks.NamedLocker                 { return tc.locker }
 func (tc *testCache) SetLocker(l locks.NamedLocker)             { tc.locker = l }
+
+func TestWriteCacheCodec(t *testing.T) {
+
+	expected := "1234"
+
+	o := co.New()
+	o.Name = "test"
+	o.Provider = "filesystem"
+	o.ProviderID = providers.Filesystem
+	o.Filesystem.CachePath = t.TempDir()
+	o.CompressionCodec = codec.Zstd
+	o.CompressionCodecID = codec.IDZstd
+	c := registration.NewCache("test", o, testLogger)
+	c.Connect()
+	defer c.Close()
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url", "http://1", "-provider", "test"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	bo := conf.Backends["default"]
+
+	resp := &http.Response{}
+	resp.Header = make(http.Header)
+	resp.StatusCode = 200
+	resp.Header.Add(headers.NameContentLength, "4")
+	d := DocumentFromHTTPResponse(resp, []byte(expected), nil, testLogger)
+	d.ContentType = "text/plain"
+
+	ctx := tc.WithResources(context.Background(),
+		&request.Resources{BackendOptions: bo, Tracer: tu.NewTestTracer(), Logger: testLogger})
+
+	tests := []struct {
+		backendCodec  string
+		compressTypes map[string]interface{}
+		expected      codec.ID
+	}{
+		// the cache's codec
+		{"", map[string]interface{}{"text/plain": true}, codec.IDZstd},
+		// the backend's codec overrides the cache's
+		{codec.Snappy, map[string]interface{}{"text/plain": true}, codec.IDSnappy},
+		// content types that are not compressible are stored uncompressed
+		{"", map[string]interface{}{}, codec.IDNone},
+	}
+
+	for i, test := range tests {
+		bo.CacheCompressionCodec = test.backendCodec
+		bo.CacheCompressionCodecID, _ = codec.Lookup(test.backendCodec)
+		err = WriteCache(ctx, c, "testKey", d, time.Minute, test.compressTypes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _, err := c.Retrieve("testKey", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if codec.ID(b[0]) != test.expected {
+			t.Errorf("test %d: expected codec %d got %d", i, test.expected, b[0])
+		}
+		d2, _, _, err := QueryCache(ctx, c, "testKey", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(d2.Body) != expected {
+			t.Errorf("test %d: expected %s got %s", i, expected, string(d2.Body))
+		}
+	}
+}
@@ -45,6 +45,12 @@ type CachingPolicy struct {
 	IfNoneMatchResult    bool `msg:"-"`
 
 	FreshnessLifetime int `msg:"freshness_lifetime"`
+	// StaleWhileRevalidate is the number of seconds past the FreshnessLifetime that the
+	// object may be served stale while it is revalidated in the background (RFC 5861)
+	StaleWhileRevalidate int `msg:"stale_while_revalidate"`
+	// StaleIfError is the number of seconds past the FreshnessLifetime that the object
+	// may be served stale when revalidation fails with a 5xx or transport error (RFC 5861)
+	StaleIfError int `msg:"stale_if_error"`
 
 	LastModified time.Time `msg:"last_modified"`
 	Expires      time.Time `msg:"expires"`
@@ -65,6 +71,8 @@ func (cp *CachingPolicy) Clone() *CachingPolicy {
 		NoCache:               cp.NoCache,
 		NoTransform:           cp.NoTransform,
 		FreshnessLifetime:     cp.FreshnessLifetime,
+		StaleWhileRevalidate:  cp.StaleWhileRevalidate,
+		StaleIfError:          cp.StaleIfError,
 		CanRevalidate:         cp.CanRevalidate,
 		MustRevalidate:        cp.MustRevalidate,
 		LastModified:          cp.LastModified,
@@ -116,6 +124,8 @@ func (cp *CachingPolicy) Merge(src *CachingPolicy) {
 
 	cp.IsFresh = src.IsFresh
 	cp.FreshnessLifetime = src.FreshnessLifetime
+	cp.StaleWhileRevalidate = src.StaleWhileRevalidate
+	cp.StaleIfError = src.StaleIfError
 	cp.CanRevalidate = src.CanRevalidate
 	cp.MustRevalidate = src.MustRevalidate
 	cp.LastModified = src.LastModified
@@ -143,10 +153,12 @@ func (cp *CachingPolicy) TTL(multiplier float64, max time.Duration) time.Duratio
 
 func (cp *CachingPolicy) String() string {
 	return fmt.Sprintf(`{ "is_fresh":%t, "no_cache":%t, "no_transform":%t, 
-	"freshness_lifetime":%d, "can_revalidate":%t, "must_revalidate":%t,`+
+	"freshness_lifetime":%d, "stale_while_revalidate":%d, "stale_if_error":%d,`+
+		` "can_revalidate":%t, "must_revalidate":%t,`+
 		` "last_modified":%d, "expires":%d, "date":%d, "local_date":%d, "etag":"%s", "if_none_match":"%s"`+
 		` "if_modified_since":%d, "if_unmodified_since":%d, "is_negative_cache":%t }`,
-		cp.IsFresh, cp.NoCache, cp.NoTransform, cp.FreshnessLifetime, cp.CanRevalidate, cp.MustRevalidate,
+		cp.IsFresh, cp.NoCache, cp.NoTransform, cp.FreshnessLifetime, cp.StaleWhileRevalidate,
+		cp.StaleIfError, cp.CanRevalidate, cp.MustRevalidate,
 		cp.LastModified.Unix(), cp.Expires.Unix(), cp.Date.Unix(), cp.LocalDate.Unix(), cp.ETag,
 		cp.IfNoneMatchValue, cp.IfModifiedSinceTime.Unix(), cp.IfUnmodifiedSinceTime.Unix(), cp.IsNegativeCache)
 }
@@ -262,13 +274,15 @@ func GetResponseCachingPolicy(code int, negativeCache map[int]time.Duration, h h
 }
 
 var supportedCCD = map[string]bool{
-	headers.ValuePrivate:         true,
-	headers.ValueNoCache:         true,
-	headers.ValueNoStore:         true,
-	headers.ValueMaxAge:          false,
-	headers.ValueSharedMaxAge:    false,
-	headers.ValueMustRevalidate:  false,
-	headers.ValueProxyRevalidate: false,
+	headers.ValuePrivate:              true,
+	headers.ValueNoCache:              true,
+	headers.ValueNoStore:              true,
+	headers.ValueMaxAge:               false,
+	headers.ValueSharedMaxAge:         false,
+	headers.ValueMustRevalidate:       false,
+	headers.ValueProxyRevalidate:      false,
+	headers.ValueStaleIfError:         false,
+	headers.ValueStaleWhileRevalidate: false,
 }
 
 func (cp *CachingPolicy) parseCacheControlDirectives(directives string) {
@@ -276,6 +290,7 @@ func (cp *CachingPolicy) parseCacheControlDirectives(directives string) {
 	var noCache bool
 	var hasSharedMaxAge bool
 	var foundFreshnessDirective bool
+	var hasRevalidateDirective bool
 	for _, d := range dl {
 		var dsub string
 		if i := strings.Index(d, "="); i > 0 {
@@ -305,14 +320,31 @@ func (cp *CachingPolicy) parseCacheControlDirectives(directives string) {
 				cp.FreshnessLifetime = secs
 			}
 		}
-		if (d == headers.ValueMustRevalidate || d == headers.ValueProxyRevalidate) ||
-			(cp.FreshnessLifetime == 0 && foundFreshnessDirective) {
+		isRevalidateDirective := d == headers.ValueMustRevalidate || d == headers.ValueProxyRevalidate
+		hasRevalidateDirective = hasRevalidateDirective || isRevalidateDirective
+		if isRevalidateDirective || (cp.FreshnessLifetime == 0 && foundFreshnessDirective) {
 			cp.MustRevalidate = true
 			cp.FreshnessLifetime = 0
 		}
 		if d == headers.ValueNoTransform {
 			cp.NoTransform = true
 		}
+		if d == headers.ValueStaleWhileRevalidate && dsub != "" {
+			if secs, err := strconv.Atoi(dsub); err == nil && secs > 0 {
+				cp.StaleWhileRevalidate = secs
+			}
+		}
+		if d == headers.ValueStaleIfError && dsub != "" {
+			if secs, err := strconv.Atoi(dsub); err == nil && secs > 0 {
+				cp.StaleIfError = secs
+			}
+		}
+	}
+
+	// must-revalidate and proxy-revalidate prohibit serving the object stale
+	if hasRevalidateDirective {
+		cp.StaleWhileRevalidate = 0
+		cp.StaleIfError = 0
 	}
 
 }
@@ -401,7 +433,8 @@ func CheckIfNoneMatch(etag string, headerValue string, ls status.LookupStatus) b
 	}
 
 	if headerValue == "*" {
-		if ls == status.LookupStatusHit || ls == status.LookupStatusRevalidated {
+		if ls == status.LookupStatusHit || ls == status.LookupStatusRevalidated ||
+			ls == status.LookupStatusStale {
 			return false
 		}
 		return true
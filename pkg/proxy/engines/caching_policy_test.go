@@ -197,6 +197,34 @@ func TestGetResponseCachingPolicy(t *testing.T) {
 	}
 }
 
+func TestGetResponseCachingPolicyStale(t *testing.T) {
+
+	tests := []struct {
+		cc       string
+		swr, sie int
+	}{
+		{headers.ValueMaxAge + "=60", 0, 0},
+		{headers.ValueMaxAge + "=60, stale-while-revalidate=30", 30, 0},
+		{headers.ValueMaxAge + "=60, stale-if-error=300", 0, 300},
+		{"Stale-While-Revalidate=30, max-age=60, stale-if-error=300", 30, 300},
+		{headers.ValueMaxAge + "=60, stale-while-revalidate=x, stale-if-error=-5", 0, 0},
+		{headers.ValueMaxAge + "=60, stale-while-revalidate=30, must-revalidate", 0, 0},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			p := GetResponseCachingPolicy(200, nil,
+				http.Header{headers.NameCacheControl: []string{test.cc}})
+			if p.StaleWhileRevalidate != test.swr {
+				t.Errorf("expected %d got %d", test.swr, p.StaleWhileRevalidate)
+			}
+			if p.StaleIfError != test.sie {
+				t.Errorf("expected %d got %d", test.sie, p.StaleIfError)
+			}
+		})
+	}
+}
+
 func TestResolveClientConditionalsIUS(t *testing.T) {
 
 	cp := &CachingPolicy{
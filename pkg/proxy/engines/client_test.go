@@ -280,6 +280,11 @@ func (c *TestClient) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRange
 		return nil, nil, false, errors.MissingURLParam(upStep)
 	}
 
+	// the query is the same at any step once the step parameter is removed
+	sqp, _ := url.ParseQuery(r.URL.RawQuery)
+	sqp.Del(upStep)
+	trq.StepTemplateURL = &url.URL{Path: r.URL.Path, RawQuery: sqp.Encode()}
+
 	if strings.Contains(trq.Statement, " offset ") {
 		trq.IsOffset = true
 		rlo.FastForwardDisable = true
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/backends"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
 	tc "github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
@@ -109,6 +110,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 	client.SetExtent(pr.upstreamRequest, trq, &trq.Extent)
 	key := o.CacheKeyPrefix + ".dpc." + pr.DeriveCacheKey("")
 	pr.cacheLock, _ = locker.RAcquire(key)
+	stepsKey := stepIndexKey(pr, trq)
 
 	// this is used to determine if Fast Forward should be activated for this request
 	normalizedNow := &timeseries.TimeRangeQuery{
@@ -128,8 +130,17 @@ checkCache:
 			span.AddEvent("Not Caching")
 		}
 		cacheStatus = status.LookupStatusPurge
-		go cache.Remove(key)
+		if !o.TimeseriesDegradedServing {
+			go cache.Remove(key)
+		}
 		cts, doc, elapsed, err = fetchTimeseries(pr, trq, client, modeler)
+		if err != nil && o.TimeseriesDegradedServing {
+			// the cached timeseries, if any, is used in place of the upstream error
+			tl.Debug(pr.Logger, "upstream request failed, checking cache for degraded response",
+				tl.Pairs{"key": key})
+			coReq.NoCache = false
+			goto checkCache
+		}
 		if err != nil {
 			pr.cacheLock.RRelease()
 			h := doc.SafeHeaderClone()
@@ -138,8 +149,21 @@ checkCache:
 			Respond(w, doc.StatusCode, h, bytes.NewReader(doc.Body))
 			return // fetchTimeseries logs the error
 		}
+		if o.TimeseriesDegradedServing {
+			// removed synchronously so that it cannot race the write-back of the
+			// fresh timeseries below
+			cache.Remove(key)
+		}
 	} else {
 		doc, cacheStatus, _, err = QueryCache(ctx, cache, key, nil)
+		if cacheStatus == status.LookupStatusKeyMiss && err == tc.ErrKNF && stepsKey != "" {
+			// the query may be cached at a finer step that can be resampled to this one
+			if rts, rdoc := resampleFromCache(ctx, pr, cache, stepsKey, key, trq,
+				modeler); rts != nil {
+				cts, doc, err = rts, rdoc, nil
+				cacheStatus = status.LookupStatusPartialHit
+			}
+		}
 		if cacheStatus == status.LookupStatusKeyMiss && err == tc.ErrKNF {
 			cts, doc, elapsed, err = fetchTimeseries(pr, trq, client, modeler)
 			if err != nil {
@@ -155,7 +179,9 @@ checkCache:
 			if doc == nil {
 				err = tpe.ErrEmptyDocumentBody
 			} else {
-				if cc.Provider == "memory" {
+				// documents from a memory cache, or from the memory L1 of a tiered
+				// cache, hold a reference to the timeseries, unless it is stored compacted
+				if doc.timeseries != nil {
 					cts = doc.timeseries
 				} else {
 					cts, err = modeler.CacheUnmarshaler(doc.Body, trq)
@@ -283,18 +309,33 @@ checkCache:
 		dpStatus["extentsFetched"] = missRanges.String()
 	}
 
+	// when sharding is configured, large miss ranges are split into smaller upstream requests
+	fetchRanges := missRanges
+	sem := newShardSemaphore(o, trq.Step)
+	if sem != nil {
+		fetchRanges = missRanges.Splice(trq.Step, o.ShardMaxPoints(trq.Step))
+	}
+
 	// maintain a list of timeseries to merge into the main timeseries
-	mts := make([]timeseries.Timeseries, 0, len(missRanges))
+	mts := make([]timeseries.Timeseries, 0, len(fetchRanges))
 	wg := sync.WaitGroup{}
 	appendLock := sync.Mutex{}
 	var uncachedValueCount int64
 
+	// the first failed upstream response, retained to be passed through to the client
+	var failedResp *http.Response
+	var failedBody []byte
+
 	// iterate each time range that the client needs and fetch from the upstream origin
-	for i := range missRanges {
+	for i := range fetchRanges {
 		wg.Add(1)
 		// This fetches the gaps from the origin and adds their datasets to the merge list
 		go func(e *timeseries.Extent, rq *proxyRequest) {
 			defer wg.Done()
+			if sem != nil {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
 
 			mrsc := request.NewResources(o, pc, cc, cache, client, rsc.Tracer, pr.Logger)
 			rq.upstreamRequest = rq.WithContext(tctx.WithResources(
@@ -321,14 +362,20 @@ checkCache:
 				doc.headerLock.Lock()
 				headers.Merge(doc.Headers, resp.Header)
 				doc.headerLock.Unlock()
-				uncachedValueCount += nts.ValueCount()
 				nts.SetTimeRangeQuery(trq)
 				nts.SetExtents([]timeseries.Extent{*e})
 				appendLock.Lock()
+				uncachedValueCount += nts.ValueCount()
 				mts = append(mts, nts)
 				appendLock.Unlock()
+			} else if resp.StatusCode != http.StatusOK {
+				appendLock.Lock()
+				if failedResp == nil {
+					failedResp, failedBody = resp, body
+				}
+				appendLock.Unlock()
 			}
-		}(&missRanges[i], pr.Clone())
+		}(&fetchRanges[i], pr.Clone())
 	}
 
 	var hasFastForwardData bool
@@ -370,6 +417,27 @@ checkCache:
 
 	wg.Wait()
 
+	// when the upstream fails to provide an uncached extent, the failed extent is left out of
+	// the response by default. With degraded serving enabled, the client instead receives the
+	// cached portion of the timeseries marked as partial, or the upstream error if nothing
+	// is cached
+	var isDegraded bool
+	if failedResp != nil && o.TimeseriesDegradedServing {
+		if cacheStatus != status.LookupStatusPartialHit {
+			if writeLock != nil {
+				writeLock.Release()
+			}
+			h := failedResp.Header.Clone()
+			recordDPCResult(r, status.LookupStatusProxyError, failedResp.StatusCode,
+				r.URL.Path, ffStatus, time.Since(now).Seconds(), missRanges, h)
+			Respond(w, failedResp.StatusCode, h, bytes.NewReader(failedBody))
+			return
+		}
+		isDegraded = true
+		tl.Warn(pr.Logger, "serving degraded timeseries due to upstream error",
+			tl.Pairs{"key": key, "upstreamStatus": failedResp.StatusCode})
+	}
+
 	// Merge the new delta timeseries into the cached timeseries
 	if len(mts) > 0 {
 		// on phit, elapsed records the time spent waiting for all upstream requests to complete
@@ -379,7 +447,7 @@ checkCache:
 
 	// this handles the tolerance part of backfill tolerance, by adding new tolerable ranges to
 	// the timeseries's volatile list, and removing those that no longer tolerate backfill
-	if bt > 0 && cacheStatus != status.LookupStatusHit {
+	if bt > 0 && cacheStatus != status.LookupStatusHit && !isDegraded {
 
 		var shouldCompress bool
 		ve := cts.VolatileExtents()
@@ -422,7 +490,10 @@ checkCache:
 		rts = cts.Clone()
 	}
 
-	if writeLock != nil {
+	if writeLock != nil && isDegraded {
+		// a degraded timeseries is not written back to the cache
+		writeLock.Release()
+	} else if writeLock != nil {
 		// if the mutex is still locked, it means we need to write the time series to cache
 		go func() {
 			defer writeLock.Release()
@@ -437,9 +508,15 @@ checkCache:
 			// Don't cache datasets with empty extents
 			// (everything was cropped so there is nothing to cache)
 			if len(cts.Extents()) > 0 {
-				if cc.Provider == "memory" {
+				if cc.Provider == "memory" && !cc.CompactTimeseries {
 					doc.timeseries = cts
 				} else {
+					doc.timeseries = nil
+					// a tiered cache with a memory L1 stores the timeseries by reference
+					// in L1, in addition to the serialized body stored in L2
+					if _, _, ok := tieredMemoryCache(cache); ok && !cc.CompactTimeseries {
+						doc.timeseries = cts
+					}
 					cdata, err := modeler.CacheMarshaler(cts, nil, 0)
 					if err != nil {
 						tl.Error(pr.Logger, "error marshaling timeseries", tl.Pairs{
@@ -459,6 +536,8 @@ checkCache:
 							"detail":      err.Error(),
 						},
 					)
+				} else if stepsKey != "" {
+					updateStepIndex(cache, stepsKey, trq.Step, key, o.TimeseriesTTL, pr.Logger)
 				}
 			}
 		}()
@@ -485,7 +564,8 @@ checkCache:
 		rts.Merge(false, ffts)
 	}
 	rts.SetExtents(nil) // so they are not included in the client response json
-	//rts.SetTimeRangeQuery(&timeseries.TimeRangeQuery{})
+	// marshalers that render the query range (e.g., Flux _start and _stop) use the client's trq
+	rts.SetTimeRangeQuery(trq)
 	rh := doc.SafeHeaderClone()
 	sc := doc.StatusCode
 
@@ -493,6 +573,9 @@ checkCache:
 	// so as to not map conflict with cacheData on WriteCache
 	logDeltaRoutine(pr.Logger, dpStatus)
 	recordDPCResult(r, cacheStatus, sc, r.URL.Path, ffStatus, elapsed.Seconds(), missRanges, rh)
+	if isDegraded {
+		rh.Set(headers.NameTricksterResult, headers.SetPartial(rh.Get(headers.NameTricksterResult)))
+	}
 
 	rsc.TS = rts
 	Respond(w, 0, rh, nil) // body and code are nil so this only sets appropriate headers; no writes
@@ -515,10 +598,80 @@ var dpcEncodingProfile = &profile.Profile{
 	SupportedHeaderVal:   providers.AllSupportedWebProviders,
 }
 
+// newShardSemaphore returns a channel that bounds the number of concurrent upstream
+// shard requests, or nil if sharding is not configured for the backend
+func newShardSemaphore(o *bo.Options, step time.Duration) chan struct{} {
+	if o == nil || o.ShardMaxPoints(step) == 0 {
+		return nil
+	}
+	n := o.ShardMaxConcurrency
+	if n <= 0 {
+		n = bo.DefaultShardMaxConcurrency
+	}
+	return make(chan struct{}, n)
+}
+
+// fetchTimeseries fetches the timeseries for the request's extent from the upstream. When
+// sharding is configured and the extent is large enough, the extent is split into multiple
+// upstream requests that are fetched concurrently and merged into a single timeseries
 func fetchTimeseries(pr *proxyRequest, trq *timeseries.TimeRangeQuery,
 	client backends.TimeseriesBackend, modeler *timeseries.Modeler) (timeseries.Timeseries,
 	*HTTPDocument, time.Duration, error) {
 
+	o := request.GetResources(pr.Request).BackendOptions
+	sem := newShardSemaphore(o, trq.Step)
+	if sem == nil {
+		return fetchExtent(pr, trq, modeler)
+	}
+	el := timeseries.ExtentList{trq.Extent}.Splice(trq.Step, o.ShardMaxPoints(trq.Step))
+	if len(el) < 2 {
+		return fetchExtent(pr, trq, modeler)
+	}
+
+	type shard struct {
+		ts  timeseries.Timeseries
+		doc *HTTPDocument
+		err error
+	}
+	shards := make([]shard, len(el))
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range el {
+		wg.Add(1)
+		go func(i int, rq *proxyRequest) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			client.SetExtent(rq.upstreamRequest, trq, &el[i])
+			s := &shards[i]
+			s.ts, s.doc, _, s.err = fetchExtent(rq, trq, modeler)
+			if s.err == nil {
+				s.ts.SetExtents(timeseries.ExtentList{el[i]})
+			}
+		}(i, pr.Clone())
+	}
+	wg.Wait()
+
+	for _, s := range shards {
+		if s.err != nil {
+			return nil, s.doc, time.Duration(0), s.err
+		}
+	}
+
+	ts, d := shards[0].ts, shards[0].doc
+	mts := make([]timeseries.Timeseries, 0, len(shards)-1)
+	for _, s := range shards[1:] {
+		headers.Merge(d.Headers, s.doc.Headers)
+		mts = append(mts, s.ts)
+	}
+	ts.Merge(true, mts...)
+	return ts, d, time.Since(start), nil
+}
+
+// fetchExtent fetches the timeseries for a single upstream request
+func fetchExtent(pr *proxyRequest, trq *timeseries.TimeRangeQuery,
+	modeler *timeseries.Modeler) (timeseries.Timeseries, *HTTPDocument, time.Duration, error) {
+
 	rsc := request.GetResources(pr.Request).Clone()
 	o := rsc.BackendOptions
 	pc := rsc.PathConfig
@@ -21,11 +21,15 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	mockprom "github.com/tricksterproxy/mockster/pkg/mocks/prometheus"
 	"github.com/tricksterproxy/trickster/pkg/backends"
+	"github.com/tricksterproxy/trickster/pkg/backends/breaker"
+	cbo "github.com/tricksterproxy/trickster/pkg/backends/breaker/options"
+	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
@@ -1385,6 +1389,183 @@ func TestDeltaProxyCacheRequestBadGateway(t *testing.T) {
 
 }
 
+func TestDeltaProxyCacheRequestDegraded(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+
+	client := rsc.BackendClient.(*TestClient)
+	o := rsc.BackendOptions
+	rsc.CacheConfig.Provider = "test"
+
+	client.RangeCacheKey = "test-range-key-degraded"
+	client.InstantCacheKey = "test-instant-key-degraded"
+
+	o.FastForwardDisable = true
+	o.TimeseriesDegradedServing = true
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s&rk=%s&ik=%s", int(step.Seconds()),
+		extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency, client.RangeCacheKey, client.InstantCacheKey)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+	expected, _ := io.ReadAll(resp.Body)
+
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "kmiss"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// with the origin down, extending the range serves the cached portion
+	ts.Close()
+	time.Sleep(time.Millisecond * 10)
+	extr.End = extr.End.Add(time.Hour)
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s&rk=%s&ik=%s", int(step.Seconds()),
+		extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency, client.RangeCacheKey, client.InstantCacheKey)
+	r.URL = u
+
+	for i := 0; i < 2; i++ {
+		w = httptest.NewRecorder()
+		client.QueryRangeHandler(w, r)
+		resp = w.Result()
+		bodyBytes, _ := io.ReadAll(resp.Body)
+
+		err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+		if err != nil {
+			t.Error(err)
+		}
+
+		err = testStringMatch(string(bodyBytes), string(expected))
+		if err != nil {
+			t.Error(err)
+		}
+
+		// the degraded response is not cached, so the second request is also a partial hit
+		err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "phit", "partial": "true"})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// a client no-cache request also falls back to the cache
+	r.Header.Set(headers.NameCacheControl, headers.ValueNoCache)
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "phit", "partial": "true"})
+	if err != nil {
+		t.Error(err)
+	}
+	r.Header.Del(headers.NameCacheControl)
+
+	// without degraded serving, the failed extent is left out of the response, which is
+	// not marked as partial
+	o.TimeseriesDegradedServing = false
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+	bodyBytes, _ := io.ReadAll(resp.Body)
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStringMatch(string(bodyBytes), string(expected))
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "phit"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if v := resp.Header.Get(headers.NameTricksterResult); strings.Contains(v, "partial") {
+		t.Errorf("expected response not to be marked partial got %s", v)
+	}
+}
+
+func TestDeltaProxyCacheRequestCircuitHalfOpen(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.BackendClient.(*TestClient)
+	o := rsc.BackendOptions
+	rsc.CacheConfig.Provider = "test"
+
+	client.RangeCacheKey = "test-range-key-breaker"
+	client.InstantCacheKey = "test-instant-key-breaker"
+
+	o.FastForwardDisable = true
+
+	cbopts := cbo.New()
+	cbopts.MinRequests = 2
+	cbopts.OpenDuration = 20 * time.Millisecond
+	cbopts.HalfOpenRequests = 2
+	cb := breaker.New(o.Name, cbopts, nil, testLogger)
+	client.SetBreaker(cb)
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s&rk=%s&ik=%s", int(step.Seconds()),
+		extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency, client.RangeCacheKey, client.InstantCacheKey)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "kmiss"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	for cb.State() != breaker.StateOpen {
+		cb.Record(http.StatusBadGateway, time.Millisecond)
+	}
+	time.Sleep(25 * time.Millisecond)
+
+	// extending the range in both directions makes two upstream fetches, each of which
+	// is a trial request, so the circuit closes once both succeed
+	extr.Start = extr.Start.Add(-time.Hour)
+	extr.End = extr.End.Add(time.Hour)
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s&rk=%s&ik=%s", int(step.Seconds()),
+		extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency, client.RangeCacheKey, client.InstantCacheKey)
+	r.URL = u
+
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "phit"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if cb.State() != breaker.StateClosed {
+		t.Errorf("expected %s got %s", breaker.StateClosed, cb.State())
+	}
+}
+
 func TestDeltaProxyCacheRequest_BackfillTolerance(t *testing.T) {
 
 	ts, w, r, rsc, err := setupTestHarnessDPC()
@@ -1525,3 +1706,214 @@ func TestDeltaProxyCacheRequestFFTTLBiggerThanStep(t *testing.T) {
 	}
 
 }
+
+func TestDeltaProxyCacheRequestSharded(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.BackendClient.(*TestClient)
+	o := rsc.BackendOptions
+	rsc.CacheConfig.Provider = "test"
+
+	o.FastForwardDisable = true
+	o.ShardMaxSizePoints = 5
+	o.ShardMaxConcurrency = 2
+
+	step := time.Duration(3600) * time.Second
+
+	now := time.Now()
+	end := now.Add(-time.Duration(12) * time.Hour)
+
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+	extn := timeseries.Extent{Start: extr.Start.Truncate(step), End: extr.End.Truncate(step)}
+
+	expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extn.Start, extn.End, step)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStringMatch(string(bodyBytes), expected)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "kmiss"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Give time for the object to be written to cache in a separate goroutine from response
+	time.Sleep(time.Millisecond * 10)
+
+	// extend the range by 12 hours, which should be fetched in multiple shards
+	extr.Start = extr.Start.Add(time.Duration(-12) * time.Hour)
+	extn.Start = extr.Start.Truncate(step)
+	expectedFetched := fmt.Sprintf("[%s]",
+		timeseries.Extent{Start: extn.Start, End: extn.Start.Add(step * 11)}.String())
+
+	expected, _, _ = mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extn.Start, extn.End, step)
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	r.URL = u
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+
+	bodyBytes, err = io.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStringMatch(string(bodyBytes), expected)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "phit"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"fetched": expectedFetched})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeltaProxyCacheRequestCompactTimeseries(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.BackendClient.(*TestClient)
+	o := rsc.BackendOptions
+	rsc.CacheConfig.CompactTimeseries = true
+	defer func() { rsc.CacheConfig.CompactTimeseries = false }()
+
+	o.FastForwardDisable = true
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+	extn := timeseries.Extent{Start: extr.Start.Truncate(step), End: extr.End.Truncate(step)}
+
+	expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extn.Start, extn.End, step)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	for _, status := range []string{"kmiss", "hit"} {
+		w = httptest.NewRecorder()
+		client.QueryRangeHandler(w, r)
+		resp := w.Result()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err = testStringMatch(string(bodyBytes), expected); err != nil {
+			t.Error(err)
+		}
+		if err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": status}); err != nil {
+			t.Error(err)
+		}
+		// Give time for the object to be written to cache in a separate goroutine from response
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// the memory cache holds the compact serialized timeseries rather than a reference
+	mc := rsc.CacheClient.(cache.MemoryCache)
+	keys, _ := rsc.CacheClient.(cache.KeyLister).Keys(o.CacheKeyPrefix + ".dpc.")
+	if len(keys) == 0 {
+		t.Fatal("expected cached timeseries")
+	}
+	for _, key := range keys {
+		ifc, _, _ := mc.RetrieveReference(key, true)
+		doc, ok := ifc.(*HTTPDocument)
+		if !ok {
+			t.Fatal("expected cached document")
+		}
+		if doc.timeseries != nil {
+			t.Error("expected nil timeseries reference")
+		}
+		if len(doc.Body) == 0 || doc.Body[0] != 0xc1 {
+			t.Error("expected compact timeseries body")
+		}
+	}
+}
+
+func TestDeltaProxyCacheRequestResample(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.BackendClient.(*TestClient)
+	o := rsc.BackendOptions
+	pc := rsc.PathConfig
+	pc.CacheKeyParams = []string{"query", "step"}
+	pc.ResampleSteps = true
+	defer func() { pc.ResampleSteps = false }()
+
+	o.FastForwardDisable = true
+	end := time.Now().Add(-time.Duration(12) * time.Hour).Truncate(300 * time.Second)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(4) * time.Hour), End: end}
+
+	// the 300s step request is served by resampling the cached 60s step timeseries
+	for _, test := range []struct {
+		step   time.Duration
+		status string
+	}{
+		{60 * time.Second, "kmiss"},
+		{300 * time.Second, "hit"},
+		{300 * time.Second, "hit"},
+		{45 * time.Second, "kmiss"},
+	} {
+		extn := timeseries.Extent{Start: extr.Start.Truncate(test.step),
+			End: extr.End.Truncate(test.step)}
+		expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency,
+			extn.Start, extn.End, test.step)
+		u := r.URL
+		u.Path = "/prometheus/api/v1/query_range"
+		u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+			int(test.step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+		w = httptest.NewRecorder()
+		client.QueryRangeHandler(w, r)
+		resp := w.Result()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err = testStringMatch(string(bodyBytes), expected); err != nil {
+			t.Error(test.step, err)
+		}
+		if err = testResultHeaderPartMatch(resp.Header,
+			map[string]string{"status": test.status}); err != nil {
+			t.Error(test.step, err)
+		}
+		// Give time for the object to be written to cache in a separate goroutine from response
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
 	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+	"github.com/tricksterproxy/trickster/pkg/proxy/ratelimit"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
 
@@ -200,7 +201,63 @@ func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64)
 	// clear the Host header before proxying or it will be forwarded upstream
 	r.Host = ""
 
-	resp, err := o.HTTPClient.Do(r)
+	cb := upstreamBreaker(rsc)
+	if cb != nil && !cb.Allow() {
+		tl.Debug(rsc.Logger, "circuit breaker is open, failing fast",
+			tl.Pairs{"backendName": o.Name, "url": r.URL.String()})
+		resp := cb.Response(r)
+		if pc != nil {
+			headers.UpdateHeaders(resp.Header, pc.ResponseHeaders)
+		}
+		if doSpan != nil {
+			doSpan.AddEvent("CircuitOpen")
+			doSpan.SetStatus(tracing.HTTPToCode(resp.StatusCode), "")
+		}
+		return resp.Body, resp, resp.ContentLength
+	}
+
+	// only the in-flight quota is enforced here; rate limits are enforced on admission
+	rl := upstreamLimiter(rsc)
+	if rl != nil && rl.Options().MaxInFlight == 0 {
+		rl = nil
+	}
+	if rl != nil {
+		if !rl.Acquire(rsc.RateLimitKey) {
+			if cb != nil {
+				cb.Release()
+			}
+			tl.Debug(rsc.Logger, "upstream in-flight limit reached, rejecting",
+				tl.Pairs{"backendName": o.Name, "url": r.URL.String()})
+			metrics.ProxyRateLimitRejected.WithLabelValues(o.Name, o.Provider,
+				ratelimit.LimitInFlight).Inc()
+			resp := rl.Response(r, ratelimit.LimitInFlight, 0)
+			if pc != nil {
+				headers.UpdateHeaders(resp.Header, pc.ResponseHeaders)
+			}
+			if doSpan != nil {
+				doSpan.AddEvent("RateLimited")
+				doSpan.SetStatus(tracing.HTTPToCode(resp.StatusCode), "")
+			}
+			return resp.Body, resp, resp.ContentLength
+		}
+		metrics.ProxyRateLimitAdmitted.WithLabelValues(o.Name, o.Provider,
+			ratelimit.LimitInFlight).Inc()
+	}
+
+	start := time.Now()
+	resp, err := doUpstream(rsc, r)
+	if cb != nil {
+		recordBreakerResult(cb, r, resp, err, time.Since(start))
+	}
+	if rl != nil {
+		key := rsc.RateLimitKey
+		release := func() { rl.Release(key) }
+		if err != nil || resp == nil || resp.Body == nil {
+			release()
+		} else {
+			resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+		}
+	}
 	if err != nil {
 		tl.Error(rsc.Logger,
 			"error downloading url", tl.Pairs{"url": r.URL.String(), "detail": err.Error()})
@@ -24,6 +24,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
@@ -34,6 +35,12 @@ import (
 
 // DeriveCacheKey calculates a query-specific keyname based on the user request
 func (pr *proxyRequest) DeriveCacheKey(extra string) string {
+	return pr.deriveCacheKey(nil, extra)
+}
+
+// deriveCacheKey calculates the cache key using the query parameters of templateURL,
+// when provided, in place of those of the TimeRangeQuery's TemplateURL
+func (pr *proxyRequest) deriveCacheKey(templateURL *url.URL, extra string) string {
 
 	rsc := request.GetResources(pr.Request)
 	pc := rsc.PathConfig
@@ -53,7 +60,9 @@ func (pr *proxyRequest) DeriveCacheKey(extra string) string {
 	}
 
 	var b []byte
-	if rsc.TimeRangeQuery != nil && rsc.TimeRangeQuery.TemplateURL != nil {
+	if templateURL != nil {
+		qp = templateURL.Query()
+	} else if rsc.TimeRangeQuery != nil && rsc.TimeRangeQuery.TemplateURL != nil {
 		qp = rsc.TimeRangeQuery.TemplateURL.Query()
 	} else {
 		var s string
@@ -88,6 +97,15 @@ func (pr *proxyRequest) DeriveCacheKey(extra string) string {
 		}
 	}
 
+	if len(pc.CacheKeyCaptures) > 0 {
+		captures := context.PathCaptures(pr.Request.Context())
+		for _, c := range pc.CacheKeyCaptures {
+			if v, ok := captures[c]; ok {
+				vals = append(vals, fmt.Sprintf("%s.%s.", "${"+c+"}", v))
+			}
+		}
+	}
+
 	for _, p := range pc.CacheKeyHeaders {
 		if v := r.Header.Get(p); v != "" {
 			vals = append(vals, fmt.Sprintf("%s.%s.", p, v))
@@ -243,6 +243,36 @@ func TestDeriveCacheKeyAuthHeader(t *testing.T) {
 
 }
 
+func TestDeriveCacheKeyPathCaptures(t *testing.T) {
+
+	cfg := &bo.Options{
+		Paths: map[string]*po.Options{
+			"root": {
+				Path:             "/{name}",
+				CacheKeyCaptures: []string{"name"},
+			},
+		},
+	}
+
+	keyFor := func(name string) string {
+		tr := httptest.NewRequest("GET", "http://127.0.0.1/values", nil)
+		ctx := ct.WithResources(context.Background(), request.NewResources(cfg,
+			cfg.Paths["root"], nil, nil, nil, nil, tl.ConsoleLogger("error")))
+		if name != "" {
+			ctx = ct.WithPathCaptures(ctx, map[string]string{"name": name})
+		}
+		return newProxyRequest(tr.WithContext(ctx), nil).DeriveCacheKey("")
+	}
+
+	k1, k2, k3 := keyFor("job"), keyFor("instance"), keyFor("")
+	if k1 == k2 || k1 == k3 || k2 == k3 {
+		t.Errorf("expected distinct cache keys got %s %s %s", k1, k2, k3)
+	}
+	if k1 != keyFor("job") {
+		t.Error("expected consistent cache key")
+	}
+}
+
 func TestDeriveCacheKeyNoPathConfig(t *testing.T) {
 
 	client, err := NewTestClient("test", &bo.Options{
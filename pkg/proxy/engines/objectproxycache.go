@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
@@ -131,8 +132,21 @@ func confirmTrueCacheHit(pr *proxyRequest) (bool, error) {
 
 	pr.cachingPolicy.Merge(pr.cacheDocument.CachingPolicy)
 
-	if (!pr.checkCacheFreshness()) && (pr.cachingPolicy.CanRevalidate) {
-		return false, handleCacheRevalidation(pr)
+	if !pr.checkCacheFreshness() {
+		// stale content is only served for full cache hits
+		if pr.cacheStatus == status.LookupStatusHit {
+			swr, sie := pr.staleWindows()
+			age := pr.staleAge()
+			if age <= swr {
+				return false, handleStaleWhileRevalidate(pr)
+			}
+			if age <= sie {
+				pr.stalePolicy = pr.cachingPolicy.Clone()
+			}
+		}
+		if pr.cachingPolicy.CanRevalidate {
+			return false, handleCacheRevalidation(pr)
+		}
 	}
 	if !pr.cachingPolicy.IsFresh {
 		pr.cacheStatus = status.LookupStatusKeyMiss
@@ -204,6 +218,10 @@ func handleCacheRevalidation(pr *proxyRequest) error {
 
 func handleCacheRevalidationResponse(pr *proxyRequest) error {
 
+	if isStaleIfError(pr) {
+		return handleStaleIfError(pr)
+	}
+
 	if pr.upstreamResponse.StatusCode == http.StatusNotModified {
 		pr.revalidation = RevalStatusOK
 		pr.cachingPolicy.IsFresh = true
@@ -221,6 +239,89 @@ func handleCacheRevalidationResponse(pr *proxyRequest) error {
 	return handleAllWrites(pr)
 }
 
+// revalidations tracks the cache keys with a background revalidation in progress
+var revalidations sync.Map
+
+// handleStaleWhileRevalidate serves the expired cache object to the client without
+// waiting on the origin, and then refreshes it from the origin in the background
+func handleStaleWhileRevalidate(pr *proxyRequest) error {
+
+	if pr.hasReadLock {
+		pr.cacheLock.RRelease()
+		pr.hasReadLock = false
+	}
+
+	// the background request is cloned before the response is served,
+	// since serving it modifies the caching policy's client conditionals
+	bg := pr.Clone()
+	bg.cachingPolicy = pr.cachingPolicy.Clone()
+	bg.cachingPolicy.ResetClientConditionals()
+	// the background request always refreshes the full object
+	bg.wantsRanges = false
+	bg.wantedRanges = nil
+	bg.upstreamRequest.Header.Del(headers.NameRange)
+
+	pr.cacheStatus = status.LookupStatusStale
+	err := handleTrueCacheHit(pr)
+
+	if _, ok := revalidations.LoadOrStore(pr.key, true); !ok {
+		go revalidateInBackground(bg)
+	}
+
+	return err
+}
+
+// revalidateInBackground refreshes an expired cache object from the origin on
+// behalf of a client that has already been served the stale copy
+func revalidateInBackground(pr *proxyRequest) {
+
+	defer revalidations.Delete(pr.key)
+
+	rsc := request.GetResources(pr.Request)
+	if !rsc.NoLock {
+		pr.cacheLock, _ = rsc.CacheClient.Locker().Acquire(pr.key)
+		pr.hasWriteLock = true
+		defer pr.cacheLock.Release()
+	}
+
+	pr.responseWriter = io.Discard
+	// if the origin fails, the stale copy is retained rather than replaced
+	pr.stalePolicy = pr.cachingPolicy.Clone()
+
+	tl.Debug(pr.Logger, "revalidating stale cache object in background", tl.Pairs{"key": pr.key})
+
+	if pr.cachingPolicy.CanRevalidate {
+		handleCacheRevalidation(pr)
+		return
+	}
+	pr.cacheStatus = status.LookupStatusKeyMiss
+	handleCacheKeyMiss(pr)
+}
+
+// isStaleIfError returns true if the upstream response is a server or transport error
+// and the request has a stale cache object that is permitted to be served in its place
+func isStaleIfError(pr *proxyRequest) bool {
+	return pr.stalePolicy != nil && pr.cacheDocument != nil && pr.upstreamResponse != nil &&
+		pr.upstreamResponse.StatusCode >= http.StatusInternalServerError
+}
+
+// handleStaleIfError serves the stale cache object in place of the upstream error response
+func handleStaleIfError(pr *proxyRequest) error {
+
+	tl.Debug(pr.Logger, "serving stale cache object due to upstream error",
+		tl.Pairs{"key": pr.key, "upstreamStatus": pr.upstreamResponse.StatusCode})
+
+	if rc, ok := pr.upstreamReader.(io.Closer); ok {
+		rc.Close()
+	}
+
+	pr.revalidation = RevalStatusFailed
+	pr.cachingPolicy = pr.stalePolicy
+	pr.writeToCache = false
+	pr.cacheStatus = status.LookupStatusStale
+	return handleTrueCacheHit(pr)
+}
+
 func handleTrueCacheHit(pr *proxyRequest) error {
 
 	d := pr.cacheDocument
@@ -262,8 +363,9 @@ func handleCacheKeyMiss(pr *proxyRequest) error {
 	rsc := request.GetResources(pr.Request)
 	pc := rsc.PathConfig
 
-	// if a we're using PCF, handle that separately
-	if !methods.HasBody(pr.Method) && !pr.wantsRanges && pc != nil &&
+	// if a we're using PCF, handle that separately. PCF is bypassed when a stale
+	// object may be served instead, since it writes the upstream response as it arrives
+	if !methods.HasBody(pr.Method) && !pr.wantsRanges && pc != nil && pr.stalePolicy == nil &&
 		pc.CollapsedForwardingType == forwarding.CFTypeProgressive {
 		if err := handlePCF(pr); err != errors.ErrPCFContentLength {
 			// if err is nil, or something else, we'll proceed.
@@ -273,6 +375,9 @@ func handleCacheKeyMiss(pr *proxyRequest) error {
 
 	pr.prepareUpstreamRequests()
 	handleUpstreamTransactions(pr)
+	if isStaleIfError(pr) {
+		return handleStaleIfError(pr)
+	}
 	return handleAllWrites(pr)
 }
 
@@ -754,6 +754,85 @@ func TestObjectProxyCacheCanRevalidate(t *testing.T) {
 	}
 }
 
+func TestObjectProxyCacheStaleWhileRevalidate(t *testing.T) {
+
+	hdr := map[string]string{headers.NameCacheControl: headers.ValueMaxAge + "=1"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdr)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.PathConfig.ResponseHeaders = hdr
+	rsc.PathConfig.StaleWhileRevalidateSecs = 30
+
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	time.Sleep(1010 * time.Millisecond)
+
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "stale"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	// wait for the background revalidation to complete
+	for i := 0; i < 100; i++ {
+		var pending bool
+		revalidations.Range(func(k, v interface{}) bool {
+			pending = true
+			return false
+		})
+		if !pending {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
+
+func TestObjectProxyCacheStaleIfError(t *testing.T) {
+
+	hdr := map[string]string{
+		headers.NameCacheControl: headers.ValueMaxAge + "=1, " + headers.ValueStaleIfError + "=1",
+		headers.NameETag:         "test-etag",
+	}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdr)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rsc.PathConfig.ResponseHeaders = hdr
+
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	// with the origin down, revalidation fails and the stale object is served
+	ts.Close()
+	time.Sleep(1010 * time.Millisecond)
+
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "stale"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	// outside of the stale-if-error window, the upstream error is served
+	time.Sleep(1010 * time.Millisecond)
+
+	_, e = testFetchOPC(r, http.StatusBadGateway, "", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
+
 func TestObjectProxyCacheRevalidated(t *testing.T) {
 
 	const dt = "Sun, 16 Jun 2019 14:19:04 GMT"
@@ -79,6 +79,9 @@ type proxyRequest struct {
 
 	collapsedForwarder ProgressiveCollapseForwarder
 	cachingPolicy      *CachingPolicy
+	// stalePolicy is the caching policy of an expired cache object that may be
+	// served in place of an upstream error response, per stale-if-error
+	stalePolicy *CachingPolicy
 
 	Logger            interface{}
 	isPCF             bool
@@ -335,6 +338,33 @@ func (pr *proxyRequest) checkCacheFreshness() bool {
 	return cp.IsFresh
 }
 
+// staleWindows returns the durations past the freshness lifetime during which the cached
+// object may be served while it is revalidated in the background (stale-while-revalidate),
+// and when revalidation fails (stale-if-error). Path-configured values take precedence
+// over directives provided by the origin.
+func (pr *proxyRequest) staleWindows() (time.Duration, time.Duration) {
+	cp := pr.cachingPolicy
+	if cp == nil || cp.NoCache || cp.IsNegativeCache {
+		return 0, 0
+	}
+	swr, sie := cp.StaleWhileRevalidate, cp.StaleIfError
+	if rsc := request.GetResources(pr.Request); rsc != nil && rsc.PathConfig != nil {
+		if rsc.PathConfig.StaleWhileRevalidateSecs > 0 {
+			swr = rsc.PathConfig.StaleWhileRevalidateSecs
+		}
+		if rsc.PathConfig.StaleIfErrorSecs > 0 {
+			sie = rsc.PathConfig.StaleIfErrorSecs
+		}
+	}
+	return time.Duration(swr) * time.Second, time.Duration(sie) * time.Second
+}
+
+// staleAge returns how long the cached object has been past its freshness lifetime
+func (pr *proxyRequest) staleAge() time.Duration {
+	cp := pr.cachingPolicy
+	return time.Since(cp.LocalDate.Add(time.Duration(cp.FreshnessLifetime) * time.Second))
+}
+
 func (pr *proxyRequest) parseRequestRanges() bool {
 	// handle byte range requests
 	var out byterange.Ranges
@@ -474,9 +504,24 @@ func (pr *proxyRequest) store() error {
 		rf = 1
 	}
 
+	ttl := pr.cachingPolicy.TTL(rf, o.MaxTTL)
+	// retain the object long enough for it to be served stale, when permitted
+	if swr, sie := pr.staleWindows(); swr > 0 || sie > 0 {
+		if sie > swr {
+			swr = sie
+		}
+		st := time.Duration(pr.cachingPolicy.FreshnessLifetime)*time.Second + swr
+		if st > o.MaxTTL {
+			st = o.MaxTTL
+		}
+		if st > ttl {
+			ttl = st
+		}
+	}
+
 	d.CachingPolicy = pr.cachingPolicy
 	err := WriteCache(pr.upstreamRequest.Context(), rsc.CacheClient, pr.key, d,
-		pr.cachingPolicy.TTL(rf, o.MaxTTL), o.CompressibleTypes)
+		ttl, o.CompressibleTypes)
 	if err != nil {
 		return err
 	}
@@ -560,7 +605,7 @@ func (pr *proxyRequest) prepareResponse() {
 		}
 		resp.Header.Del(headers.NameContentRange)
 		if pr.cacheStatus == status.LookupStatusHit || pr.cacheStatus == status.LookupStatusRevalidated ||
-			pr.cacheStatus == status.LookupStatusPartialHit {
+			pr.cacheStatus == status.LookupStatusPartialHit || pr.cacheStatus == status.LookupStatusStale {
 			pr.responseBody = d.Body
 		}
 	}
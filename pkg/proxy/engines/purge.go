@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tricksterproxy/trickster/pkg/backends"
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// CacheKeys returns the cache keys that the proxy engines would derive for the
+// provided request, which must carry Resources that include the Backend Options
+// and the matching Path Config. The list includes the HTTP Proxy and Object Proxy Cache
+// keys, and for Time Series Backends, the Delta Proxy Cache key when the request
+// is a parseable time range query.
+func CacheKeys(r *http.Request) []string {
+
+	rsc := request.GetResources(r)
+	if rsc == nil || rsc.BackendOptions == nil {
+		return nil
+	}
+	o := rsc.BackendOptions
+
+	rsc.TimeRangeQuery = nil
+	k := newProxyRequest(r, nil).DeriveCacheKey("")
+	keys := []string{o.CacheKeyPrefix + "." + k, o.CacheKeyPrefix + ".opc." + k}
+
+	client, ok := rsc.BackendClient.(backends.TimeseriesBackend)
+	if !ok {
+		return keys
+	}
+
+	trq, _, _, err := client.ParseTimeRangeQuery(r)
+	if err != nil || trq == nil {
+		return keys
+	}
+	rsc.TimeRangeQuery = trq
+	pr := newProxyRequest(r, nil)
+	client.SetExtent(pr.upstreamRequest, trq, &trq.Extent)
+	return append(keys, o.CacheKeyPrefix+".dpc."+pr.DeriveCacheKey(""))
+}
+
+// PurgeKeys removes each of the provided keys from the cache
+func PurgeKeys(c cache.Cache, keys []string) {
+	// Remove is used rather than BulkRemove, since some cache providers
+	// expect the caller of BulkRemove to update the cache index
+	for _, key := range keys {
+		c.Remove(key)
+	}
+}
+
+// PurgeTimeseriesExtent removes from the cache each of the provided Delta Proxy Cache
+// objects whose cached extents overlap with the provided extent, and returns the keys
+// of the removed objects. The provided context must carry request Resources.
+func PurgeTimeseriesExtent(ctx context.Context, c cache.Cache, keys []string,
+	modeler *timeseries.Modeler, e timeseries.Extent) []string {
+
+	var logger interface{}
+	if rsc, ok := tc.Resources(ctx).(*request.Resources); ok {
+		logger = rsc.Logger
+	}
+
+	removed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		doc, _, _, err := QueryCache(ctx, c, key, nil)
+		if err != nil || doc == nil {
+			continue
+		}
+		ts := doc.timeseries
+		if ts == nil {
+			if modeler == nil || modeler.CacheUnmarshaler == nil {
+				continue
+			}
+			ts, err = modeler.CacheUnmarshaler(doc.Body, nil)
+			if err != nil {
+				tl.Warn(logger, "could not unmarshal cached timeseries for purge",
+					tl.Pairs{"cacheKey": key, "detail": err.Error()})
+				continue
+			}
+		}
+		if len(ts.Extents().Crop(e)) == 0 {
+			continue
+		}
+		c.Remove(key)
+		removed = append(removed, key)
+	}
+	return removed
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	tst "github.com/tricksterproxy/trickster/pkg/util/testing/timeseries/model"
+)
+
+func TestCacheKeysAndPurge(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.BackendClient.(*TestClient)
+	o := rsc.BackendOptions
+	o.FastForwardDisable = true
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	client.QueryRangeHandler(w, r)
+	time.Sleep(time.Millisecond * 10)
+
+	keys := CacheKeys(r.Clone(r.Context()))
+	if len(keys) != 3 {
+		t.Fatalf("expected %d got %d", 3, len(keys))
+	}
+	dpcKey := keys[2]
+	if _, _, err := rsc.CacheClient.Retrieve(dpcKey, false); err != nil {
+		t.Fatalf("expected cached object for key %s: %s", dpcKey, err.Error())
+	}
+
+	// an extent outside of the cached extent should not purge the object
+	removed := PurgeTimeseriesExtent(r.Context(), rsc.CacheClient, keys, tst.Modeler(),
+		timeseries.Extent{Start: end.Add(time.Hour), End: end.Add(2 * time.Hour)})
+	if len(removed) != 0 {
+		t.Errorf("expected %d got %d", 0, len(removed))
+	}
+
+	removed = PurgeTimeseriesExtent(r.Context(), rsc.CacheClient, keys, tst.Modeler(),
+		timeseries.Extent{Start: end.Add(-time.Hour), End: end})
+	if len(removed) != 1 || removed[0] != dpcKey {
+		t.Errorf("expected %s got %v", dpcKey, removed)
+	}
+
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	err = testResultHeaderPartMatch(w.Result().Header, map[string]string{"status": "kmiss"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	PurgeKeys(rsc.CacheClient, keys)
+	if _, _, err := rsc.CacheClient.Retrieve(dpcKey, false); err == nil {
+		t.Errorf("expected cache miss for key %s", dpcKey)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// stepIndex maps each step at which a query's timeseries is cached to its cache key
+type stepIndex map[time.Duration]string
+
+// stepIndexKey returns the cache key of the step index for the request's query, or
+// an empty string when the path does not allow resampling, or the query's step can't
+// be isolated by the backend
+func stepIndexKey(pr *proxyRequest, trq *timeseries.TimeRangeQuery) string {
+	rsc := request.GetResources(pr.Request)
+	if rsc.PathConfig == nil || !rsc.PathConfig.ResampleSteps ||
+		trq.StepTemplateURL == nil || trq.Step <= 0 {
+		return ""
+	}
+	return rsc.BackendOptions.CacheKeyPrefix + ".dpc.steps." +
+		pr.deriveCacheKey(trq.StepTemplateURL, "")
+}
+
+func readStepIndex(c cache.Cache, indexKey string) stepIndex {
+	b, ls, err := c.Retrieve(indexKey, false)
+	if err != nil || ls != status.LookupStatusHit {
+		return nil
+	}
+	idx := make(stepIndex)
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil
+	}
+	return idx
+}
+
+// updateStepIndex records the cache key of the query's timeseries at the provided step
+func updateStepIndex(c cache.Cache, indexKey string, step time.Duration, key string,
+	ttl time.Duration, logger interface{}) {
+	lock, _ := c.Locker().Acquire(indexKey)
+	defer lock.Release()
+	idx := readStepIndex(c, indexKey)
+	if idx == nil {
+		idx = make(stepIndex)
+	} else if idx[step] == key {
+		return
+	}
+	idx[step] = key
+	b, err := json.Marshal(idx)
+	if err == nil {
+		err = c.Store(indexKey, b, ttl)
+	}
+	if err != nil {
+		tl.Warn(logger, "error writing step index to cache",
+			tl.Pairs{"cacheKey": indexKey, "detail": err.Error()})
+	}
+}
+
+// resampleFromCache returns the query's timeseries resampled from the closest finer step
+// at which it is cached, along with a new document for it, or nil if there is none
+func resampleFromCache(ctx context.Context, pr *proxyRequest, c cache.Cache,
+	indexKey, key string, trq *timeseries.TimeRangeQuery,
+	modeler *timeseries.Modeler) (timeseries.Timeseries, *HTTPDocument) {
+
+	idx := readStepIndex(c, indexKey)
+	if len(idx) == 0 {
+		return nil, nil
+	}
+	steps := make([]time.Duration, 0, len(idx))
+	for step, k := range idx {
+		if step > 0 && step < trq.Step && trq.Step%step == 0 && k != key {
+			steps = append(steps, step)
+		}
+	}
+	// the closest finer step requires the least work to resample
+	sort.Slice(steps, func(i, j int) bool { return steps[i] > steps[j] })
+
+	agg := request.GetResources(pr.Request).PathConfig.ResampleAggregation
+	for _, step := range steps {
+		k := idx[step]
+		lock, _ := c.Locker().RAcquire(k)
+		doc, ls, _, err := QueryCache(ctx, c, k, nil)
+		if err != nil || ls != status.LookupStatusHit || doc == nil {
+			lock.RRelease()
+			continue
+		}
+		ts := doc.timeseries
+		if ts == nil {
+			ts, err = modeler.CacheUnmarshaler(doc.Body, nil)
+			if err != nil {
+				lock.RRelease()
+				continue
+			}
+		}
+		r, ok := ts.(timeseries.Resampler)
+		if !ok {
+			lock.RRelease()
+			return nil, nil
+		}
+		rts := r.Resample(trq.Step, agg)
+		lock.RRelease()
+		if len(rts.Extents()) == 0 {
+			continue
+		}
+		tl.Debug(pr.Logger, "resampled cached timeseries", tl.Pairs{"cacheKey": k,
+			"fromStep": step, "toStep": trq.Step, "aggregation": agg})
+		d := &HTTPDocument{
+			Status:      doc.Status,
+			StatusCode:  doc.StatusCode,
+			ContentType: doc.ContentType,
+			Headers:     doc.SafeHeaderClone(),
+			timeseries:  rts,
+		}
+		return rts, d
+	}
+	return nil, nil
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/backends/breaker"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+	"github.com/tricksterproxy/trickster/pkg/observability/metrics"
+	"github.com/tricksterproxy/trickster/pkg/proxy/ratelimit"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/retry"
+)
+
+// upstreamLatencies holds a *retry.LatencyTracker for each backend, keyed by backend name
+var upstreamLatencies sync.Map
+
+func latencyTracker(name string) *retry.LatencyTracker {
+	if lt, ok := upstreamLatencies.Load(name); ok {
+		return lt.(*retry.LatencyTracker)
+	}
+	lt, _ := upstreamLatencies.LoadOrStore(name, retry.NewLatencyTracker())
+	return lt.(*retry.LatencyTracker)
+}
+
+// upstreamBreaker returns the Circuit Breaker for the request's backend, if any
+func upstreamBreaker(rsc *request.Resources) *breaker.Breaker {
+	if rsc == nil || rsc.BackendClient == nil {
+		return nil
+	}
+	return rsc.BackendClient.Breaker()
+}
+
+// upstreamLimiter returns the Rate Limiter for the request's backend, if any
+func upstreamLimiter(rsc *request.Resources) *ratelimit.Limiter {
+	if rsc == nil || rsc.BackendClient == nil {
+		return nil
+	}
+	return rsc.BackendClient.Limiter()
+}
+
+// releaseOnClose releases an in-flight upstream request slot once the response body
+// has been fully read or closed, whichever comes first
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (r *releaseOnClose) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil {
+		r.once.Do(r.release)
+	}
+	return n, err
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
+
+// recordBreakerResult records the outcome of an upstream request with the backend's Circuit
+// Breaker. Requests abandoned by the client are not indicative of the backend's health, so
+// their trial slot is released rather than recorded
+func recordBreakerResult(cb *breaker.Breaker, r *http.Request, resp *http.Response,
+	err error, elapsed time.Duration) {
+	if err != nil && r.Context().Err() != nil {
+		cb.Release()
+		return
+	}
+	statusCode := http.StatusBadGateway
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	cb.Record(statusCode, elapsed)
+}
+
+// cancelOnClose cancels the context of a winning hedged request once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// discardResponse drains and closes the body of an upstream response that will not be used
+func discardResponse(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// doUpstream makes the upstream request using the backend's HTTP Client. Replayable
+// requests are retried and hedged in accordance with the backend's retry options
+func doUpstream(rsc *request.Resources, r *http.Request) (*http.Response, error) {
+
+	o := rsc.BackendOptions
+	lt := latencyTracker(o.Name)
+
+	ro := o.Retry
+	if ro == nil || (!ro.RetriesEnabled() && !ro.HedgingEnabled()) || !retry.IsReplayable(r) {
+		return timedDo(o, r, lt)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := hedgedDo(rsc, r, lt)
+		reason := retry.Reason(ro, resp, err)
+		if reason == "" || attempt >= ro.MaxAttempts {
+			return resp, err
+		}
+		discardResponse(resp)
+		metrics.ProxyUpstreamRetries.WithLabelValues(o.Name, o.Provider, reason).Inc()
+		d := retry.Backoff(attempt, ro.BackoffBase, ro.BackoffMax)
+		tl.Debug(rsc.Logger, "retrying upstream request",
+			tl.Pairs{"url": r.URL.String(), "attempt": attempt, "reason": reason,
+				"backoff": d.String()})
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			t.Stop()
+			return nil, r.Context().Err()
+		}
+		r = retry.Replay(r.Context(), r)
+	}
+}
+
+// timedDo makes a single upstream request and records its latency when it succeeds
+func timedDo(o *bo.Options, r *http.Request, lt *retry.LatencyTracker) (*http.Response, error) {
+	start := time.Now()
+	resp, err := o.HTTPClient.Do(r)
+	if err == nil {
+		lt.Observe(time.Since(start))
+	}
+	return resp, err
+}
+
+type hedgeResult struct {
+	resp *http.Response
+	err  error
+	idx  int
+}
+
+// hedgedDo makes an upstream request and, if hedging is enabled and no response has been
+// received within the backend's hedging delay, a second identical request. The first
+// non-retryable response wins and the other request is canceled
+func hedgedDo(rsc *request.Resources, r *http.Request,
+	lt *retry.LatencyTracker) (*http.Response, error) {
+
+	o := rsc.BackendOptions
+	ro := o.Retry
+
+	var delay time.Duration
+	if ro.HedgingEnabled() {
+		if delay = lt.Percentile(ro.HedgePercentile); delay > 0 && delay < ro.HedgeMinDelay {
+			delay = ro.HedgeMinDelay
+		}
+	}
+	if delay <= 0 {
+		return timedDo(o, r, lt)
+	}
+
+	ch := make(chan hedgeResult, 2)
+	cancels := make([]context.CancelFunc, 0, 2)
+	send := func(req *http.Request) {
+		idx := len(cancels) - 1
+		go func() {
+			resp, err := timedDo(o, req, lt)
+			ch <- hedgeResult{resp: resp, err: err, idx: idx}
+		}()
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	cancels = append(cancels, cancel)
+	send(r.WithContext(ctx))
+	pending := 1
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	var fallback *hedgeResult
+	for {
+		select {
+		case <-t.C:
+			tl.Debug(rsc.Logger, "sending hedged upstream request",
+				tl.Pairs{"url": r.URL.String(), "delay": delay.String()})
+			ctx, cancel := context.WithCancel(r.Context())
+			cancels = append(cancels, cancel)
+			send(retry.Replay(ctx, r))
+			pending++
+		case res := <-ch:
+			pending--
+			if pending > 0 && retry.Reason(ro, res.resp, res.err) != "" {
+				// hold a retryable failure in reserve while the other request is in flight
+				fallback = &res
+				continue
+			}
+			if fallback != nil {
+				discardResponse(fallback.resp)
+			}
+			for i, c := range cancels {
+				if i != res.idx {
+					c()
+				}
+			}
+			if pending > 0 {
+				// drain the losing request once it returns
+				go func() {
+					l := <-ch
+					discardResponse(l.resp)
+				}()
+			}
+			if len(cancels) > 1 {
+				winner := "primary"
+				if res.idx > 0 {
+					winner = "hedge"
+				}
+				metrics.ProxyUpstreamHedges.WithLabelValues(o.Name, o.Provider, winner).Inc()
+			}
+			if res.resp == nil || res.resp.Body == nil {
+				cancels[res.idx]()
+				return res.resp, res.err
+			}
+			res.resp.Body = &cancelOnClose{ReadCloser: res.resp.Body, cancel: cancels[res.idx]}
+			return res.resp, res.err
+		}
+	}
+}
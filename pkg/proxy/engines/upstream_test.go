@@ -0,0 +1,271 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/cmd/trickster/config"
+	"github.com/tricksterproxy/trickster/pkg/backends"
+	"github.com/tricksterproxy/trickster/pkg/backends/breaker"
+	cbo "github.com/tricksterproxy/trickster/pkg/backends/breaker/options"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	rlo "github.com/tricksterproxy/trickster/pkg/proxy/ratelimit/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/retry"
+	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
+)
+
+func setupUpstreamTest(t *testing.T, name string,
+	handler func(n int32, w http.ResponseWriter)) (*bo.Options, *int32, func()) {
+
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(atomic.AddInt32(&hits, 1), w)
+	}))
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-url", ts.URL, "-provider", "test", "-log-level", "debug"})
+	if err != nil {
+		ts.Close()
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	o := conf.Backends["default"]
+	o.Name = name
+	o.HTTPClient = http.DefaultClient
+	o.Retry.BackoffBase = time.Millisecond
+	o.Retry.BackoffMax = time.Millisecond
+
+	return o, &hits, ts.Close
+}
+
+func upstreamTestRequest(o *bo.Options, method string) (*httptest.ResponseRecorder, *http.Request) {
+	pc := &po.Options{Path: "/", RequestHeaders: map[string]string{},
+		ResponseHeaders: map[string]string{}}
+	r := httptest.NewRequest(method, "http://"+o.Host+"/", nil)
+	r = r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(o, pc, nil, nil, nil, tu.NewTestTracer(), testLogger)))
+	return httptest.NewRecorder(), r
+}
+
+func TestDoUpstreamRetry(t *testing.T) {
+
+	o, hits, closer := setupUpstreamTest(t, "test-retry", func(n int32, w http.ResponseWriter) {
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("test"))
+	})
+	defer closer()
+
+	// without retries, the first failure is returned
+	w, r := upstreamTestRequest(o, http.MethodGet)
+	DoProxy(w, r, true)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	o.Retry.MaxAttempts = 3
+	atomic.StoreInt32(hits, 0)
+	w, r = upstreamTestRequest(o, http.MethodGet)
+	DoProxy(w, r, true)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if b, _ := io.ReadAll(w.Body); string(b) != "test" {
+		t.Errorf("expected %s got %s", "test", string(b))
+	}
+	if n := atomic.LoadInt32(hits); n != 3 {
+		t.Errorf("expected %d upstream requests got %d", 3, n)
+	}
+
+	// retries are exhausted
+	o.Retry.MaxAttempts = 2
+	atomic.StoreInt32(hits, 0)
+	w, r = upstreamTestRequest(o, http.MethodGet)
+	DoProxy(w, r, true)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	// non-idempotent methods are not retried
+	o.Retry.MaxAttempts = 3
+	atomic.StoreInt32(hits, 0)
+	w, r = upstreamTestRequest(o, http.MethodPost)
+	DoProxy(w, r, true)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("expected %d upstream requests got %d", 1, n)
+	}
+}
+
+func TestDoUpstreamRetryConnectError(t *testing.T) {
+
+	o, _, closer := setupUpstreamTest(t, "test-retry-connect", func(n int32, w http.ResponseWriter) {})
+	closer()
+
+	o.Retry.MaxAttempts = 3
+	w, r := upstreamTestRequest(o, http.MethodGet)
+	DoProxy(w, r, true)
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected %d got %d", http.StatusBadGateway, w.Code)
+	}
+}
+
+func TestDoUpstreamHedge(t *testing.T) {
+
+	o, hits, closer := setupUpstreamTest(t, "test-hedge", func(n int32, w http.ResponseWriter) {
+		if n == 1 {
+			time.Sleep(500 * time.Millisecond)
+			w.Write([]byte("slow"))
+			return
+		}
+		w.Write([]byte("fast"))
+	})
+	defer closer()
+
+	lt := latencyTracker(o.Name)
+	for i := 0; i < retry.MinLatencySamples; i++ {
+		lt.Observe(10 * time.Millisecond)
+	}
+	o.Retry.HedgePercentile = 50
+
+	w, r := upstreamTestRequest(o, http.MethodGet)
+	start := time.Now()
+	DoProxy(w, r, true)
+	if d := time.Since(start); d >= 500*time.Millisecond {
+		t.Errorf("expected hedged response in less than 500ms got %s", d)
+	}
+	if b, _ := io.ReadAll(w.Body); string(b) != "fast" {
+		t.Errorf("expected %s got %s", "fast", string(b))
+	}
+	if n := atomic.LoadInt32(hits); n != 2 {
+		t.Errorf("expected %d upstream requests got %d", 2, n)
+	}
+}
+
+func TestDoProxyCircuitBreaker(t *testing.T) {
+
+	o, hits, closer := setupUpstreamTest(t, "test-breaker", func(n int32, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer closer()
+
+	cbopts := cbo.New()
+	cbopts.MinRequests = 2
+	cbopts.ResponseBody = "circuit open"
+	client, err := backends.New(o.Name, o, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := breaker.New(o.Name, cbopts, nil, testLogger)
+	client.SetBreaker(cb)
+
+	for i := 0; i < 3; i++ {
+		w, r := upstreamTestRequest(o, http.MethodGet)
+		tc.Resources(r.Context()).(*request.Resources).BackendClient = client
+		DoProxy(w, r, true)
+		if i < 2 {
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("expected %d got %d", http.StatusInternalServerError, w.Code)
+			}
+			continue
+		}
+		// the circuit is open, so the request fails fast without reaching the backend
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Code)
+		}
+		if v := w.Header().Get(headers.NameTrkCBStatus); v != "open" {
+			t.Errorf("expected %s got %s", "open", v)
+		}
+		if b, _ := io.ReadAll(w.Body); string(b) != "circuit open" {
+			t.Errorf("expected %s got %s", "circuit open", string(b))
+		}
+	}
+	if n := atomic.LoadInt32(hits); n != 2 {
+		t.Errorf("expected %d upstream requests got %d", 2, n)
+	}
+	if cb.State() != breaker.StateOpen {
+		t.Errorf("expected %s got %s", breaker.StateOpen, cb.State())
+	}
+}
+
+func TestDoProxyInFlightLimit(t *testing.T) {
+
+	release := make(chan struct{})
+	o, hits, closer := setupUpstreamTest(t, "test-in-flight", func(n int32, w http.ResponseWriter) {
+		if n == 1 {
+			<-release
+		}
+		w.Write([]byte("test"))
+	})
+	defer closer()
+
+	o.RateLimit = rlo.New()
+	o.RateLimit.MaxInFlight = 1
+	client, err := backends.New(o.Name, o, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doRequest := func() *httptest.ResponseRecorder {
+		w, r := upstreamTestRequest(o, http.MethodGet)
+		tc.Resources(r.Context()).(*request.Resources).BackendClient = client
+		DoProxy(w, r, true)
+		return w
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- doRequest() }()
+	for atomic.LoadInt32(hits) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the only slot is taken by the first request, so the second is rejected
+	w := doRequest()
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected %d got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if v := w.Header().Get(headers.NameRetryAfter); v != "1" {
+		t.Errorf("expected %s got %s", "1", v)
+	}
+
+	close(release)
+	if w = <-done; w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+
+	// the slot is released once the first response is read
+	if w = doRequest(); w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if n := atomic.LoadInt32(hits); n != 2 {
+		t.Errorf("expected %d upstream requests got %d", 2, n)
+	}
+}
@@ -48,6 +48,10 @@ var ErrNotSelectStatement = errors.New("not a select statement")
 // ErrNotTimeRangeQuery indicates an error that the time series request does not contain a query
 var ErrNotTimeRangeQuery = errors.New("not a time range query")
 
+// ErrUncacheableModifier indicates an error that the time series request uses a query modifier
+// (e.g., @ or a negative offset) whose results cannot be assembled from cached partial ranges
+var ErrUncacheableModifier = errors.New("query modifier is not cacheable")
+
 // ErrNoRanges indicates an error that the range request does not contain any usable ranges
 var ErrNoRanges = errors.New("no usable ranges")
 
@@ -70,3 +70,12 @@ func HandleBadGateway(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusBadGateway)
 	w.Write(nil)
 }
+
+// HandleGatewayTimeout responds to an HTTP Request with 504 Gateway Timeout
+func HandleGatewayTimeout(w http.ResponseWriter, r *http.Request) {
+	if w == nil {
+		return
+	}
+	w.WriteHeader(http.StatusGatewayTimeout)
+	w.Write(nil)
+}
@@ -0,0 +1,322 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package purge provides an administrative handler endpoint that is usually
+// mapped to /trickster/cache/purge and removes objects from the cache by key,
+// by sample request, by key prefix, or by time series extent
+package purge
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/backends"
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+	"github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/engines"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// ValueMessageHTTP represents the HTTP Header Value of "message/http", which is used
+// to provide a complete sample request in the body of a purge request
+const ValueMessageHTTP = "message/http"
+
+// Result is the response document for a purge request
+type Result struct {
+	Backend string   `json:"backend,omitempty"`
+	Cache   string   `json:"cache"`
+	Count   int      `json:"count"`
+	Keys    []string `json:"keys"`
+}
+
+type purgeError struct {
+	status int
+	error
+}
+
+var errUnauthorized = &purgeError{http.StatusUnauthorized, errors.New("unauthorized")}
+var errMethodNotAllowed = &purgeError{http.StatusMethodNotAllowed, errors.New("method not allowed")}
+var errNoSelector = &purgeError{http.StatusBadRequest,
+	errors.New("one of key, path, prefix, all, or start/end must be provided")}
+var errMultipleSelectors = &purgeError{http.StatusBadRequest,
+	errors.New("only one of key, path, prefix, all, or start/end may be provided")}
+var errNoCache = &purgeError{http.StatusBadRequest,
+	errors.New("a backend or cache name must be provided")}
+var errNotListable = &purgeError{http.StatusNotImplemented,
+	errors.New("cache provider does not support purging by prefix")}
+var errNotTimeseries = &purgeError{http.StatusBadRequest,
+	errors.New("purging by extent requires a time series backend")}
+
+func badRequest(format string, a ...interface{}) error {
+	return &purgeError{http.StatusBadRequest, fmt.Errorf(format, a...)}
+}
+
+// Handler returns an http.Handler that purges objects from the caches used by the provided
+// Backends. Requests must provide the token in an Authorization: Bearer header.
+//
+// Each request must provide a backend (or, for prefix purges, a cache) name via the
+// 'backend' or 'cache' query parameter, along with exactly one of the following selectors:
+//
+// key=<cacheKey>: removes the objects with the provided keys (may be repeated)
+//
+// path=<path?query>: derives the keys for a sample request to the backend, as the
+// proxy engines would, and removes those objects. The optional 'method' parameter sets
+// the sample request method. Alternatively, a complete sample request, including headers
+// and body, may be provided as the purge request body with a Content-Type of message/http
+//
+// prefix=<cacheKeyPrefix>: removes all objects written under the provided cache key prefix
+//
+// all=true: removes all objects written under the backend's cache key prefix
+//
+// start=<time>&end=<time>: removes the backend's time series objects whose cached extents
+// overlap the provided time range. Times are Unix epoch seconds or RFC3339.
+func Handler(token string, bknds backends.Backends, caches map[string]cache.Cache,
+	logger interface{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, err := handle(token, bknds, caches, logger, r)
+		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if pe, ok := err.(*purgeError); ok {
+				code = pe.status
+			}
+			w.Header().Set(headers.NameContentType, headers.ValueTextPlain)
+			w.WriteHeader(code)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		tl.Info(logger, "cache objects purged", tl.Pairs{"backendName": res.Backend,
+			"cacheName": res.Cache, "count": res.Count})
+		b, _ := json.Marshal(res)
+		w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	})
+}
+
+func handle(token string, bknds backends.Backends, caches map[string]cache.Cache,
+	logger interface{}, r *http.Request) (*Result, error) {
+
+	if !authorized(token, r) {
+		return nil, errUnauthorized
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		return nil, errMethodNotAllowed
+	}
+
+	qp := r.URL.Query()
+	isSample := qp.Get("path") != "" ||
+		strings.HasPrefix(r.Header.Get(headers.NameContentType), ValueMessageHTTP)
+	isExtent := qp.Get("start") != "" || qp.Get("end") != ""
+	isAll := qp.Get("all") == "true"
+
+	var n int
+	for _, b := range []bool{len(qp["key"]) > 0, isSample, qp.Get("prefix") != "", isAll, isExtent} {
+		if b {
+			n++
+		}
+	}
+	if n == 0 {
+		return nil, errNoSelector
+	}
+	if n > 1 {
+		return nil, errMultipleSelectors
+	}
+
+	var b backends.Backend
+	var c cache.Cache
+	res := &Result{Backend: qp.Get("backend")}
+
+	if res.Backend != "" {
+		if b = bknds.Get(res.Backend); b == nil {
+			return nil, &purgeError{http.StatusNotFound,
+				fmt.Errorf("unknown backend name: %s", res.Backend)}
+		}
+		if c = b.Cache(); c == nil {
+			return nil, badRequest("backend %s does not use a cache", res.Backend)
+		}
+	} else if cn := qp.Get("cache"); cn != "" {
+		var ok bool
+		if c, ok = caches[cn]; !ok || c == nil {
+			return nil, &purgeError{http.StatusNotFound, fmt.Errorf("unknown cache name: %s", cn)}
+		}
+	}
+	if c == nil || (b == nil && qp.Get("prefix") == "") {
+		return nil, errNoCache
+	}
+	res.Cache = c.Configuration().Name
+
+	var err error
+	switch {
+	case len(qp["key"]) > 0:
+		res.Keys = qp["key"]
+		engines.PurgeKeys(c, res.Keys)
+	case isSample:
+		var sr *http.Request
+		if sr, err = sampleRequest(r); err != nil {
+			return nil, err
+		}
+		res.Keys = sampleKeys(b, c, sr, logger)
+		engines.PurgeKeys(c, res.Keys)
+	case qp.Get("prefix") != "":
+		if res.Keys, err = listKeys(c, qp.Get("prefix")+"."); err != nil {
+			return nil, err
+		}
+		engines.PurgeKeys(c, res.Keys)
+	case isAll:
+		if res.Keys, err = listKeys(c, b.Configuration().CacheKeyPrefix+"."); err != nil {
+			return nil, err
+		}
+		engines.PurgeKeys(c, res.Keys)
+	case isExtent:
+		if res.Keys, err = purgeExtent(b, c, r, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	res.Count = len(res.Keys)
+	return res, nil
+}
+
+func authorized(token string, r *http.Request) bool {
+	if token == "" {
+		return false
+	}
+	const prefix = "Bearer "
+	v := r.Header.Get(headers.NameAuthorization)
+	if !strings.HasPrefix(v, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(v[len(prefix):]), []byte(token)) == 1
+}
+
+func listKeys(c cache.Cache, prefix string) ([]string, error) {
+	kl, ok := c.(cache.KeyLister)
+	if !ok {
+		return nil, errNotListable
+	}
+	keys, err := kl.Keys(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// sampleRequest returns the sample request described by the purge request
+func sampleRequest(r *http.Request) (*http.Request, error) {
+	if strings.HasPrefix(r.Header.Get(headers.NameContentType), ValueMessageHTTP) {
+		sr, err := http.ReadRequest(bufio.NewReader(r.Body))
+		if err != nil {
+			return nil, badRequest("invalid sample request: %s", err.Error())
+		}
+		return sr, nil
+	}
+	qp := r.URL.Query()
+	method := qp.Get("method")
+	if method == "" {
+		method = http.MethodGet
+	}
+	sr, err := http.NewRequest(strings.ToUpper(method), qp.Get("path"), nil)
+	if err != nil {
+		return nil, badRequest("invalid sample request: %s", err.Error())
+	}
+	return sr, nil
+}
+
+// sampleKeys returns the cache keys for the sample request, resolving the path config,
+// applying the request rewriters and building the upstream URL in the same manner as
+// the router and backend handlers
+func sampleKeys(b backends.Backend, c cache.Cache, sr *http.Request,
+	logger interface{}) []string {
+	o := b.Configuration()
+	// the backend name path prefix is stripped, as with path-based routing
+	if !o.PathRoutingDisabled && strings.HasPrefix(sr.URL.Path, "/"+o.Name+"/") {
+		sr.URL.Path = sr.URL.Path[len(o.Name)+1:]
+	}
+	pc := po.Lookup(o.Paths).Match(sr.Method, sr.URL.Path)
+	// path captures are attached as by the router, for use by the rewriters and keys
+	if pc != nil {
+		if cp, ok := matching.Captures(pc.Matcher, sr.URL.Path); ok && len(cp) > 0 {
+			sr = sr.WithContext(context.WithPathCaptures(sr.Context(), cp))
+		}
+	}
+	if pc != nil && len(pc.ReqRewriter) > 0 {
+		pc.ReqRewriter.Execute(sr)
+	}
+	if len(o.ReqRewriter) > 0 {
+		o.ReqRewriter.Execute(sr)
+	}
+	// backend handlers derive keys from the upstream URL
+	if bu := b.BaseUpstreamURL(); bu != nil {
+		sr.URL = urls.BuildUpstreamURL(sr, bu)
+	}
+	rsc := request.NewResources(o, pc, c.Configuration(), c, b, nil, logger)
+	return engines.CacheKeys(request.SetResources(sr, rsc))
+}
+
+func purgeExtent(b backends.Backend, c cache.Cache, r *http.Request,
+	logger interface{}) ([]string, error) {
+	client, ok := b.(backends.TimeseriesBackend)
+	if !ok {
+		return nil, errNotTimeseries
+	}
+	qp := r.URL.Query()
+	e := timeseries.Extent{End: time.Now()}
+	var err error
+	if v := qp.Get("start"); v != "" {
+		if e.Start, err = parseTime(v); err != nil {
+			return nil, badRequest("invalid start time: %s", v)
+		}
+	}
+	if v := qp.Get("end"); v != "" {
+		if e.End, err = parseTime(v); err != nil {
+			return nil, badRequest("invalid end time: %s", v)
+		}
+	}
+	if e.End.Before(e.Start) {
+		return nil, badRequest("end time must not be before start time")
+	}
+	keys, err := listKeys(c, b.Configuration().CacheKeyPrefix+".dpc.")
+	if err != nil {
+		return nil, err
+	}
+	rsc := request.NewResources(b.Configuration(), nil, c.Configuration(), c, b, nil, logger)
+	r = request.SetResources(r, rsc)
+	return engines.PurgeTimeseriesExtent(r.Context(), c, keys, client.Modeler(), e), nil
+}
+
+// parseTime converts a Unix epoch seconds or RFC3339 string to a time.Time
+func parseTime(s string) (time.Time, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		s, ns := math.Modf(t)
+		return time.Unix(int64(s), int64(ns*float64(time.Second))), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
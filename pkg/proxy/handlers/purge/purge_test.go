@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package purge
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/backends"
+	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
+	"github.com/tricksterproxy/trickster/pkg/backends/prometheus"
+	"github.com/tricksterproxy/trickster/pkg/backends/prometheus/model"
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/registration"
+	tl "github.com/tricksterproxy/trickster/pkg/observability/logging"
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/engines"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+
+	"github.com/gorilla/mux"
+)
+
+const testToken = "test-token"
+
+func setupPurgeHandler(t *testing.T) (http.Handler, cache.Cache) {
+	h, c, _ := setupPurgeBackend(t)
+	return h, c
+}
+
+func setupPurgeBackend(t *testing.T) (http.Handler, cache.Cache, backends.Backend) {
+	logger := tl.ConsoleLogger("error")
+	cc := co.New()
+	cc.Name = "default"
+	c := registration.NewCache("default", cc, logger)
+
+	o := bo.New()
+	o.Name = "prom1"
+	o.Provider = "prometheus"
+	o.Host = "127.0.0.1:9090"
+	o.CacheKeyPrefix = o.Host
+	client, err := prometheus.NewClient("prom1", o, mux.NewRouter(), c, model.NewModeler())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Paths = client.DefaultPathConfigs(o)
+	for _, p := range o.Paths {
+		p.Handler = client.Handlers()[p.HandlerName]
+	}
+
+	bknds := backends.Backends{"prom1": client}
+	caches := map[string]cache.Cache{"default": c}
+	return Handler(testToken, bknds, caches, logger), c, client
+}
+
+func purgeRequest(h http.Handler, method, query, token string) (*httptest.ResponseRecorder, *Result) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, "http://127.0.0.1:8484/trickster/cache/purge?"+query, nil)
+	if token != "" {
+		r.Header.Set(headers.NameAuthorization, "Bearer "+token)
+	}
+	h.ServeHTTP(w, r)
+	res := &Result{}
+	if w.Code == http.StatusOK {
+		json.Unmarshal(w.Body.Bytes(), res)
+	}
+	return w, res
+}
+
+func TestHandlerErrors(t *testing.T) {
+
+	h, _ := setupPurgeHandler(t)
+
+	tests := []struct {
+		method, query, token string
+		expected             int
+	}{
+		{http.MethodPost, "backend=prom1&key=a", "", http.StatusUnauthorized},
+		{http.MethodPost, "backend=prom1&key=a", "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "backend=prom1&key=a", testToken, http.StatusMethodNotAllowed},
+		{http.MethodPost, "backend=prom1", testToken, http.StatusBadRequest},
+		{http.MethodPost, "backend=prom1&key=a&all=true", testToken, http.StatusBadRequest},
+		{http.MethodPost, "backend=prom2&key=a", testToken, http.StatusNotFound},
+		{http.MethodPost, "cache=cache2&prefix=a", testToken, http.StatusNotFound},
+		{http.MethodPost, "key=a", testToken, http.StatusBadRequest},
+		{http.MethodPost, "backend=prom1&start=x", testToken, http.StatusBadRequest},
+		{http.MethodPost, "backend=prom1&start=20&end=10", testToken, http.StatusBadRequest},
+	}
+
+	for i, test := range tests {
+		w, _ := purgeRequest(h, test.method, test.query, test.token)
+		if w.Code != test.expected {
+			t.Errorf("(%d) expected %d got %d", i, test.expected, w.Code)
+		}
+	}
+}
+
+func TestHandlerPurgeByKey(t *testing.T) {
+
+	h, c := setupPurgeHandler(t)
+	c.Store("key1", []byte("data"), time.Minute)
+	c.Store("key2", []byte("data"), time.Minute)
+
+	w, res := purgeRequest(h, http.MethodDelete, "backend=prom1&key=key1", testToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if res.Count != 1 {
+		t.Errorf("expected %d got %d", 1, res.Count)
+	}
+	if _, _, err := c.Retrieve("key1", false); err == nil {
+		t.Error("expected cache miss for key1")
+	}
+	if _, _, err := c.Retrieve("key2", false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHandlerPurgeByPrefix(t *testing.T) {
+
+	h, c := setupPurgeHandler(t)
+	c.Store("127.0.0.1:9090.opc.1", []byte("data"), time.Minute)
+	c.Store("127.0.0.1:9090.dpc.2", []byte("data"), time.Minute)
+	c.Store("other.opc.3", []byte("data"), time.Minute)
+	time.Sleep(time.Millisecond * 10)
+
+	w, res := purgeRequest(h, http.MethodPost, "backend=prom1&all=true", testToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if res.Count != 2 {
+		t.Errorf("expected %d got %d", 2, res.Count)
+	}
+
+	w, res = purgeRequest(h, http.MethodPost, "cache=default&prefix=other", testToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if res.Count != 1 || res.Keys[0] != "other.opc.3" {
+		t.Errorf("expected %s got %v", "[other.opc.3]", res.Keys)
+	}
+}
+
+func TestHandlerPurgeBySampleRequest(t *testing.T) {
+
+	h, _ := setupPurgeHandler(t)
+
+	v := url.Values{"query": {"up"}, "start": {"0"}, "end": {"3600"}, "step": {"15"}}
+	q := "backend=prom1&path=" + url.QueryEscape("/prom1/api/v1/query_range?"+v.Encode())
+	w, res := purgeRequest(h, http.MethodPost, q, testToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if res.Count != 3 {
+		t.Fatalf("expected %d got %d", 3, res.Count)
+	}
+	if !strings.HasPrefix(res.Keys[2], "127.0.0.1:9090.dpc.") {
+		t.Errorf("expected dpc key got %s", res.Keys[2])
+	}
+
+	// the same sample, provided as a message/http body, should derive the same keys
+	w2 := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8484/trickster/cache/purge?backend=prom1",
+		strings.NewReader("GET /api/v1/query_range?"+v.Encode()+" HTTP/1.1\r\nHost: prom\r\n\r\n"))
+	r.Header.Set(headers.NameAuthorization, "Bearer "+testToken)
+	r.Header.Set(headers.NameContentType, ValueMessageHTTP)
+	h.ServeHTTP(w2, r)
+	res2 := &Result{}
+	json.Unmarshal(w2.Body.Bytes(), res2)
+	if res2.Count != 3 || res2.Keys[2] != res.Keys[2] {
+		t.Errorf("expected %v got %v", res.Keys, res2.Keys)
+	}
+}
+
+func TestHandlerPurgeBySampleRequestPathCaptures(t *testing.T) {
+
+	h, c, b := setupPurgeBackend(t)
+	pc := &po.Options{
+		Path:             "/api/v1/label/{name}/values",
+		MatchType:        matching.PathMatchTypeTemplate,
+		Methods:          []string{http.MethodGet},
+		HandlerName:      "proxycache",
+		CacheKeyCaptures: []string{"name"},
+	}
+	pc.Matcher, _ = matching.Compile(pc.MatchType, pc.Path)
+	o := b.Configuration()
+	o.Paths[pc.Path+"-GET"] = pc
+
+	// the keys derived for the request as routed, with its path captures attached
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/label/job/values", nil)
+	r = r.WithContext(tc.WithPathCaptures(r.Context(), map[string]string{"name": "job"}))
+	r.URL = urls.BuildUpstreamURL(r, b.BaseUpstreamURL())
+	expected := engines.CacheKeys(request.SetResources(r,
+		request.NewResources(o, pc, c.Configuration(), c, b, nil, tl.ConsoleLogger("error"))))
+
+	q := "backend=prom1&path=" + url.QueryEscape("/prom1/api/v1/label/job/values")
+	w, res := purgeRequest(h, http.MethodPost, q, testToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if len(res.Keys) != len(expected) || res.Keys[1] != expected[1] {
+		t.Errorf("expected %v got %v", expected, res.Keys)
+	}
+}
+
+func TestHandlerPurgeByExtent(t *testing.T) {
+
+	h, _ := setupPurgeHandler(t)
+	w, res := purgeRequest(h, http.MethodPost,
+		"backend=prom1&start=0&end=2020-01-01T00:00:00Z", testToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if res.Count != 0 {
+		t.Errorf("expected %d got %d", 0, res.Count)
+	}
+}
@@ -30,6 +30,8 @@ const (
 
 	// ValueApplicationCSV represents the HTTP Header Value of "application/csv"
 	ValueApplicationCSV = "application/csv"
+	// ValueApplicationFlux represents the HTTP Header Value of "application/vnd.flux"
+	ValueApplicationFlux = "application/vnd.flux"
 	// ValueApplicationJSON represents the HTTP Header Value of "application/json"
 	ValueApplicationJSON = "application/json"
 	// ValueChunked represents the HTTP Header Value of "chunked"
@@ -54,6 +56,12 @@ const (
 	ValuePublic = "public"
 	// ValueSharedMaxAge represents the HTTP Header Value of "s-maxage"
 	ValueSharedMaxAge = "s-maxage"
+	// ValueStaleIfError represents the HTTP Header Value of "stale-if-error"
+	ValueStaleIfError = "stale-if-error"
+	// ValueStaleWhileRevalidate represents the HTTP Header Value of "stale-while-revalidate"
+	ValueStaleWhileRevalidate = "stale-while-revalidate"
+	// ValueTextCSV represents the HTTP Header Value of "text/csv"
+	ValueTextCSV = "text/csv"
 	// ValueTextPlain represents the HTTP Header Value of "text/plain"
 	ValueTextPlain = "text/plain"
 	// ValueXFormURLEncoded represents the HTTP Header Value of "application/x-www-form-urlencoded"
@@ -120,6 +128,8 @@ const (
 	NameETag = "Etag"
 	// NameLocation represents the HTTP Header Name of "location"
 	NameLocation = "Location"
+	// NameRetryAfter represents the HTTP Header Name of "Retry-After"
+	NameRetryAfter = "Retry-After"
 	// NameTe represents the HTTP Header Name of "TE"
 	NameTe = "Te"
 	// NameTrailer represents the HTTP Header Name of "Trailer"
@@ -131,6 +141,10 @@ const (
 	NameTrkHCStatus = "Trk-HC-Status"
 	// NameTrkHCDetail represents the HTTP Header Name of "Trk-HC-Detail"
 	NameTrkHCDetail = "Trk-HC-Detail"
+	// NameTrkCBStatus represents the HTTP Header Name of "Trk-CB-Status"
+	NameTrkCBStatus = "Trk-CB-Status"
+	// NameTrkRLLimit represents the HTTP Header Name of "Trk-RL-Limit"
+	NameTrkRLLimit = "Trk-RL-Limit"
 )
 
 // Lookup represents a simple lookup for internal header manipulation
@@ -31,6 +31,11 @@ type ResultHeaderParts struct {
 	Status            string
 	Fetched           timeseries.ExtentList
 	FastForwardStatus string
+	// Dropped is the number of ALB pool members whose responses were not
+	// included in a merged response because they missed the merge deadline
+	Dropped int
+	// Partial indicates the response omits data that the backend failed to provide
+	Partial bool
 }
 
 func (p ResultHeaderParts) String() string {
@@ -45,6 +50,12 @@ func (p ResultHeaderParts) String() string {
 	if p.FastForwardStatus != "" {
 		sb.WriteString("; ffstatus=" + p.FastForwardStatus)
 	}
+	if p.Dropped > 0 {
+		sb.WriteString("; dropped=" + strconv.Itoa(p.Dropped))
+	}
+	if p.Partial {
+		sb.WriteString("; partial=true")
+	}
 	return sb.String()
 }
 
@@ -63,6 +74,21 @@ func MakeResultsHeader(engine, status, ffstatus string, fetched timeseries.Exten
 	return p.String()
 }
 
+// SetDroppedCount sets the number of ALB pool members dropped from a merged response
+// in the provided Trickster Result Header value
+func SetDroppedCount(h string, dropped int) string {
+	r := parseResultHeaderVals(h)
+	r.Dropped = dropped
+	return r.String()
+}
+
+// SetPartial marks the provided Trickster Result Header value as a partial response
+func SetPartial(h string) string {
+	r := parseResultHeaderVals(h)
+	r.Partial = true
+	return r.String()
+}
+
 // MergeResultHeaderVals merges 2 Trickster Result Headers
 func MergeResultHeaderVals(h1, h2 string) string {
 
@@ -89,6 +115,9 @@ func MergeResultHeaderVals(h1, h2 string) string {
 		r1.FastForwardStatus = "phit"
 	}
 
+	r1.Dropped += r2.Dropped
+	r1.Partial = r1.Partial || r2.Partial
+
 	if len(r1.Fetched) == 0 {
 		r1.Fetched = r2.Fetched
 	} else {
@@ -124,6 +153,12 @@ func parseResultHeaderVals(h string) ResultHeaderParts {
 				if val != "" {
 					r.FastForwardStatus = val
 				}
+			case "partial":
+				r.Partial = val == "true"
+			case "dropped":
+				if n, err := strconv.Atoi(val); err == nil {
+					r.Dropped = n
+				}
 			case "fetched":
 				val = strings.ReplaceAll(strings.ReplaceAll(val, "[", ""), "]", "")
 				fparts := strings.Split(val, ";")
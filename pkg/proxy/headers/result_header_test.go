@@ -67,6 +67,41 @@ func TestMergeResultHeaderVals(t *testing.T) {
 
 }
 
+func TestSetDroppedCount(t *testing.T) {
+
+	const h1 = "engine=ObjectProxyCache; status=hit"
+	const ex1 = "engine=ObjectProxyCache; status=hit; dropped=2"
+
+	if res := SetDroppedCount(h1, 2); res != ex1 {
+		t.Errorf("unexpected header: %s", res)
+	}
+
+	if res := SetDroppedCount(ex1, 0); res != h1 {
+		t.Errorf("unexpected header: %s", res)
+	}
+
+	if res := MergeResultHeaderVals(ex1, "engine=ObjectProxyCache; status=hit; dropped=1"); res !=
+		"engine=ObjectProxyCache; status=hit; dropped=3" {
+		t.Errorf("unexpected merged header: %s", res)
+	}
+
+}
+
+func TestSetPartial(t *testing.T) {
+
+	const h1 = "engine=DeltaProxyCache; status=phit"
+	const ex1 = "engine=DeltaProxyCache; status=phit; partial=true"
+
+	if res := SetPartial(h1); res != ex1 {
+		t.Errorf("unexpected header: %s", res)
+	}
+
+	if res := MergeResultHeaderVals(h1, ex1); res != ex1 {
+		t.Errorf("unexpected merged header: %s", res)
+	}
+
+}
+
 func TestParseResultHeaderVals(t *testing.T) {
 
 	const h1 = "engine=ObjectProxyCache; status=phit; fetched=[aaa-bbb]; ffstatus=hit"
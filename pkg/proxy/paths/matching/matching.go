@@ -16,7 +16,12 @@
 
 package matching
 
-import "strconv"
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 // PathMatchType enumerates the types of Path Matches used when registering Paths with the Router
 type PathMatchType int
@@ -26,12 +31,20 @@ const (
 	PathMatchTypeExact = PathMatchType(iota)
 	// PathMatchTypePrefix indicates the router will map the Path by prefix against incoming requests
 	PathMatchTypePrefix
+	// PathMatchTypeRegex indicates the router will map the Path by regular expression
+	// against incoming requests
+	PathMatchTypeRegex
+	// PathMatchTypeTemplate indicates the router will map the Path by a template of
+	// literal and named segments (e.g., /api/v1/label/{name}/values) against incoming requests
+	PathMatchTypeTemplate
 )
 
 // Names is a map of PathMatchTypes keyed by string name
 var Names = map[string]PathMatchType{
-	"exact":  PathMatchTypeExact,
-	"prefix": PathMatchTypePrefix,
+	"exact":    PathMatchTypeExact,
+	"prefix":   PathMatchTypePrefix,
+	"regex":    PathMatchTypeRegex,
+	"template": PathMatchTypeTemplate,
 }
 
 // Values is a map of PathMatchTypes valued by string name
@@ -49,3 +62,100 @@ func (t PathMatchType) String() string {
 	}
 	return strconv.Itoa(int(t))
 }
+
+// Precedence returns the rank of the PathMatchType when more than one Path matches a
+// request; lower ranks are preferred. Exact matches are preferred over template matches,
+// template matches over regex matches, and regex matches over prefix matches.
+func (t PathMatchType) Precedence() int {
+	switch t {
+	case PathMatchTypeExact:
+		return 0
+	case PathMatchTypeTemplate:
+		return 1
+	case PathMatchTypeRegex:
+		return 2
+	}
+	return 3
+}
+
+// ErrInvalidTemplate is returned when a path template is malformed
+var ErrInvalidTemplate = errors.New("invalid path template")
+
+var templateSegment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Compile returns a Regexp that matches the provided path according to the PathMatchType.
+// nil is returned for exact and prefix match types, which do not require a Regexp.
+// Regex paths are anchored to the full request path, and named capture groups
+// (e.g., (?P<name>[^/]+)) are available as path captures.
+func Compile(t PathMatchType, path string) (*regexp.Regexp, error) {
+	switch t {
+	case PathMatchTypeRegex:
+		return regexp.Compile("^(?:" + path + ")$")
+	case PathMatchTypeTemplate:
+		return compileTemplate(path)
+	}
+	return nil, nil
+}
+
+// compileTemplate converts a path template into an anchored Regexp. {name} matches and
+// captures one full path segment as name, {name...} matches and captures the remainder
+// of the path, and * matches any run of characters within a single path segment.
+func compileTemplate(path string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	seen := make(map[string]bool)
+	for len(path) > 0 {
+		i := strings.IndexAny(path, "{}*")
+		if i < 0 {
+			sb.WriteString(regexp.QuoteMeta(path))
+			break
+		}
+		sb.WriteString(regexp.QuoteMeta(path[:i]))
+		switch path[i] {
+		case '*':
+			sb.WriteString("[^/]*")
+			path = path[i+1:]
+		case '}':
+			return nil, ErrInvalidTemplate
+		case '{':
+			j := strings.Index(path[i:], "}")
+			if j < 0 {
+				return nil, ErrInvalidTemplate
+			}
+			name := path[i+1 : i+j]
+			pattern := "[^/]+"
+			if strings.HasSuffix(name, "...") {
+				name = strings.TrimSuffix(name, "...")
+				pattern = ".*"
+			}
+			if !templateSegment.MatchString(name) || seen[name] {
+				return nil, ErrInvalidTemplate
+			}
+			seen[name] = true
+			sb.WriteString("(?P<" + name + ">" + pattern + ")")
+			path = path[i+j+1:]
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// Captures returns the named capture groups of re that match the provided path,
+// and false if the path does not match
+func Captures(re *regexp.Regexp, path string) (map[string]string, bool) {
+	if re == nil {
+		return nil, false
+	}
+	m := re.FindStringSubmatch(path)
+	if m == nil {
+		return nil, false
+	}
+	names := re.SubexpNames()
+	out := make(map[string]string, len(names))
+	for i, n := range names {
+		if i > 0 && n != "" {
+			out[n] = m[i]
+		}
+	}
+	return out, true
+}
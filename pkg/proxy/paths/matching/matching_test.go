@@ -16,14 +16,16 @@
 
 package matching
 
-import "testing"
+import (
+	"testing"
+)
 
 func TestPMTString(t *testing.T) {
 
 	t1 := PathMatchTypeExact
 	t2 := PathMatchTypePrefix
 
-	var t3 PathMatchType = 3
+	var t3 PathMatchType = 30
 
 	if t1.String() != "exact" {
 		t.Errorf("expected %s got %s", "exact", t1.String())
@@ -33,7 +35,80 @@ func TestPMTString(t *testing.T) {
 		t.Errorf("expected %s got %s", "prefix", t2.String())
 	}
 
-	if t3.String() != "3" {
-		t.Errorf("expected %s got %s", "3", t3.String())
+	if t3.String() != "30" {
+		t.Errorf("expected %s got %s", "30", t3.String())
+	}
+
+	if PathMatchTypeTemplate.String() != "template" {
+		t.Errorf("expected %s got %s", "template", PathMatchTypeTemplate.String())
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	l := []PathMatchType{PathMatchTypeExact, PathMatchTypeTemplate,
+		PathMatchTypeRegex, PathMatchTypePrefix}
+	for i := 1; i < len(l); i++ {
+		if l[i-1].Precedence() >= l[i].Precedence() {
+			t.Errorf("expected %s to precede %s", l[i-1], l[i])
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+
+	tests := []struct {
+		mt       PathMatchType
+		path     string
+		input    string
+		match    bool
+		captures map[string]string
+		err      bool
+	}{
+		{PathMatchTypeExact, "/api", "/api", false, nil, false},
+		{PathMatchTypeTemplate, "/api/v1/label/{name}/values", "/api/v1/label/job/values",
+			true, map[string]string{"name": "job"}, false},
+		{PathMatchTypeTemplate, "/api/v1/label/{name}/values", "/api/v1/label/a/b/values",
+			false, nil, false},
+		{PathMatchTypeTemplate, "/render/*.png", "/render/graph.png", true,
+			map[string]string{}, false},
+		{PathMatchTypeTemplate, "/render/*.png", "/render/graphxpng", false, nil, false},
+		{PathMatchTypeTemplate, "/files/{path...}", "/files/a/b/c", true,
+			map[string]string{"path": "a/b/c"}, false},
+		{PathMatchTypeTemplate, "/{a}/{a}", "", false, nil, true},
+		{PathMatchTypeTemplate, "/{a", "", false, nil, true},
+		{PathMatchTypeTemplate, "/a}", "", false, nil, true},
+		{PathMatchTypeTemplate, "/{1a}", "", false, nil, true},
+		{PathMatchTypeRegex, `/api/v1/(?P<kind>query|query_range)`, "/api/v1/query_range",
+			true, map[string]string{"kind": "query_range"}, false},
+		{PathMatchTypeRegex, `/api/v1/(?P<kind>query|query_range)`, "/api/v1/query_rangex",
+			false, nil, false},
+		{PathMatchTypeRegex, `/api/(`, "", false, nil, true},
+	}
+
+	for i, test := range tests {
+		re, err := Compile(test.mt, test.path)
+		if test.err {
+			if err == nil {
+				t.Errorf("test %d expected error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		c, ok := Captures(re, test.input)
+		if ok != test.match {
+			t.Errorf("test %d expected match %t got %t", i, test.match, ok)
+		}
+		if len(c) != len(test.captures) {
+			t.Errorf("test %d expected %v got %v", i, test.captures, c)
+			continue
+		}
+		for k, v := range test.captures {
+			if c[k] != v {
+				t.Errorf("test %d expected %s got %s", i, v, c[k])
+			}
+		}
 	}
 }
@@ -20,6 +20,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/key"
@@ -27,6 +29,8 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	resprewriter "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
 	"github.com/tricksterproxy/trickster/pkg/util/copiers"
 	strutil "github.com/tricksterproxy/trickster/pkg/util/strings"
 	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
@@ -36,7 +40,8 @@ import (
 type Options struct {
 	// Path indicates the HTTP Request's URL PATH to which this configuration applies
 	Path string `yaml:"path,omitempty"`
-	// MatchTypeName indicates the type of path match the router will apply to the path ('exact' or 'prefix')
+	// MatchTypeName indicates the type of path match the router will apply to the path
+	// ('exact', 'prefix', 'regex' or 'template')
 	MatchTypeName string `yaml:"match_type,omitempty"`
 	// HandlerName provides the name of the HTTP handler to use
 	HandlerName string `yaml:"handler,omitempty"`
@@ -50,6 +55,9 @@ type Options struct {
 	// CacheKeyFormFields provides the list of http request body fields to be included
 	// in the hash for each request's cache key
 	CacheKeyFormFields []string `yaml:"cache_key_form_fields,omitempty"`
+	// CacheKeyCaptures provides the list of named path captures from a 'regex' or 'template'
+	// path to be included in the hash for each request's cache key
+	CacheKeyCaptures []string `yaml:"cache_key_captures,omitempty"`
 	// RequestHeaders is a map of headers that will be added to requests to the upstream Origin for this path
 	RequestHeaders map[string]string `yaml:"request_headers,omitempty"`
 	// RequestParams is a map of headers that will be added to requests to the upstream Origin for this path
@@ -65,8 +73,23 @@ type Options struct {
 	// ReqRewriterName is the name of a configured Rewriter that will modify the request prior to
 	// processing by the backend client
 	ReqRewriterName string `yaml:"req_rewriter_name,omitempty"`
+	// RespRewriterName is the name of a configured Response Rewriter that will modify the
+	// response prior to it being sent to the client
+	RespRewriterName string `yaml:"resp_rewriter_name,omitempty"`
 	// NoMetrics, when set to true, disables metrics decoration for the path
 	NoMetrics bool `yaml:"no_metrics"`
+	// StaleWhileRevalidateSecs, when > 0, overrides the origin's stale-while-revalidate
+	// Cache-Control directive for objects cached by the Object Proxy Cache for this path
+	StaleWhileRevalidateSecs int `yaml:"stale_while_revalidate_secs,omitempty"`
+	// StaleIfErrorSecs, when > 0, overrides the origin's stale-if-error
+	// Cache-Control directive for objects cached by the Object Proxy Cache for this path
+	StaleIfErrorSecs int `yaml:"stale_if_error_secs,omitempty"`
+	// ResampleSteps, when true, allows the Delta Proxy Cache to serve a request for this path
+	// by resampling a timeseries cached at a finer step for the same query
+	ResampleSteps bool `yaml:"resample_steps,omitempty"`
+	// ResampleAggregationName is the aggregation used when resampling a timeseries to a
+	// coarser step: 'sample' (default), 'sum', 'min', 'max', 'first' or 'last'
+	ResampleAggregationName string `yaml:"resample_aggregation,omitempty"`
 
 	// Handler is the HTTP Handler represented by the Path's HandlerName
 	Handler http.Handler `yaml:"-"`
@@ -74,8 +97,12 @@ type Options struct {
 	ResponseBodyBytes []byte `yaml:"-"`
 	// MatchType is the PathMatchType representation of MatchTypeName
 	MatchType matching.PathMatchType `yaml:"-"`
+	// Matcher is the compiled Regexp for 'regex' and 'template' MatchTypes
+	Matcher *regexp.Regexp `yaml:"-"`
 	// CollapsedForwardingType is the typed representation of CollapsedForwardingName
 	CollapsedForwardingType forwarding.CollapsedForwardingType `yaml:"-"`
+	// ResampleAggregation is the typed representation of ResampleAggregationName
+	ResampleAggregation timeseries.Aggregation `yaml:"-"`
 	// KeyHasher points to an optional function that hashes the cacheKey with a custom algorithm
 	// NOTE: This is used by some backends like IronDB, but is not configurable by end users.
 	KeyHasher key.HasherFunc `yaml:"-"`
@@ -83,6 +110,8 @@ type Options struct {
 	Custom []string `yaml:"-"`
 	// ReqRewriter is the rewriter handler as indicated by RuleName
 	ReqRewriter rewriter.RewriteInstructions
+	// RespRewriter is the response rewriter as indicated by RespRewriterName
+	RespRewriter *resprewriter.Rewriter `yaml:"-"`
 
 	// HasCustomResponseBody is a boolean indicating if the response body is custom
 	// this flag allows an empty string response to be configured as a return value
@@ -92,6 +121,64 @@ type Options struct {
 // Lookup is a map of Options
 type Lookup map[string]*Options
 
+// Match returns the Options from the Lookup that the router would select for a request
+// with the provided method and path, following the precedence of Sorted. nil is returned
+// if no Options match.
+func (l Lookup) Match(method, path string) *Options {
+	for _, p := range l.Sorted() {
+		if hasMethod(p.Methods, method) && p.Matches(path) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Sorted returns the Options in the Lookup in the order in which they are matched against
+// requests: exact paths first, then template, regex and prefix paths. Within a match type,
+// longer paths are preferred over shorter ones, and equal-length paths are ordered lexically.
+func (l Lookup) Sorted() []*Options {
+	out := make([]*Options, 0, len(l))
+	for _, p := range l {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].precedes(out[j]) })
+	return out
+}
+
+func (o *Options) precedes(o2 *Options) bool {
+	p1, p2 := o.MatchType.Precedence(), o2.MatchType.Precedence()
+	if p1 != p2 {
+		return p1 < p2
+	}
+	if len(o.Path) != len(o2.Path) {
+		return len(o.Path) > len(o2.Path)
+	}
+	if o.Path != o2.Path {
+		return o.Path < o2.Path
+	}
+	return strings.Join(o.Methods, ",") < strings.Join(o2.Methods, ",")
+}
+
+// Matches returns true if the provided request path matches the Options' Path
+func (o *Options) Matches(path string) bool {
+	switch o.MatchType {
+	case matching.PathMatchTypePrefix:
+		return strings.HasPrefix(path, o.Path)
+	case matching.PathMatchTypeRegex, matching.PathMatchTypeTemplate:
+		return o.Matcher != nil && o.Matcher.MatchString(path)
+	}
+	return o.Path == path
+}
+
+func hasMethod(list []string, method string) bool {
+	for _, m := range list {
+		if m == method || m == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 // New returns a newly-instantiated path *Options
 func New() *Options {
 	return &Options{
@@ -118,27 +205,36 @@ func (o *Options) Clone() *Options {
 	c := &Options{
 		Path: o.Path,
 		//		BackendOptions:            o.BackendOptions,
-		MatchTypeName:           o.MatchTypeName,
-		MatchType:               o.MatchType,
-		HandlerName:             o.HandlerName,
-		Handler:                 o.Handler,
-		RequestHeaders:          copiers.CopyStringLookup(o.RequestHeaders),
-		RequestParams:           copiers.CopyStringLookup(o.RequestParams),
-		ReqRewriter:             o.ReqRewriter,
-		ReqRewriterName:         o.ReqRewriterName,
-		ResponseHeaders:         copiers.CopyStringLookup(o.ResponseHeaders),
-		ResponseBody:            o.ResponseBody,
-		ResponseBodyBytes:       o.ResponseBodyBytes,
-		CollapsedForwardingName: o.CollapsedForwardingName,
-		CollapsedForwardingType: o.CollapsedForwardingType,
-		NoMetrics:               o.NoMetrics,
-		HasCustomResponseBody:   o.HasCustomResponseBody,
-		Methods:                 copiers.CopyStrings(o.Methods),
-		CacheKeyParams:          copiers.CopyStrings(o.CacheKeyParams),
-		CacheKeyHeaders:         copiers.CopyStrings(o.CacheKeyHeaders),
-		CacheKeyFormFields:      copiers.CopyStrings(o.CacheKeyFormFields),
-		Custom:                  copiers.CopyStrings(o.Custom),
-		KeyHasher:               o.KeyHasher,
+		MatchTypeName:            o.MatchTypeName,
+		MatchType:                o.MatchType,
+		Matcher:                  o.Matcher,
+		HandlerName:              o.HandlerName,
+		Handler:                  o.Handler,
+		RequestHeaders:           copiers.CopyStringLookup(o.RequestHeaders),
+		RequestParams:            copiers.CopyStringLookup(o.RequestParams),
+		ReqRewriter:              o.ReqRewriter,
+		ReqRewriterName:          o.ReqRewriterName,
+		RespRewriter:             o.RespRewriter,
+		RespRewriterName:         o.RespRewriterName,
+		ResponseHeaders:          copiers.CopyStringLookup(o.ResponseHeaders),
+		ResponseBody:             o.ResponseBody,
+		ResponseBodyBytes:        o.ResponseBodyBytes,
+		CollapsedForwardingName:  o.CollapsedForwardingName,
+		CollapsedForwardingType:  o.CollapsedForwardingType,
+		NoMetrics:                o.NoMetrics,
+		StaleWhileRevalidateSecs: o.StaleWhileRevalidateSecs,
+		StaleIfErrorSecs:         o.StaleIfErrorSecs,
+		ResampleSteps:            o.ResampleSteps,
+		ResampleAggregationName:  o.ResampleAggregationName,
+		ResampleAggregation:      o.ResampleAggregation,
+		HasCustomResponseBody:    o.HasCustomResponseBody,
+		Methods:                  copiers.CopyStrings(o.Methods),
+		CacheKeyParams:           copiers.CopyStrings(o.CacheKeyParams),
+		CacheKeyHeaders:          copiers.CopyStrings(o.CacheKeyHeaders),
+		CacheKeyFormFields:       copiers.CopyStrings(o.CacheKeyFormFields),
+		CacheKeyCaptures:         copiers.CopyStrings(o.CacheKeyCaptures),
+		Custom:                   copiers.CopyStrings(o.Custom),
+		KeyHasher:                o.KeyHasher,
 	}
 	return c
 }
@@ -167,6 +263,8 @@ func (o *Options) Merge(o2 *Options) {
 			o.CacheKeyHeaders = o2.CacheKeyHeaders
 		case "cache_key_form_fields":
 			o.CacheKeyFormFields = o2.CacheKeyFormFields
+		case "cache_key_captures":
+			o.CacheKeyCaptures = o2.CacheKeyCaptures
 		case "request_headers":
 			o.RequestHeaders = o2.RequestHeaders
 		case "request_params":
@@ -181,21 +279,38 @@ func (o *Options) Merge(o2 *Options) {
 			o.ResponseBodyBytes = o2.ResponseBodyBytes
 		case "no_metrics":
 			o.NoMetrics = o2.NoMetrics
+		case "stale_while_revalidate_secs":
+			o.StaleWhileRevalidateSecs = o2.StaleWhileRevalidateSecs
+		case "stale_if_error_secs":
+			o.StaleIfErrorSecs = o2.StaleIfErrorSecs
+		case "resample_steps":
+			o.ResampleSteps = o2.ResampleSteps
+		case "resample_aggregation":
+			o.ResampleAggregationName = o2.ResampleAggregationName
+			o.ResampleAggregation = o2.ResampleAggregation
 		case "collapsed_forwarding":
 			o.CollapsedForwardingName = o2.CollapsedForwardingName
 			o.CollapsedForwardingType = o2.CollapsedForwardingType
 		case "req_rewriter_name":
 			o.ReqRewriterName = o2.ReqRewriterName
 			o.ReqRewriter = o2.ReqRewriter
+		case "resp_rewriter_name":
+			o.RespRewriterName = o2.RespRewriterName
+			o.RespRewriter = o2.RespRewriter
 		}
 	}
 	o.Custom = strutil.Unique(o.Custom)
+	// the matcher depends on both the path and match type, which may have come from either
+	if o.Matcher != nil || o2.Matcher != nil {
+		o.Matcher, _ = matching.Compile(o.MatchType, o.Path)
+	}
 }
 
 var pathMembers = []string{"path", "match_type", "handler", "methods", "cache_key_params",
 	"cache_key_headers", "default_ttl_ms", "request_headers", "response_headers",
 	"response_headers", "response_code", "response_body", "no_metrics", "collapsed_forwarding",
-	"req_rewriter_name",
+	"req_rewriter_name", "stale_while_revalidate_secs", "stale_if_error_secs",
+	"resample_steps", "resample_aggregation", "resp_rewriter_name", "cache_key_captures",
 }
 
 func SetDefaults(
@@ -203,11 +318,19 @@ func SetDefaults(
 	metadata yamlx.KeyLookup,
 	paths Lookup,
 	crw map[string]rewriter.RewriteInstructions,
+	crsw map[string]*resprewriter.Rewriter,
 ) error {
 	if metadata == nil {
 		return errors.New("invalid config metadata")
 	}
-	for k, p := range paths {
+	// the paths are also keyed by path and methods below, so the configured keys are
+	// collected first to ensure each path is only processed under its configured key
+	keys := make([]string, 0, len(paths))
+	for k := range paths {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		p := paths[k]
 		if metadata.IsDefined("backends", backendName, "paths", k, "req_rewriter_name") &&
 			p.ReqRewriterName != "" {
 			ri, ok := crw[p.ReqRewriterName]
@@ -217,6 +340,18 @@ func SetDefaults(
 			}
 			p.ReqRewriter = ri
 		}
+		if metadata.IsDefined("backends", backendName, "paths", k, "resp_rewriter_name") &&
+			p.RespRewriterName != "" {
+			rw, ok := crsw[p.RespRewriterName]
+			if !ok {
+				return fmt.Errorf("invalid response rewriter name %s in path %s of backend options %s",
+					p.RespRewriterName, k, backendName)
+			}
+			p.RespRewriter = rw
+		}
+		if p.StaleWhileRevalidateSecs < 0 || p.StaleIfErrorSecs < 0 {
+			return fmt.Errorf("invalid stale window in path %s of backend options %s", k, backendName)
+		}
 		if len(p.Methods) == 0 {
 			p.Methods = []string{http.MethodGet, http.MethodHead}
 		}
@@ -239,6 +374,15 @@ func SetDefaults(
 		} else {
 			p.CollapsedForwardingType = forwarding.CFTypeBasic
 		}
+		if metadata.IsDefined("backends", backendName, "paths", k, "resample_aggregation") {
+			agg, ok := timeseries.AggregationNames[strings.ToLower(p.ResampleAggregationName)]
+			if !ok {
+				return fmt.Errorf("invalid resample_aggregation %s in path %s of backend options %s",
+					p.ResampleAggregationName, k, backendName)
+			}
+			p.ResampleAggregation = agg
+			p.ResampleAggregationName = agg.String()
+		}
 		if mt, ok := matching.Names[strings.ToLower(p.MatchTypeName)]; ok {
 			p.MatchType = mt
 			p.MatchTypeName = p.MatchType.String()
@@ -246,6 +390,12 @@ func SetDefaults(
 			p.MatchType = matching.PathMatchTypeExact
 			p.MatchTypeName = p.MatchType.String()
 		}
+		re, err := matching.Compile(p.MatchType, p.Path)
+		if err != nil {
+			return fmt.Errorf("invalid %s path %s in path %s of backend options %s: %v",
+				p.MatchTypeName, p.Path, k, backendName, err)
+		}
+		p.Matcher = re
 		paths[p.Path+"-"+strings.Join(p.Methods, "-")] = p
 	}
 	return nil
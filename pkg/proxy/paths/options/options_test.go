@@ -17,11 +17,15 @@
 package options
 
 import (
+	"fmt"
 	"net/http"
 	"testing"
 
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	resprewriter "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
 )
 
 func TestNew(t *testing.T) {
@@ -61,7 +65,9 @@ func TestPathMerge(t *testing.T) {
 	pc2.Custom = []string{"path", "match_type", "handler", "methods",
 		"cache_key_params", "cache_key_headers", "cache_key_form_fields",
 		"request_headers", "request_params", "response_headers",
-		"response_code", "response_body", "no_metrics", "collapsed_forwarding"}
+		"response_code", "response_body", "no_metrics", "collapsed_forwarding",
+		"stale_while_revalidate_secs", "stale_if_error_secs", "resample_steps",
+		"resample_aggregation"}
 
 	expectedPath := "testPath"
 	expectedHandlerName := "testHandler"
@@ -81,6 +87,11 @@ func TestPathMerge(t *testing.T) {
 	pc2.NoMetrics = true
 	pc2.CollapsedForwardingName = "progressive"
 	pc2.CollapsedForwardingType = forwarding.CFTypeProgressive
+	pc2.StaleWhileRevalidateSecs = 30
+	pc2.StaleIfErrorSecs = 300
+	pc2.ResampleSteps = true
+	pc2.ResampleAggregationName = "sum"
+	pc2.ResampleAggregation = timeseries.AggregationSum
 
 	pc.Merge(pc2)
 
@@ -141,16 +152,200 @@ func TestPathMerge(t *testing.T) {
 		t.Errorf("expected %s got %s", "progressive", pc.CollapsedForwardingName)
 	}
 
+	if pc.StaleWhileRevalidateSecs != 30 {
+		t.Errorf("expected %d got %d", 30, pc.StaleWhileRevalidateSecs)
+	}
+
+	if pc.StaleIfErrorSecs != 300 {
+		t.Errorf("expected %d got %d", 300, pc.StaleIfErrorSecs)
+	}
+
+	if !pc.ResampleSteps || pc.ResampleAggregation != timeseries.AggregationSum {
+		t.Errorf("expected %s got %s", "sum", pc.ResampleAggregation)
+	}
+
 }
 
 func TestMerge(t *testing.T) {
 
 	o := &Options{}
-	o2 := &Options{Custom: []string{"req_rewriter_name"}}
+	o2 := &Options{Custom: []string{"req_rewriter_name", "resp_rewriter_name"},
+		RespRewriterName: "test", RespRewriter: &resprewriter.Rewriter{Name: "test"}}
 	o.Merge(o2)
 
-	if len(o.Custom) != 1 {
-		t.Errorf("expected %d got %d", 1, len(o.Custom))
+	if len(o.Custom) != 2 {
+		t.Errorf("expected %d got %d", 2, len(o.Custom))
+	}
+
+	if o.RespRewriterName != "test" || o.RespRewriter != o2.RespRewriter {
+		t.Error("expected merged response rewriter")
 	}
 
 }
+
+func TestLookupMatch(t *testing.T) {
+
+	l := Lookup{
+		"/": {Path: "/", MatchType: matching.PathMatchTypePrefix,
+			Methods: []string{http.MethodGet}},
+		"/api/v1/": {Path: "/api/v1/", MatchType: matching.PathMatchTypePrefix,
+			Methods: []string{http.MethodGet}},
+		"/api/v1/query": {Path: "/api/v1/query", MatchType: matching.PathMatchTypeExact,
+			Methods: []string{http.MethodGet, http.MethodPost}},
+		"/api/v1/label/{name}/values": {Path: "/api/v1/label/{name}/values",
+			MatchType: matching.PathMatchTypeTemplate, Methods: []string{http.MethodGet}},
+		"/api/v1/label/.*": {Path: "/api/v1/label/.*",
+			MatchType: matching.PathMatchTypeRegex, Methods: []string{http.MethodGet}},
+		"/api/v1/label/__name__/values": {Path: "/api/v1/label/__name__/values",
+			MatchType: matching.PathMatchTypeExact, Methods: []string{http.MethodGet}},
+	}
+	for _, p := range l {
+		p.Matcher, _ = matching.Compile(p.MatchType, p.Path)
+	}
+
+	tests := []struct {
+		method, path, expected string
+	}{
+		{http.MethodGet, "/api/v1/query", "/api/v1/query"},
+		{http.MethodPost, "/api/v1/query", "/api/v1/query"},
+		{http.MethodGet, "/api/v1/query_range", "/api/v1/"},
+		{http.MethodGet, "/other", "/"},
+		{http.MethodPost, "/other", ""},
+		{http.MethodGet, "/api/v1/label/job/values", "/api/v1/label/{name}/values"},
+		{http.MethodGet, "/api/v1/label/__name__/values", "/api/v1/label/__name__/values"},
+		{http.MethodGet, "/api/v1/label/job/other", "/api/v1/label/.*"},
+	}
+
+	for i, test := range tests {
+		p := l.Match(test.method, test.path)
+		var path string
+		if p != nil {
+			path = p.Path
+		}
+		if path != test.expected {
+			t.Errorf("(%d) expected %s got %s", i, test.expected, path)
+		}
+	}
+}
+
+func TestSetDefaultsResample(t *testing.T) {
+
+	yml := "backends:\n  test:\n    paths:\n      series:\n        path: /series\n" +
+		"        resample_steps: true\n        resample_aggregation: %s\n"
+
+	for _, test := range []struct {
+		name     string
+		expected timeseries.Aggregation
+		isErr    bool
+	}{
+		{"MAX", timeseries.AggregationMax, false},
+		{"average", 0, true},
+	} {
+		md, err := yamlx.GetKeyList(fmt.Sprintf(yml, test.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := New()
+		p.Path = "/series"
+		p.ResampleSteps = true
+		p.ResampleAggregationName = test.name
+		err = SetDefaults("test", md, Lookup{"series": p}, nil, nil)
+		if test.isErr {
+			if err == nil {
+				t.Errorf("expected error for aggregation %s", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+		}
+		if p.ResampleAggregation != test.expected || p.ResampleAggregationName != "max" {
+			t.Errorf("expected %s got %s", test.expected, p.ResampleAggregation)
+		}
+	}
+}
+
+func TestSetDefaultsRespRewriter(t *testing.T) {
+
+	md, err := yamlx.GetKeyList("backends:\n  test:\n    paths:\n      series:\n" +
+		"        path: /series\n        resp_rewriter_name: test\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := New()
+	p.Path = "/series"
+	p.RespRewriterName = "test"
+	err = SetDefaults("test", md, Lookup{"series": p}, nil, nil)
+	if err == nil {
+		t.Error("expected error for invalid response rewriter name")
+	}
+
+	rsw := map[string]*resprewriter.Rewriter{"test": {Name: "test"}}
+	p = New()
+	p.Path = "/series"
+	p.RespRewriterName = "test"
+	err = SetDefaults("test", md, Lookup{"series": p}, nil, rsw)
+	if err != nil {
+		t.Error(err)
+	}
+	if p.RespRewriter != rsw["test"] {
+		t.Error("expected response rewriter")
+	}
+}
+
+func TestSetDefaultsMatchType(t *testing.T) {
+
+	yml := "backends:\n  test:\n    paths:\n      label:\n        path: %s\n" +
+		"        match_type: %s\n        cache_key_captures: [ name ]\n"
+
+	for i, test := range []struct {
+		path, matchType string
+		isErr           bool
+		hasMatcher      bool
+	}{
+		{"/api/v1/label/{name}/values", "template", false, true},
+		{"/api/v1/label/(?P<name>[^/]+)/values", "REGEX", false, true},
+		{"/api/v1/label/{name/values", "template", true, false},
+		{"/api/v1/label/(", "regex", true, false},
+		{"/api/v1/label/", "prefix", false, false},
+	} {
+		md, err := yamlx.GetKeyList(fmt.Sprintf(yml, test.path, test.matchType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := New()
+		p.Path = test.path
+		p.MatchTypeName = test.matchType
+		p.CacheKeyCaptures = []string{"name"}
+		err = SetDefaults("test", md, Lookup{"label": p}, nil, nil)
+		if test.isErr {
+			if err == nil {
+				t.Errorf("(%d) expected error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if (p.Matcher != nil) != test.hasMatcher {
+			t.Errorf("(%d) expected matcher %t", i, test.hasMatcher)
+		}
+		if test.hasMatcher && !p.Matches("/api/v1/label/job/values") {
+			t.Errorf("(%d) expected path match", i)
+		}
+		if len(p.Custom) != 3 || p.Custom[2] != "cache_key_captures" {
+			t.Errorf("(%d) unexpected custom list %v", i, p.Custom)
+		}
+		c := p.Clone()
+		if c.Matcher != p.Matcher || len(c.CacheKeyCaptures) != 1 {
+			t.Errorf("(%d) expected cloned matcher and captures", i)
+		}
+		o := New()
+		o.Merge(p)
+		if (o.Matcher != nil) != test.hasMatcher || len(o.CacheKeyCaptures) != 1 {
+			t.Errorf("(%d) expected merged matcher and captures", i)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for backend rate limits and concurrency quotas
+package options
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
+)
+
+// Rate Limit Keys
+const (
+	// KeyBackend applies a single limit to all requests to the backend
+	KeyBackend = "backend"
+	// KeyClientIP applies a separate limit per client IP address
+	KeyClientIP = "client_ip"
+	// KeyHeader applies a separate limit per value of the header named by KeyHeader
+	KeyHeader = "header"
+	// KeyAuthorization applies a separate limit per value of the Authorization header
+	KeyAuthorization = "authorization"
+)
+
+var keys = map[string]interface{}{
+	KeyBackend:       nil,
+	KeyClientIP:      nil,
+	KeyHeader:        nil,
+	KeyAuthorization: nil,
+}
+
+const (
+	// DefaultKeyBy is the default rate limit key
+	DefaultKeyBy = KeyBackend
+	// DefaultResponseCode is the default status code of responses to rejected requests
+	DefaultResponseCode = http.StatusTooManyRequests
+)
+
+// Options defines the Rate Limit Options for a Backend
+type Options struct {
+	// RequestsPerSecond is the sustained rate of requests admitted per key. 0 (default) is unlimited
+	RequestsPerSecond float64 `yaml:"requests_per_second,omitempty"`
+	// Burst is the number of requests per key that may be admitted at once beyond the
+	// sustained rate. The default is RequestsPerSecond, rounded up
+	Burst int `yaml:"burst,omitempty"`
+	// MaxInFlight is the maximum number of upstream requests per key that may be in flight to
+	// the backend at once. 0 (default) is unlimited
+	MaxInFlight int `yaml:"max_in_flight,omitempty"`
+	// KeyBy determines how requests are grouped for limiting. Options are 'backend' (default),
+	// 'client_ip', 'header' and 'authorization'
+	KeyBy string `yaml:"key_by,omitempty"`
+	// KeyHeader is the name of the header whose value is the key when KeyBy is 'header'
+	KeyHeader string `yaml:"key_header,omitempty"`
+	// ResponseCode is the status code of responses to rejected requests. The default is 429
+	ResponseCode int `yaml:"response_code,omitempty"`
+}
+
+// New returns a New Options object with the default values
+func New() *Options {
+	return &Options{
+		KeyBy:        DefaultKeyBy,
+		ResponseCode: DefaultResponseCode,
+	}
+}
+
+// Clone returns a perfect copy of the Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		RequestsPerSecond: o.RequestsPerSecond,
+		Burst:             o.Burst,
+		MaxInFlight:       o.MaxInFlight,
+		KeyBy:             o.KeyBy,
+		KeyHeader:         o.KeyHeader,
+		ResponseCode:      o.ResponseCode,
+	}
+}
+
+// SetDefaults iterates the provided Options, and overlays user-set values onto the default Options
+func SetDefaults(name string, options *Options, metadata yamlx.KeyLookup) (*Options, error) {
+
+	if metadata == nil {
+		return nil, errors.New("invalid metadata")
+	}
+
+	if !metadata.IsDefined("backends", name, "rate_limit") || options == nil {
+		return nil, nil
+	}
+
+	o := New()
+
+	if metadata.IsDefined("backends", name, "rate_limit", "requests_per_second") {
+		if options.RequestsPerSecond < 0 {
+			return nil, errors.New("value for 'requests_per_second' is invalid")
+		}
+		o.RequestsPerSecond = options.RequestsPerSecond
+	}
+
+	if metadata.IsDefined("backends", name, "rate_limit", "burst") {
+		if options.Burst < 0 {
+			return nil, errors.New("value for 'burst' is invalid")
+		}
+		o.Burst = options.Burst
+	}
+	if o.Burst == 0 && o.RequestsPerSecond > 0 {
+		o.Burst = int(math.Ceil(o.RequestsPerSecond))
+	}
+
+	if metadata.IsDefined("backends", name, "rate_limit", "max_in_flight") {
+		if options.MaxInFlight < 0 {
+			return nil, errors.New("value for 'max_in_flight' is invalid")
+		}
+		o.MaxInFlight = options.MaxInFlight
+	}
+
+	if metadata.IsDefined("backends", name, "rate_limit", "key_by") {
+		if _, ok := keys[options.KeyBy]; !ok {
+			return nil, fmt.Errorf("invalid rate limit key_by: %s", options.KeyBy)
+		}
+		o.KeyBy = options.KeyBy
+	}
+
+	if metadata.IsDefined("backends", name, "rate_limit", "key_header") {
+		o.KeyHeader = options.KeyHeader
+	}
+	if o.KeyBy == KeyHeader && o.KeyHeader == "" {
+		return nil, errors.New("rate limit key_by 'header' requires a key_header")
+	}
+
+	if metadata.IsDefined("backends", name, "rate_limit", "response_code") {
+		if options.ResponseCode < 100 || options.ResponseCode > 599 {
+			return nil, errors.New("value for 'response_code' is invalid")
+		}
+		o.ResponseCode = options.ResponseCode
+	}
+
+	return o, nil
+}
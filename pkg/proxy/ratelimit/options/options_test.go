@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
+
+	"gopkg.in/yaml.v2"
+)
+
+type testOptions1 struct {
+	Backends map[string]*testOptions2 `yaml:"backends,omitempty"`
+}
+
+type testOptions2 struct {
+	RateLimit *Options `yaml:"rate_limit,omitempty"`
+}
+
+func fromYAML(conf string) (*Options, yamlx.KeyLookup, error) {
+	to := &testOptions1{}
+	err := yaml.Unmarshal([]byte(conf), to)
+	if err != nil {
+		return nil, nil, err
+	}
+	md, err := yamlx.GetKeyList(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, v := range to.Backends {
+		if v != nil && v.RateLimit != nil {
+			return v.RateLimit, md, nil
+		}
+	}
+	return nil, md, nil
+}
+
+const testYAML = `
+backends:
+  test:
+    rate_limit:
+      requests_per_second: 2.5
+      max_in_flight: 4
+      key_by: header
+      key_header: X-Grafana-User
+      response_code: 503
+`
+
+func TestClone(t *testing.T) {
+	o := New()
+	o.RequestsPerSecond = 10
+	c := o.Clone()
+	if c.RequestsPerSecond != 10 || c.KeyBy != DefaultKeyBy {
+		t.Error("clone mismatch")
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+
+	if _, err := SetDefaults("test", nil, nil); err == nil {
+		t.Error("expected error for invalid metadata")
+	}
+
+	o, md, err := fromYAML(testYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err = SetDefaults("test", o, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.RequestsPerSecond != 2.5 || o.Burst != 3 || o.MaxInFlight != 4 ||
+		o.KeyBy != KeyHeader || o.KeyHeader != "X-Grafana-User" || o.ResponseCode != 503 {
+		t.Errorf("unexpected options %+v", o)
+	}
+
+	// backends without a rate_limit section have no limits
+	o, md, _ = fromYAML("backends:\n  test:\n    provider: rpc\n")
+	o, err = SetDefaults("test", o, md)
+	if err != nil || o != nil {
+		t.Error("expected nil options and error")
+	}
+}
+
+func TestSetDefaultsInvalid(t *testing.T) {
+
+	tests := []string{
+		"requests_per_second: -1",
+		"burst: -1",
+		"max_in_flight: -1",
+		"key_by: invalid",
+		"key_by: header",
+		"response_code: 99",
+	}
+
+	for _, test := range tests {
+		o, md, err := fromYAML("backends:\n  test:\n    rate_limit:\n      " + test + "\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = SetDefaults("test", o, md); err == nil {
+			t.Errorf("expected error for %s", test)
+		}
+	}
+}
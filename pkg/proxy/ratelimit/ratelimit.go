@@ -0,0 +1,188 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ratelimit provides token bucket rate limits and in-flight request
+// quotas for backends
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	rlo "github.com/tricksterproxy/trickster/pkg/proxy/ratelimit/options"
+)
+
+// Limit Names, as reported in metrics
+const (
+	// LimitRate is the token bucket rate limit
+	LimitRate = "rate"
+	// LimitInFlight is the in-flight upstream request quota
+	LimitInFlight = "in_flight"
+)
+
+// pruneInterval is how often idle keys are removed from the Limiter
+const pruneInterval = time.Minute
+
+type entry struct {
+	tokens   float64
+	last     time.Time
+	inFlight int
+}
+
+// Limiter enforces the rate limits and in-flight quotas of a backend, separately for each key
+type Limiter struct {
+	options *rlo.Options
+
+	mtx       sync.Mutex
+	entries   map[string]*entry
+	lastPrune time.Time
+}
+
+// New returns a new Limiter for the provided Options
+func New(o *rlo.Options) *Limiter {
+	return &Limiter{
+		options:   o,
+		entries:   make(map[string]*entry),
+		lastPrune: time.Now(),
+	}
+}
+
+// Options returns the Limiter's Options
+func (l *Limiter) Options() *rlo.Options {
+	return l.options
+}
+
+// Key returns the key under which the request is limited
+func (l *Limiter) Key(r *http.Request) string {
+	switch l.options.KeyBy {
+	case rlo.KeyClientIP:
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			return host
+		}
+		return r.RemoteAddr
+	case rlo.KeyHeader:
+		return r.Header.Get(l.options.KeyHeader)
+	case rlo.KeyAuthorization:
+		return r.Header.Get(headers.NameAuthorization)
+	}
+	return ""
+}
+
+// entry returns the entry for the key, creating it when necessary. The caller must hold the lock
+func (l *Limiter) entry(key string, now time.Time) *entry {
+	if now.Sub(l.lastPrune) >= pruneInterval {
+		l.prune(now)
+	}
+	e, ok := l.entries[key]
+	if !ok {
+		e = &entry{tokens: float64(l.options.Burst), last: now}
+		l.entries[key] = e
+	}
+	return e
+}
+
+// prune removes keys with no requests in flight whose token buckets have refilled,
+// since they are indistinguishable from new keys. The caller must hold the lock
+func (l *Limiter) prune(now time.Time) {
+	for k, e := range l.entries {
+		if e.inFlight > 0 {
+			continue
+		}
+		if l.options.RequestsPerSecond > 0 &&
+			e.tokens+now.Sub(e.last).Seconds()*l.options.RequestsPerSecond < float64(l.options.Burst) {
+			continue
+		}
+		delete(l.entries, k)
+	}
+	l.lastPrune = now
+}
+
+// Allow takes a token from the key's bucket, returning true if the request is admitted.
+// When it is not, the returned duration is the time until a token is available
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	rps := l.options.RequestsPerSecond
+	if rps <= 0 {
+		return true, 0
+	}
+	now := time.Now()
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	e := l.entry(key, now)
+	e.tokens = math.Min(float64(l.options.Burst), e.tokens+now.Sub(e.last).Seconds()*rps)
+	e.last = now
+	if e.tokens >= 1 {
+		e.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - e.tokens) / rps * float64(time.Second))
+}
+
+// Acquire takes one of the key's in-flight upstream request slots, returning true if one
+// was available. Each successful Acquire must be followed by a Release
+func (l *Limiter) Acquire(key string) bool {
+	if l.options.MaxInFlight <= 0 {
+		return true
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	e := l.entry(key, time.Now())
+	if e.inFlight >= l.options.MaxInFlight {
+		return false
+	}
+	e.inFlight++
+	return true
+}
+
+// Release returns an in-flight upstream request slot taken by Acquire
+func (l *Limiter) Release(key string) {
+	if l.options.MaxInFlight <= 0 {
+		return
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if e, ok := l.entries[key]; ok && e.inFlight > 0 {
+		e.inFlight--
+	}
+}
+
+// Response returns the response to a request rejected by the named limit. retryAfter is
+// rounded up to whole seconds for the Retry-After header, with a minimum of 1
+func (l *Limiter) Response(r *http.Request, limit string, retryAfter time.Duration) *http.Response {
+	secs := int(math.Ceil(retryAfter.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	body := []byte(http.StatusText(l.options.ResponseCode))
+	h := http.Header{}
+	h.Set(headers.NameRetryAfter, strconv.Itoa(secs))
+	h.Set(headers.NameContentType, headers.ValueTextPlain)
+	h.Set(headers.NameTrkRLLimit, limit)
+	return &http.Response{
+		StatusCode:    l.options.ResponseCode,
+		Status:        http.StatusText(l.options.ResponseCode),
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}
+}
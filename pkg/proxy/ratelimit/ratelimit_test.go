@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	rlo "github.com/tricksterproxy/trickster/pkg/proxy/ratelimit/options"
+)
+
+func TestKey(t *testing.T) {
+
+	r := httptest.NewRequest(http.MethodGet, "http://trickster/", nil)
+	r.RemoteAddr = "10.0.0.1:12345"
+	r.Header.Set("X-Grafana-User", "alice")
+	r.Header.Set(headers.NameAuthorization, "Bearer token")
+
+	tests := []struct {
+		keyBy, keyHeader, expected string
+	}{
+		{rlo.KeyBackend, "", ""},
+		{rlo.KeyClientIP, "", "10.0.0.1"},
+		{rlo.KeyHeader, "X-Grafana-User", "alice"},
+		{rlo.KeyAuthorization, "", "Bearer token"},
+	}
+
+	for _, test := range tests {
+		o := rlo.New()
+		o.KeyBy = test.keyBy
+		o.KeyHeader = test.keyHeader
+		if k := New(o).Key(r); k != test.expected {
+			t.Errorf("expected %s got %s", test.expected, k)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+
+	o := rlo.New()
+	o.RequestsPerSecond = 10
+	o.Burst = 2
+	l := New(o)
+
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("expected request to be allowed")
+	}
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("expected request to be allowed")
+	}
+	ok, d := l.Allow("a")
+	if ok {
+		t.Error("expected request to be rejected")
+	}
+	if d <= 0 || d > 100*time.Millisecond {
+		t.Errorf("unexpected retry after %s", d)
+	}
+
+	// keys are limited separately
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("expected request to be allowed")
+	}
+
+	time.Sleep(d + 10*time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("expected request to be allowed after refill")
+	}
+
+	// no rate limit
+	l = New(rlo.New())
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatal("expected request to be allowed")
+		}
+	}
+}
+
+func TestAcquire(t *testing.T) {
+
+	o := rlo.New()
+	o.MaxInFlight = 2
+	l := New(o)
+
+	if !l.Acquire("a") || !l.Acquire("a") {
+		t.Error("expected slots to be available")
+	}
+	if l.Acquire("a") {
+		t.Error("expected no slots to be available")
+	}
+	if !l.Acquire("b") {
+		t.Error("expected slot to be available")
+	}
+	l.Release("a")
+	if !l.Acquire("a") {
+		t.Error("expected slot to be available after release")
+	}
+}
+
+func TestPrune(t *testing.T) {
+
+	o := rlo.New()
+	o.RequestsPerSecond = 1000
+	o.Burst = 1
+	o.MaxInFlight = 1
+	l := New(o)
+
+	l.Allow("a")
+	l.Acquire("b")
+	time.Sleep(5 * time.Millisecond)
+	l.mtx.Lock()
+	l.prune(time.Now())
+	_, okA := l.entries["a"]
+	_, okB := l.entries["b"]
+	l.mtx.Unlock()
+	if okA {
+		t.Error("expected refilled key to be pruned")
+	}
+	if !okB {
+		t.Error("expected key with requests in flight to be retained")
+	}
+}
+
+func TestResponse(t *testing.T) {
+
+	l := New(rlo.New())
+	resp := l.Response(nil, LimitRate, 1500*time.Millisecond)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected %d got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+	if v := resp.Header.Get(headers.NameRetryAfter); v != "2" {
+		t.Errorf("expected %s got %s", "2", v)
+	}
+	if v := resp.Header.Get(headers.NameTrkRLLimit); v != LimitRate {
+		t.Errorf("expected %s got %s", LimitRate, v)
+	}
+	if b, _ := io.ReadAll(resp.Body); string(b) != "Too Many Requests" {
+		t.Errorf("expected %s got %s", "Too Many Requests", string(b))
+	}
+	resp = l.Response(nil, LimitInFlight, 0)
+	if v := resp.Header.Get(headers.NameRetryAfter); v != "1" {
+		t.Errorf("expected %s got %s", "1", v)
+	}
+}
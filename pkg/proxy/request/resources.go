@@ -51,6 +51,10 @@ type Resources struct {
 	TS                timeseries.Timeseries
 	TSReqestOptions   *timeseries.RequestOptions
 	Response          *http.Response
+	// RateLimitKey is the key under which the request is limited by the backend's Rate Limiter
+	RateLimitKey string
+	// Warnings are included in the response, when supported by the output format
+	Warnings []string
 }
 
 // Clone returns an exact copy of the subject Resources collection
@@ -73,6 +77,8 @@ func (r *Resources) Clone() *Resources {
 		TSMarshaler:       r.TSMarshaler,
 		TS:                r.TS,
 		TSReqestOptions:   r.TSReqestOptions,
+		RateLimitKey:      r.RateLimitKey,
+		Warnings:          r.Warnings,
 	}
 }
 
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -191,6 +192,26 @@ func checkTokens(input string) bool {
 	return false
 }
 
+var tokenPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandTokens replaces any ${name} tokens in the input with the matching named
+// segment captured from the request path. Tokens without a capture are left as-is.
+func expandTokens(r *http.Request, input string) string {
+	if r == nil {
+		return input
+	}
+	captures := context.PathCaptures(r.Context())
+	if len(captures) == 0 {
+		return input
+	}
+	return tokenPattern.ReplaceAllStringFunc(input, func(t string) string {
+		if v, ok := captures[t[2:len(t)-1]]; ok {
+			return v
+		}
+		return t
+	})
+}
+
 type rwiKeyBasedSetter struct {
 	key, value string
 	hasTokens  bool
@@ -217,6 +238,11 @@ func (ri *rwiKeyBasedSetter) Parse(parts []string) error {
 }
 
 func (ri *rwiKeyBasedSetter) Execute(r *http.Request) {
+	if ri.hasTokens {
+		c := *ri
+		c.value = expandTokens(r, ri.value)
+		ri = &c
+	}
 	dict := ri.dict(r)
 	dict.Set(ri.key, ri.value)
 	if qp, ok := dict.(url.Values); ok {
@@ -257,6 +283,12 @@ type mappable map[string][]string
 
 func (ri *rwiKeyBasedAppender) Execute(r *http.Request) {
 
+	if ri.hasTokens {
+		c := *ri
+		c.value = expandTokens(r, ri.value)
+		ri = &c
+	}
+
 	dict := ri.dict(r)
 	var m mappable
 	var ok bool
@@ -365,6 +397,14 @@ func (ri *rwiKeyBasedReplacer) Parse(parts []string) error {
 
 func (ri *rwiKeyBasedReplacer) Execute(r *http.Request) {
 
+	if ri.hasTokens {
+		c := *ri
+		c.key = expandTokens(r, ri.key)
+		c.search = expandTokens(r, ri.search)
+		c.replacement = expandTokens(r, ri.replacement)
+		ri = &c
+	}
+
 	if ri.depth == 0 {
 		ri.depth = -1
 	}
@@ -435,6 +475,13 @@ func (ri *rwiKeyBasedDeleter) Parse(parts []string) error {
 
 func (ri *rwiKeyBasedDeleter) Execute(r *http.Request) {
 
+	if ri.hasTokens {
+		c := *ri
+		c.key = expandTokens(r, ri.key)
+		c.value = expandTokens(r, ri.value)
+		ri = &c
+	}
+
 	dict := ri.dict(r)
 
 	if ri.value == "" {
@@ -520,6 +567,11 @@ func (ri *rwiPathSetter) HasTokens() bool {
 }
 
 func (ri *rwiPathSetter) Execute(r *http.Request) {
+	if ri.hasTokens {
+		c := *ri
+		c.value = expandTokens(r, ri.value)
+		ri = &c
+	}
 	if ri.depth > -1 {
 		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/")
 		parts := strings.Split(r.URL.Path, "/")
@@ -570,6 +622,12 @@ func (ri *rwiPathReplacer) Parse(parts []string) error {
 }
 
 func (ri *rwiPathReplacer) Execute(r *http.Request) {
+	if ri.hasTokens {
+		c := *ri
+		c.search = expandTokens(r, ri.search)
+		c.replacement = expandTokens(r, ri.replacement)
+		ri = &c
+	}
 	r.URL.Path = strings.Replace(r.URL.Path, ri.search, ri.replacement, ri.depth)
 }
 
@@ -605,6 +663,11 @@ func (ri *rwiBasicSetter) Parse(parts []string) error {
 }
 
 func (ri *rwiBasicSetter) Execute(r *http.Request) {
+	if ri.hasTokens {
+		c := *ri
+		c.value = expandTokens(r, ri.value)
+		ri = &c
+	}
 	ri.setter(r, ri.value)
 }
 
@@ -655,6 +718,12 @@ func (ri *rwiBasicReplacer) Parse(parts []string) error {
 }
 
 func (ri *rwiBasicReplacer) Execute(r *http.Request) {
+	if ri.hasTokens {
+		c := *ri
+		c.search = expandTokens(r, ri.search)
+		c.replacement = expandTokens(r, ri.replacement)
+		ri = &c
+	}
 	val := ri.getter(r)
 	val = strings.Replace(val, ri.search, ri.replacement, ri.depth)
 	ri.setter(r, val)
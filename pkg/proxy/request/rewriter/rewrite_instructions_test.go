@@ -403,6 +403,56 @@ func TestHasTokens(t *testing.T) {
 
 }
 
+func TestExpandTokens(t *testing.T) {
+
+	rl := options.RewriteList{
+		[]string{"path", "set", "/api/v1/label/${name}/values"},
+		[]string{"param", "set", "match[]", "${metric}"},
+		[]string{"header", "set", "X-Unknown", "${unknown}"},
+		[]string{"header", "replace", "X-Label", "${name}", "label"},
+		[]string{"hostname", "replace", "${host}", "example.org"},
+	}
+	ri, err := parseRewriteList(rl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, "http://example.com/labels/job", nil)
+	r.Header.Set("X-Label", "job")
+	r = r.WithContext(tctx.WithPathCaptures(context.Background(),
+		map[string]string{"name": "job", "metric": "up", "host": "example.com"}))
+	ri.Execute(r)
+
+	if r.URL.Path != "/api/v1/label/job/values" {
+		t.Errorf("expected %s got %s", "/api/v1/label/job/values", r.URL.Path)
+	}
+	if v := r.URL.Query().Get("match[]"); v != "up" {
+		t.Errorf("expected %s got %s", "up", v)
+	}
+	if v := r.Header.Get("X-Unknown"); v != "${unknown}" {
+		t.Errorf("expected %s got %s", "${unknown}", v)
+	}
+	if v := r.Header.Get("X-Label"); v != "label" {
+		t.Errorf("expected %s got %s", "label", v)
+	}
+	if r.URL.Host != "example.org" {
+		t.Errorf("expected %s got %s", "example.org", r.URL.Host)
+	}
+
+	// the compiled instructions must retain their tokens for subsequent requests
+	r, _ = http.NewRequest(http.MethodGet, "http://example.com/labels/instance", nil)
+	r = r.WithContext(tctx.WithPathCaptures(context.Background(),
+		map[string]string{"name": "instance"}))
+	ri.Execute(r)
+	if r.URL.Path != "/api/v1/label/instance/values" {
+		t.Errorf("expected %s got %s", "/api/v1/label/instance/values", r.URL.Path)
+	}
+
+	if v := expandTokens(nil, "${name}"); v != "${name}" {
+		t.Errorf("expected %s got %s", "${name}", v)
+	}
+}
+
 func TestNilRequestGetters(t *testing.T) {
 	for _, f := range scalarGets {
 		v := f(nil)
@@ -36,6 +36,18 @@ type ResponseGate struct {
 // ResponseGates represents a slice of type *ResponseGate
 type ResponseGates []*ResponseGate
 
+// Warnings returns the warnings attached to the Resources of each ResponseGate,
+// such as notice of pool members that were dropped from the merge
+func (rgs ResponseGates) Warnings() []string {
+	var w []string
+	for _, rg := range rgs {
+		if rg != nil && rg.Resources != nil {
+			w = append(w, rg.Resources.Warnings...)
+		}
+	}
+	return w
+}
+
 // NewResponseGate provides a new ResponseGate object
 func NewResponseGate(w http.ResponseWriter, r *http.Request, rsc *request.Resources) *ResponseGate {
 	rg := &ResponseGate{ResponseWriter: w, Request: r, Resources: rsc}
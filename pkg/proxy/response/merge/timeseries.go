@@ -23,7 +23,9 @@ import (
 
 	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/dataset"
 )
 
 // Timeseries merges the provided Responses into a single Timeseries Dataset
@@ -90,6 +92,25 @@ func Timeseries(w http.ResponseWriter, r *http.Request, rgs ResponseGates) {
 		ts.Merge(true, tsm...)
 	}
 
+	if ds, ok := ts.(*dataset.DataSet); ok {
+		DeduplicateReplicas(r, ds)
+		ds.Warnings = append(ds.Warnings, rgs.Warnings()...)
+	}
+
 	headers.StripMergeHeaders(h)
 	f(ts, rlo, statusCode, w)
 }
+
+// DeduplicateReplicas collapses the series of the merged DataSet that differ only by the
+// replica labels configured for the ALB. When merging the responses of HA replicas, the
+// request's resources are those of the ALB, whose options name the replica labels.
+func DeduplicateReplicas(r *http.Request, ds *dataset.DataSet) {
+	if ds == nil {
+		return
+	}
+	if rsc := request.GetResources(r); rsc != nil && rsc.BackendOptions != nil &&
+		rsc.BackendOptions.ALBOptions != nil && len(rsc.BackendOptions.ALBOptions.ReplicaLabels) > 0 {
+		ds.Deduplicate(rsc.BackendOptions.ALBOptions.ReplicaLabels,
+			rsc.BackendOptions.ALBOptions.ReplicaPenalty)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "github.com/tricksterproxy/trickster/pkg/util/copiers"
+
+// MaxRewriterChainExecutions is the maximum number of chained Response Rewriters that
+// will execute for a single response
+const MaxRewriterChainExecutions int = 32
+
+// RewriteList is a list of Rewrite Instructions
+type RewriteList [][]string
+
+// Options is a collection of Options pertaining to Response Rewriter Instructions
+type Options struct {
+	// MatchStatusCodes, when set, limits the rewriter to responses with one of these status codes
+	MatchStatusCodes []int `yaml:"match_status_codes,omitempty"`
+	// MatchContentTypes, when set, limits the rewriter to responses whose Content-Type
+	// begins with one of these values
+	MatchContentTypes []string `yaml:"match_content_types,omitempty"`
+	// MatchRequestHeaders, when set, limits the rewriter to responses for requests having each
+	// of these headers, with a value matching the provided regular expression
+	MatchRequestHeaders map[string]string `yaml:"match_request_headers,omitempty"`
+	// Instructions is the list of Rewrite Instructions to execute on the response, in order
+	Instructions RewriteList `yaml:"instructions,omitempty"`
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	o2 := &Options{
+		MatchContentTypes:   copiers.CopyStrings(o.MatchContentTypes),
+		MatchRequestHeaders: copiers.CopyStringLookup(o.MatchRequestHeaders),
+	}
+	if len(o.MatchStatusCodes) > 0 {
+		o2.MatchStatusCodes = make([]int, len(o.MatchStatusCodes))
+		copy(o2.MatchStatusCodes, o.MatchStatusCodes)
+	}
+	if len(o.Instructions) > 0 {
+		o2.Instructions = o.Instructions.Clone()
+	}
+	return o2
+}
+
+// Clone returns an exact copy of the subject RewriteList
+func (rl RewriteList) Clone() RewriteList {
+	var rl2 RewriteList
+	if len(rl) > 0 {
+		rl2 = make(RewriteList, len(rl))
+		for i := range rl {
+			rl2[i] = copiers.CopyStrings(rl[i])
+		}
+	}
+	return rl2
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "testing"
+
+func TestClone(t *testing.T) {
+
+	o := &Options{
+		MatchStatusCodes:    []int{200},
+		MatchContentTypes:   []string{"application/json"},
+		MatchRequestHeaders: map[string]string{"Origin": ".*"},
+		Instructions: [][]string{
+			{"test"},
+		},
+	}
+
+	c := o.Clone()
+	if len(c.Instructions) != 1 {
+		t.Errorf("Expected %d got %d", 1, len(c.Instructions))
+	}
+	if len(c.MatchStatusCodes) != 1 || c.MatchStatusCodes[0] != 200 {
+		t.Errorf("Expected %v got %v", o.MatchStatusCodes, c.MatchStatusCodes)
+	}
+	if len(c.MatchContentTypes) != 1 || len(c.MatchRequestHeaders) != 1 {
+		t.Error("expected cloned match conditions")
+	}
+	c.MatchStatusCodes[0] = 404
+	if o.MatchStatusCodes[0] != 200 {
+		t.Error("expected a deep copy of status codes")
+	}
+
+}
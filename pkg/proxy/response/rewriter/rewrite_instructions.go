@@ -0,0 +1,501 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rewriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter/options"
+)
+
+// Response is the subject of Response Rewriter Instructions
+type Response struct {
+	// StatusCode is the response's HTTP Status Code
+	StatusCode int
+	// Header is the response's HTTP Header
+	Header http.Header
+	// Body is the response's decoded body. It is nil when the rewriter does not modify bodies
+	Body []byte
+	// Request is the HTTP Request that produced the response
+	Request *http.Request
+
+	chainHops int
+}
+
+type rewriteInstruction interface {
+	String() string
+	Parse([]string) error
+	Execute(*Response)
+	RewritesBody() bool
+}
+
+// RewriteInstructions is a list of type []rewriteInstruction
+type RewriteInstructions []rewriteInstruction
+
+var rewriters = map[string]func() rewriteInstruction{
+	"header-set":     func() rewriteInstruction { return &rwiHeaderSetter{} },
+	"header-append":  func() rewriteInstruction { return &rwiHeaderAppender{} },
+	"header-replace": func() rewriteInstruction { return &rwiHeaderReplacer{} },
+	"header-delete":  func() rewriteInstruction { return &rwiHeaderDeleter{} },
+	"body-replace":   func() rewriteInstruction { return &rwiBodyReplacer{} },
+	"json-set":       func() rewriteInstruction { return &rwiJSONSetter{} },
+	"json-delete":    func() rewriteInstruction { return &rwiJSONDeleter{} },
+	"json-rename":    func() rewriteInstruction { return &rwiJSONRenamer{} },
+	"chain-exec":     func() rewriteInstruction { return &rwiChainExecutor{} },
+}
+
+func (ris RewriteInstructions) String() string {
+	l := make([]string, len(ris))
+	for i, instr := range ris {
+		l[i] = instr.String()
+	}
+	return "[" + strings.Join(l, ",") + "]"
+}
+
+// Execute executes the Rewriter Instructions on the provided Response
+func (ris RewriteInstructions) Execute(rs *Response) {
+	for _, instr := range ris {
+		instr.Execute(rs)
+	}
+}
+
+// RewritesBody returns true if any of the Rewriter Instructions modify the response body
+func (ris RewriteInstructions) RewritesBody() bool {
+	for _, instr := range ris {
+		if instr.RewritesBody() {
+			return true
+		}
+	}
+	return false
+}
+
+type rwiHeaderSetter struct {
+	key, value string
+}
+
+func (ri *rwiHeaderSetter) String() string {
+	return fmt.Sprintf(`{"type":"headerSetter","key":"%s","value":"%s"}`, ri.key, ri.value)
+}
+
+func (ri *rwiHeaderSetter) Parse(parts []string) error {
+	if len(parts) != 4 {
+		return errBadParams
+	}
+	ri.key = parts[2]
+	ri.value = parts[3]
+	return nil
+}
+
+func (ri *rwiHeaderSetter) Execute(rs *Response) {
+	rs.Header.Set(ri.key, ri.value)
+}
+
+func (ri *rwiHeaderSetter) RewritesBody() bool {
+	return false
+}
+
+type rwiHeaderAppender struct {
+	key, value string
+}
+
+func (ri *rwiHeaderAppender) String() string {
+	return fmt.Sprintf(`{"type":"headerAppender","key":"%s","value":"%s"}`, ri.key, ri.value)
+}
+
+func (ri *rwiHeaderAppender) Parse(parts []string) error {
+	if len(parts) != 4 {
+		return errBadParams
+	}
+	ri.key = parts[2]
+	ri.value = parts[3]
+	return nil
+}
+
+func (ri *rwiHeaderAppender) Execute(rs *Response) {
+	for _, v := range rs.Header.Values(ri.key) {
+		if v == ri.value {
+			// the desired value is already in the header, do nothing
+			return
+		}
+	}
+	rs.Header.Add(ri.key, ri.value)
+}
+
+func (ri *rwiHeaderAppender) RewritesBody() bool {
+	return false
+}
+
+type rwiHeaderReplacer struct {
+	key, search, replacement string
+}
+
+func (ri *rwiHeaderReplacer) String() string {
+	return fmt.Sprintf(`{"type":"headerReplacer","key":"%s","search":"%s","replacement":"%s"}`,
+		ri.key, ri.search, ri.replacement)
+}
+
+func (ri *rwiHeaderReplacer) Parse(parts []string) error {
+	if len(parts) != 5 || parts[3] == "" {
+		return errBadParams
+	}
+	ri.key = parts[2]
+	ri.search = parts[3]
+	ri.replacement = parts[4]
+	return nil
+}
+
+func (ri *rwiHeaderReplacer) Execute(rs *Response) {
+	vals := rs.Header.Values(ri.key)
+	for i := range vals {
+		vals[i] = strings.Replace(vals[i], ri.search, ri.replacement, -1)
+	}
+}
+
+func (ri *rwiHeaderReplacer) RewritesBody() bool {
+	return false
+}
+
+type rwiHeaderDeleter struct {
+	key, value string
+}
+
+func (ri *rwiHeaderDeleter) String() string {
+	return fmt.Sprintf(`{"type":"headerDeleter","key":"%s","value":"%s"}`, ri.key, ri.value)
+}
+
+func (ri *rwiHeaderDeleter) Parse(parts []string) error {
+	pl := len(parts)
+	if pl != 3 && pl != 4 {
+		return errBadParams
+	}
+	ri.key = parts[2]
+	if pl == 4 {
+		ri.value = parts[3]
+	}
+	return nil
+}
+
+// Execute deletes the header, or when a value is provided, only those header values that
+// are equal to it or are prefixed by it and an equals sign (e.g., a named Set-Cookie)
+func (ri *rwiHeaderDeleter) Execute(rs *Response) {
+	if ri.value == "" {
+		rs.Header.Del(ri.key)
+		return
+	}
+	vals := rs.Header.Values(ri.key)
+	kept := make([]string, 0, len(vals))
+	for _, v := range vals {
+		if v == ri.value || strings.HasPrefix(v, ri.value+"=") {
+			continue
+		}
+		kept = append(kept, v)
+	}
+	if len(kept) == len(vals) {
+		return
+	}
+	rs.Header.Del(ri.key)
+	for _, v := range kept {
+		rs.Header.Add(ri.key, v)
+	}
+}
+
+func (ri *rwiHeaderDeleter) RewritesBody() bool {
+	return false
+}
+
+type rwiBodyReplacer struct {
+	search      *regexp.Regexp
+	replacement []byte
+}
+
+func (ri *rwiBodyReplacer) String() string {
+	return fmt.Sprintf(`{"type":"bodyReplacer","search":"%s","replacement":"%s"}`,
+		ri.search, ri.replacement)
+}
+
+func (ri *rwiBodyReplacer) Parse(parts []string) error {
+	if len(parts) != 4 || parts[2] == "" {
+		return errBadParams
+	}
+	re, err := regexp.Compile(parts[2])
+	if err != nil {
+		return err
+	}
+	ri.search = re
+	ri.replacement = []byte(parts[3])
+	return nil
+}
+
+func (ri *rwiBodyReplacer) Execute(rs *Response) {
+	if len(rs.Body) == 0 {
+		return
+	}
+	rs.Body = ri.search.ReplaceAll(rs.Body, ri.replacement)
+}
+
+func (ri *rwiBodyReplacer) RewritesBody() bool {
+	return true
+}
+
+// jsonPath is a dot-separated path to members of a JSON document, where each part is an
+// object member name, an array index, or * for every member or element
+type jsonPath []string
+
+func parseJSONPath(s string) (jsonPath, error) {
+	if s == "" {
+		return nil, errBadJSONPath
+	}
+	parts := strings.Split(s, ".")
+	for _, p := range parts {
+		if p == "" {
+			return nil, errBadJSONPath
+		}
+	}
+	return jsonPath(parts), nil
+}
+
+// walk calls f with each object that is addressed by all but the last part of the path;
+// when create is true, missing objects along the path are created
+func (jp jsonPath) walk(v interface{}, create bool, f func(map[string]interface{})) {
+	if len(jp) == 1 {
+		if m, ok := v.(map[string]interface{}); ok {
+			f(m)
+		}
+		return
+	}
+	p, next := jp[0], jp[1:]
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if p == "*" {
+			for _, c := range t {
+				next.walk(c, false, f)
+			}
+			return
+		}
+		c, ok := t[p]
+		if !ok {
+			if !create {
+				return
+			}
+			c = make(map[string]interface{})
+			t[p] = c
+		}
+		next.walk(c, create, f)
+	case []interface{}:
+		if p == "*" {
+			for _, c := range t {
+				next.walk(c, false, f)
+			}
+			return
+		}
+		i, err := strconv.Atoi(p)
+		if err != nil || i < 0 || i >= len(t) {
+			return
+		}
+		next.walk(t[i], create, f)
+	}
+}
+
+// rewriteJSON decodes the response body as JSON, passes it to f and encodes the result
+// into the response body. Bodies that are not valid JSON are left as-is
+func rewriteJSON(rs *Response, f func(interface{})) {
+	if len(rs.Body) == 0 {
+		return
+	}
+	var doc interface{}
+	d := json.NewDecoder(bytes.NewReader(rs.Body))
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return
+	}
+	f(doc)
+	buf := &bytes.Buffer{}
+	e := json.NewEncoder(buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(doc); err != nil {
+		return
+	}
+	rs.Body = bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+type rwiJSONSetter struct {
+	path  jsonPath
+	value interface{}
+	raw   string
+}
+
+func (ri *rwiJSONSetter) String() string {
+	return fmt.Sprintf(`{"type":"jsonSetter","path":"%s","value":"%s"}`,
+		strings.Join(ri.path, "."), ri.raw)
+}
+
+// Parse parses the instruction. The value is used as a JSON value when it is valid JSON,
+// and is otherwise used as a string
+func (ri *rwiJSONSetter) Parse(parts []string) error {
+	if len(parts) != 4 {
+		return errBadParams
+	}
+	var err error
+	if ri.path, err = parseJSONPath(parts[2]); err != nil {
+		return err
+	}
+	ri.raw = parts[3]
+	d := json.NewDecoder(strings.NewReader(ri.raw))
+	d.UseNumber()
+	if err := d.Decode(&ri.value); err != nil || d.More() {
+		ri.value = ri.raw
+	}
+	return nil
+}
+
+func (ri *rwiJSONSetter) Execute(rs *Response) {
+	k := ri.path[len(ri.path)-1]
+	rewriteJSON(rs, func(doc interface{}) {
+		ri.path.walk(doc, true, func(m map[string]interface{}) {
+			if k == "*" {
+				for mk := range m {
+					m[mk] = ri.value
+				}
+				return
+			}
+			m[k] = ri.value
+		})
+	})
+}
+
+func (ri *rwiJSONSetter) RewritesBody() bool {
+	return true
+}
+
+type rwiJSONDeleter struct {
+	path jsonPath
+}
+
+func (ri *rwiJSONDeleter) String() string {
+	return fmt.Sprintf(`{"type":"jsonDeleter","path":"%s"}`, strings.Join(ri.path, "."))
+}
+
+func (ri *rwiJSONDeleter) Parse(parts []string) error {
+	if len(parts) != 3 {
+		return errBadParams
+	}
+	var err error
+	ri.path, err = parseJSONPath(parts[2])
+	return err
+}
+
+func (ri *rwiJSONDeleter) Execute(rs *Response) {
+	k := ri.path[len(ri.path)-1]
+	rewriteJSON(rs, func(doc interface{}) {
+		ri.path.walk(doc, false, func(m map[string]interface{}) {
+			if k == "*" {
+				for mk := range m {
+					delete(m, mk)
+				}
+				return
+			}
+			delete(m, k)
+		})
+	})
+}
+
+func (ri *rwiJSONDeleter) RewritesBody() bool {
+	return true
+}
+
+type rwiJSONRenamer struct {
+	path    jsonPath
+	newName string
+}
+
+func (ri *rwiJSONRenamer) String() string {
+	return fmt.Sprintf(`{"type":"jsonRenamer","path":"%s","name":"%s"}`,
+		strings.Join(ri.path, "."), ri.newName)
+}
+
+func (ri *rwiJSONRenamer) Parse(parts []string) error {
+	if len(parts) != 4 || parts[3] == "" {
+		return errBadParams
+	}
+	var err error
+	if ri.path, err = parseJSONPath(parts[2]); err != nil {
+		return err
+	}
+	if ri.path[len(ri.path)-1] == "*" {
+		return errBadJSONPath
+	}
+	ri.newName = parts[3]
+	return nil
+}
+
+func (ri *rwiJSONRenamer) Execute(rs *Response) {
+	k := ri.path[len(ri.path)-1]
+	rewriteJSON(rs, func(doc interface{}) {
+		ri.path.walk(doc, false, func(m map[string]interface{}) {
+			if v, ok := m[k]; ok {
+				delete(m, k)
+				m[ri.newName] = v
+			}
+		})
+	})
+}
+
+func (ri *rwiJSONRenamer) RewritesBody() bool {
+	return true
+}
+
+type rwiChainExecutor struct {
+	rewriterName string
+	rewriter     *Rewriter
+}
+
+func (ri *rwiChainExecutor) String() string {
+	return fmt.Sprintf(`{"type":"chainExecutor","rewriter":"%s"}`, ri.rewriterName)
+}
+
+func (ri *rwiChainExecutor) Parse(parts []string) error {
+	if len(parts) != 3 || strings.Trim(parts[2], " \t\n") == "" {
+		return errBadParams
+	}
+	ri.rewriterName = parts[2]
+	// a separate process will validate and map the rewriter based on this parsed name
+	return nil
+}
+
+func (ri *rwiChainExecutor) Execute(rs *Response) {
+	if ri.rewriter == nil {
+		return
+	}
+	// this only executes the chained rewriter while the response's chain hops are below
+	// the max allowed (32)
+	rs.chainHops++
+	if rs.chainHops < options.MaxRewriterChainExecutions && ri.rewriter.Matches(rs) {
+		ri.rewriter.instructions.Execute(rs)
+	}
+}
+
+// RewritesBody returns false, since the chained rewriter's body instructions are accounted
+// for when the chains are mapped
+func (ri *rwiChainExecutor) RewritesBody() bool {
+	return false
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rewriter
+
+import (
+	"net/http"
+	"testing"
+)
+
+func executeList(t *testing.T, l [][]string, rs *Response) {
+	ri, err := parseRewriteList(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ri.Execute(rs)
+}
+
+func TestHeaderInstructions(t *testing.T) {
+
+	rs := &Response{Header: http.Header{
+		"Set-Cookie": []string{"session=abc; Path=/", "theme=dark"},
+		"Location":   []string{"http://upstream.internal:9090/graph"},
+		"Vary":       []string{"Accept-Encoding"},
+	}}
+
+	executeList(t, [][]string{
+		{"header", "set", "Access-Control-Allow-Origin", "*"},
+		{"header", "append", "Vary", "Origin"},
+		{"header", "append", "Vary", "Origin"},
+		{"header", "replace", "Location", "upstream.internal:9090", "metrics.example.com"},
+		{"header", "delete", "Set-Cookie", "session"},
+	}, rs)
+
+	if v := rs.Header.Get("Access-Control-Allow-Origin"); v != "*" {
+		t.Errorf("expected %s got %s", "*", v)
+	}
+	if v := rs.Header.Values("Vary"); len(v) != 2 || v[1] != "Origin" {
+		t.Errorf("expected %s got %s", "[Accept-Encoding Origin]", v)
+	}
+	if v := rs.Header.Get("Location"); v != "http://metrics.example.com/graph" {
+		t.Errorf("expected %s got %s", "http://metrics.example.com/graph", v)
+	}
+	if v := rs.Header.Values("Set-Cookie"); len(v) != 1 || v[0] != "theme=dark" {
+		t.Errorf("expected %s got %s", "[theme=dark]", v)
+	}
+
+	executeList(t, [][]string{{"header", "delete", "Set-Cookie"}}, rs)
+	if _, ok := rs.Header["Set-Cookie"]; ok {
+		t.Error("expected Set-Cookie to be deleted")
+	}
+
+}
+
+func TestBodyInstructions(t *testing.T) {
+
+	const body = `{"status":"success","data":{"resultType":"vector","result":[` +
+		`{"metric":{"__name__":"up","job":"node","pod":"a"},"value":[1,"1"]},` +
+		`{"metric":{"__name__":"up","job":"api","pod":"b"},"value":[1,"0"]}]}}`
+
+	tests := []struct {
+		instr    [][]string
+		expected string
+	}{
+		{
+			[][]string{{"body", "replace", `"job":"(\w+)"`, `"job":"prod-$1"`}},
+			`{"status":"success","data":{"resultType":"vector","result":[` +
+				`{"metric":{"__name__":"up","job":"prod-node","pod":"a"},"value":[1,"1"]},` +
+				`{"metric":{"__name__":"up","job":"prod-api","pod":"b"},"value":[1,"0"]}]}}`,
+		},
+		{
+			[][]string{{"json", "rename", "data.result.*.metric.job", "service"}},
+			`{"data":{"result":[` +
+				`{"metric":{"__name__":"up","pod":"a","service":"node"},"value":[1,"1"]},` +
+				`{"metric":{"__name__":"up","pod":"b","service":"api"},"value":[1,"0"]}],` +
+				`"resultType":"vector"},"status":"success"}`,
+		},
+		{
+			[][]string{
+				{"json", "delete", "data.result.*.metric.pod"},
+				{"json", "set", "data.result.0.metric.env", "prod"},
+				{"json", "set", "meta.cached", "true"},
+			},
+			`{"data":{"result":[` +
+				`{"metric":{"__name__":"up","env":"prod","job":"node"},"value":[1,"1"]},` +
+				`{"metric":{"__name__":"up","job":"api"},"value":[1,"0"]}],` +
+				`"resultType":"vector"},"meta":{"cached":true},"status":"success"}`,
+		},
+		{
+			[][]string{{"json", "delete", "data.*"}},
+			`{"data":{},"status":"success"}`,
+		},
+	}
+
+	for i, test := range tests {
+		rs := &Response{Body: []byte(body)}
+		executeList(t, test.instr, rs)
+		if string(rs.Body) != test.expected {
+			t.Errorf("test %d expected %s got %s", i, test.expected, string(rs.Body))
+		}
+	}
+
+	// a body that is not json is left as-is
+	rs := &Response{Body: []byte("not json")}
+	executeList(t, [][]string{{"json", "delete", "data"}}, rs)
+	if string(rs.Body) != "not json" {
+		t.Errorf("expected %s got %s", "not json", string(rs.Body))
+	}
+
+}
+
+func TestParseInstructions(t *testing.T) {
+
+	invalid := [][]string{
+		{"header", "set", "key"},
+		{"header", "replace", "key", "", "value"},
+		{"header", "delete"},
+		{"body", "replace", "(", "value"},
+		{"json", "set", "a..b", "value"},
+		{"json", "delete", ""},
+		{"json", "rename", "a.*", "b"},
+		{"chain", "exec", " "},
+		{"status", "set", "200"},
+	}
+
+	for i, instr := range invalid {
+		if _, err := parseRewriteList([][]string{instr}); err == nil {
+			t.Errorf("test %d expected error for invalid instruction %v", i, instr)
+		}
+	}
+
+	ri, err := parseRewriteList([][]string{
+		{"header", "set", "key", "value"},
+		{"json", "set", "a.b", `{"c":1}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ri.RewritesBody() {
+		t.Error("expected body rewrites")
+	}
+	if ri[:1].RewritesBody() {
+		t.Error("expected no body rewrites")
+	}
+	const expected = `[{"type":"headerSetter","key":"key","value":"value"},` +
+		`{"type":"jsonSetter","path":"a.b","value":"{"c":1}"}]`
+	if ri.String() != expected {
+		t.Errorf("expected %s got %s", expected, ri.String())
+	}
+
+}
@@ -0,0 +1,298 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rewriter
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/encoding/providers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter/options"
+)
+
+// Rewriter is a compiled Response Rewriter
+type Rewriter struct {
+	// Name is the name of the Rewriter in the configuration
+	Name string
+
+	statusCodes    map[int]interface{}
+	contentTypes   []string
+	requestHeaders map[string]*regexp.Regexp
+	instructions   RewriteInstructions
+	rewritesBody   bool
+}
+
+// ProcessConfigs validates and compiles rewriters from the provided configuration map
+func ProcessConfigs(rwl map[string]*options.Options) (map[string]*Rewriter, error) {
+	if rwl == nil {
+		return nil, errInvalidRewriterOptions
+	}
+
+	crw := make(map[string]*Rewriter)
+	for k, v := range rwl {
+		if v == nil {
+			return nil, errInvalidRewriterOptions
+		}
+		rw, err := newRewriter(k, v)
+		if err != nil {
+			return nil, err
+		}
+		crw[k] = rw
+	}
+
+	// this validates the rewriter names in the rewriter chains
+	for _, rw := range crw {
+		for _, instr := range rw.instructions {
+			if ce, ok := instr.(*rwiChainExecutor); ok {
+				crw2, ok := crw[ce.rewriterName]
+				if !ok {
+					return nil, errInvalidRewriterOptions
+				}
+				ce.rewriter = crw2
+			}
+		}
+	}
+
+	// a rewriter buffers the response body when it, or any rewriter in its chains,
+	// modifies the body
+	for _, rw := range crw {
+		rw.rewritesBody = rw.chainRewritesBody(make(map[*Rewriter]interface{}))
+	}
+
+	return crw, nil
+}
+
+func newRewriter(name string, o *options.Options) (*Rewriter, error) {
+	rw := &Rewriter{Name: name, contentTypes: o.MatchContentTypes}
+	if len(o.MatchStatusCodes) > 0 {
+		rw.statusCodes = make(map[int]interface{})
+		for _, c := range o.MatchStatusCodes {
+			rw.statusCodes[c] = nil
+		}
+	}
+	if len(o.MatchRequestHeaders) > 0 {
+		rw.requestHeaders = make(map[string]*regexp.Regexp)
+		for k, v := range o.MatchRequestHeaders {
+			re, err := regexp.Compile(v)
+			if err != nil {
+				return nil, err
+			}
+			rw.requestHeaders[k] = re
+		}
+	}
+	ri, err := parseRewriteList(o.Instructions)
+	if err != nil {
+		return nil, err
+	}
+	rw.instructions = ri
+	return rw, nil
+}
+
+func parseRewriteList(rl options.RewriteList) (RewriteInstructions, error) {
+	fri := make(RewriteInstructions, 0, len(rl))
+	for _, sri := range rl {
+		if len(sri) > 1 {
+			key := sri[0] + "-" + sri[1]
+			f, ok := rewriters[key]
+			if !ok {
+				return nil, errBadParams
+			}
+			ri := f()
+			err := ri.Parse(sri)
+			if err != nil {
+				return nil, err
+			}
+			fri = append(fri, ri)
+		}
+	}
+	return fri, nil
+}
+
+func (rw *Rewriter) chainRewritesBody(visited map[*Rewriter]interface{}) bool {
+	if _, ok := visited[rw]; ok {
+		return false
+	}
+	visited[rw] = nil
+	if rw.instructions.RewritesBody() {
+		return true
+	}
+	for _, instr := range rw.instructions {
+		if ce, ok := instr.(*rwiChainExecutor); ok && ce.rewriter != nil &&
+			ce.rewriter.chainRewritesBody(visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns true if the Rewriter's match conditions are met by the Response
+func (rw *Rewriter) Matches(rs *Response) bool {
+	if rw.statusCodes != nil {
+		if _, ok := rw.statusCodes[rs.StatusCode]; !ok {
+			return false
+		}
+	}
+	if len(rw.contentTypes) > 0 {
+		ct := rs.Header.Get(headers.NameContentType)
+		var ok bool
+		for _, v := range rw.contentTypes {
+			if strings.HasPrefix(ct, v) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if rw.requestHeaders != nil {
+		if rs.Request == nil {
+			return false
+		}
+		for k, re := range rw.requestHeaders {
+			vals, ok := rs.Request.Header[http.CanonicalHeaderKey(k)]
+			if !ok || !re.MatchString(strings.Join(vals, ", ")) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Execute executes the Rewriter on the provided Response, if its match conditions are met
+func (rw *Rewriter) Execute(rs *Response) {
+	if rw.Matches(rs) {
+		rw.instructions.Execute(rs)
+	}
+}
+
+// String returns the Rewriter's instructions as a string
+func (rw *Rewriter) String() string {
+	return rw.instructions.String()
+}
+
+// Rewrite returns a handler that passes the request to the next Handler and executes
+// the Rewriter on its response
+func Rewrite(rw *Rewriter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rww := &responseWriter{ResponseWriter: w, rewriter: rw, request: r}
+		next.ServeHTTP(rww, r)
+		rww.close()
+	})
+}
+
+// responseWriter executes the Rewriter when the response header is written. When the
+// Rewriter modifies the body, the response is buffered until the handler has returned
+type responseWriter struct {
+	http.ResponseWriter
+	rewriter *Rewriter
+	request  *http.Request
+
+	response    *Response
+	buffer      *bytes.Buffer
+	wroteHeader bool
+}
+
+func (rww *responseWriter) WriteHeader(code int) {
+	if rww.wroteHeader {
+		return
+	}
+	rww.wroteHeader = true
+	rs := &Response{StatusCode: code, Header: rww.ResponseWriter.Header(), Request: rww.request}
+	if !rww.rewriter.Matches(rs) {
+		rww.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if !rww.rewriter.rewritesBody {
+		rww.rewriter.instructions.Execute(rs)
+		rww.ResponseWriter.WriteHeader(code)
+		return
+	}
+	rww.response = rs
+	rww.buffer = &bytes.Buffer{}
+}
+
+func (rww *responseWriter) Write(b []byte) (int, error) {
+	if !rww.wroteHeader {
+		rww.WriteHeader(http.StatusOK)
+	}
+	if rww.buffer != nil {
+		return rww.buffer.Write(b)
+	}
+	return rww.ResponseWriter.Write(b)
+}
+
+// Flush flushes the response to the client, unless it is being buffered for rewriting
+func (rww *responseWriter) Flush() {
+	if !rww.wroteHeader {
+		rww.WriteHeader(http.StatusOK)
+	}
+	if rww.buffer != nil {
+		return
+	}
+	if f, ok := rww.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// close executes the Rewriter on any buffered response and writes it to the client
+func (rww *responseWriter) close() {
+	if rww.buffer == nil {
+		return
+	}
+	rs := rww.response
+	body := rww.buffer.Bytes()
+	if ce := rs.Header.Get(headers.NameContentEncoding); ce != "" && ce != "identity" {
+		if b, ok := decodeBody(ce, body); ok {
+			rs.Header.Del(headers.NameContentEncoding)
+			body = b
+		} else {
+			// bodies that can't be decoded are passed through with only their headers rewritten
+			body = nil
+		}
+	}
+	if body == nil {
+		// body instructions are a no-op on a nil body
+		rww.rewriter.instructions.Execute(rs)
+		rww.ResponseWriter.WriteHeader(rs.StatusCode)
+		rww.ResponseWriter.Write(rww.buffer.Bytes())
+		return
+	}
+	rs.Body = body
+	rww.rewriter.instructions.Execute(rs)
+	rs.Header.Del(headers.NameContentLength)
+	rww.ResponseWriter.WriteHeader(rs.StatusCode)
+	rww.ResponseWriter.Write(rs.Body)
+}
+
+func decodeBody(contentEncoding string, b []byte) ([]byte, bool) {
+	f := providers.GetDecoderInitializer(contentEncoding)
+	if f == nil {
+		return nil, false
+	}
+	r := f(bytes.NewReader(b))
+	defer r.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
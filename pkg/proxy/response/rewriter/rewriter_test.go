@@ -0,0 +1,169 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rewriter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/encoding/gzip"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter/options"
+)
+
+func TestProcessConfigs(t *testing.T) {
+
+	_, err := ProcessConfigs(nil)
+	if err != errInvalidRewriterOptions {
+		t.Errorf("expected error for nil options: %v", err)
+	}
+
+	_, err = ProcessConfigs(map[string]*options.Options{"test": {
+		Instructions: options.RewriteList{{"chain", "exec", "missing"}},
+	}})
+	if err != errInvalidRewriterOptions {
+		t.Errorf("expected error for invalid chain: %v", err)
+	}
+
+	_, err = ProcessConfigs(map[string]*options.Options{"test": {
+		MatchRequestHeaders: map[string]string{"Origin": "("},
+	}})
+	if err == nil {
+		t.Error("expected error for invalid regular expression")
+	}
+
+	_, err = ProcessConfigs(map[string]*options.Options{"test": {
+		Instructions: options.RewriteList{{"header", "invalid", "key"}},
+	}})
+	if err != errBadParams {
+		t.Errorf("expected error for invalid instruction: %v", err)
+	}
+
+	crw, err := ProcessConfigs(map[string]*options.Options{
+		"headers": {Instructions: options.RewriteList{{"chain", "exec", "body"}}},
+		"body":    {Instructions: options.RewriteList{{"chain", "exec", "headers"}, {"json", "delete", "a"}}},
+		"plain":   {Instructions: options.RewriteList{{"header", "delete", "Set-Cookie"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !crw["headers"].rewritesBody || !crw["body"].rewritesBody || crw["plain"].rewritesBody {
+		t.Error("unexpected body rewrite detection")
+	}
+	if crw["plain"].String() != `[{"type":"headerDeleter","key":"Set-Cookie","value":""}]` {
+		t.Errorf("unexpected string %s", crw["plain"].String())
+	}
+
+	// a chain loop is cut off after the maximum chain executions
+	rs := &Response{Header: http.Header{}, Body: []byte(`{"a":1,"b":2}`)}
+	crw["headers"].Execute(rs)
+	if string(rs.Body) != `{"b":2}` {
+		t.Errorf("expected %s got %s", `{"b":2}`, string(rs.Body))
+	}
+
+}
+
+func testHandler(code int, contentType, contentEncoding string, body []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameContentType, contentType)
+		w.Header().Set(headers.NameContentLength, "1000")
+		if contentEncoding != "" {
+			w.Header().Set(headers.NameContentEncoding, contentEncoding)
+		}
+		w.Header().Add("Set-Cookie", "session=abc")
+		w.WriteHeader(code)
+		w.Write(body)
+	})
+}
+
+func TestRewrite(t *testing.T) {
+
+	crw, err := ProcessConfigs(map[string]*options.Options{
+		"cors": {
+			MatchRequestHeaders: map[string]string{"Origin": `^https://.*\.example\.com$`},
+			Instructions: options.RewriteList{
+				{"header", "set", "Access-Control-Allow-Origin", "*"},
+			},
+		},
+		"label": {
+			MatchStatusCodes:  []int{200},
+			MatchContentTypes: []string{"application/json"},
+			Instructions: options.RewriteList{
+				{"header", "delete", "Set-Cookie"},
+				{"json", "rename", "metric.job", "service"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const body = `{"metric":{"job":"node"}}`
+	gzBody, _ := gzip.Encode([]byte(body))
+
+	tests := []struct {
+		rewriter        string
+		origin          string
+		code            int
+		contentType     string
+		contentEncoding string
+		body            []byte
+		expectedBody    string
+		expectedHeader  string
+		expectedValue   string
+	}{
+		{"cors", "https://grafana.example.com", 200, "application/json", "", []byte(body),
+			body, "Access-Control-Allow-Origin", "*"},
+		{"cors", "https://example.org", 200, "application/json", "", []byte(body),
+			body, "Access-Control-Allow-Origin", ""},
+		{"label", "", 200, "application/json", "", []byte(body),
+			`{"metric":{"service":"node"}}`, "Set-Cookie", ""},
+		{"label", "", 200, "application/json", "gzip", gzBody,
+			`{"metric":{"service":"node"}}`, "Content-Encoding", ""},
+		{"label", "", 200, "application/json", "unknown", []byte(body),
+			body, "Content-Encoding", "unknown"},
+		{"label", "", 500, "application/json", "", []byte(body),
+			body, "Set-Cookie", "session=abc"},
+		{"label", "", 200, "text/plain", "", []byte(body),
+			body, "Set-Cookie", "session=abc"},
+	}
+
+	for i, test := range tests {
+		h := Rewrite(crw[test.rewriter], testHandler(test.code, test.contentType,
+			test.contentEncoding, test.body))
+		r, _ := http.NewRequest("GET", "http://trickster/api/v1/query", nil)
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		resp := w.Result()
+		b, _ := io.ReadAll(resp.Body)
+		if string(b) != test.expectedBody {
+			t.Errorf("test %d expected %s got %s", i, test.expectedBody, string(b))
+		}
+		if v := resp.Header.Get(test.expectedHeader); v != test.expectedValue {
+			t.Errorf("test %d expected %s got %s", i, test.expectedValue, v)
+		}
+		if resp.StatusCode != test.code {
+			t.Errorf("test %d expected %d got %d", i, test.code, resp.StatusCode)
+		}
+	}
+
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retry
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyWindowSize is the number of recent upstream latencies retained by a LatencyTracker
+const LatencyWindowSize = 1024
+
+// MinLatencySamples is the minimum number of observed latencies required before
+// a LatencyTracker will report a Percentile
+const MinLatencySamples = 20
+
+// recomputeInterval is the number of observations after which a cached percentile is recomputed
+const recomputeInterval = 32
+
+// LatencyTracker retains a sliding window of recent upstream latencies, from which
+// the hedging delay is derived
+type LatencyTracker struct {
+	mtx     sync.Mutex
+	samples []time.Duration
+	next    int
+	count   int64
+
+	cachedPercentile float64
+	cachedValue      time.Duration
+	cachedAt         int64
+}
+
+// NewLatencyTracker returns a new LatencyTracker
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{samples: make([]time.Duration, 0, LatencyWindowSize)}
+}
+
+// Observe records an upstream latency
+func (lt *LatencyTracker) Observe(d time.Duration) {
+	lt.mtx.Lock()
+	if len(lt.samples) < LatencyWindowSize {
+		lt.samples = append(lt.samples, d)
+	} else {
+		lt.samples[lt.next] = d
+	}
+	lt.next = (lt.next + 1) % LatencyWindowSize
+	lt.count++
+	lt.mtx.Unlock()
+}
+
+// Percentile returns the latency at the provided percentile (0-100) of the retained window,
+// or 0 if fewer than MinLatencySamples have been observed
+func (lt *LatencyTracker) Percentile(p float64) time.Duration {
+	lt.mtx.Lock()
+	defer lt.mtx.Unlock()
+	n := len(lt.samples)
+	if n < MinLatencySamples {
+		return 0
+	}
+	if lt.cachedAt > 0 && lt.cachedPercentile == p && lt.count-lt.cachedAt < recomputeInterval {
+		return lt.cachedValue
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, lt.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(p / 100 * float64(n))
+	if i >= n {
+		i = n - 1
+	}
+	lt.cachedPercentile = p
+	lt.cachedValue = sorted[i]
+	lt.cachedAt = lt.count
+	return lt.cachedValue
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyTracker(t *testing.T) {
+
+	lt := NewLatencyTracker()
+	for i := 1; i < MinLatencySamples; i++ {
+		lt.Observe(time.Duration(i) * time.Millisecond)
+	}
+	if d := lt.Percentile(50); d != 0 {
+		t.Errorf("expected 0 got %s", d)
+	}
+
+	for i := MinLatencySamples; i <= 100; i++ {
+		lt.Observe(time.Duration(i) * time.Millisecond)
+	}
+	if d := lt.Percentile(95); d != 96*time.Millisecond {
+		t.Errorf("expected %s got %s", 96*time.Millisecond, d)
+	}
+	if d := lt.Percentile(50); d != 51*time.Millisecond {
+		t.Errorf("expected %s got %s", 51*time.Millisecond, d)
+	}
+
+	// once the window is full, the oldest samples are replaced
+	for i := 0; i < LatencyWindowSize; i++ {
+		lt.Observe(time.Second)
+	}
+	if d := lt.Percentile(1); d != time.Second {
+		t.Errorf("expected %s got %s", time.Second, d)
+	}
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for retrying and hedging upstream requests
+package options
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/copiers"
+	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
+)
+
+// Retryable Error Classes
+const (
+	// ErrorClassConnect is a failure to establish a connection to the upstream
+	ErrorClassConnect = "connect"
+	// ErrorClassReset is a connection reset by the upstream
+	ErrorClassReset = "reset"
+	// ErrorClassEOF is a connection closed by the upstream before a response was received
+	ErrorClassEOF = "eof"
+	// ErrorClassTimeout is an upstream request that timed out
+	ErrorClassTimeout = "timeout"
+)
+
+var errorClasses = map[string]interface{}{
+	ErrorClassConnect: nil,
+	ErrorClassReset:   nil,
+	ErrorClassEOF:     nil,
+	ErrorClassTimeout: nil,
+}
+
+const (
+	// DefaultMaxAttempts is the default maximum number of upstream attempts (no retries)
+	DefaultMaxAttempts = 1
+	// DefaultBackoffBaseMS is the default backoff before the first retry
+	DefaultBackoffBaseMS = 50
+	// DefaultBackoffMaxMS is the default maximum backoff between retries
+	DefaultBackoffMaxMS = 1000
+)
+
+// DefaultRetryableCodes returns the default list of retryable upstream status codes
+func DefaultRetryableCodes() []int {
+	return []int{502, 503, 504}
+}
+
+// DefaultRetryableErrors returns the default list of retryable upstream error classes
+func DefaultRetryableErrors() []string {
+	return []string{ErrorClassConnect, ErrorClassReset, ErrorClassEOF}
+}
+
+// Options defines the Retry and Hedging Options for upstream requests
+type Options struct {
+	// MaxAttempts is the maximum number of upstream attempts for an idempotent request,
+	// including the first. The default of 1 disables retries
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+	// RetryableCodes is the list of upstream response status codes that will be retried
+	RetryableCodes []int `yaml:"retryable_codes,omitempty"`
+	// RetryableErrors is the list of upstream error classes that will be retried.
+	// Options are 'connect', 'reset', 'eof' and 'timeout'
+	RetryableErrors []string `yaml:"retryable_errors,omitempty"`
+	// BackoffBaseMS is the backoff before the first retry. Each subsequent backoff is doubled
+	// and jittered, up to BackoffMaxMS
+	BackoffBaseMS int `yaml:"backoff_base_ms,omitempty"`
+	// BackoffMaxMS is the maximum backoff between retries
+	BackoffMaxMS int `yaml:"backoff_max_ms,omitempty"`
+	// HedgePercentile is the percentile of recent upstream latencies after which a hedged
+	// request is sent if the first has not yet responded. The default of 0 disables hedging
+	HedgePercentile float64 `yaml:"hedge_percentile,omitempty"`
+	// HedgeMinDelayMS is the minimum time to wait before sending a hedged request
+	HedgeMinDelayMS int `yaml:"hedge_min_delay_ms,omitempty"`
+
+	// BackoffBase is the time.Duration representation of BackoffBaseMS
+	BackoffBase time.Duration `yaml:"-"`
+	// BackoffMax is the time.Duration representation of BackoffMaxMS
+	BackoffMax time.Duration `yaml:"-"`
+	// HedgeMinDelay is the time.Duration representation of HedgeMinDelayMS
+	HedgeMinDelay time.Duration `yaml:"-"`
+	// RetryableCodesLookup is a map of RetryableCodes for quick lookup
+	RetryableCodesLookup map[int]interface{} `yaml:"-"`
+	// RetryableErrorsLookup is a map of RetryableErrors for quick lookup
+	RetryableErrorsLookup map[string]interface{} `yaml:"-"`
+}
+
+// New returns a New Options object with the default values
+func New() *Options {
+	o := &Options{
+		MaxAttempts:     DefaultMaxAttempts,
+		RetryableCodes:  DefaultRetryableCodes(),
+		RetryableErrors: DefaultRetryableErrors(),
+		BackoffBaseMS:   DefaultBackoffBaseMS,
+		BackoffMaxMS:    DefaultBackoffMaxMS,
+	}
+	o.compile()
+	return o
+}
+
+// Clone returns a perfect copy of the Options
+func (o *Options) Clone() *Options {
+	c := &Options{
+		MaxAttempts:     o.MaxAttempts,
+		BackoffBaseMS:   o.BackoffBaseMS,
+		BackoffMaxMS:    o.BackoffMaxMS,
+		HedgePercentile: o.HedgePercentile,
+		HedgeMinDelayMS: o.HedgeMinDelayMS,
+		RetryableErrors: copiers.CopyStrings(o.RetryableErrors),
+	}
+	if o.RetryableCodes != nil {
+		c.RetryableCodes = make([]int, len(o.RetryableCodes))
+		copy(c.RetryableCodes, o.RetryableCodes)
+	}
+	c.compile()
+	return c
+}
+
+// compile populates the Options' derived fields from their user-facing counterparts
+func (o *Options) compile() {
+	o.BackoffBase = time.Duration(o.BackoffBaseMS) * time.Millisecond
+	o.BackoffMax = time.Duration(o.BackoffMaxMS) * time.Millisecond
+	o.HedgeMinDelay = time.Duration(o.HedgeMinDelayMS) * time.Millisecond
+	o.RetryableCodesLookup = make(map[int]interface{}, len(o.RetryableCodes))
+	for _, c := range o.RetryableCodes {
+		o.RetryableCodesLookup[c] = nil
+	}
+	o.RetryableErrorsLookup = make(map[string]interface{}, len(o.RetryableErrors))
+	for _, e := range o.RetryableErrors {
+		o.RetryableErrorsLookup[e] = nil
+	}
+}
+
+// RetriesEnabled returns true if the Options permit more than one upstream attempt
+func (o *Options) RetriesEnabled() bool {
+	return o.MaxAttempts > 1
+}
+
+// HedgingEnabled returns true if the Options permit hedged upstream requests
+func (o *Options) HedgingEnabled() bool {
+	return o.HedgePercentile > 0
+}
+
+// SetDefaults iterates the provided Options, and overlays user-set values onto the default Options
+func SetDefaults(name string, options *Options, metadata yamlx.KeyLookup) (*Options, error) {
+
+	if metadata == nil {
+		return nil, errors.New("invalid metadata")
+	}
+
+	o := New()
+
+	if !metadata.IsDefined("backends", name, "retry") || options == nil {
+		return o, nil
+	}
+
+	if metadata.IsDefined("backends", name, "retry", "max_attempts") {
+		if options.MaxAttempts < 1 {
+			return nil, errors.New("value for 'max_attempts' is invalid")
+		}
+		o.MaxAttempts = options.MaxAttempts
+	}
+
+	if metadata.IsDefined("backends", name, "retry", "retryable_codes") {
+		for _, c := range options.RetryableCodes {
+			if c < 100 || c > 599 {
+				return nil, fmt.Errorf("'retryable_codes' entry [%d] is invalid", c)
+			}
+		}
+		o.RetryableCodes = options.RetryableCodes
+	}
+
+	if metadata.IsDefined("backends", name, "retry", "retryable_errors") {
+		for _, e := range options.RetryableErrors {
+			if _, ok := errorClasses[e]; !ok {
+				return nil, fmt.Errorf("'retryable_errors' entry [%s] is invalid", e)
+			}
+		}
+		o.RetryableErrors = options.RetryableErrors
+	}
+
+	if metadata.IsDefined("backends", name, "retry", "backoff_base_ms") {
+		if options.BackoffBaseMS < 0 {
+			return nil, errors.New("value for 'backoff_base_ms' is invalid")
+		}
+		o.BackoffBaseMS = options.BackoffBaseMS
+	}
+
+	if metadata.IsDefined("backends", name, "retry", "backoff_max_ms") {
+		if options.BackoffMaxMS < 0 {
+			return nil, errors.New("value for 'backoff_max_ms' is invalid")
+		}
+		o.BackoffMaxMS = options.BackoffMaxMS
+	}
+
+	if metadata.IsDefined("backends", name, "retry", "hedge_percentile") {
+		if options.HedgePercentile < 0 || options.HedgePercentile >= 100 {
+			return nil, errors.New("value for 'hedge_percentile' is invalid")
+		}
+		o.HedgePercentile = options.HedgePercentile
+	}
+
+	if metadata.IsDefined("backends", name, "retry", "hedge_min_delay_ms") {
+		if options.HedgeMinDelayMS < 0 {
+			return nil, errors.New("value for 'hedge_min_delay_ms' is invalid")
+		}
+		o.HedgeMinDelayMS = options.HedgeMinDelayMS
+	}
+
+	o.compile()
+
+	return o, nil
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/yamlx"
+
+	"gopkg.in/yaml.v2"
+)
+
+type testOptions1 struct {
+	Backends map[string]*testOptions2 `yaml:"backends,omitempty"`
+}
+
+type testOptions2 struct {
+	Retry *Options `yaml:"retry,omitempty"`
+}
+
+func fromYAML(conf string) (*Options, yamlx.KeyLookup, error) {
+	to := &testOptions1{}
+	err := yaml.Unmarshal([]byte(conf), to)
+	if err != nil {
+		return nil, nil, err
+	}
+	md, err := yamlx.GetKeyList(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, v := range to.Backends {
+		if v != nil && v.Retry != nil {
+			return v.Retry, md, nil
+		}
+	}
+	return nil, md, nil
+}
+
+const testYAML = `
+backends:
+  test:
+    retry:
+      max_attempts: 3
+      retryable_codes: [ 500, 503 ]
+      retryable_errors: [ reset, timeout ]
+      backoff_base_ms: 10
+      backoff_max_ms: 100
+      hedge_percentile: 95
+      hedge_min_delay_ms: 5
+`
+
+func TestNew(t *testing.T) {
+	o := New()
+	if o.RetriesEnabled() || o.HedgingEnabled() {
+		t.Error("expected retries and hedging to be disabled")
+	}
+	if _, ok := o.RetryableCodesLookup[503]; !ok {
+		t.Error("expected 503 to be retryable")
+	}
+	if o.BackoffMax != time.Second {
+		t.Errorf("expected %s got %s", time.Second, o.BackoffMax)
+	}
+}
+
+func TestClone(t *testing.T) {
+	o := New()
+	o.HedgePercentile = 90
+	c := o.Clone()
+	c.RetryableCodes[0] = 500
+	if o.RetryableCodes[0] != 502 {
+		t.Error("clone mismatch")
+	}
+	if c.HedgePercentile != 90 || !c.HedgingEnabled() {
+		t.Error("clone mismatch")
+	}
+	if _, ok := c.RetryableErrorsLookup[ErrorClassReset]; !ok {
+		t.Error("clone mismatch")
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+
+	_, err := SetDefaults("test", nil, nil)
+	if err == nil {
+		t.Error("expected error for invalid metadata")
+	}
+
+	o, md, err := fromYAML(testYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err = SetDefaults("test", o, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.MaxAttempts != 3 || !o.RetriesEnabled() {
+		t.Errorf("expected %d got %d", 3, o.MaxAttempts)
+	}
+	if _, ok := o.RetryableCodesLookup[502]; ok {
+		t.Error("expected 502 to not be retryable")
+	}
+	if _, ok := o.RetryableErrorsLookup[ErrorClassTimeout]; !ok {
+		t.Error("expected timeout to be retryable")
+	}
+	if o.BackoffBase != 10*time.Millisecond || o.BackoffMax != 100*time.Millisecond {
+		t.Errorf("unexpected backoff %s %s", o.BackoffBase, o.BackoffMax)
+	}
+	if o.HedgePercentile != 95 || o.HedgeMinDelay != 5*time.Millisecond {
+		t.Errorf("unexpected hedge options %f %s", o.HedgePercentile, o.HedgeMinDelay)
+	}
+
+	// backends without retry options get the defaults
+	o, md, _ = fromYAML("backends:\n  test:\n    provider: rpc\n")
+	o, err = SetDefaults("test", o, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.MaxAttempts != DefaultMaxAttempts {
+		t.Errorf("expected %d got %d", DefaultMaxAttempts, o.MaxAttempts)
+	}
+}
+
+func TestSetDefaultsInvalid(t *testing.T) {
+
+	tests := []string{
+		"max_attempts: 0",
+		"retryable_codes: [ 600 ]",
+		"retryable_errors: [ invalid ]",
+		"backoff_base_ms: -1",
+		"backoff_max_ms: -1",
+		"hedge_percentile: 100",
+		"hedge_min_delay_ms: -1",
+	}
+
+	for _, test := range tests {
+		o, md, err := fromYAML("backends:\n  test:\n    retry:\n      " + test + "\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = SetDefaults("test", o, md); err == nil {
+			t.Errorf("expected error for %s", test)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package retry provides functionality for retrying and hedging upstream requests
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
+	ro "github.com/tricksterproxy/trickster/pkg/proxy/retry/options"
+)
+
+// Backoff returns the jittered exponential backoff to wait before the provided retry
+// attempt (starting at 1). The backoff doubles with each attempt up to max, and a random
+// jitter of up to half the backoff is subtracted so that concurrent retries are spread out
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	if base <= 0 || attempt < 1 {
+		return 0
+	}
+	d := base
+	for i := 1; i < attempt && (max <= 0 || d < max); i++ {
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	if j := int64(d / 2); j > 0 {
+		d -= time.Duration(rand.Int63n(j + 1))
+	}
+	return d
+}
+
+// ErrorClass returns the retryable error class of the provided upstream error,
+// or an empty string if the error is not of a known class
+func ErrorClass(err error) string {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return ""
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return ro.ErrorClassReset
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ro.ErrorClassConnect
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ro.ErrorClassEOF
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ro.ErrorClassTimeout
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return ro.ErrorClassTimeout
+	}
+	var oe *net.OpError
+	if errors.As(err, &oe) && oe.Op == "dial" {
+		return ro.ErrorClassConnect
+	}
+	return ""
+}
+
+// Reason returns the reason the provided upstream response or error should be retried,
+// which is either the error class or the response status code, or an empty string
+// if it should not be retried
+func Reason(o *ro.Options, resp *http.Response, err error) string {
+	if err != nil {
+		if c := ErrorClass(err); c != "" {
+			if _, ok := o.RetryableErrorsLookup[c]; ok {
+				return c
+			}
+		}
+		return ""
+	}
+	if resp != nil {
+		if _, ok := o.RetryableCodesLookup[resp.StatusCode]; ok {
+			return strconv.Itoa(resp.StatusCode)
+		}
+	}
+	return ""
+}
+
+// IsReplayable returns true if the request may be safely sent upstream more than once,
+// which requires an idempotent method and a body that can be re-read
+func IsReplayable(r *http.Request) bool {
+	return methods.IsIdempotent(r.Method) &&
+		(r.Body == nil || r.Body == http.NoBody || r.GetBody != nil)
+}
+
+// Replay returns a copy of the request with the provided context, and a fresh body
+// if the request has one
+func Replay(ctx context.Context, r *http.Request) *http.Request {
+	r2 := r.Clone(ctx)
+	if r.GetBody != nil {
+		if b, err := r.GetBody(); err == nil {
+			r2.Body = b
+		}
+	}
+	return r2
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	ro "github.com/tricksterproxy/trickster/pkg/proxy/retry/options"
+)
+
+func TestBackoff(t *testing.T) {
+
+	if d := Backoff(0, time.Second, 0); d != 0 {
+		t.Errorf("expected 0 got %s", d)
+	}
+	if d := Backoff(1, 0, time.Second); d != 0 {
+		t.Errorf("expected 0 got %s", d)
+	}
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 20; i++ {
+			d := Backoff(test.attempt, 100*time.Millisecond, time.Second)
+			if d < test.min || d > test.max {
+				t.Errorf("attempt %d: expected between %s and %s got %s",
+					test.attempt, test.min, test.max, d)
+			}
+		}
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorClass(t *testing.T) {
+
+	ue := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://example.com", Err: err}
+	}
+
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{nil, ""},
+		{errors.New("other"), ""},
+		{ue(context.Canceled), ""},
+		{ue(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}),
+			ro.ErrorClassReset},
+		{ue(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}),
+			ro.ErrorClassConnect},
+		{ue(&net.OpError{Op: "dial", Err: errors.New("no such host")}), ro.ErrorClassConnect},
+		{ue(io.EOF), ro.ErrorClassEOF},
+		{ue(context.DeadlineExceeded), ro.ErrorClassTimeout},
+		{ue(timeoutError{}), ro.ErrorClassTimeout},
+	}
+
+	for _, test := range tests {
+		if c := ErrorClass(test.err); c != test.expected {
+			t.Errorf("%v: expected %s got %s", test.err, test.expected, c)
+		}
+	}
+}
+
+func TestReason(t *testing.T) {
+
+	o := ro.New()
+
+	if r := Reason(o, nil, io.EOF); r != ro.ErrorClassEOF {
+		t.Errorf("expected %s got %s", ro.ErrorClassEOF, r)
+	}
+	if r := Reason(o, nil, context.DeadlineExceeded); r != "" {
+		t.Errorf("expected empty reason got %s", r)
+	}
+	if r := Reason(o, &http.Response{StatusCode: 503}, nil); r != "503" {
+		t.Errorf("expected %s got %s", "503", r)
+	}
+	if r := Reason(o, &http.Response{StatusCode: 500}, nil); r != "" {
+		t.Errorf("expected empty reason got %s", r)
+	}
+}
+
+func TestIsReplayable(t *testing.T) {
+
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	if !IsReplayable(r) {
+		t.Error("expected true")
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "http://example.com/", nil)
+	if IsReplayable(r) {
+		t.Error("expected false")
+	}
+
+	r = httptest.NewRequest(http.MethodPut, "http://example.com/", bytes.NewBufferString("x"))
+	r.GetBody = nil
+	if IsReplayable(r) {
+		t.Error("expected false")
+	}
+
+	r, _ = http.NewRequest(http.MethodPut, "http://example.com/", bytes.NewBufferString("body"))
+	if !IsReplayable(r) {
+		t.Error("expected true")
+	}
+	io.ReadAll(r.Body)
+
+	r2 := Replay(context.Background(), r)
+	b, _ := io.ReadAll(r2.Body)
+	if string(b) != "body" {
+		t.Errorf("expected %s got %s", "body", string(b))
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/pprof"
-	"sort"
 	"strings"
 
 	"github.com/tricksterproxy/trickster/cmd/trickster/config"
@@ -29,6 +28,10 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/backends/alb"
 	"github.com/tricksterproxy/trickster/pkg/backends/clickhouse"
 	modelch "github.com/tricksterproxy/trickster/pkg/backends/clickhouse/model"
+	"github.com/tricksterproxy/trickster/pkg/backends/elasticsearch"
+	modeles "github.com/tricksterproxy/trickster/pkg/backends/elasticsearch/model"
+	"github.com/tricksterproxy/trickster/pkg/backends/graphite"
+	modelgr "github.com/tricksterproxy/trickster/pkg/backends/graphite/model"
 	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
 	"github.com/tricksterproxy/trickster/pkg/backends/influxdb"
 	modelflux "github.com/tricksterproxy/trickster/pkg/backends/influxdb/model"
@@ -50,6 +53,8 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	resprewriter "github.com/tricksterproxy/trickster/pkg/proxy/response/rewriter"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
 	"github.com/tricksterproxy/trickster/pkg/util/middleware"
 
 	"github.com/gorilla/mux"
@@ -134,7 +139,7 @@ func RegisterProxyRoutes(conf *config.Config, router *mux.Router, metricsRouter
 			return nil, err
 		}
 	}
-	err = rule.ValidateOptions(clients, conf.CompiledRewriters)
+	err = rule.ValidateOptions(clients, conf.CompiledRewriters, conf.CompiledResponseRewriters)
 	if err != nil {
 		return nil, err
 	}
@@ -187,6 +192,10 @@ func registerBackendRoutes(router *mux.Router, metricsRouter *http.ServeMux, con
 		client, err = irondb.NewClient(k, o, mux.NewRouter(), c, modeliron.NewModeler())
 	case "clickhouse":
 		client, err = clickhouse.NewClient(k, o, mux.NewRouter(), c, modelch.NewModeler())
+	case "elasticsearch", "opensearch":
+		client, err = elasticsearch.NewClient(k, o, mux.NewRouter(), c, modeles.NewModeler())
+	case "graphite":
+		client, err = graphite.NewClient(k, o, mux.NewRouter(), c, modelgr.NewModeler())
 	case "rpc", "reverseproxycache":
 		client, err = reverseproxycache.NewClient(k, o, mux.NewRouter(), c)
 	case "rp", "reverseproxy", "proxy":
@@ -194,11 +203,11 @@ func registerBackendRoutes(router *mux.Router, metricsRouter *http.ServeMux, con
 	case "rule":
 		client, err = rule.NewClient(k, o, mux.NewRouter(), clients)
 	case "alb":
-		if strings.HasPrefix(o.ALBOptions.MechanismName, "tsm") {
-			// for now assume prometheus but will need to revisit if more mergeable providers are supported
-			o.ALBOptions.MergeablePaths = prometheus.MergeablePaths()
+		var om *timeseries.Modeler
+		if o.ALBOptions != nil && o.ALBOptions.OutputFormat != "" {
+			om = outputModeler(o.ALBOptions.OutputFormat)
 		}
-		client, err = alb.NewClient(k, o, mux.NewRouter())
+		client, err = alb.NewClient(k, o, mux.NewRouter(), om)
 	}
 	if err != nil {
 		return err
@@ -228,6 +237,26 @@ func registerBackendRoutes(router *mux.Router, metricsRouter *http.ServeMux, con
 	return nil
 }
 
+// outputModeler returns the Modeler for the provided time series provider name,
+// which ALBs use to write merged responses in their configured output_format
+func outputModeler(provider string) *timeseries.Modeler {
+	switch strings.ToLower(provider) {
+	case "prometheus":
+		return modelprom.NewModeler()
+	case "influxdb":
+		return modelflux.NewModeler()
+	case "irondb":
+		return modeliron.NewModeler()
+	case "clickhouse":
+		return modelch.NewModeler()
+	case "elasticsearch", "opensearch":
+		return modeles.NewModeler()
+	case "graphite":
+		return modelgr.NewModeler()
+	}
+	return nil
+}
+
 // RegisterPathRoutes will take the provided default paths map,
 // merge it with any path data in the provided backend options, and then register
 // the path routes to the appropriate handler from the provided handlers map
@@ -251,12 +280,23 @@ func RegisterPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 	decorate := func(po *po.Options) http.Handler {
 		// default base route is the path handler
 		h := po.Handler
+		// attach any response rewriters
+		if po.RespRewriter != nil {
+			h = resprewriter.Rewrite(po.RespRewriter, h)
+		}
+		if o.RespRewriter != nil {
+			h = resprewriter.Rewrite(o.RespRewriter, h)
+		}
 		// attach distributed tracer
 		if tr != nil {
 			h = middleware.Trace(tr, h)
 		}
 		// attach compression handler
 		h = encoding.HandleCompression(h, o.CompressibleTypes)
+		// attach the rate limiter
+		if l := client.Limiter(); l != nil {
+			h = middleware.RateLimit(l, o.Name, o.Provider, h)
+		}
 		// add Backend, Cache, and Path Configs to the HTTP Request's context
 		h = middleware.WithResourcesContext(client, o, c, po, tr, logger, h)
 		// attach any request rewriters
@@ -270,6 +310,10 @@ func RegisterPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 		if !po.NoMetrics {
 			h = middleware.Decorate(o.Name, o.Provider, po.Path, h)
 		}
+		// attach any named segments captured by a regex or template path match
+		if po.Matcher != nil {
+			h = middleware.PathCaptures(po.Matcher, h)
+		}
 		return h
 	}
 
@@ -299,12 +343,10 @@ func RegisterPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 		}
 	}
 
-	plist := make([]string, 0, len(pathsWithVerbs))
 	deletes := make([]string, 0, len(pathsWithVerbs))
-	for k, p := range pathsWithVerbs {
+	for _, p := range pathsWithVerbs {
 		if h, ok := handlers[p.HandlerName]; ok && h != nil {
 			p.Handler = h
-			plist = append(plist, k)
 		} else {
 			tl.Info(logger, "invalid handler name for path",
 				tl.Pairs{"path": p.Path, "handlerName": p.HandlerName})
@@ -315,22 +357,17 @@ func RegisterPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 		delete(pathsWithVerbs, p)
 	}
 
-	sort.Sort(ByLen(plist))
-	for i := len(plist)/2 - 1; i >= 0; i-- {
-		opp := len(plist) - 1 - i
-		plist[i], plist[opp] = plist[opp], plist[i]
-	}
-
 	or := client.Router().(*mux.Router)
 
-	for _, v := range plist {
-		p := pathsWithVerbs[v]
+	// paths are registered in order of match precedence, since the router
+	// selects the first registered route that matches a request
+	for _, p := range po.Lookup(pathsWithVerbs).Sorted() {
 
 		pathPrefix := "/" + o.Name
 		handledPath := pathPrefix + p.Path
 
 		tl.Debug(logger, "registering backend handler path",
-			tl.Pairs{"backendName": o.Name, "path": v, "handlerName": p.HandlerName,
+			tl.Pairs{"backendName": o.Name, "path": p.Path, "handlerName": p.HandlerName,
 				"backendHost": o.Host, "handledPath": handledPath, "matchType": p.MatchType,
 				"frontendHosts": strings.Join(o.Hosts, ",")})
 		if p.Handler != nil && len(p.Methods) > 0 {
@@ -352,6 +389,19 @@ func RegisterPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 						decorate(p))).Methods(p.Methods...)
 				}
 				or.PathPrefix(p.Path).Handler(decorate(p)).Methods(p.Methods...)
+			case matching.PathMatchTypeRegex, matching.PathMatchTypeTemplate:
+				// Case where we path match by regex or template
+				// Host Header Routing
+				for _, h := range o.Hosts {
+					router.MatcherFunc(pathMatcher(p, "")).Handler(decorate(p)).
+						Methods(p.Methods...).Host(h)
+				}
+				if !o.PathRoutingDisabled {
+					// Path Routing
+					router.MatcherFunc(pathMatcher(p, pathPrefix)).
+						Handler(middleware.StripPathPrefix(pathPrefix, decorate(p))).Methods(p.Methods...)
+				}
+				or.MatcherFunc(pathMatcher(p, "")).Handler(decorate(p)).Methods(p.Methods...)
 			default:
 				// default to exact match
 				// Host Header Routing
@@ -380,10 +430,21 @@ func RegisterDefaultBackendRoutes(router *mux.Router, bknds backends.Backends,
 		c cache.Cache, client backends.Backend) http.Handler {
 		// default base route is the path handler
 		h := po.Handler
+		// attach any response rewriters
+		if po.RespRewriter != nil {
+			h = resprewriter.Rewrite(po.RespRewriter, h)
+		}
+		if o.RespRewriter != nil {
+			h = resprewriter.Rewrite(o.RespRewriter, h)
+		}
 		// attach distributed tracer
 		if tr != nil {
 			h = middleware.Trace(tr, h)
 		}
+		// attach the rate limiter
+		if l := client.Limiter(); l != nil {
+			h = middleware.RateLimit(l, o.Name, o.Provider, h)
+		}
 		// add Backend, Cache, and Path Configs to the HTTP Request's context
 		h = middleware.WithResourcesContext(client, o, c, po, tr, logger, h)
 		// attach any request rewriters
@@ -397,6 +458,10 @@ func RegisterDefaultBackendRoutes(router *mux.Router, bknds backends.Backends,
 		if !po.NoMetrics {
 			h = middleware.Decorate(o.Name, o.Provider, po.Path, h)
 		}
+		// attach any named segments captured by a regex or template path match
+		if po.Matcher != nil {
+			h = middleware.PathCaptures(po.Matcher, h)
+		}
 		return h
 	}
 
@@ -409,7 +474,7 @@ func RegisterDefaultBackendRoutes(router *mux.Router, bknds backends.Backends,
 			}
 			tl.Info(logger,
 				"registering default backend handler paths", tl.Pairs{"backendName": o.Name})
-			for _, p := range o.Paths {
+			for _, p := range po.Lookup(o.Paths).Sorted() {
 				if p.Handler != nil && len(p.Methods) > 0 {
 					tl.Debug(logger, "registering default backend handler paths",
 						tl.Pairs{"backendName": o.Name, "path": p.Path, "handlerName": p.HandlerName,
@@ -418,6 +483,11 @@ func RegisterDefaultBackendRoutes(router *mux.Router, bknds backends.Backends,
 					case matching.PathMatchTypePrefix:
 						// Case where we path match by prefix
 						router.PathPrefix(p.Path).Handler(decorate(o, p, tr, b.Cache(), b)).Methods(p.Methods...)
+					case matching.PathMatchTypeRegex, matching.PathMatchTypeTemplate:
+						// Case where we path match by regex or template
+						router.MatcherFunc(pathMatcher(p, "")).
+							Handler(decorate(o, p, tr, b.Cache(), b)).Methods(p.Methods...)
+						continue
 					default:
 						// default to exact match
 						router.Handle(p.Path, decorate(o, p, tr, b.Cache(), b)).Methods(p.Methods...)
@@ -430,17 +500,13 @@ func RegisterDefaultBackendRoutes(router *mux.Router, bknds backends.Backends,
 
 }
 
-// ByLen allows sorting of a string slice by string length
-type ByLen []string
-
-func (a ByLen) Len() int {
-	return len(a)
-}
-
-func (a ByLen) Less(i, j int) bool {
-	return len(a[i]) < len(a[j])
-}
-
-func (a ByLen) Swap(i, j int) {
-	a[i], a[j] = a[j], a[i]
+// pathMatcher returns a route matcher for a regex or template path. When prefix is
+// provided, it must lead the request path, and is removed prior to matching
+func pathMatcher(p *po.Options, prefix string) mux.MatcherFunc {
+	return func(r *http.Request, _ *mux.RouteMatch) bool {
+		if r.URL == nil || !strings.HasPrefix(r.URL.Path, prefix) {
+			return false
+		}
+		return p.Matches(r.URL.Path[len(prefix):])
+	}
 }
@@ -17,13 +17,22 @@
 package routing
 
 import (
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/tricksterproxy/trickster/cmd/trickster/config"
 	"github.com/tricksterproxy/trickster/pkg/backends"
+	"github.com/tricksterproxy/trickster/pkg/backends/alb"
 	"github.com/tricksterproxy/trickster/pkg/backends/healthcheck"
 	bo "github.com/tricksterproxy/trickster/pkg/backends/options"
 	"github.com/tricksterproxy/trickster/pkg/backends/reverseproxycache"
@@ -217,6 +226,141 @@ func TestRegisterProxyRoutesClickHouse(t *testing.T) {
 
 }
 
+func TestRegisterProxyRoutesElasticsearch(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", "http://1", "-provider", "elasticsearch"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	proxyClients, err := RegisterProxyRoutes(conf, mux.NewRouter(), http.NewServeMux(), caches,
+		nil, tl.ConsoleLogger("info"), false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(proxyClients) == 0 {
+		t.Errorf("expected %d got %d", 1, 0)
+	}
+
+}
+
+func TestRegisterProxyRoutesGraphite(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", "http://1", "-provider", "graphite"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	proxyClients, err := RegisterProxyRoutes(conf, mux.NewRouter(), http.NewServeMux(), caches,
+		nil, tl.ConsoleLogger("info"), false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(proxyClients) == 0 {
+		t.Errorf("expected %d got %d", 1, 0)
+	}
+
+}
+
+func TestRegisterProxyRoutesGraphiteRender(t *testing.T) {
+
+	var mtx sync.Mutex
+	var requested []string
+	// the stand-in server returns datapoints after from, through until, as Graphite does
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, _ := strconv.ParseInt(r.FormValue("from"), 10, 64)
+		until, _ := strconv.ParseInt(r.FormValue("until"), 10, 64)
+		mtx.Lock()
+		requested = append(requested, r.FormValue("from")+"-"+r.FormValue("until")+
+			"-"+r.FormValue("maxDataPoints"))
+		mtx.Unlock()
+		values := make([]string, 0)
+		for ts := from - from%60 + 60; ts <= until; ts += 60 {
+			values = append(values, fmt.Sprintf(`[1,%d]`, ts))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `[{"target":"web.cpu","datapoints":[%s]}]`, strings.Join(values, ","))
+	}))
+	defer upstream.Close()
+
+	f := filepath.Join(t.TempDir(), "trickster.yaml")
+	err := os.WriteFile(f, []byte(fmt.Sprintf(`
+backends:
+  graphite:
+    provider: graphite
+    origin_url: %s
+    graphite:
+      step_ms: 60000
+`, upstream.URL)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, _, err := config.Load("trickster", "test", []string{"-config", f})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, http.NewServeMux(), caches,
+		nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	end := time.Now().Truncate(time.Minute).Add(-10 * time.Minute)
+	start := end.Add(-time.Hour)
+	render := func(until time.Time) string {
+		q := url.Values{"target": {"web.cpu"}, "format": {"json"}, "maxDataPoints": {"100"},
+			"from":  {strconv.FormatInt(start.Unix(), 10)},
+			"until": {strconv.FormatInt(until.Unix(), 10)}}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+			"http://trickster/graphite/render?"+q.Encode(), nil))
+		b, _ := io.ReadAll(w.Result().Body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected %d got %d: %s", http.StatusOK, w.Code, string(b))
+		}
+		return string(b)
+	}
+
+	b := render(end)
+	if c := strings.Count(b, "[1,"); c != 61 {
+		t.Errorf("expected %d datapoints got %d: %s", 61, c, b)
+	}
+	if !strings.Contains(b, fmt.Sprintf("[1,%d]", start.Unix())) {
+		t.Errorf("expected datapoint at %d: %s", start.Unix(), b)
+	}
+
+	// a request for a later range fetches only the datapoints that are not yet cached
+	b = render(end.Add(5 * time.Minute))
+	if c := strings.Count(b, "[1,"); c != 66 {
+		t.Errorf("expected %d datapoints got %d: %s", 66, c, b)
+	}
+
+	expected := []string{
+		fmt.Sprintf("%d-%d-", start.Unix()-61, end.Unix()),
+		fmt.Sprintf("%d-%d-", end.Unix()-1, end.Add(5*time.Minute).Unix()),
+	}
+	if len(requested) != len(expected) {
+		t.Fatalf("expected %d upstream requests got %d: %v", len(expected), len(requested), requested)
+	}
+	for i := range expected {
+		if requested[i] != expected[i] {
+			t.Errorf("expected upstream request %s got %s", expected[i], requested[i])
+		}
+	}
+}
+
 func TestRegisterProxyRoutesIRONdb(t *testing.T) {
 
 	conf, _, err := config.Load("trickster", "test",
@@ -263,6 +407,37 @@ func TestRegisterProxyRoutesWithReqRewriters(t *testing.T) {
 	}
 }
 
+func TestRegisterProxyRoutesWithRespRewriters(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-config", "../../testdata/test.routing.resp_rewriter.conf"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, http.NewServeMux(), caches,
+		nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://trickster/test/local", nil))
+	resp := w.Result()
+	b, _ := io.ReadAll(resp.Body)
+	if string(b) != "served by trickster" {
+		t.Errorf("expected %s got %s", "served by trickster", string(b))
+	}
+	for _, h := range []string{"Test-Path", "Test-Origin"} {
+		if v := resp.Header.Get(h); v != "pass" {
+			t.Errorf("expected %s got %s for %s", "pass", v, h)
+		}
+	}
+}
+
 func TestRegisterProxyRoutesMultipleDefaults(t *testing.T) {
 	expected1 := "only one backend can be marked as default. Found both test and test2"
 	expected2 := "only one backend can be marked as default. Found both test2 and test"
@@ -416,7 +591,7 @@ func TestValidateRuleClients(t *testing.T) {
 	}
 
 	var cl = backends.Backends{"test": c}
-	rule.ValidateOptions(cl, nil)
+	rule.ValidateOptions(cl, nil, nil)
 
 	conf, _, err := config.Load("trickster", "test",
 		[]string{"-log-level", "debug", "-origin-url", "http://1", "-provider", "rpc"})
@@ -437,3 +612,374 @@ func TestValidateRuleClients(t *testing.T) {
 	}
 
 }
+
+func TestRegisterProxyRoutesWithPathMatching(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-config", "../../testdata/test.routing.path_matching.conf"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, http.NewServeMux(), caches,
+		nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path, expected string
+	}{
+		{"/api/v1/label/__name__/values", "exact"},
+		{"/api/v1/label/job/values", "template"},
+		{"/api/v1/series", "regex"},
+		{"/api/v1/labels", "regex"},
+		{"/api/v1/label/job", "prefix"},
+		{"/api/v1/seriesx", "prefix"},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://trickster/test"+test.path, nil))
+		b, _ := io.ReadAll(w.Result().Body)
+		if string(b) != test.expected {
+			t.Errorf("expected %s got %s for %s", test.expected, string(b), test.path)
+		}
+	}
+}
+
+func TestRegisterProxyRoutesALBMergeInflux(t *testing.T) {
+
+	newUpstream := func(host string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ts := time.Now().Truncate(time.Minute).Add(-10*time.Minute).UnixNano() / 1e6
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"results":[{"statement_id":0,"series":[{"name":"cpu",`+
+				`"tags":{"host":"%s"},"columns":["time","mean"],"values":[[%d,1]]}]}]}`, host, ts)
+		}))
+	}
+	a, b := newUpstream("a"), newUpstream("b")
+	defer a.Close()
+	defer b.Close()
+
+	f := filepath.Join(t.TempDir(), "trickster.yaml")
+	err := os.WriteFile(f, []byte(fmt.Sprintf(`
+backends:
+  influx-a:
+    provider: influxdb
+    origin_url: %s
+  influx-b:
+    provider: influxdb
+    origin_url: %s
+  merged:
+    provider: alb
+    alb:
+      mechanism: tsm
+      healthy_floor: -1
+      pool: [ influx-a, influx-b ]
+`, a.URL, b.URL)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, _, err := config.Load("trickster", "test", []string{"-config", f})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	clients, err := RegisterProxyRoutes(conf, router, http.NewServeMux(), caches,
+		nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hcs := healthcheck.StatusLookup{"influx-a": &healthcheck.Status{},
+		"influx-b": &healthcheck.Status{}}
+	if err = alb.StartALBPools(clients, hcs); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	q := url.Values{"epoch": {"ms"}, "q": {`SELECT mean("value") FROM "cpu" ` +
+		`WHERE time >= now() - 1h GROUP BY time(1m), "host"`}}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"http://trickster/merged/query?"+q.Encode(), nil))
+	b2, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d: %s", http.StatusOK, w.Code, string(b2))
+	}
+	for _, h := range []string{`"host":"a"`, `"host":"b"`} {
+		if !strings.Contains(string(b2), h) {
+			t.Errorf("expected merged response to contain %s: %s", h, string(b2))
+		}
+	}
+}
+
+func TestRegisterProxyRoutesALBOutputFormat(t *testing.T) {
+
+	ts := time.Now().Truncate(time.Minute).Add(-10 * time.Minute).Unix()
+	influx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"results":[{"statement_id":0,"series":[{"name":"cpu",`+
+			`"tags":{"host":"a"},"columns":["time","mean"],"values":[[%d,1]]}]}]}`, ts*1000)
+	}))
+	defer influx.Close()
+	prom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[`+
+			`{"metric":{"host":"b"},"values":[[%d,"2"]]}]}}`, ts)
+	}))
+	defer prom.Close()
+
+	f := filepath.Join(t.TempDir(), "trickster.yaml")
+	err := os.WriteFile(f, []byte(fmt.Sprintf(`
+request_rewriters:
+  promql-to-influxql:
+    instructions:
+      - [ 'path', 'set', '/query' ]
+      - [ 'param', 'set', 'epoch', 'ms' ]
+      - [ 'param', 'set', 'q', 'SELECT mean("value") FROM "cpu" WHERE time >= now() - 1h GROUP BY time(1m), "host"' ]
+backends:
+  influx-a:
+    provider: influxdb
+    origin_url: %s
+    paths:
+      promql:
+        path: /api/v1/query_range
+        handler: query
+        req_rewriter_name: promql-to-influxql
+  prom-b:
+    provider: prometheus
+    origin_url: %s
+  merged:
+    provider: alb
+    alb:
+      mechanism: tsm
+      healthy_floor: -1
+      output_format: prometheus
+      pool: [ influx-a, prom-b ]
+`, influx.URL, prom.URL)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, _, err := config.Load("trickster", "test", []string{"-config", f})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	clients, err := RegisterProxyRoutes(conf, router, http.NewServeMux(), caches,
+		nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hcs := healthcheck.StatusLookup{"influx-a": &healthcheck.Status{},
+		"prom-b": &healthcheck.Status{}}
+	if err = alb.StartALBPools(clients, hcs); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	q := url.Values{"query": {"cpu"}, "step": {"60"},
+		"start": {strconv.FormatInt(ts-3600, 10)}, "end": {strconv.FormatInt(ts+300, 10)}}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"http://trickster/merged/api/v1/query_range?"+q.Encode(), nil))
+	b, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d: %s", http.StatusOK, w.Code, string(b))
+	}
+	for _, s := range []string{`"resultType":"matrix"`, `"host":"a"`, `"host":"b"`} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("expected merged response to contain %s: %s", s, string(b))
+		}
+	}
+}
+
+func TestRegisterProxyRoutesALBTimeTiered(t *testing.T) {
+
+	var mtx sync.Mutex
+	ranges := make(map[string]string)
+	newUpstream := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start, _ := strconv.ParseInt(r.FormValue("start"), 10, 64)
+			end, _ := strconv.ParseInt(r.FormValue("end"), 10, 64)
+			mtx.Lock()
+			ranges[name] = r.FormValue("start") + "-" + r.FormValue("end")
+			mtx.Unlock()
+			values := make([]string, 0)
+			for ts := start; ts <= end; ts += 60 {
+				values = append(values, fmt.Sprintf(`[%d,"1"]`, ts))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[`+
+				`{"metric":{"tier":"%s"},"values":[%s]}]}}`, name, strings.Join(values, ","))
+		}))
+	}
+	recent, archive := newUpstream("recent"), newUpstream("archive")
+	defer recent.Close()
+	defer archive.Close()
+
+	f := filepath.Join(t.TempDir(), "trickster.yaml")
+	err := os.WriteFile(f, []byte(fmt.Sprintf(`
+backends:
+  prom-recent:
+    provider: prometheus
+    origin_url: %s
+    fast_forward_disable: true
+  prom-archive:
+    provider: prometheus
+    origin_url: %s
+    fast_forward_disable: true
+  tiered:
+    provider: alb
+    alb:
+      mechanism: tt
+      healthy_floor: -1
+      pool: [ prom-recent, prom-archive ]
+      tier_boundaries_ms: [ 3600000 ]
+`, recent.URL, archive.URL)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, _, err := config.Load("trickster", "test", []string{"-config", f})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	clients, err := RegisterProxyRoutes(conf, router, http.NewServeMux(), caches,
+		nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hcs := healthcheck.StatusLookup{"prom-recent": &healthcheck.Status{},
+		"prom-archive": &healthcheck.Status{}}
+	if err = alb.StartALBPools(clients, hcs); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Truncate(time.Minute)
+	boundary := now.Add(-time.Hour).Unix()
+	q := url.Values{"query": {"up"}, "step": {"60"},
+		"start": {strconv.FormatInt(now.Add(-3*time.Hour).Unix(), 10)},
+		"end":   {strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)}}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"http://trickster/tiered/api/v1/query_range?"+q.Encode(), nil))
+	b, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d: %s", http.StatusOK, w.Code, string(b))
+	}
+	for _, s := range []string{`"tier":"recent"`, `"tier":"archive"`} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("expected merged response to contain %s: %s", s, string(b))
+		}
+	}
+	if r := ranges["recent"]; !strings.HasPrefix(r, strconv.FormatInt(boundary, 10)+"-") {
+		t.Errorf("expected recent tier to start at %d got %s", boundary, r)
+	}
+	if r := ranges["archive"]; !strings.HasSuffix(r, "-"+strconv.FormatInt(boundary-60, 10)) {
+		t.Errorf("expected archive tier to end at %d got %s", boundary-60, r)
+	}
+}
+
+func TestRegisterProxyRoutesALBReplicaDedup(t *testing.T) {
+
+	newUpstream := func(replica string, skip int64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start, _ := strconv.ParseInt(r.FormValue("start"), 10, 64)
+			end, _ := strconv.ParseInt(r.FormValue("end"), 10, 64)
+			values := make([]string, 0)
+			for ts := start; ts <= end; ts += 60 {
+				if ts == skip {
+					continue
+				}
+				values = append(values, fmt.Sprintf(`[%d,"%s"]`, ts, replica))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[`+
+				`{"metric":{"job":"test","replica":"%s"},"values":[%s]}]}}`,
+				replica, strings.Join(values, ","))
+		}))
+	}
+
+	now := time.Now().Truncate(time.Minute)
+	start, end := now.Add(-time.Hour).Unix(), now.Add(-10*time.Minute).Unix()
+	// replica a is missing the point at start + 5m, which is filled from replica b
+	a, b := newUpstream("a", start+300), newUpstream("b", 0)
+	defer a.Close()
+	defer b.Close()
+
+	f := filepath.Join(t.TempDir(), "trickster.yaml")
+	err := os.WriteFile(f, []byte(fmt.Sprintf(`
+backends:
+  prom-a:
+    provider: prometheus
+    origin_url: %s
+  prom-b:
+    provider: prometheus
+    origin_url: %s
+  ha:
+    provider: alb
+    alb:
+      mechanism: tsm
+      healthy_floor: -1
+      pool: [ prom-a, prom-b ]
+      replica_labels: [ replica ]
+`, a.URL, b.URL)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, _, err := config.Load("trickster", "test", []string{"-config", f})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	clients, err := RegisterProxyRoutes(conf, router, http.NewServeMux(), caches,
+		nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hcs := healthcheck.StatusLookup{"prom-a": &healthcheck.Status{},
+		"prom-b": &healthcheck.Status{}}
+	if err = alb.StartALBPools(clients, hcs); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	q := url.Values{"query": {"up"}, "step": {"60"},
+		"start": {strconv.FormatInt(start, 10)}, "end": {strconv.FormatInt(end, 10)}}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"http://trickster/ha/api/v1/query_range?"+q.Encode(), nil))
+	body, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d: %s", http.StatusOK, w.Code, string(body))
+	}
+	s := string(body)
+	if strings.Count(s, `"metric"`) != 1 || strings.Contains(s, `"replica"`) {
+		t.Errorf("expected a single series without the replica label: %s", s)
+	}
+	for _, v := range []string{fmt.Sprintf(`[%d,"a"]`, start),
+		fmt.Sprintf(`[%d,"b"]`, start+300), fmt.Sprintf(`[%d,"b"]`, end)} {
+		if !strings.Contains(s, v) {
+			t.Errorf("expected deduplicated series to contain %s: %s", v, s)
+		}
+	}
+	if strings.Contains(s, fmt.Sprintf(`[%d,"b"]`, start)) {
+		t.Errorf("expected overlapping points to be taken from a single replica: %s", s)
+	}
+}
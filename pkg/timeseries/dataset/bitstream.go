@@ -0,0 +1,302 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataset
+
+import (
+	"io"
+	"math"
+	"math/bits"
+)
+
+// bitWriter appends individual bits to a byte slice
+type bitWriter struct {
+	stream []byte
+	// count is the number of bits still available in the last byte of the stream
+	count uint8
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.count == 0 {
+		w.stream = append(w.stream, 0)
+		w.count = 8
+	}
+	if bit {
+		w.stream[len(w.stream)-1] |= 1 << (w.count - 1)
+	}
+	w.count--
+}
+
+func (w *bitWriter) writeByte(b byte) {
+	if w.count == 0 {
+		w.stream = append(w.stream, b)
+		return
+	}
+	w.stream[len(w.stream)-1] |= b >> (8 - w.count)
+	w.stream = append(w.stream, b<<w.count)
+}
+
+// writeBits writes the nbits least significant bits of u
+func (w *bitWriter) writeBits(u uint64, nbits int) {
+	u <<= 64 - uint(nbits)
+	for nbits >= 8 {
+		w.writeByte(byte(u >> 56))
+		u <<= 8
+		nbits -= 8
+	}
+	for nbits > 0 {
+		w.writeBit(u>>63 == 1)
+		u <<= 1
+		nbits--
+	}
+}
+
+// bitReader reads individual bits from a byte slice written by a bitWriter
+type bitReader struct {
+	stream []byte
+	pos    int
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	if r.pos>>3 >= len(r.stream) {
+		return false, io.ErrUnexpectedEOF
+	}
+	bit := r.stream[r.pos>>3]&(0x80>>uint(r.pos&7)) != 0
+	r.pos++
+	return bit, nil
+}
+
+// readBits reads nbits into the least significant bits of the returned value
+func (r *bitReader) readBits(nbits int) (uint64, error) {
+	var u uint64
+	for nbits > 0 {
+		i := r.pos >> 3
+		if i >= len(r.stream) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		avail := 8 - r.pos&7
+		take := avail
+		if nbits < take {
+			take = nbits
+		}
+		b := (r.stream[i] >> uint(avail-take)) & byte(1<<uint(take)-1)
+		u = u<<uint(take) | uint64(b)
+		r.pos += take
+		nbits -= take
+	}
+	return u, nil
+}
+
+// delta-of-delta buckets, as bit widths of the two's complement dod value
+var dodBuckets = []struct {
+	prefix, prefixBits, bits int
+}{
+	{0x02, 2, 14}, // 10
+	{0x06, 3, 17}, // 110
+	{0x0e, 4, 20}, // 1110
+}
+
+// fitsBits returns true if x can be represented as a two's complement value of nbits
+func fitsBits(x int64, nbits int) bool {
+	return x >= -(1<<uint(nbits-1)) && x <= 1<<uint(nbits-1)-1
+}
+
+// writeInt64s writes the values using delta-of-delta encoding, so that values at
+// regular intervals, such as the timestamps of a series, use a single bit each
+func writeInt64s(w *bitWriter, vals []int64) {
+	if len(vals) == 0 {
+		return
+	}
+	w.writeBits(uint64(vals[0]), 64)
+	if len(vals) == 1 {
+		return
+	}
+	delta := vals[1] - vals[0]
+	w.writeBits(uint64(delta), 64)
+	for i := 2; i < len(vals); i++ {
+		d := vals[i] - vals[i-1]
+		dod := d - delta
+		delta = d
+		if dod == 0 {
+			w.writeBit(false)
+			continue
+		}
+		written := false
+		for _, bk := range dodBuckets {
+			if fitsBits(dod, bk.bits) {
+				w.writeBits(uint64(bk.prefix), bk.prefixBits)
+				w.writeBits(uint64(dod), bk.bits)
+				written = true
+				break
+			}
+		}
+		if !written {
+			w.writeBits(0x0f, 4) // 1111
+			w.writeBits(uint64(dod), 64)
+		}
+	}
+}
+
+// readInt64s reads n values written by writeInt64s
+func readInt64s(r *bitReader, n int) ([]int64, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	vals := make([]int64, n)
+	u, err := r.readBits(64)
+	if err != nil {
+		return nil, err
+	}
+	vals[0] = int64(u)
+	if n == 1 {
+		return vals, nil
+	}
+	u, err = r.readBits(64)
+	if err != nil {
+		return nil, err
+	}
+	delta := int64(u)
+	vals[1] = vals[0] + delta
+	for i := 2; i < n; i++ {
+		// count the leading 1 bits of the prefix, up to 4
+		var ones int
+		for ones < 4 {
+			bit, err := r.readBit()
+			if err != nil {
+				return nil, err
+			}
+			if !bit {
+				break
+			}
+			ones++
+		}
+		var dod int64
+		switch ones {
+		case 0:
+		case 4:
+			u, err = r.readBits(64)
+			if err != nil {
+				return nil, err
+			}
+			dod = int64(u)
+		default:
+			nbits := dodBuckets[ones-1].bits
+			u, err = r.readBits(nbits)
+			if err != nil {
+				return nil, err
+			}
+			dod = int64(u)
+			// sign extend
+			if u>>uint(nbits-1) == 1 {
+				dod -= 1 << uint(nbits)
+			}
+		}
+		delta += dod
+		vals[i] = vals[i-1] + delta
+	}
+	return vals, nil
+}
+
+// writeFloat64s writes the values using XOR encoding, so that repeated values
+// use a single bit each, and similar values use only their meaningful bits
+func writeFloat64s(w *bitWriter, vals []float64) {
+	if len(vals) == 0 {
+		return
+	}
+	prev := math.Float64bits(vals[0])
+	w.writeBits(prev, 64)
+	leading, trailing := uint8(0xff), uint8(0)
+	for _, f := range vals[1:] {
+		v := math.Float64bits(f)
+		x := v ^ prev
+		prev = v
+		if x == 0 {
+			w.writeBit(false)
+			continue
+		}
+		w.writeBit(true)
+		l := uint8(bits.LeadingZeros64(x))
+		t := uint8(bits.TrailingZeros64(x))
+		if l >= 32 {
+			l = 31
+		}
+		// reuse the previous window of meaningful bits when the value fits inside of it
+		if leading != 0xff && l >= leading && t >= trailing {
+			w.writeBit(false)
+			w.writeBits(x>>trailing, 64-int(leading)-int(trailing))
+			continue
+		}
+		leading, trailing = l, t
+		w.writeBit(true)
+		w.writeBits(uint64(l), 5)
+		sig := 64 - int(l) - int(t)
+		// 64 meaningful bits does not fit into 6 bits, and is written as 0,
+		// which is otherwise impossible since x is not 0
+		w.writeBits(uint64(sig), 6)
+		w.writeBits(x>>t, sig)
+	}
+}
+
+// readFloat64s reads n values written by writeFloat64s
+func readFloat64s(r *bitReader, n int) ([]float64, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	vals := make([]float64, n)
+	prev, err := r.readBits(64)
+	if err != nil {
+		return nil, err
+	}
+	vals[0] = math.Float64frombits(prev)
+	var leading, trailing int
+	for i := 1; i < n; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return nil, err
+		}
+		if bit {
+			bit, err = r.readBit()
+			if err != nil {
+				return nil, err
+			}
+			if bit {
+				l, err := r.readBits(5)
+				if err != nil {
+					return nil, err
+				}
+				sig, err := r.readBits(6)
+				if err != nil {
+					return nil, err
+				}
+				if sig == 0 {
+					sig = 64
+				}
+				leading = int(l)
+				trailing = 64 - leading - int(sig)
+				if trailing < 0 {
+					return nil, errInvalidColumnarData
+				}
+			}
+			u, err := r.readBits(64 - leading - trailing)
+			if err != nil {
+				return nil, err
+			}
+			prev ^= u << uint(trailing)
+		}
+		vals[i] = math.Float64frombits(prev)
+	}
+	return vals, nil
+}
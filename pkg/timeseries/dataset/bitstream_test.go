@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataset
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBitstream(t *testing.T) {
+	w := &bitWriter{}
+	w.writeBit(true)
+	w.writeBits(0x2a, 6)
+	w.writeBits(0xdeadbeef, 32)
+	w.writeBit(false)
+	w.writeBits(math.MaxUint64, 64)
+
+	r := &bitReader{stream: w.stream}
+	if b, err := r.readBit(); err != nil || !b {
+		t.Errorf("expected %t got %t", true, b)
+	}
+	if u, _ := r.readBits(6); u != 0x2a {
+		t.Errorf("expected %d got %d", 0x2a, u)
+	}
+	if u, _ := r.readBits(32); u != 0xdeadbeef {
+		t.Errorf("expected %d got %d", uint64(0xdeadbeef), u)
+	}
+	if b, _ := r.readBit(); b {
+		t.Errorf("expected %t got %t", false, b)
+	}
+	if u, _ := r.readBits(64); u != math.MaxUint64 {
+		t.Errorf("expected %d got %d", uint64(math.MaxUint64), u)
+	}
+	// only the padding bits of the last byte remain
+	if _, err := r.readBits(8); err == nil {
+		t.Error("expected error for read beyond the end of the stream")
+	}
+}
+
+func TestInt64s(t *testing.T) {
+	tests := [][]int64{
+		{},
+		{42},
+		{-5, 5},
+		{0, 15e9, 30e9, 45e9, 60e9, 75e9},
+		{0, 15e9, 30e9 + 1, 45e9 - 5000, 60e9 + 70000, 75e9, 90e9 + 1e9, 1e18, -1e18,
+			math.MaxInt64, math.MinInt64, 0},
+	}
+	for i, vals := range tests {
+		w := &bitWriter{}
+		writeInt64s(w, vals)
+		out, err := readInt64s(&bitReader{stream: w.stream}, len(vals))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != len(vals) {
+			t.Fatalf("test %d expected %d values got %d", i, len(vals), len(out))
+		}
+		for j := range vals {
+			if out[j] != vals[j] {
+				t.Errorf("test %d index %d expected %d got %d", i, j, vals[j], out[j])
+			}
+		}
+	}
+	// regular intervals use a single bit per value after the first two
+	w := &bitWriter{}
+	writeInt64s(w, []int64{0, 15e9, 30e9, 45e9, 60e9, 75e9, 90e9, 105e9, 120e9, 135e9})
+	if len(w.stream) != 17 {
+		t.Errorf("expected %d got %d", 17, len(w.stream))
+	}
+	if _, err := readInt64s(&bitReader{stream: w.stream[:8]}, 10); err == nil {
+		t.Error("expected error for truncated stream")
+	}
+}
+
+func TestFloat64s(t *testing.T) {
+	vals := []float64{1, 1, 1.5, 2.25, -3, 1e300, 1e-300, 0, math.Inf(1), math.Inf(-1),
+		math.NaN(), 12, 12, 12.000001, math.MaxFloat64, math.SmallestNonzeroFloat64}
+	w := &bitWriter{}
+	writeFloat64s(w, vals)
+	out, err := readFloat64s(&bitReader{stream: w.stream}, len(vals))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range vals {
+		if math.Float64bits(out[i]) != math.Float64bits(vals[i]) {
+			t.Errorf("index %d expected %v got %v", i, vals[i], out[i])
+		}
+	}
+	if _, err := readFloat64s(&bitReader{stream: w.stream[:10]}, len(vals)); err == nil {
+		t.Error("expected error for truncated stream")
+	}
+}
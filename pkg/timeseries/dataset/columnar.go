@@ -0,0 +1,503 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataset
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+
+	"github.com/tinylib/msgp/msgp"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/epoch"
+)
+
+// The columnar encoding stores each Series' Points as a set of compressed columns:
+// the timestamps and point sizes are delta-of-delta encoded, and each value field
+// is stored in a column typed by the values it holds. Float64 columns, and String
+// columns whose values are all canonically-formatted numbers (as with Prometheus),
+// are XOR-encoded; Int64 columns are delta-of-delta encoded; Bool columns use one
+// bit per value; and any other column falls back to per-value msgpack encoding.
+// DataSet- and SeriesHeader-level fields are stored with msgpack.
+
+// columnarMagic prefixes a columnar-encoded DataSet. 0xc1 is never used by msgpack,
+// so columnar documents are distinguishable from msgpack-encoded DataSets
+const columnarMagic byte = 0xc1
+
+// columnarVersion is the version of the columnar encoding
+const columnarVersion byte = 1
+
+const (
+	seriesModeColumns byte = iota
+	seriesModeRows
+)
+
+type columnKind byte
+
+const (
+	columnGeneric columnKind = iota
+	columnFloat64
+	columnFloatString
+	columnInt64
+	columnBool
+)
+
+var errInvalidColumnarData = errors.New("invalid columnar dataset data")
+
+// isColumnar returns true if the byte slice is a columnar-encoded DataSet
+func isColumnar(b []byte) bool {
+	return len(b) > 1 && b[0] == columnarMagic
+}
+
+// marshalColumnar encodes the DataSet into the columnar format
+func (ds *DataSet) marshalColumnar() ([]byte, error) {
+	// the msgpack-encoded shell carries all DataSet-level fields except the Results
+	shell := &DataSet{
+		Status:             ds.Status,
+		ExtentList:         ds.ExtentList,
+		Error:              ds.Error,
+		ErrorType:          ds.ErrorType,
+		Warnings:           ds.Warnings,
+		TimeRangeQuery:     ds.TimeRangeQuery,
+		VolatileExtentList: ds.VolatileExtentList,
+	}
+	sb, err := shell.MarshalMsg(nil)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, len(sb)+512)
+	b = append(b, columnarMagic, columnarVersion)
+	b = appendBytes(b, sb)
+	b = appendCount(b, len(ds.Results), ds.Results == nil)
+	for _, r := range ds.Results {
+		if r == nil {
+			b = append(b, 0)
+			continue
+		}
+		b = append(b, 1)
+		b = appendVarint(b, int64(r.StatementID))
+		b = appendBytes(b, []byte(r.Error))
+		b = appendCount(b, len(r.SeriesList), r.SeriesList == nil)
+		for _, s := range r.SeriesList {
+			if s == nil {
+				b = append(b, 0)
+				continue
+			}
+			b = append(b, 1)
+			b, err = s.appendColumnar(b)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+func (s *Series) appendColumnar(b []byte) ([]byte, error) {
+	hb, err := s.Header.MarshalMsg(nil)
+	if err != nil {
+		return nil, err
+	}
+	b = appendBytes(b, hb)
+	b = appendVarint(b, s.PointSize)
+	b = appendCount(b, len(s.Points), s.Points == nil)
+	if len(s.Points) == 0 {
+		return b, nil
+	}
+
+	epochs := make([]int64, len(s.Points))
+	sizes := make([]int64, len(s.Points))
+	for i, p := range s.Points {
+		epochs[i] = int64(p.Epoch)
+		sizes[i] = int64(p.Size)
+	}
+	w := &bitWriter{}
+	writeInt64s(w, epochs)
+	b = appendBytes(b, w.stream)
+	w = &bitWriter{}
+	writeInt64s(w, sizes)
+	b = appendBytes(b, w.stream)
+
+	width := len(s.Points[0].Values)
+	for _, p := range s.Points {
+		if len(p.Values) != width || p.Values == nil {
+			// points with differing value counts can't be split into columns
+			b = append(b, seriesModeRows)
+			rb := make([]byte, 0, len(s.Points)*16)
+			for _, p := range s.Points {
+				rb = appendCount(rb, len(p.Values), p.Values == nil)
+				for _, v := range p.Values {
+					rb, err = msgp.AppendIntf(rb, v)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+			return appendBytes(b, rb), nil
+		}
+	}
+
+	b = append(b, seriesModeColumns)
+	b = appendUvarint(b, uint64(width))
+	for j := 0; j < width; j++ {
+		kind := s.Points.columnKind(j)
+		b = append(b, byte(kind))
+		var cb []byte
+		switch kind {
+		case columnFloat64, columnFloatString:
+			vals := make([]float64, len(s.Points))
+			for i, p := range s.Points {
+				if kind == columnFloat64 {
+					vals[i] = p.Values[j].(float64)
+				} else {
+					// columnKind has already verified that the value parses
+					vals[i], _ = strconv.ParseFloat(p.Values[j].(string), 64)
+				}
+			}
+			w := &bitWriter{}
+			writeFloat64s(w, vals)
+			cb = w.stream
+		case columnInt64:
+			vals := make([]int64, len(s.Points))
+			for i, p := range s.Points {
+				vals[i] = p.Values[j].(int64)
+			}
+			w := &bitWriter{}
+			writeInt64s(w, vals)
+			cb = w.stream
+		case columnBool:
+			w := &bitWriter{}
+			for _, p := range s.Points {
+				w.writeBit(p.Values[j].(bool))
+			}
+			cb = w.stream
+		default:
+			cb = make([]byte, 0, len(s.Points)*8)
+			for _, p := range s.Points {
+				cb, err = msgp.AppendIntf(cb, p.Values[j])
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		b = appendBytes(b, cb)
+	}
+	return b, nil
+}
+
+// columnKind returns the kind of column that can losslessly hold the values
+// found at index j of each Point
+func (p Points) columnKind(j int) columnKind {
+	var kind columnKind
+	for i, pt := range p {
+		var k columnKind
+		switch v := pt.Values[j].(type) {
+		case float64:
+			k = columnFloat64
+		case int64:
+			k = columnInt64
+		case bool:
+			k = columnBool
+		case string:
+			if !isCanonicalFloat(v) {
+				return columnGeneric
+			}
+			k = columnFloatString
+		default:
+			return columnGeneric
+		}
+		if i == 0 {
+			kind = k
+		} else if k != kind {
+			return columnGeneric
+		}
+	}
+	return kind
+}
+
+// isCanonicalFloat returns true if s is a number that is reproduced exactly by
+// formatFloat, so that it can be stored as a float64 without loss
+func isCanonicalFloat(s string) bool {
+	f, err := strconv.ParseFloat(s, 64)
+	return err == nil && formatFloat(f) == s
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// unmarshalColumnar decodes a columnar-encoded DataSet
+func unmarshalColumnar(b []byte) (*DataSet, error) {
+	if !isColumnar(b) {
+		return nil, errInvalidColumnarData
+	}
+	if b[1] != columnarVersion {
+		return nil, errInvalidColumnarData
+	}
+	r := &byteReader{b: b[2:]}
+	ds := &DataSet{}
+	if _, err := ds.UnmarshalMsg(r.bytes()); r.err == nil && err != nil {
+		return nil, err
+	}
+	n, isNil := r.count()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if !isNil {
+		ds.Results = make([]*Result, n)
+	}
+	for i := range ds.Results {
+		if r.byte() != 1 {
+			continue
+		}
+		res := &Result{
+			StatementID: int(r.varint()),
+			Error:       string(r.bytes()),
+		}
+		n, isNil = r.count()
+		if r.err != nil {
+			return nil, r.err
+		}
+		if !isNil {
+			res.SeriesList = make([]*Series, n)
+		}
+		for k := range res.SeriesList {
+			if r.byte() != 1 {
+				continue
+			}
+			s, err := r.series()
+			if err != nil {
+				return nil, err
+			}
+			res.SeriesList[k] = s
+		}
+		ds.Results[i] = res
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return ds, nil
+}
+
+func (r *byteReader) series() (*Series, error) {
+	s := &Series{}
+	if _, err := s.Header.UnmarshalMsg(r.bytes()); r.err == nil && err != nil {
+		return nil, err
+	}
+	s.PointSize = r.varint()
+	n, isNil := r.count()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if isNil {
+		return s, nil
+	}
+	s.Points = make(Points, n)
+	if n == 0 {
+		return s, nil
+	}
+
+	epochs, err := readInt64s(&bitReader{stream: r.bytes()}, n)
+	if err != nil {
+		return nil, err
+	}
+	sizes, err := readInt64s(&bitReader{stream: r.bytes()}, n)
+	if err != nil {
+		return nil, err
+	}
+	for i := range s.Points {
+		s.Points[i].Epoch = epoch.Epoch(epochs[i])
+		s.Points[i].Size = int(sizes[i])
+	}
+
+	switch r.byte() {
+	case seriesModeRows:
+		rr := &byteReader{b: r.bytes()}
+		for i := range s.Points {
+			c, isNil := rr.count()
+			if rr.err != nil {
+				return nil, rr.err
+			}
+			if isNil {
+				continue
+			}
+			s.Points[i].Values = make([]interface{}, c)
+			for j := range s.Points[i].Values {
+				s.Points[i].Values[j], rr.b, err = msgp.ReadIntfBytes(rr.b)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	case seriesModeColumns:
+		width := int(r.uvarint())
+		if r.err != nil {
+			return nil, r.err
+		}
+		if width > len(r.b) {
+			return nil, errInvalidColumnarData
+		}
+		for i := range s.Points {
+			s.Points[i].Values = make([]interface{}, width)
+		}
+		for j := 0; j < width; j++ {
+			kind := columnKind(r.byte())
+			cb := r.bytes()
+			if r.err != nil {
+				return nil, r.err
+			}
+			switch kind {
+			case columnFloat64, columnFloatString:
+				vals, err := readFloat64s(&bitReader{stream: cb}, n)
+				if err != nil {
+					return nil, err
+				}
+				for i, v := range vals {
+					if kind == columnFloat64 {
+						s.Points[i].Values[j] = v
+					} else {
+						s.Points[i].Values[j] = formatFloat(v)
+					}
+				}
+			case columnInt64:
+				vals, err := readInt64s(&bitReader{stream: cb}, n)
+				if err != nil {
+					return nil, err
+				}
+				for i, v := range vals {
+					s.Points[i].Values[j] = v
+				}
+			case columnBool:
+				br := &bitReader{stream: cb}
+				for i := range s.Points {
+					v, err := br.readBit()
+					if err != nil {
+						return nil, err
+					}
+					s.Points[i].Values[j] = v
+				}
+			case columnGeneric:
+				for i := range s.Points {
+					s.Points[i].Values[j], cb, err = msgp.ReadIntfBytes(cb)
+					if err != nil {
+						return nil, err
+					}
+				}
+			default:
+				return nil, errInvalidColumnarData
+			}
+		}
+	default:
+		return nil, errInvalidColumnarData
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return s, nil
+}
+
+// appendBytes appends the length-prefixed byte slice
+func appendBytes(b, v []byte) []byte {
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+// appendCount appends the length of a slice, preserving the distinction
+// between nil and empty slices
+func appendCount(b []byte, n int, isNil bool) []byte {
+	if isNil {
+		return appendUvarint(b, 0)
+	}
+	return appendUvarint(b, uint64(n)+1)
+}
+
+// byteReader reads values written by the columnar encoder, retaining the first error
+type byteReader struct {
+	b   []byte
+	err error
+}
+
+func (r *byteReader) fail() {
+	if r.err == nil {
+		r.err = errInvalidColumnarData
+	}
+	r.b = nil
+}
+
+func (r *byteReader) byte() byte {
+	if len(r.b) == 0 {
+		r.fail()
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *byteReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *byteReader) varint() int64 {
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *byteReader) bytes() []byte {
+	l := r.uvarint()
+	if l > uint64(len(r.b)) {
+		r.fail()
+		return nil
+	}
+	v := r.b[:l]
+	r.b = r.b[l:]
+	return v
+}
+
+// count reads a value written by appendCount. Since every counted item occupies
+// at least one byte, counts larger than the remaining data are rejected
+func (r *byteReader) count() (int, bool) {
+	v := r.uvarint()
+	if v == 0 {
+		return 0, true
+	}
+	if v-1 > uint64(len(r.b)) {
+		r.fail()
+		return 0, false
+	}
+	return int(v - 1), false
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataset
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/epoch"
+)
+
+func testColumnarDataSet() *DataSet {
+	newSeries := func(name string, pts Points) *Series {
+		sh := testSeriesHeader()
+		sh.Name = name
+		return &Series{Header: sh, Points: pts, PointSize: pts.Size()}
+	}
+	var prom, typed, mixed, ragged Points
+	for i := 0; i < 100; i++ {
+		e := epoch.Epoch(int64(i) * 15 * timeseries.Second)
+		v := strconv.FormatFloat(float64(i%7)*1.25, 'f', -1, 64)
+		prom = append(prom, Point{Epoch: e, Size: len(v) + 32,
+			Values: []interface{}{v}})
+		typed = append(typed, Point{Epoch: e + epoch.Epoch(i%3), Size: 48,
+			Values: []interface{}{float64(i) / 3, int64(i * i), i%2 == 0, "host-a"}})
+		mixed = append(mixed, Point{Epoch: e, Size: 40,
+			Values: []interface{}{"1e+06", int64(i), nil, float64(i)}})
+		ragged = append(ragged, Point{Epoch: e, Size: 24,
+			Values: make([]interface{}, i%3)})
+	}
+	mixed[50].Values[1] = float64(50)
+	mixed[60].Values[3] = "NaN"
+	for i := range ragged {
+		for j := range ragged[i].Values {
+			ragged[i].Values[j] = int64(j)
+		}
+	}
+	ragged[10].Values = nil
+
+	return &DataSet{
+		Status:     "success",
+		ExtentList: timeseries.ExtentList{timeseries.Extent{Start: time.Unix(0, 0), End: time.Unix(1485, 0)}},
+		Warnings:   []string{"test warning"},
+		TimeRangeQuery: &timeseries.TimeRangeQuery{Step: 15 * time.Second,
+			Statement: "up"},
+		Results: []*Result{
+			{
+				StatementID: 1,
+				SeriesList: []*Series{
+					newSeries("prom", prom),
+					newSeries("typed", typed),
+					newSeries("mixed", mixed),
+					newSeries("ragged", ragged),
+					newSeries("empty", Points{}),
+					newSeries("nil", nil),
+					nil,
+				},
+			},
+			{StatementID: 2, Error: "test error"},
+			nil,
+		},
+	}
+}
+
+func TestMarshalDataSetColumnar(t *testing.T) {
+	ds := testColumnarDataSet()
+	b, err := MarshalDataSet(ds, nil, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isColumnar(b) {
+		t.Fatal("expected columnar encoding")
+	}
+	mb, _ := ds.MarshalMsg(nil)
+	if len(b) >= len(mb) {
+		t.Errorf("expected columnar size %d to be less than msgpack size %d", len(b), len(mb))
+	}
+
+	ts, err := UnmarshalDataSet(b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds2 := ts.(*DataSet)
+	if ds2.Status != ds.Status || ds2.Warnings[0] != ds.Warnings[0] ||
+		len(ds2.ExtentList) != 1 || !ds2.ExtentList[0].End.Equal(ds.ExtentList[0].End) {
+		t.Error("mismatched dataset fields")
+	}
+	if ds2.TimeRangeQuery == nil || ds2.TimeRangeQuery.Step != ds.TimeRangeQuery.Step ||
+		ds2.TimeRangeQuery.Statement != "up" {
+		t.Error("mismatched time range query")
+	}
+	if len(ds2.Results) != 3 || ds2.Results[2] != nil || ds2.Results[1].Error != "test error" ||
+		ds2.Results[1].SeriesList != nil {
+		t.Fatal("mismatched results")
+	}
+	sl1, sl2 := ds.Results[0].SeriesList, ds2.Results[0].SeriesList
+	if len(sl2) != len(sl1) || sl2[len(sl2)-1] != nil {
+		t.Fatal("mismatched series list")
+	}
+	for i, s := range sl1[:len(sl1)-1] {
+		s2 := sl2[i]
+		if s2.Header.Name != s.Header.Name || s2.PointSize != s.PointSize ||
+			!reflect.DeepEqual(s2.Header.FieldsList, s.Header.FieldsList) {
+			t.Errorf("mismatched series header for %s", s.Header.Name)
+		}
+		if !reflect.DeepEqual(s2.Points, s.Points) {
+			t.Errorf("mismatched points for %s", s.Header.Name)
+		}
+	}
+}
+
+func TestUnmarshalDataSetMsgpack(t *testing.T) {
+	// datasets cached in the msgpack format remain readable
+	ds := testColumnarDataSet()
+	ds.TimeRangeQuery.StepNS = ds.TimeRangeQuery.Step.Nanoseconds()
+	b, err := ds.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := UnmarshalDataSet(b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds2 := ts.(*DataSet)
+	if ds2.TimeRangeQuery.Step != ds.TimeRangeQuery.Step {
+		t.Errorf("expected %s got %s", ds.TimeRangeQuery.Step, ds2.TimeRangeQuery.Step)
+	}
+	if !reflect.DeepEqual(ds2.Results[0].SeriesList[1].Points, ds.Results[0].SeriesList[1].Points) {
+		t.Error("mismatched points")
+	}
+}
+
+func TestUnmarshalColumnarInvalid(t *testing.T) {
+	b, err := MarshalDataSet(testColumnarDataSet(), nil, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []int{2, 3, 20, len(b) / 2, len(b) - 1} {
+		if _, err := UnmarshalDataSet(b[:l], nil); err == nil {
+			t.Errorf("expected error for data truncated to %d bytes", l)
+		}
+	}
+	b[1] = 0xff
+	if _, err := UnmarshalDataSet(b, nil); err != errInvalidColumnarData {
+		t.Errorf("expected %v got %v", errInvalidColumnarData, err)
+	}
+}
+
+func BenchmarkMarshalColumnar(b *testing.B) {
+	ds := testColumnarDataSet()
+	for i := 0; i < b.N; i++ {
+		MarshalDataSet(ds, nil, 200)
+	}
+}
+
+func BenchmarkUnmarshalColumnar(b *testing.B) {
+	data, _ := MarshalDataSet(testColumnarDataSet(), nil, 200)
+	for i := 0; i < b.N; i++ {
+		UnmarshalDataSet(data, nil)
+	}
+}
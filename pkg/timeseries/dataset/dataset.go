@@ -537,10 +537,19 @@ func (ds *DataSet) Sort() {
 	}
 }
 
-// UnmarshalDataSet unmarshals the dataset from a msgpack-formatted byte slice
+// UnmarshalDataSet unmarshals the dataset from a columnar- or msgpack-formatted byte slice
 func UnmarshalDataSet(b []byte, trq *timeseries.TimeRangeQuery) (timeseries.Timeseries, error) {
-	ds := &DataSet{}
-	_, err := ds.UnmarshalMsg(b)
+	var ds *DataSet
+	var err error
+	if isColumnar(b) {
+		ds, err = unmarshalColumnar(b)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ds = &DataSet{}
+		_, err = ds.UnmarshalMsg(b)
+	}
 	if err == nil {
 		if ds.TimeRangeQuery != nil {
 			ds.TimeRangeQuery.Step = time.Duration(ds.TimeRangeQuery.StepNS)
@@ -551,7 +560,7 @@ func UnmarshalDataSet(b []byte, trq *timeseries.TimeRangeQuery) (timeseries.Time
 	return ds, err
 }
 
-// MarshalDataSet marshals the dataset into a msgpack-formatted byte slice
+// MarshalDataSet marshals the dataset into a columnar-formatted byte slice
 func MarshalDataSet(ts timeseries.Timeseries, rlo *timeseries.RequestOptions, status int) ([]byte, error) {
 	ds, ok := ts.(*DataSet)
 	if !ok {
@@ -560,7 +569,7 @@ func MarshalDataSet(ts timeseries.Timeseries, rlo *timeseries.RequestOptions, st
 	if ds.TimeRangeQuery != nil {
 		ds.TimeRangeQuery.StepNS = ds.TimeRangeQuery.Step.Nanoseconds()
 	}
-	return ds.MarshalMsg(nil)
+	return ds.marshalColumnar()
 }
 
 // VolatileExtents returns the list of time Extents in the dataset that should be re-fetched
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataset
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries/epoch"
+)
+
+// Deduplicate collapses the Series in each Result that are identical apart from the provided
+// replica tags into a single Series, without those tags. The collapsed Series takes its Points
+// from one replica at a time: the current replica is used as long as its next Point is no more
+// than the penalty after the earliest next Point among all of the replicas. Otherwise, the
+// replica with the earliest next Point is used from that timestamp on, which fills the gaps in
+// one replica's Series from the others without interleaving their Points
+func (ds *DataSet) Deduplicate(replicaTags []string, penalty time.Duration) {
+	if len(replicaTags) == 0 {
+		return
+	}
+	ds.UpdateLock.Lock()
+	defer ds.UpdateLock.Unlock()
+	for _, r := range ds.Results {
+		if r == nil || len(r.SeriesList) == 0 {
+			continue
+		}
+		groups := make(map[Hash]int)
+		replicas := make([][]*Series, 0, len(r.SeriesList))
+		for _, s := range r.SeriesList {
+			if s == nil {
+				continue
+			}
+			h := withoutTags(s.Header, replicaTags)
+			k := h.CalculateHash()
+			i, ok := groups[k]
+			if !ok {
+				i = len(replicas)
+				groups[k] = i
+				replicas = append(replicas, nil)
+			}
+			replicas[i] = append(replicas[i], &Series{Header: h, Points: s.Points})
+		}
+		sl := make(SeriesList, len(replicas))
+		for i, rs := range replicas {
+			s := rs[0]
+			if len(rs) > 1 {
+				points := make([]Points, len(rs))
+				for j := range rs {
+					points[j] = rs[j].Points
+				}
+				s.Points = dedupPoints(points, epoch.Epoch(penalty.Nanoseconds()))
+			}
+			s.PointSize = s.Points.Size()
+			sl[i] = s
+		}
+		r.SeriesList = sl
+	}
+}
+
+// withoutTags returns a copy of the SeriesHeader with the provided tags removed
+func withoutTags(sh SeriesHeader, tags []string) SeriesHeader {
+	var found bool
+	for _, t := range tags {
+		if _, ok := sh.Tags[t]; ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return sh
+	}
+	h := sh.Clone()
+	for _, t := range tags {
+		delete(h.Tags, t)
+	}
+	h.Size = h.CalculateSize()
+	return h
+}
+
+// dedupPoints returns a single list of Points from the provided replicas' lists of Points,
+// using the penalty to decide when to switch replicas
+func dedupPoints(replicas []Points, penalty epoch.Epoch) Points {
+	var n int
+	for i, p := range replicas {
+		if len(p) > n {
+			n = len(p)
+		}
+		if !sort.IsSorted(p) {
+			p = p.Clone()
+			sort.Sort(p)
+			replicas[i] = p
+		}
+	}
+	out := make(Points, 0, n)
+	pos := make([]int, len(replicas))
+	cur := -1
+	for {
+		// find the replica with the earliest next Point
+		next := -1
+		for i, p := range replicas {
+			if pos[i] < len(p) && (next < 0 || p[pos[i]].Epoch < replicas[next][pos[next]].Epoch) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+		earliest := replicas[next][pos[next]].Epoch
+		if cur < 0 || pos[cur] >= len(replicas[cur]) ||
+			replicas[cur][pos[cur]].Epoch > earliest+penalty {
+			cur = next
+		}
+		pt := replicas[cur][pos[cur]]
+		out = append(out, pt)
+		// skip the other replicas' Points that are covered by the chosen Point
+		for i, p := range replicas {
+			for pos[i] < len(p) && p[pos[i]].Epoch <= pt.Epoch {
+				pos[i]++
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataset
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/epoch"
+)
+
+// testReplicaSeries returns a Series with the provided replica tag and a Point with the
+// value v at each of the provided times, in seconds
+func testReplicaSeries(replica, v string, times ...int64) *Series {
+	s := &Series{Header: SeriesHeader{Name: "up",
+		Tags: Tags{"job": "test", "replica": replica}}}
+	for _, t := range times {
+		s.Points = append(s.Points, Point{Epoch: epoch.Epoch(t * timeseries.Second),
+			Size: 32, Values: []interface{}{v}})
+	}
+	return s
+}
+
+func TestDeduplicate(t *testing.T) {
+
+	ds := &DataSet{Results: []*Result{{SeriesList: []*Series{
+		// replica a is missing 120 and 180
+		testReplicaSeries("a", "a", 0, 60, 240),
+		testReplicaSeries("b", "b", 0, 60, 120, 180, 240, 300),
+		// a series without replicas is not collapsed, but loses the replica tag
+		{Header: SeriesHeader{Name: "other", Tags: Tags{"replica": "a"}},
+			Points: Points{{Epoch: 0, Values: []interface{}{"c"}}}},
+	}}}}
+
+	ds.Deduplicate(nil, 0)
+	if len(ds.Results[0].SeriesList) != 3 {
+		t.Fatal("expected no deduplication without replica tags")
+	}
+
+	ds.Deduplicate([]string{"replica", "prometheus_replica"}, 5*time.Second)
+	sl := ds.Results[0].SeriesList
+	if len(sl) != 2 {
+		t.Fatalf("expected %d got %d", 2, len(sl))
+	}
+	if _, ok := sl[0].Header.Tags["replica"]; ok || sl[0].Header.Tags["job"] != "test" {
+		t.Errorf("unexpected tags %v", sl[0].Header.Tags)
+	}
+	if _, ok := sl[1].Header.Tags["replica"]; ok || sl[1].Header.Name != "other" {
+		t.Errorf("unexpected header %v", sl[1].Header)
+	}
+
+	// points are taken from a until its gap, and then from b
+	expected := []string{"a", "a", "b", "b", "b", "b"}
+	if len(sl[0].Points) != len(expected) {
+		t.Fatalf("expected %d got %d", len(expected), len(sl[0].Points))
+	}
+	for i, p := range sl[0].Points {
+		e := epoch.Epoch(int64(i) * 60 * timeseries.Second)
+		if p.Epoch != e || p.Values[0] != expected[i] {
+			t.Errorf("unexpected point %d: %d %v", i, p.Epoch, p.Values)
+		}
+	}
+	if sl[0].PointSize != sl[0].Points.Size() {
+		t.Errorf("expected %d got %d", sl[0].Points.Size(), sl[0].PointSize)
+	}
+}
+
+func TestDedupPoints(t *testing.T) {
+
+	pts := func(s *Series) Points { return s.Points }
+
+	tests := []struct {
+		replicas []Points
+		penalty  time.Duration
+		expected []string
+	}{
+		// identical replicas use only the first
+		{[]Points{pts(testReplicaSeries("a", "a", 0, 60)), pts(testReplicaSeries("b", "b", 0, 60))},
+			0, []string{"a:0", "a:60"}},
+		// the replica with the earliest point is used first
+		{[]Points{pts(testReplicaSeries("a", "a", 60, 120)), pts(testReplicaSeries("b", "b", 0, 60))},
+			0, []string{"b:0", "b:60", "a:120"}},
+		// timestamps within the penalty of the earliest point stay on the current replica
+		{[]Points{pts(testReplicaSeries("a", "a", 0, 62, 120)),
+			pts(testReplicaSeries("b", "b", 0, 60, 120))},
+			5 * time.Second, []string{"a:0", "a:62", "a:120"}},
+		// without a penalty, every earlier point switches replicas
+		{[]Points{pts(testReplicaSeries("a", "a", 0, 62, 120)),
+			pts(testReplicaSeries("b", "b", 0, 60, 120))},
+			0, []string{"a:0", "b:60", "a:62", "a:120"}},
+		// unsorted points are sorted before deduplication
+		{[]Points{pts(testReplicaSeries("a", "a", 120, 0, 60)), pts(testReplicaSeries("b", "b", 180))},
+			0, []string{"a:0", "a:60", "a:120", "b:180"}},
+		{nil, 0, []string{}},
+	}
+
+	for i, test := range tests {
+		out := dedupPoints(test.replicas, epoch.Epoch(test.penalty.Nanoseconds()))
+		if len(out) != len(test.expected) {
+			t.Errorf("test %d: expected %d got %d", i, len(test.expected), len(out))
+			continue
+		}
+		for j, p := range out {
+			s := p.Values[0].(string) + ":" + strconv.FormatInt(int64(p.Epoch)/timeseries.Second, 10)
+			if s != test.expected[j] {
+				t.Errorf("test %d: expected %s got %s", i, test.expected[j], s)
+			}
+		}
+	}
+}
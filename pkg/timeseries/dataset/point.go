@@ -19,9 +19,6 @@
 package dataset
 
 import (
-	"sync"
-	"sync/atomic"
-
 	"github.com/tricksterproxy/trickster/pkg/timeseries/epoch"
 )
 
@@ -51,15 +48,9 @@ func (p *Point) Clone() Point {
 // Size returns the memory utilization of the Points in bytes
 func (p Points) Size() int64 {
 	var c int64 = 16
-	var wg sync.WaitGroup
-	for i, pt := range p {
-		wg.Add(1)
-		go func(s, e int64, j int) {
-			atomic.AddInt64(&c, s)
-			wg.Done()
-		}(int64(pt.Size), int64(pt.Epoch), i)
+	for _, pt := range p {
+		c += int64(pt.Size)
 	}
-	wg.Wait()
 	return c
 }
 
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataset
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/epoch"
+)
+
+// Resample returns a copy of the DataSet at the provided step, which must be a multiple
+// of the DataSet's step. With AggregationSample, only the Points whose timestamps fall on
+// the new step are kept. Otherwise, the Points in each bucket of time between a timestamp
+// at the new step and the next are combined into a single Point using the Aggregation.
+// Only the time ranges that are fully represented at the new step remain in the Extents.
+func (ds *DataSet) Resample(step time.Duration, agg timeseries.Aggregation) timeseries.Timeseries {
+	ds.UpdateLock.Lock()
+	defer ds.UpdateLock.Unlock()
+
+	from := ds.Step()
+	buckets := agg != timeseries.AggregationSample
+	out := &DataSet{
+		Status:     ds.Status,
+		Error:      ds.Error,
+		ErrorType:  ds.ErrorType,
+		ExtentList: ds.ExtentList.Resample(from, step, buckets),
+		Results:    make([]*Result, len(ds.Results)),
+	}
+	if ds.Warnings != nil {
+		out.Warnings = make([]string, len(ds.Warnings))
+		copy(out.Warnings, ds.Warnings)
+	}
+	if ds.TimeRangeQuery != nil {
+		out.TimeRangeQuery = ds.TimeRangeQuery.Clone()
+		out.TimeRangeQuery.Step = step
+		out.TimeRangeQuery.StepNS = step.Nanoseconds()
+	}
+	// any timestamp at the new step that covers a volatile timestamp is itself volatile
+	if len(ds.VolatileExtentList) > 0 {
+		out.VolatileExtentList = make(timeseries.ExtentList, len(ds.VolatileExtentList))
+		for i, e := range ds.VolatileExtentList {
+			out.VolatileExtentList[i] = timeseries.Extent{Start: e.Start.Truncate(step),
+				End: e.End.Truncate(step)}
+		}
+		out.VolatileExtentList = out.VolatileExtentList.Compress(step)
+	}
+
+	for i, r := range ds.Results {
+		if r == nil {
+			continue
+		}
+		out.Results[i] = &Result{
+			StatementID: r.StatementID,
+			Error:       r.Error,
+			SeriesList:  make([]*Series, 0, len(r.SeriesList)),
+		}
+		for _, s := range r.SeriesList {
+			if s == nil {
+				continue
+			}
+			pts := s.Points.resample(step, agg, out.ExtentList)
+			out.Results[i].SeriesList = append(out.Results[i].SeriesList, &Series{
+				Header:    s.Header.Clone(),
+				Points:    pts,
+				PointSize: pts.Size(),
+			})
+		}
+	}
+	return out
+}
+
+// resample returns the chronologically-sorted Points at the provided step, excluding
+// any timestamps at the new step that are not included in the ExtentList
+func (p Points) resample(step time.Duration, agg timeseries.Aggregation,
+	el timeseries.ExtentList) Points {
+	out := make(Points, 0, len(p))
+	var bucket time.Time
+	var pt Point
+	n := 0
+	flush := func() {
+		if n > 0 && extentsInclude(el, bucket) {
+			out = append(out, pt)
+		}
+		n = 0
+	}
+	for _, v := range p {
+		t := time.Unix(0, int64(v.Epoch))
+		b := t.Truncate(step)
+		if agg == timeseries.AggregationSample {
+			if b.Equal(t) && extentsInclude(el, b) {
+				out = append(out, v.Clone())
+			}
+			continue
+		}
+		if n == 0 || !b.Equal(bucket) {
+			flush()
+			bucket = b
+			pt = v.Clone()
+			pt.Epoch = epoch.Epoch(b.UnixNano())
+			n = 1
+			continue
+		}
+		n++
+		switch agg {
+		case timeseries.AggregationFirst:
+		case timeseries.AggregationLast:
+			pt.Values = v.Clone().Values
+			pt.Size = v.Size
+		default:
+			for j := range pt.Values {
+				if j < len(v.Values) {
+					pt.Values[j] = aggregateValue(agg, pt.Values[j], v.Values[j])
+				}
+			}
+		}
+	}
+	flush()
+	return out
+}
+
+func extentsInclude(el timeseries.ExtentList, t time.Time) bool {
+	for i := range el {
+		if el[i].Includes(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregateValue combines two values of a Point using the Aggregation. Numeric values, and
+// strings holding numbers, are summed or compared; other values keep the latest value
+func aggregateValue(agg timeseries.Aggregation, a, b interface{}) interface{} {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return aggregateFloat(agg, x, y)
+		}
+	case int64:
+		if y, ok := b.(int64); ok {
+			return aggregateInt(agg, x, y)
+		}
+	case int:
+		if y, ok := b.(int); ok {
+			return int(aggregateInt(agg, int64(x), int64(y)))
+		}
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			break
+		}
+		// integer strings are combined as integers to retain their precision
+		if i1, err := strconv.ParseInt(x, 10, 64); err == nil {
+			if i2, err := strconv.ParseInt(y, 10, 64); err == nil {
+				return strconv.FormatInt(aggregateInt(agg, i1, i2), 10)
+			}
+		}
+		f1, err := strconv.ParseFloat(x, 64)
+		if err != nil {
+			break
+		}
+		f2, err := strconv.ParseFloat(y, 64)
+		if err != nil {
+			break
+		}
+		return formatFloat(aggregateFloat(agg, f1, f2))
+	}
+	return b
+}
+
+func aggregateFloat(agg timeseries.Aggregation, a, b float64) float64 {
+	switch agg {
+	case timeseries.AggregationSum:
+		return a + b
+	case timeseries.AggregationMin:
+		if b < a {
+			return b
+		}
+		return a
+	case timeseries.AggregationMax:
+		if b > a {
+			return b
+		}
+		return a
+	}
+	return b
+}
+
+func aggregateInt(agg timeseries.Aggregation, a, b int64) int64 {
+	switch agg {
+	case timeseries.AggregationSum:
+		return a + b
+	case timeseries.AggregationMin:
+		if b < a {
+			return b
+		}
+		return a
+	case timeseries.AggregationMax:
+		if b > a {
+			return b
+		}
+		return a
+	}
+	return b
+}
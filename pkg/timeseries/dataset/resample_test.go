@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataset
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/epoch"
+)
+
+func testResampleDataSet() *DataSet {
+	var pts Points
+	// 15s points from 30s through 315s, so the first and last 60s buckets are incomplete
+	for i := 2; i < 22; i++ {
+		pts = append(pts, Point{
+			Epoch:  epoch.Epoch(int64(i) * 15 * timeseries.Second),
+			Size:   32,
+			Values: []interface{}{strconv.Itoa(i), float64(i), "label"},
+		})
+	}
+	return &DataSet{
+		Status: "success",
+		ExtentList: timeseries.ExtentList{timeseries.Extent{Start: time.Unix(30, 0),
+			End: time.Unix(315, 0)}},
+		VolatileExtentList: timeseries.ExtentList{timeseries.Extent{Start: time.Unix(300, 0),
+			End: time.Unix(315, 0)}},
+		TimeRangeQuery: &timeseries.TimeRangeQuery{Step: 15 * time.Second},
+		Results:        []*Result{{SeriesList: []*Series{{Header: testSeriesHeader(), Points: pts}}}},
+	}
+}
+
+func TestResample(t *testing.T) {
+
+	ds := testResampleDataSet()
+	step := 60 * time.Second
+
+	rs := ds.Resample(step, timeseries.AggregationSample).(*DataSet)
+	if rs.Step() != step {
+		t.Errorf("expected %s got %s", step, rs.Step())
+	}
+	expected := timeseries.ExtentList{timeseries.Extent{Start: time.Unix(60, 0), End: time.Unix(300, 0)}}
+	if !rs.ExtentList.Equal(expected) {
+		t.Errorf("expected %s got %s", expected, rs.ExtentList)
+	}
+	pts := rs.Results[0].SeriesList[0].Points
+	if len(pts) != 5 {
+		t.Fatalf("expected %d got %d", 5, len(pts))
+	}
+	for i, p := range pts {
+		e := epoch.Epoch(int64(i+1) * 60 * timeseries.Second)
+		if p.Epoch != e || p.Values[0] != strconv.Itoa((i+1)*4) {
+			t.Errorf("unexpected point %d: %d %v", i, p.Epoch, p.Values)
+		}
+	}
+	expected = timeseries.ExtentList{timeseries.Extent{Start: time.Unix(300, 0), End: time.Unix(300, 0)}}
+	if !rs.VolatileExtentList.Equal(expected) {
+		t.Errorf("expected %s got %s", expected, rs.VolatileExtentList)
+	}
+	// the source is not modified
+	if len(ds.Results[0].SeriesList[0].Points) != 20 || ds.Step() != 15*time.Second {
+		t.Error("source dataset was modified")
+	}
+
+	tests := []struct {
+		agg      timeseries.Aggregation
+		expected func(k int) (string, float64)
+	}{
+		{timeseries.AggregationSum, func(k int) (string, float64) {
+			return strconv.Itoa(16*k + 6), float64(16*k + 6)
+		}},
+		{timeseries.AggregationMin, func(k int) (string, float64) {
+			return strconv.Itoa(4 * k), float64(4 * k)
+		}},
+		{timeseries.AggregationMax, func(k int) (string, float64) {
+			return strconv.Itoa(4*k + 3), float64(4*k + 3)
+		}},
+		{timeseries.AggregationFirst, func(k int) (string, float64) {
+			return strconv.Itoa(4 * k), float64(4 * k)
+		}},
+		{timeseries.AggregationLast, func(k int) (string, float64) {
+			return strconv.Itoa(4*k + 3), float64(4*k + 3)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.agg.String(), func(t *testing.T) {
+			rs := ds.Resample(step, test.agg).(*DataSet)
+			// only the complete buckets at 60s through 240s remain
+			expected := timeseries.ExtentList{timeseries.Extent{Start: time.Unix(60, 0),
+				End: time.Unix(240, 0)}}
+			if !rs.ExtentList.Equal(expected) {
+				t.Errorf("expected %s got %s", expected, rs.ExtentList)
+			}
+			pts := rs.Results[0].SeriesList[0].Points
+			if len(pts) != 4 {
+				t.Fatalf("expected %d got %d", 4, len(pts))
+			}
+			for i, p := range pts {
+				k := i + 1
+				s, f := test.expected(k)
+				if p.Epoch != epoch.Epoch(int64(k)*60*timeseries.Second) ||
+					p.Values[0] != s || p.Values[1] != f || p.Values[2] != "label" {
+					t.Errorf("unexpected point %d: %d %v", i, p.Epoch, p.Values)
+				}
+			}
+			if rs.Results[0].SeriesList[0].PointSize != pts.Size() {
+				t.Error("mismatched point size")
+			}
+		})
+	}
+}
+
+func TestAggregateValue(t *testing.T) {
+	if v := aggregateValue(timeseries.AggregationSum, "1.5", "2.25"); v != "3.75" {
+		t.Errorf("expected %s got %v", "3.75", v)
+	}
+	if v := aggregateValue(timeseries.AggregationSum, "9007199254740993", "1"); v != "9007199254740994" {
+		t.Errorf("expected %s got %v", "9007199254740994", v)
+	}
+	if v := aggregateValue(timeseries.AggregationMax, 3, 5); v != 5 {
+		t.Errorf("expected %d got %v", 5, v)
+	}
+	if v := aggregateValue(timeseries.AggregationMin, int64(3), int64(5)); v != int64(3) {
+		t.Errorf("expected %d got %v", 3, v)
+	}
+	if v := aggregateValue(timeseries.AggregationSum, "a", "b"); v != "b" {
+		t.Errorf("expected %s got %v", "b", v)
+	}
+	if v := aggregateValue(timeseries.AggregationSum, 1.0, "b"); v != "b" {
+		t.Errorf("expected %s got %v", "b", v)
+	}
+}
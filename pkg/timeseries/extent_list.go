@@ -329,6 +329,59 @@ func (el ExtentList) CalculateDeltas(want Extent, step time.Duration) ExtentList
 	return ins
 }
 
+// Splice breaks each extent in the list into step-aligned chunks of no more than
+// maxPoints timestamps. Chunk boundaries are aligned to multiples of the chunk duration,
+// so a given time range is always split the same way, regardless of the requested extent.
+func (el ExtentList) Splice(step time.Duration, maxPoints int) ExtentList {
+	if step <= 0 || maxPoints <= 0 || len(el) == 0 {
+		return el
+	}
+	size := step * time.Duration(maxPoints)
+	out := make(ExtentList, 0, len(el))
+	for _, e := range el {
+		for s := e.Start; !s.After(e.End); {
+			end := s.Truncate(size).Add(size - step)
+			if end.Before(s) {
+				end = s
+			}
+			if end.After(e.End) {
+				end = e.End
+			}
+			out = append(out, Extent{Start: s, End: end})
+			s = end.Add(step)
+		}
+	}
+	return out
+}
+
+// Resample returns the extents of the list, whose timestamps are at step, that are fully
+// represented at the coarser step. When buckets is true, each timestamp at the coarser step
+// represents the bucket of time until its next timestamp, and is only represented when the
+// entire bucket is included in the list; otherwise, a timestamp is represented when it is
+// included in the list.
+func (el ExtentList) Resample(step, coarser time.Duration, buckets bool) ExtentList {
+	if step <= 0 || coarser < step || len(el) == 0 {
+		return ExtentList{}
+	}
+	out := make(ExtentList, 0, len(el))
+	for _, e := range el {
+		start := e.Start.Truncate(coarser)
+		if start.Before(e.Start) {
+			start = start.Add(coarser)
+		}
+		end := e.End
+		if buckets {
+			end = end.Add(step - coarser)
+		}
+		end = end.Truncate(coarser)
+		if end.Before(start) {
+			continue
+		}
+		out = append(out, Extent{Start: start, End: end, LastUsed: e.LastUsed})
+	}
+	return out.Compress(coarser)
+}
+
 // Size returns the approximate memory utilization in bytes of the timeseries
 func (el ExtentList) Size() int {
 	return len(el) * 72
@@ -896,3 +896,97 @@ func TestTimestampCount(t *testing.T) {
 	}
 
 }
+
+func TestSplice(t *testing.T) {
+
+	tests := []struct {
+		el        ExtentList
+		step      time.Duration
+		maxPoints int
+		expected  ExtentList
+	}{
+		{ // 0 - splicing disabled
+			ExtentList{Extent{Start: t100, End: t1000}}, time.Second * 100, 0,
+			ExtentList{Extent{Start: t100, End: t1000}},
+		},
+		{ // 1 - aligned to boundaries
+			ExtentList{Extent{Start: t100, End: t1000}}, time.Second * 100, 3,
+			ExtentList{
+				Extent{Start: t100, End: t200},
+				Extent{Start: t300, End: time.Unix(500, 0)},
+				Extent{Start: t600, End: time.Unix(800, 0)},
+				Extent{Start: t900, End: t1000},
+			},
+		},
+		{ // 2 - extent smaller than a chunk
+			ExtentList{Extent{Start: t100, End: t100}}, time.Second * 100, 3,
+			ExtentList{Extent{Start: t100, End: t100}},
+		},
+		{ // 3 - multiple extents
+			ExtentList{Extent{Start: t100, End: t300}, Extent{Start: t1100, End: t1400}},
+			time.Second * 100, 2,
+			ExtentList{
+				Extent{Start: t100, End: t100},
+				Extent{Start: t200, End: t300},
+				Extent{Start: t1100, End: t1100},
+				Extent{Start: t1200, End: t1300},
+				Extent{Start: t1400, End: t1400},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			el := test.el.Splice(test.step, test.maxPoints)
+			if !el.Equal(test.expected) {
+				t.Errorf("expected %s got %s", test.expected.String(), el.String())
+			}
+		})
+	}
+}
+
+func TestExtentListResample(t *testing.T) {
+
+	step := time.Second * 100
+	coarser := time.Second * 300
+
+	tests := []struct {
+		el       ExtentList
+		buckets  bool
+		expected ExtentList
+	}{
+		{ // 0 - sampled timestamps
+			ExtentList{Extent{Start: t100, End: t1000}}, false,
+			ExtentList{Extent{Start: t300, End: t900}},
+		},
+		{ // 1 - complete buckets only
+			ExtentList{Extent{Start: t100, End: t1000}}, true,
+			ExtentList{Extent{Start: t300, End: t600}},
+		},
+		{ // 2 - no coarser timestamps
+			ExtentList{Extent{Start: t100, End: t200}}, false,
+			ExtentList{},
+		},
+		{ // 3 - multiple extents
+			ExtentList{Extent{Start: t100, End: t300}, Extent{Start: t600, End: t1000}}, true,
+			ExtentList{Extent{Start: t600, End: t600}},
+		},
+		{ // 4 - multiple extents that become contiguous at the coarser step
+			ExtentList{Extent{Start: t100, End: t300}, Extent{Start: t600, End: t1000}}, false,
+			ExtentList{Extent{Start: t300, End: t900}},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			el := test.el.Resample(step, coarser, test.buckets)
+			if !el.Equal(test.expected) {
+				t.Errorf("expected %s got %s", test.expected.String(), el.String())
+			}
+		})
+	}
+
+	if el := (ExtentList{Extent{Start: t100, End: t1000}}).Resample(coarser, step, false); len(el) != 0 {
+		t.Errorf("expected empty list got %s", el.String())
+	}
+}
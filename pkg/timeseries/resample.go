@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import (
+	"strconv"
+	"time"
+)
+
+// Aggregation identifies how the values of a Timeseries are combined when it is
+// resampled to a coarser step
+type Aggregation byte
+
+const (
+	// AggregationSample keeps only the values whose timestamps fall exactly on the coarser
+	// step, which is correct for backends whose values at a timestamp do not depend on
+	// the step, such as Prometheus
+	AggregationSample Aggregation = iota
+	// AggregationSum sums the values of each bucket of the coarser step
+	AggregationSum
+	// AggregationMin keeps the lowest value of each bucket of the coarser step
+	AggregationMin
+	// AggregationMax keeps the highest value of each bucket of the coarser step
+	AggregationMax
+	// AggregationFirst keeps the earliest value of each bucket of the coarser step
+	AggregationFirst
+	// AggregationLast keeps the latest value of each bucket of the coarser step
+	AggregationLast
+)
+
+// AggregationNames is a map of Aggregations keyed by name
+var AggregationNames = map[string]Aggregation{
+	"sample": AggregationSample,
+	"sum":    AggregationSum,
+	"min":    AggregationMin,
+	"max":    AggregationMax,
+	"first":  AggregationFirst,
+	"last":   AggregationLast,
+}
+
+// AggregationValues is a map of Aggregation names keyed by Aggregation
+var AggregationValues = make(map[Aggregation]string)
+
+func init() {
+	for k, v := range AggregationNames {
+		AggregationValues[v] = k
+	}
+}
+
+func (a Aggregation) String() string {
+	if v, ok := AggregationValues[a]; ok {
+		return v
+	}
+	return strconv.Itoa(int(a))
+}
+
+// Resampler is an optional interface for Timeseries that can be converted to a coarser step
+type Resampler interface {
+	// Resample should return a copy of the Timeseries at the provided step, which must be a
+	// multiple of the Timeseries' step, combining values with the provided Aggregation
+	Resample(time.Duration, Aggregation) Timeseries
+}
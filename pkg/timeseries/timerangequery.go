@@ -38,6 +38,9 @@ type TimeRangeQuery struct {
 	Step time.Duration `msg:"-"`
 	// TemplateURL is used by some Backend providers for templatization of url parameters containing timestamps
 	TemplateURL *url.URL `msg:"-"`
+	// StepTemplateURL is the TemplateURL with the step removed or tokenized, so that it is the
+	// same for queries that differ only by step. It is nil when the step can't be isolated
+	StepTemplateURL *url.URL `msg:"-"`
 	// IsOffset is true if the query uses a relative offset modifier
 	IsOffset bool `msg:"-"`
 	// StepNS is the nanosecond representation for Step
@@ -89,6 +92,10 @@ func (trq *TimeRangeQuery) Clone() *TimeRangeQuery {
 		t.TemplateURL = urls.Clone(trq.TemplateURL)
 	}
 
+	if trq.StepTemplateURL != nil {
+		t.StepTemplateURL = urls.Clone(trq.StepTemplateURL)
+	}
+
 	if trq.Labels != nil {
 		t.Labels = make(map[string]string)
 		for k, v := range trq.Labels {
@@ -140,13 +147,13 @@ func (trq *TimeRangeQuery) GetBackfillTolerance(def time.Duration, points int) t
 // Size returns the memory usage in bytes of the TimeRangeQuery
 func (trq *TimeRangeQuery) Size() int {
 	return len(trq.Statement) + 24 + 8 + trq.TimestampDefinition.Size() + // Extent=24 + Step=8
-		urls.Size(trq.TemplateURL) + 11 // FFwDisable=1 IsOffset=1 StepNS=8 CustomData=1
+		urls.Size(trq.TemplateURL) + urls.Size(trq.StepTemplateURL) + 11 // FFwDisable=1 IsOffset=1 StepNS=8 CustomData=1
 }
 
 // ExtractBackfillTolerance will look for the BackfillToleranceFlag in the provided string
 // and return the BackfillTolerance value if present
 func (trq *TimeRangeQuery) ExtractBackfillTolerance(input string) {
-	if x := strings.Index(input, BackfillToleranceFlag); x > 1 {
+	if x := strings.Index(input, BackfillToleranceFlag); x >= 0 {
 		x += 29
 		y := x
 		for ; y < len(input); y++ {
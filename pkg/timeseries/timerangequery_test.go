@@ -80,6 +80,7 @@ func TestClone(t *testing.T) {
 	trq.TagFieldDefintions = []FieldDefinition{{}}
 	trq.ValueFieldDefinitions = []FieldDefinition{{}}
 	trq.Labels = map[string]string{"test": "trickster"}
+	trq.StepTemplateURL, _ = url.Parse("http://127.0.0.1/?query=up")
 
 	c := trq.Clone()
 	if !reflect.DeepEqual(trq, c) {
@@ -20,7 +20,11 @@ package middleware
 
 import (
 	"net/http"
+	"regexp"
 	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 )
 
 // StripPathPrefix removes the provided prefix from incoming HTTP Requests URLs
@@ -44,3 +48,16 @@ func StripPathPrefix(prefix string, next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// PathCaptures attaches the named segments captured from the request path by the
+// provided Regexp to the request context, for use by rewriters and cache key derivation
+func PathCaptures(re *regexp.Regexp, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r != nil && r.URL != nil {
+			if c, ok := matching.Captures(re, r.URL.Path); ok && len(c) > 0 {
+				r = r.WithContext(context.WithPathCaptures(r.Context(), c))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/tricksterproxy/trickster/pkg/observability/metrics"
+	"github.com/tricksterproxy/trickster/pkg/proxy/ratelimit"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+)
+
+// RateLimit admits requests according to the Limiter's rate limit, and responds to those
+// exceeding it on the Limiter's behalf. The request's limit key is saved to its Resources
+// so that its upstream requests are counted against the same in-flight quota
+func RateLimit(l *ratelimit.Limiter, backendName, backendProvider string,
+	next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := l.Key(r)
+		if l.Options().RequestsPerSecond > 0 {
+			ok, retryAfter := l.Allow(key)
+			if !ok {
+				metrics.ProxyRateLimitRejected.WithLabelValues(backendName, backendProvider,
+					ratelimit.LimitRate).Inc()
+				resp := l.Response(r, ratelimit.LimitRate, retryAfter)
+				for k, v := range resp.Header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(resp.StatusCode)
+				io.Copy(w, resp.Body)
+				return
+			}
+			metrics.ProxyRateLimitAdmitted.WithLabelValues(backendName, backendProvider,
+				ratelimit.LimitRate).Inc()
+		}
+		if rsc := request.GetResources(r); rsc != nil {
+			rsc.RateLimitKey = key
+		}
+		next.ServeHTTP(w, r)
+	})
+}